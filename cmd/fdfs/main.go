@@ -0,0 +1,195 @@
+/**
+ * Command fdfs is the command-line tool of FastDFS, the settings are read
+ * from the client.conf as the C tools.
+ *
+ *	fdfs [-c client.conf] [-json] [-timeout 30s] <command> [arguments]
+ *
+ * the commands:
+ *
+ *	upload [-group group] [-ext ext] [-meta name=value,...] [-appender] <local file|->
+ *	download [-offset n] [-length n] <file id> [local file|-]
+ *	delete <file id>...
+ *	info <file id>
+ *	meta get <file id>
+ *	meta set [-merge] <file id> <name=value>...
+ *	append <appender file id> <local file|->
+ *	truncate [-size n] <appender file id>
+ *	monitor [group]
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"github.com/go/fastdfs"
+)
+
+const DefaultConfFilename = "/etc/fdfs/client.conf"
+
+var errUsage = errors.New("usage")
+
+/**
+ * the subcommand
+ */
+type command struct {
+	usage  string
+	run    func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]*command{
+	"upload":{"upload [-group group] [-ext ext] [-meta name=value,...] [-appender] <local file|->", runUpload},
+	"download":{"download [-offset n] [-length n] <file id> [local file|-]", runDownload},
+	"delete":{"delete <file id>...", runDelete},
+	"info":{"info <file id>", runInfo},
+	"meta":{"meta get <file id>\n  meta set [-merge] <file id> <name=value>...", runMeta},
+	"append":{"append <appender file id> <local file|->", runAppend},
+	"truncate":{"truncate [-size n] <appender file id>", runTruncate},
+	"monitor":{"monitor [group]", runMonitor},
+}
+
+/**
+ * the state of the command line
+ */
+type cli struct {
+	client   *fastdfs.Client
+	json     bool
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+func main() {
+	ctx,stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+/**
+ * run the command line
+ *
+ * @return the exit code, 0 for success, 1 for failure and 2 for the wrong usage
+ */
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var flags = flag.NewFlagSet("fdfs", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var confFilename = flags.String("c", DefaultConfFilename, "the config file of the client.conf format")
+	var jsonOutput = flags.Bool("json", false, "print the output as JSON")
+	var timeout = flags.Duration("timeout", 0, "the timeout of the command, 0 for no timeout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: fdfs [-c client.conf] [-json] [-timeout 30s] <command> [arguments]\n\ncommands:")
+		var names = make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _,name := range names {
+			fmt.Fprintln(stderr, "  " + commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	var cmd = commands[flags.Arg(0)]
+	if cmd == nil {
+		fmt.Fprintf(stderr, "fdfs: unknown command %s\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	config,err := fastdfs.NewConfigByIniFile(*confFilename)
+	if err != nil {
+		fmt.Fprintf(stderr, "fdfs: load %s: %v\n", *confFilename, err)
+		return 1
+	}
+	var c = &cli{
+		client:fastdfs.NewClient(config),
+		json:*jsonOutput,
+		stdin:stdin,
+		stdout:stdout,
+		stderr:stderr,
+	}
+	defer c.client.Close()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx,cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if err = cmd.run(ctx, c, flags.Args()[1:]); err != nil {
+		if err == errUsage {
+			fmt.Fprintln(stderr, "usage: fdfs " + cmd.usage)
+			return 2
+		}
+		fmt.Fprintf(stderr, "fdfs %s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	return 0
+}
+
+/**
+ * create the flag set of the subcommand
+ */
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	var flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {}
+
+	return flags
+}
+
+/**
+ * print the value as JSON
+ */
+func (c *cli) printJSON(v interface{}) error {
+	var encoder = json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+/**
+ * print the rows as a table aligned by the columns
+ *
+ * @param header the column names, nil for no header
+ * @param rows   the rows of the table
+ */
+func (c *cli) printTable(header []string, rows [][]string) error {
+	var w = tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _,row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+/**
+ * format the time, empty for the zero unix time
+ */
+func formatTime(t time.Time) string {
+	if t.Unix() <= 0 {
+		return ""
+	}
+
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"github.com/go/fastdfs/fastdfstest"
+)
+
+type testCli struct {
+	t             *testing.T
+	confFilename  string
+}
+
+// run the command line and return the stdout, fail if the exit code is unexpected
+func (c *testCli) run(code int, stdin string, args ...string) string {
+	var stdout,stderr bytes.Buffer
+	if n := run(context.Background(), append([]string{"-c", c.confFilename}, args...), strings.NewReader(stdin), &stdout, &stderr); n != code {
+		c.t.Fatalf("fdfs %v exit code %d, expect %d, stderr %s", args, n, code, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func TestCommands(t *testing.T) {
+	tracker,err := fastdfstest.NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	for _,groupName := range []string{"group1", "group2"} {
+		if _,err = tracker.AddStorage(groupName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var dir = t.TempDir()
+	var c = &testCli{t:t, confFilename:filepath.Join(dir, "client.conf")}
+	if err = os.WriteFile(c.confFilename, []byte("connect_timeout = 2\nnetwork_timeout = 30\ntracker_server = " + tracker.Addr().String() + "\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var localFilename = filepath.Join(dir, "hello.txt")
+	if err = os.WriteFile(localFilename, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fileId = strings.TrimSpace(c.run(0, "", "upload", "-group", "group2", "-meta", "width=100,height=200", localFilename))
+	if !strings.HasPrefix(fileId, "group2/") || !strings.HasSuffix(fileId, ".txt") {
+		t.Fatalf("file id %s", fileId)
+	}
+	if output := c.run(0, "", "download", fileId); output != "hello world" {
+		t.Fatalf("download %q", output)
+	}
+	if output := c.run(0, "", "download", "-offset", "6", "-length", "3", fileId, "-"); output != "wor" {
+		t.Fatalf("download range %q", output)
+	}
+	var downloadFilename = filepath.Join(dir, "download.txt")
+	c.run(0, "", "download", fileId, downloadFilename)
+	if content,err := os.ReadFile(downloadFilename); err != nil || string(content) != "hello world" {
+		t.Fatalf("download file %q, err %v", content, err)
+	}
+
+	var info fileInfoOutput
+	if err = json.Unmarshal([]byte(c.run(0, "", "-json", "info", fileId)), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.FileId != fileId || info.FileSize != 11 || info.CreateTimestamp == 0 {
+		t.Fatalf("info %+v", info)
+	}
+	if output := c.run(0, "", "info", fileId); !strings.Contains(output, "file size    11\n") {
+		t.Fatalf("info %s", output)
+	}
+
+	c.run(0, "", "meta", "set", "-merge", fileId, "width=300", "depth=1")
+	var meta map[string]string
+	if err = json.Unmarshal([]byte(c.run(0, "", "-json", "meta", "get", fileId)), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if len(meta) != 3 || meta["width"] != "300" || meta["height"] != "200" || meta["depth"] != "1" {
+		t.Fatalf("merged metadata %v", meta)
+	}
+	c.run(0, "", "meta", "set", fileId, "width=1")
+	if output := c.run(0, "", "meta", "get", fileId); output != "width  1\n" {
+		t.Fatalf("overwritten metadata %q", output)
+	}
+
+	// appender file from stdin
+	var upload map[string]string
+	if err = json.Unmarshal([]byte(c.run(0, "abc", "-json", "upload", "-appender", "-ext", "log", "-")), &upload); err != nil {
+		t.Fatal(err)
+	}
+	var appenderFileId = upload["file_id"]
+	c.run(0, "def", "append", appenderFileId, "-")
+	c.run(0, "", "append", appenderFileId, localFilename)
+	if output := c.run(0, "", "download", appenderFileId); output != "abcdefhello world" {
+		t.Fatalf("appended %q", output)
+	}
+	c.run(0, "", "truncate", "-size", "4", appenderFileId)
+	if output := c.run(0, "", "download", appenderFileId); output != "abcd" {
+		t.Fatalf("truncated %q", output)
+	}
+
+	var groups []groupOutput
+	if err = json.Unmarshal([]byte(c.run(0, "", "-json", "monitor")), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[1].GroupName != "group2" || len(groups[1].Storages) != 1 || groups[1].Storages[0].Status != "ACTIVE" {
+		t.Fatalf("groups %+v", groups)
+	}
+	var output = c.run(0, "", "monitor", "group1")
+	if !strings.HasPrefix(output, "GROUP") || !strings.Contains(output, "\ngroup1:\nID") || strings.Contains(output, "group2") {
+		t.Fatalf("monitor %s", output)
+	}
+
+	c.run(1, "", "delete", fileId, appenderFileId, "group1/M00/00/00/invalid")
+	c.run(1, "", "info", fileId)
+	c.run(1, "", "download", "invalid")
+	c.run(1, "", "upload", filepath.Join(dir, "missing.txt"))
+	c.run(1, "", "meta", "set", fileId, "invalid")
+
+	for _,args := range [][]string{{}, {"unknown"}, {"upload"}, {"download"}, {"delete"}, {"info"}, {"meta"}, {"meta", "get"},
+		{"meta", "set", fileId}, {"meta", "list", fileId}, {"append", fileId}, {"truncate"}, {"monitor", "group1", "group2"}, {"-x", "info"}} {
+		c.run(2, "", args...)
+	}
+	if n := run(context.Background(), []string{"-c", filepath.Join(dir, "missing.conf"), "monitor"}, nil, &bytes.Buffer{}, &bytes.Buffer{}); n != 1 {
+		t.Fatalf("missing config exit code %d", n)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"github.com/go/fastdfs"
+)
+
+/**
+ * the JSON output of the group
+ */
+type groupOutput struct {
+	GroupName        string          `json:"group_name"`
+	TotalMB          int64           `json:"total_mb"`
+	FreeMB           int64           `json:"free_mb"`
+	TrunkFreeMB      int64           `json:"trunk_free_mb"`
+	StorageCount     int             `json:"storage_count"`
+	ActiveCount      int             `json:"active_count"`
+	StoragePort      int             `json:"storage_port"`
+	StorageHttpPort  int             `json:"storage_http_port"`
+	Storages         []storageOutput `json:"storages"`
+}
+
+/**
+ * the JSON output of the storage server
+ */
+type storageOutput struct {
+	Id                    string `json:"id"`
+	IpAddr                string `json:"ip_addr"`
+	Status                string `json:"status"`
+	Version               string `json:"version"`
+	TotalMB               int64  `json:"total_mb"`
+	FreeMB                int64  `json:"free_mb"`
+	TrunkServer           bool   `json:"trunk_server"`
+	JoinTime              int64  `json:"join_time"`
+	UpTime                int64  `json:"up_time"`
+	LastHeartBeatTime     int64  `json:"last_heart_beat_time"`
+	TotalUploadCount      int64  `json:"total_upload_count"`
+	SuccessUploadCount    int64  `json:"success_upload_count"`
+	TotalDownloadCount    int64  `json:"total_download_count"`
+	SuccessDownloadCount  int64  `json:"success_download_count"`
+	TotalDeleteCount      int64  `json:"total_delete_count"`
+	SuccessDeleteCount    int64  `json:"success_delete_count"`
+}
+
+func newGroupOutput(groupStat *fastdfs.StructGroupStat, storageStats []fastdfs.StructStorageStat) *groupOutput {
+	var g = &groupOutput{
+		GroupName:groupStat.GetGroupName(),
+		TotalMB:groupStat.GetTotalMB(),
+		FreeMB:groupStat.GetFreeMB(),
+		TrunkFreeMB:groupStat.GetTrunkFreeMB(),
+		StorageCount:groupStat.GetStorageCount(),
+		ActiveCount:groupStat.GetActiveCount(),
+		StoragePort:groupStat.GetStoragePort(),
+		StorageHttpPort:groupStat.GetStorageHttpPort(),
+		Storages:make([]storageOutput, 0, len(storageStats)),
+	}
+	for i := range storageStats {
+		var s = &storageStats[i]
+		g.Storages = append(g.Storages, storageOutput{
+			Id:s.GetId(),
+			IpAddr:s.GetIpAddr(),
+			Status:fastdfs.GetStorageStatusCaption(s.GetStatus()),
+			Version:s.GetVersion(),
+			TotalMB:s.GetTotalMB(),
+			FreeMB:s.GetFreeMB(),
+			TrunkServer:s.IsTrunkServer(),
+			JoinTime:s.GetJoinTime().Unix(),
+			UpTime:s.GetUpTime().Unix(),
+			LastHeartBeatTime:s.GetLastHeartBeatTime().Unix(),
+			TotalUploadCount:s.GetTotalUploadCount(),
+			SuccessUploadCount:s.GetSuccessUploadCount(),
+			TotalDownloadCount:s.GetTotalDownloadCount(),
+			SuccessDownloadCount:s.GetSuccessDownloadCount(),
+			TotalDeleteCount:s.GetTotalDeleteCount(),
+			SuccessDeleteCount:s.GetSuccessDeleteCount(),
+		})
+	}
+
+	return g
+}
+
+func runMonitor(ctx context.Context, c *cli, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+
+	var trackerClient = c.client.NewTrackerClient()
+	var groupStats []fastdfs.StructGroupStat
+	if len(args) == 1 {
+		groupStat,err := trackerClient.ListOneGroupCtx(ctx, nil, args[0])
+		if err != nil {
+			return err
+		}
+		groupStats = []fastdfs.StructGroupStat{*groupStat}
+	} else {
+		var err error
+		if groupStats,err = trackerClient.ListGroupsCtx(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	var groups = make([]*groupOutput, 0, len(groupStats))
+	for i := range groupStats {
+		storageStats,err := trackerClient.ListStoragesCtx(ctx, nil, groupStats[i].GetGroupName())
+		if err != nil {
+			return err
+		}
+		groups = append(groups, newGroupOutput(&groupStats[i], storageStats))
+	}
+
+	if c.json {
+		return c.printJSON(groups)
+	}
+	var rows = make([][]string, 0, len(groups))
+	for _,g := range groups {
+		rows = append(rows, []string{g.GroupName, strconv.FormatInt(g.TotalMB, 10), strconv.FormatInt(g.FreeMB, 10),
+			strconv.FormatInt(g.TrunkFreeMB, 10), strconv.Itoa(g.StorageCount), strconv.Itoa(g.ActiveCount),
+			strconv.Itoa(g.StoragePort), strconv.Itoa(g.StorageHttpPort)})
+	}
+	if err := c.printTable([]string{"GROUP", "TOTAL MB", "FREE MB", "TRUNK FREE MB", "STORAGES", "ACTIVE", "PORT", "HTTP PORT"}, rows); err != nil {
+		return err
+	}
+	for _,g := range groups {
+		rows = rows[:0]
+		for _,s := range g.Storages {
+			var trunk = ""
+			if s.TrunkServer {
+				trunk = "*"
+			}
+			rows = append(rows, []string{s.Id, s.IpAddr, s.Status, s.Version, strconv.FormatInt(s.TotalMB, 10),
+				strconv.FormatInt(s.FreeMB, 10), trunk,
+				fmt.Sprintf("%d/%d", s.SuccessUploadCount, s.TotalUploadCount),
+				fmt.Sprintf("%d/%d", s.SuccessDownloadCount, s.TotalDownloadCount),
+				fmt.Sprintf("%d/%d", s.SuccessDeleteCount, s.TotalDeleteCount),
+				formatTime(time.Unix(s.LastHeartBeatTime, 0))})
+		}
+		fmt.Fprintf(c.stdout, "\n%s:\n", g.GroupName)
+		if err := c.printTable([]string{"ID", "IP", "STATUS", "VERSION", "TOTAL MB", "FREE MB", "TRUNK", "UPLOADS", "DOWNLOADS", "DELETES", "LAST HEART BEAT"}, rows); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"github.com/go/fastdfs"
+)
+
+/**
+ * the JSON output of the file info
+ */
+type fileInfoOutput struct {
+	FileId           string `json:"file_id"`
+	FileSize         int64  `json:"file_size"`
+	CreateTimestamp  int64  `json:"create_timestamp"`
+	Crc32            uint32 `json:"crc32"`
+	SourceIpAddr     string `json:"source_ip_addr"`
+}
+
+/**
+ * parse the metadata formatted as name=value
+ *
+ * @param pairs the name value pairs
+ * @return the metadata
+ */
+func parseMetadata(pairs []string) ([]fastdfs.NameValuePair, error) {
+	var metaList = make([]fastdfs.NameValuePair, 0, len(pairs))
+	for _,pair := range pairs {
+		var pos = strings.IndexByte(pair, '=')
+		if pos <= 0 {
+			return nil, fmt.Errorf("invalid metadata %s, expect name=value", pair)
+		}
+		metaList = append(metaList, *fastdfs.NewNameValuePair(pair[:pos], pair[pos + 1:]))
+	}
+
+	return metaList, nil
+}
+
+/**
+ * split the file id into the group name and the remote filename
+ */
+func splitFileId(fileId string) (string, string, error) {
+	var parts = make([]string, 2)
+	if errno := fastdfs.SplitFileId(fileId, parts); errno != 0 {
+		return "", "", fmt.Errorf("invalid file id %s", fileId)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+/**
+ * open the source to upload, the content of stdin is read into memory
+ * as the size is required before sending
+ */
+func (c *cli) openSource(name string) (interface{}, error) {
+	if name != "-" {
+		return name, nil
+	}
+
+	return io.ReadAll(c.stdin)
+}
+
+func runUpload(ctx context.Context, c *cli, args []string) error {
+	var flags = c.newFlagSet("upload")
+	var groupName = flags.String("group", "", "the group to upload to")
+	var fileExtName = flags.String("ext", "", "the ext name, the ext name of the local file by default")
+	var meta = flags.String("meta", "", "the metadata formatted as name=value,...")
+	var appender = flags.Bool("appender", false, "upload an appender file")
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	source,err := c.openSource(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var opts = []fastdfs.UploadOption{fastdfs.WithGroup(*groupName), fastdfs.WithExtName(*fileExtName)}
+	if *meta != "" {
+		metaList,err := parseMetadata(strings.Split(*meta, ","))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, fastdfs.WithMetadata(metaList...))
+	}
+	if *appender {
+		opts = append(opts, fastdfs.WithAppender())
+	}
+	fileId,err := c.client.NewStorageClient1(nil, nil).Upload(ctx, source, opts...)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]string{"file_id":fileId.String()})
+	}
+	_,err = fmt.Fprintln(c.stdout, fileId.String())
+	return err
+}
+
+func runDownload(ctx context.Context, c *cli, args []string) error {
+	var flags = c.newFlagSet("download")
+	var fileOffset = flags.Int64("offset", 0, "the start offset of the file")
+	var downloadBytes = flags.Int64("length", 0, "the bytes to download, 0 for the remain bytes from the offset")
+	if flags.Parse(args) != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		return errUsage
+	}
+	groupName,remoteFilename,err := splitFileId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var dest interface{} = c.stdout
+	if flags.NArg() == 2 && flags.Arg(1) != "-" {
+		dest = flags.Arg(1)
+	}
+	return c.client.NewStorageClient1(nil, nil).Download(ctx, groupName, remoteFilename, dest, fastdfs.WithRange(*fileOffset, *downloadBytes))
+}
+
+func runDelete(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var storageClient = c.client.NewStorageClient1(nil, nil)
+	var failed = 0
+	for _,fileId := range args {
+		if _,err := storageClient.DeleteFile1Ctx(ctx, fileId); err != nil {
+			fmt.Fprintf(c.stderr, "fdfs delete: %s: %v\n", fileId, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(args))
+	}
+
+	return nil
+}
+
+func runInfo(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	fileInfo,err := c.client.NewStorageClient1(nil, nil).QueryFileInfo1Ctx(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(&fileInfoOutput{
+			FileId:args[0],
+			FileSize:fileInfo.GetFileSize(),
+			CreateTimestamp:fileInfo.GetCreateTimestamp().Unix(),
+			Crc32:uint32(fileInfo.GetCrc32()),
+			SourceIpAddr:fileInfo.GetSourceIpAddr(),
+		})
+	}
+	return c.printTable(nil, [][]string{
+		{"file id", args[0]},
+		{"file size", strconv.FormatInt(fileInfo.GetFileSize(), 10)},
+		{"create time", formatTime(fileInfo.GetCreateTimestamp())},
+		{"crc32", fmt.Sprintf("%08X", uint32(fileInfo.GetCrc32()))},
+		{"source ip", fileInfo.GetSourceIpAddr()},
+	})
+}
+
+func runMeta(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var storageClient = c.client.NewStorageClient1(nil, nil)
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			return errUsage
+		}
+		metaList,err := storageClient.GetMetadata1Ctx(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if c.json {
+			var output = make(map[string]string, len(metaList))
+			for _,pair := range metaList {
+				output[pair.GetName()] = pair.GetValue()
+			}
+			return c.printJSON(output)
+		}
+		var rows = make([][]string, 0, len(metaList))
+		for _,pair := range metaList {
+			rows = append(rows, []string{pair.GetName(), pair.GetValue()})
+		}
+		return c.printTable(nil, rows)
+	case "set":
+		var flags = c.newFlagSet("meta set")
+		var merge = flags.Bool("merge", false, "merge into the metadata instead of overwriting")
+		if flags.Parse(args[1:]) != nil || flags.NArg() < 2 {
+			return errUsage
+		}
+		metaList,err := parseMetadata(flags.Args()[1:])
+		if err != nil {
+			return err
+		}
+		var opFlag byte = fastdfs.STORAGE_SET_METADATA_FLAG_OVERWRITE
+		if *merge {
+			opFlag = fastdfs.STORAGE_SET_METADATA_FLAG_MERGE
+		}
+		_,err = storageClient.SetMetadata1Ctx(ctx, flags.Arg(0), metaList, opFlag)
+		return err
+	}
+
+	return errUsage
+}
+
+func runAppend(ctx context.Context, c *cli, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	groupName,appenderFilename,err := splitFileId(args[0])
+	if err != nil {
+		return err
+	}
+
+	source,err := c.openSource(args[1])
+	if err != nil {
+		return err
+	}
+	return c.client.NewStorageClient1(nil, nil).Append(ctx, groupName, appenderFilename, source)
+}
+
+func runTruncate(ctx context.Context, c *cli, args []string) error {
+	var flags = c.newFlagSet("truncate")
+	var size = flags.Int("size", 0, "the file size after truncated")
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	_,err := c.client.NewStorageClient1(nil, nil).TruncateFileBySize1Ctx(ctx, flags.Arg(0), *size)
+	return err
+}
@@ -0,0 +1,40 @@
+/**
+ * Command fdfs_exporter serves the statistics of the FastDFS cluster in the
+ * Prometheus text format, the settings are read from the client.conf.
+ *
+ *	fdfs_exporter [-c client.conf] [-listen :9164] [-path /metrics] [-interval 15s]
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/exporter"
+)
+
+func main() {
+	var confFilename = flag.String("c", "/etc/fdfs/client.conf", "the config file of the client.conf format")
+	var listen = flag.String("listen", ":9164", "the address to listen on")
+	var path = flag.String("path", "/metrics", "the path of the metrics")
+	var interval = flag.Duration("interval", exporter.DefaultInterval, "the interval of polling the tracker")
+	flag.Parse()
+
+	config,err := fastdfs.NewConfigByIniFile(*confFilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fdfs_exporter: load %s: %v\n", *confFilename, err)
+		os.Exit(1)
+	}
+	var e = exporter.NewExporter(fastdfs.NewClient(config), exporter.WithInterval(*interval))
+	e.Start()
+	defer e.Close()
+
+	var mux = http.NewServeMux()
+	mux.Handle(*path, e)
+	if err = http.ListenAndServe(*listen, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "fdfs_exporter: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,270 @@
+package fastdfs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+/**
+ * translate the storage server address returned by the tracker to the address
+ * the client connects, such as the address mapped by NAT or the container
+ * network.<br>
+ * the translator is called by the operations concurrently.
+ */
+type AddressTranslator interface {
+	/**
+	 * @param ip_addr the ip address returned by the tracker
+	 * @param port    the port returned by the tracker, or the http port for the download URL
+	 * @return the host and the port to connect
+	 */
+	Translate(ipAddr string, port int) (string, int)
+}
+
+/**
+ * the function as the AddressTranslator
+ */
+type AddressTranslatorFunc func(ipAddr string, port int) (string, int)
+
+func (f AddressTranslatorFunc) Translate(ipAddr string, port int) (string, int) {
+	return f(ipAddr, port)
+}
+
+/**
+ * the address translation table, the rules are matched in the order:<br>
+ * the static rules of ip:port, the static rules of ip, the CIDR rules in
+ * the order added, then the callback; the address matching nothing is kept.
+ */
+type AddressTable struct {
+	hosts     map[string]hostPort //the static rules keyed by ip or ip:port
+	networks  []networkRule
+	callback  AddressTranslatorFunc
+}
+
+type hostPort struct {
+	host  string
+	port  int //0 to keep the port
+}
+
+/**
+ * rewrite the addresses of the network
+ */
+type networkRule struct {
+	from  *net.IPNet
+	to    *net.IPNet //rewrite the network prefix, nil to map to the host
+	host  hostPort
+}
+
+/**
+ * Constructor of the empty table
+ */
+func NewAddressTable() *AddressTable {
+	return &AddressTable{hosts:make(map[string]hostPort)}
+}
+
+/**
+ * parse the rules formatted as "from to", separated by comma, such as
+ * "172.17.0.2:23000 203.0.113.5:23001, 172.18.0.0/16 10.8.0.0/16"
+ *
+ * @param rules the rules, see AddRule
+ * @return the table
+ */
+func ParseAddressTable(rules string) (*AddressTable, error) {
+	var table = NewAddressTable()
+	for _,rule := range strings.Split(rules, ",") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		if err := table.addRuleString(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+/**
+ * add the rule formatted as "from to"
+ */
+func (t *AddressTable) addRuleString(rule string) error {
+	var fields = strings.Fields(rule)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid address rule %q, the correct format is \"from to\"", strings.TrimSpace(rule))
+	}
+
+	return t.AddRule(fields[0], fields[1])
+}
+
+/**
+ * add the rule, from is an ip, ip:port or CIDR:<br>
+ * ip or ip:port maps to host or host:port, the port is kept without it;<br>
+ * CIDR maps to CIDR of the same prefix length, the network prefix is
+ * replaced and the host part is kept, or maps to host or host:port.
+ *
+ * @param from the address returned by the tracker
+ * @param to   the address to connect
+ * @return error if the rule is invalid
+ */
+func (t *AddressTable) AddRule(from, to string) error {
+	if strings.Contains(from, "/") {
+		_,fromNet,err := net.ParseCIDR(from)
+		if err != nil {
+			return err
+		}
+		var rule = networkRule{from:fromNet}
+		if strings.Contains(to, "/") {
+			if _,rule.to,err = net.ParseCIDR(to); err != nil {
+				return err
+			}
+			fromOnes,fromBits := fromNet.Mask.Size()
+			toOnes,toBits := rule.to.Mask.Size()
+			if fromOnes != toOnes || fromBits != toBits {
+				return fmt.Errorf("the prefix length of %s and %s differ", from, to)
+			}
+		} else if rule.host,err = parseHostPort(to); err != nil {
+			return err
+		}
+		t.networks = append(t.networks, rule)
+		return nil
+	}
+
+	var key = from
+	if host,port,err := net.SplitHostPort(from); err == nil {
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid ip address %s", host)
+		}
+		if _,err = strconv.Atoi(port); err != nil {
+			return fmt.Errorf("invalid port of %s", from)
+		}
+		key = net.JoinHostPort(net.ParseIP(host).String(), port)
+	} else if ip := net.ParseIP(strings.Trim(from, "[]")); ip != nil {
+		key = ip.String()
+	} else {
+		return fmt.Errorf("invalid address %s, expect ip, ip:port or CIDR", from)
+	}
+	target,err := parseHostPort(to)
+	if err != nil {
+		return err
+	}
+	t.hosts[key] = target
+
+	return nil
+}
+
+/**
+ * @param callback translate the addresses matching no rule
+ */
+func (t *AddressTable) SetCallback(callback AddressTranslatorFunc) {
+	t.callback = callback
+}
+
+/**
+ * parse host or host:port, the IPv6 address with port is bracketed
+ */
+func parseHostPort(address string) (hostPort, error) {
+	if address == "" {
+		return hostPort{}, fmt.Errorf("empty address")
+	}
+	host,port,err := net.SplitHostPort(address)
+	if err != nil {
+		// no port
+		return hostPort{host:strings.Trim(address, "[]")}, nil
+	}
+	portNum,err := strconv.Atoi(port)
+	if err != nil || portNum <= 0 || portNum > 65535 {
+		return hostPort{}, fmt.Errorf("invalid port of %s", address)
+	}
+
+	return hostPort{host:host, port:portNum}, nil
+}
+
+func (t *AddressTable) Translate(ipAddr string, port int) (string, int) {
+	var ip = net.ParseIP(ipAddr)
+	if ip != nil {
+		var target,ok = t.hosts[net.JoinHostPort(ip.String(), strconv.Itoa(port))]
+		if !ok {
+			target,ok = t.hosts[ip.String()]
+		}
+		if ok {
+			return target.translate(port)
+		}
+
+		for _,rule := range t.networks {
+			if !rule.from.Contains(ip) {
+				continue
+			}
+			if rule.to == nil {
+				return rule.host.translate(port)
+			}
+			return rewriteNetwork(ip, rule.from, rule.to).String(), port
+		}
+	}
+	if t.callback != nil {
+		return t.callback(ipAddr, port)
+	}
+
+	return ipAddr, port
+}
+
+func (h hostPort) translate(port int) (string, int) {
+	if h.port == 0 {
+		return h.host, port
+	}
+
+	return h.host, h.port
+}
+
+/**
+ * replace the network prefix of the ip
+ *
+ * @param ip   the ip in the network from
+ * @param from the network of the ip
+ * @param to   the network of the same prefix length
+ * @return the ip in the network to
+ */
+func rewriteNetwork(ip net.IP, from, to *net.IPNet) net.IP {
+	if v4 := ip.To4(); v4 != nil && len(from.IP) == net.IPv4len {
+		ip = v4
+	}
+	var result = make(net.IP, len(to.IP))
+	for i := range result {
+		result[i] = to.IP[i] & to.Mask[i] | ip[i] &^ from.Mask[i]
+	}
+
+	return result
+}
+
+/**
+ * @param translator the translator of the storage addresses returned by the tracker, nil for none
+ */
+func WithAddressTranslator(translator AddressTranslator) ConfigOption {
+	return func(c *Config) {
+		c.AddressTranslator = translator
+	}
+}
+
+/**
+ * translate the storage address returned by the tracker
+ *
+ * @param ip_addr the ip address returned by the tracker
+ * @param port    the port
+ * @return the host and the port to connect
+ */
+func (c *Config) translateAddr(ipAddr string, port int) (string, int) {
+	var translator = GAddressTranslator
+	if c != nil {
+		translator = c.AddressTranslator
+	}
+	if translator == nil {
+		return ipAddr, port
+	}
+
+	host,translatedPort := translator.Translate(ipAddr, port)
+	if host != ipAddr || translatedPort != port {
+		c.getLogger().Debug("fastdfs: address translated", "addr", net.JoinHostPort(ipAddr, strconv.Itoa(port)),
+			"translated", net.JoinHostPort(host, strconv.Itoa(translatedPort)))
+	}
+
+	return host, translatedPort
+}
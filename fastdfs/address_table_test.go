@@ -0,0 +1,129 @@
+package fastdfs
+
+import (
+	"testing"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"github.com/go/properties"
+)
+
+func TestAddressTable(t *testing.T) {
+	table,err := ParseAddressTable("172.17.0.2:23000 203.0.113.5:23001, 172.17.0.2 203.0.113.5,172.18.0.0/16 10.8.0.0/16, 172.19.0.0/16 storage.example.com:8888")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table.SetCallback(func(ipAddr string, port int) (string, int) {
+		if ipAddr == "192.168.0.1" {
+			return "gateway", port + 1
+		}
+		return ipAddr, port
+	})
+
+	var cases = []struct {
+		ip        string
+		port      int
+		host      string
+		hostPort  int
+	}{
+		{"172.17.0.2", 23000, "203.0.113.5", 23001}, //ip:port first
+		{"172.17.0.2", 8080, "203.0.113.5", 8080},   //the port is kept
+		{"172.18.3.4", 23000, "10.8.3.4", 23000},    //the host part is kept
+		{"172.19.3.4", 23000, "storage.example.com", 8888},
+		{"192.168.0.1", 23000, "gateway", 23001},
+		{"192.168.0.2", 23000, "192.168.0.2", 23000},
+		{"not-an-ip", 23000, "not-an-ip", 23000},
+	}
+	for _,c := range cases {
+		if host,port := table.Translate(c.ip, c.port); host != c.host || port != c.hostPort {
+			t.Errorf("translate %s:%d to %s:%d, expect %s:%d", c.ip, c.port, host, port, c.host, c.hostPort)
+		}
+	}
+
+	// the CIDR rules are matched in the order added
+	table = NewAddressTable()
+	for _,rule := range [][2]string{{"10.0.0.0/8", "host1"}, {"10.1.0.0/16", "host2"}, {"fd00::/64", "fd01::/64"}} {
+		if err = table.AddRule(rule[0], rule[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if host,_ := table.Translate("10.1.2.3", 23000); host != "host1" {
+		t.Fatalf("translate to %s, expect host1", host)
+	}
+	if host,_ := table.Translate("fd00::1:2", 23000); host != "fd01::1:2" {
+		t.Fatalf("translate to %s, expect fd01::1:2", host)
+	}
+
+	for _,rules := range []string{"172.17.0.2", "172.17.0.2 1.2.3.4 5.6.7.8", "172.18.0.0/16 10.8.0.0/24", "172.18.0.0/33 10.8.0.0/16",
+		"storage 1.2.3.4", "172.17.0.2:x 1.2.3.4", "172.17.0.2 1.2.3.4:0", "172.17.0.2 1.2.3.4:70000"} {
+		if _,err = ParseAddressTable(rules); err == nil {
+			t.Errorf("parse invalid rules %q succeeded", rules)
+		}
+	}
+	if table,err = ParseAddressTable(" , "); err != nil || len(table.hosts) != 0 || len(table.networks) != 0 {
+		t.Fatalf("parse empty rules: %v", err)
+	}
+}
+
+func TestAddressTranslation(t *testing.T) {
+	listener,_ := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+	var port = listener.Addr().(*net.TCPAddr).Port
+	// the tracker returns the address unreachable from the client
+	var tracker = startQueryTracker(t, []string{"10.255.0.1"}, []string{"10.255.0.1"}, 23000)
+	defer tracker.Close()
+
+	var table = NewAddressTable()
+	if err := table.AddRule("10.255.0.1:23000", "127.0.0.1:" + strconv.Itoa(port)); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.AddRule("10.255.0.1:8080", "files.example.com:80"); err != nil {
+		t.Fatal(err)
+	}
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil), WithAddressTranslator(table)))
+	var storageClient = client.NewStorageClient()
+	fileId,err := storageClient.UploadAppenderBuffer([]byte("hello"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data,err := storageClient.DownloadBuffer(fileId.GetGroupName(), fileId.GetFilename())
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("download %q, err %v", data, err)
+	}
+
+	servers,err := client.NewTrackerClient().GetStorages(nil, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE, fileId.GetGroupName(), fileId.GetFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servers[0].GetIpAddr() != "127.0.0.1" || servers[0].GetPort() != port {
+		t.Fatalf("storage %s:%d, expect 127.0.0.1:%d", servers[0].GetIpAddr(), servers[0].GetPort(), port)
+	}
+
+	// the http port of the download URL is translated too
+	u,err := client.NewURLSigner(WithHttpPort(8080)).BuildFetchURL(context.Background(), client.NewTrackerClient(), fileId.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "http://files.example.com/" + fileId.String()) {
+		t.Fatalf("url %s", u)
+	}
+}
+
+func TestAddressTableConfig(t *testing.T) {
+	var props = properties.NewProperties()
+	props.Put(PropKeyTrackerServers, "10.0.11.101:22122")
+	props.Put(PropKeyStorageAddressMap, "172.17.0.2 203.0.113.5, 172.18.0.0/16 10.8.0.0/16")
+	config,err := NewConfigByProperties(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host,_ := config.translateAddr("172.18.1.2", 23000); host != "10.8.1.2" {
+		t.Fatalf("translate to %s, expect 10.8.1.2", host)
+	}
+
+	props.Put(PropKeyStorageAddressMap, "172.17.0.2")
+	if _,err = NewConfigByProperties(props); err == nil {
+		t.Fatalf("invalid %s is accepted", PropKeyStorageAddressMap)
+	}
+}
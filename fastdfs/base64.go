@@ -148,7 +148,7 @@ func (this *Base64) Encode(b []byte) (string, error) {
 	}
 
 	// must be local for recursion to work.
-	var sb = bytes.NewBuffer(nil)
+	var sb = bytes.NewBuffer(make([]byte, 0, outputLength))
 
 	// must be local for recursion to work.
 	var linePos = 0
@@ -257,12 +257,6 @@ func (this *Base64) Encode(b []byte) (string, error) {
 		} // end switch
 	}
 
-	if outputLength != sb.Len() {
-		fmt.Println("oops: minor program flaw: output length mis-estimated")
-		fmt.Println("estimate:", outputLength)
-		fmt.Println("actual:", sb.Len())
-	}
-
 	return sb.String(), nil
 }
 
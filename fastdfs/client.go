@@ -0,0 +1,117 @@
+package fastdfs
+
+/**
+ * FastDFS client with its own settings, several clients can work
+ * with different clusters in one process
+ */
+type Client struct {
+	config *Config //nil for the global settings
+}
+
+/**
+ * Constructor
+ *
+ * @param config the client settings, nil for the global settings
+ */
+func NewClient(config *Config) *Client {
+	return &Client{
+		config:config,
+	}
+}
+
+/**
+ * @return the client settings
+ */
+func (c *Client) GetConfig() *Config {
+	if c.config == nil {
+		return GetDefaultConfig()
+	}
+
+	return c.config
+}
+
+/**
+ * create tracker client with the tracker group of the settings
+ */
+func (c *Client) NewTrackerClient() *TrackerClient {
+	return &TrackerClient{
+		trackerGroup:c.config.getTrackerGroup(),
+		config:c.config,
+	}
+}
+
+/**
+ * create tracker client with specified tracker group
+ *
+ * @param trackerGroup the tracker group object
+ */
+func (c *Client) NewTrackerClientByGroup(trackerGroup *TrackerGroup) *TrackerClient {
+	return &TrackerClient{
+		trackerGroup:trackerGroup,
+		config:c.config,
+	}
+}
+
+/**
+ * create storage client, the servers are queried from tracker
+ */
+func (c *Client) NewStorageClient() *StorageClient {
+	return &StorageClient{
+		config:c.config,
+	}
+}
+
+/**
+ * create storage client with tracker server and storage server
+ *
+ * @param trackerServer the tracker server, can be null
+ * @param storageServer the storage server, can be null
+ */
+func (c *Client) NewStorageClientByServer(trackerServer *TrackerServer, storageServer *StorageServer) *StorageClient {
+	return &StorageClient{
+		trackerServer:trackerServer,
+		storageServer:storageServer,
+		config:c.config,
+	}
+}
+
+/**
+ * create storage client for 1 field file id
+ *
+ * @param trackerServer the tracker server, can be null
+ * @param storageServer the storage server, can be null
+ */
+func (c *Client) NewStorageClient1(trackerServer *TrackerServer, storageServer *StorageServer) *StorageClient1 {
+	return &StorageClient1{
+		StorageClient:*c.NewStorageClientByServer(trackerServer, storageServer),
+	}
+}
+
+/**
+ * get token for file URL with the secret key of the settings
+ *
+ * @param remoteFilename the filename return by FastDFS server
+ * @param ts             unix timestamp, unit: second
+ * @return token string
+ */
+func (c *Client) GetToken(remoteFilename string, ts int) (string, error) {
+	var secretKey = GSecretKey
+	if c.config != nil {
+		secretKey = c.config.SecretKey
+	}
+
+	return GetTokenByCharset(remoteFilename, ts, secretKey, c.config.getCharset())
+}
+
+/**
+ * close the idle connections of the connection pool
+ */
+func (c *Client) Close() {
+	var pool = GConnectionPool
+	if c.config != nil {
+		pool = c.config.ConnectionPool
+	}
+	if pool != nil {
+		pool.Close()
+	}
+}
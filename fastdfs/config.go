@@ -0,0 +1,625 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/go/fastdfs/proto"
+	"github.com/go/properties"
+)
+
+/**
+ * client settings, the global variables are the settings of the default client
+ */
+type Config struct {
+	ConnectTimeout          int    //millisecond
+	NetworkTimeout          int    //millisecond
+	Charset                 string
+	AntiStealToken          bool   //if anti-steal token
+	SecretKey               string //generage token secret key
+	TrackerHttpPort         int
+	TrackerGroup            *TrackerGroup
+	ConnectionPoolEnabled   bool
+	ConnectionPool          *ConnectionPool
+	RetryPolicy             *RetryPolicy //nil for no retry
+	Logger                  Logger       //nil for no logging
+	Interceptors            []Interceptor //the interceptors of the operations, the first is the outermost
+	Tracer                  Tracer       //the tracer of the protocol round-trips, nil for none
+	AddressTranslator       AddressTranslator //translate the storage addresses returned by the tracker, nil for none
+	ProtocolVersion         proto.Version //the protocol version of the servers, 0 for PROTO_VERSION_5
+}
+
+/**
+ * config option
+ */
+type ConfigOption func(c *Config)
+
+/**
+ * Constructor with default settings
+ *
+ * @param options the options to apply
+ */
+func NewConfig(options ...ConfigOption) *Config {
+	var config = &Config{
+		ConnectTimeout:DefaultConnectTimeout * 1000,
+		NetworkTimeout:DefaultNetworkTimeout * 1000,
+		Charset:DefaultCharset,
+		AntiStealToken:DefaultHttpAntiStealToken,
+		SecretKey:DefaultHttpSecretKey,
+		TrackerHttpPort:DefaultHttpTrackerHttpPort,
+		ConnectionPoolEnabled:DefaultConnectionPoolEnabled,
+	}
+	for _,option := range options {
+		option(config)
+	}
+	if config.ConnectionPoolEnabled && config.ConnectionPool == nil {
+		config.ConnectionPool = NewConnectionPool(DefaultConnectionPoolMaxCountPerEntry, DefaultConnectionPoolMaxIdleCountPerEntry,
+			DefaultConnectionPoolMaxIdleTime * time.Second, DefaultConnectionPoolMaxWaitTimeInMs * time.Millisecond)
+	}
+	config.applyPoolTimeout()
+
+	return config
+}
+
+/**
+ * load config from ini file
+ *
+ * @param confFilename config filename
+ */
+func NewConfigByIniFile(confFilename string) (*Config, error) {
+	var config = NewConfig()
+	if err := config.LoadIniFile(confFilename); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+/**
+ * load from ini file, the items of the ini file not found take the default value,
+ * the connection pool is rebuilt only when its items are found, the settings
+ * without ini item such as the retry policy, the logger, the interceptors and
+ * the tracer keep the current value
+ *
+ * @param confFilename config filename
+ */
+func (c *Config) LoadIniFile(confFilename string) error {
+	iniReader,err := NewIniFileReader(confFilename)
+	if err != nil {
+		return err
+	}
+
+	c.ConnectTimeout = iniReader.GetIntValue(ConfKeyConnectTimeout, DefaultConnectTimeout)
+	if c.ConnectTimeout < 0 {
+		c.ConnectTimeout = DefaultConnectTimeout
+	}
+	c.ConnectTimeout *= 1000 //millisecond
+
+	c.NetworkTimeout = iniReader.GetIntValue(ConfKeyNetworkTimeout, DefaultNetworkTimeout)
+	if c.NetworkTimeout < 0 {
+		c.NetworkTimeout = DefaultNetworkTimeout
+	}
+	c.NetworkTimeout *= 1000 //millisecond
+
+	c.Charset = iniReader.GetStrValue(ConfKeyCharset)
+	if c.Charset == "" || len(c.Charset) == 0 {
+		c.Charset = "ISO8859-1"
+	}
+
+	var szTrackerServers = iniReader.GetValues(ConfKeyTrackerServer)
+	if szTrackerServers == nil {
+		return errors.New("item \"tracker_server\" in " + confFilename + " not found")
+	}
+
+	var trackerServers = make([]net.Addr, len(szTrackerServers))
+	for i := 0; i < len(szTrackerServers); i++ {
+		if trackerServers[i],err = parseTrackerServer(szTrackerServers[i]); err != nil {
+			return err
+		}
+	}
+	c.TrackerGroup = NewTrackerGroup(trackerServers)
+
+	c.TrackerHttpPort = iniReader.GetIntValue(ConfKeyHttpTrackerHttpPort, 80)
+	c.AntiStealToken = iniReader.GetBoolValue(ConfKeyHttpAntiStealToken, false)
+	if c.AntiStealToken {
+		c.SecretKey = iniReader.GetStrValue(ConfKeyHttpSecretKey)
+	}
+
+	if rules := iniReader.GetValues(ConfKeyStorageAddressMap); rules != nil {
+		var table = NewAddressTable()
+		for _,rule := range rules {
+			if err = table.addRuleString(rule); err != nil {
+				return err
+			}
+		}
+		c.AddressTranslator = table
+	}
+
+	if version := iniReader.GetStrValue(ConfKeyProtocolVersion); version != "" {
+		if c.ProtocolVersion,err = ParseProtocolVersion(version); err != nil {
+			return err
+		}
+	}
+
+	if iniReader.GetStrValue(ConfKeyConnectionPoolEnabled) != "" || iniReader.GetStrValue(ConfKeyConnectionPoolMaxCountPerEntry) != "" ||
+		iniReader.GetStrValue(ConfKeyConnectionPoolMaxIdleCountPerEntry) != "" || iniReader.GetStrValue(ConfKeyConnectionPoolMaxIdleTime) != "" ||
+		iniReader.GetStrValue(ConfKeyConnectionPoolMaxWaitTimeInMs) != "" {
+		var enabled = iniReader.GetBoolValue(ConfKeyConnectionPoolEnabled, DefaultConnectionPoolEnabled)
+		var maxCountPerEntry = iniReader.GetIntValue(ConfKeyConnectionPoolMaxCountPerEntry, DefaultConnectionPoolMaxCountPerEntry)
+		var maxIdleCountPerEntry = iniReader.GetIntValue(ConfKeyConnectionPoolMaxIdleCountPerEntry, DefaultConnectionPoolMaxIdleCountPerEntry)
+		var maxIdleTime = iniReader.GetIntValue(ConfKeyConnectionPoolMaxIdleTime, DefaultConnectionPoolMaxIdleTime)
+		var maxWaitTimeInMs = iniReader.GetIntValue(ConfKeyConnectionPoolMaxWaitTimeInMs, DefaultConnectionPoolMaxWaitTimeInMs)
+		c.rebuildConnectionPool(enabled, maxCountPerEntry, maxIdleCountPerEntry, time.Duration(maxIdleTime) * time.Second, time.Duration(maxWaitTimeInMs) * time.Millisecond)
+	}
+	c.applyPoolTimeout()
+
+	return nil
+}
+
+/**
+ * load config from properties file
+ *
+ * @param propsFilePath properties file path, the item fastdfs.tracker_servers is required
+ */
+func NewConfigByPropertiesFile(propsFilePath string) (*Config, error) {
+	var props = properties.NewProperties()
+	file,err := os.Open(propsFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err = props.Load(file); err != nil {
+		return nil, err
+	}
+
+	return NewConfigByProperties(props)
+}
+
+/**
+ * load config from properties, the items not found take the default value
+ *
+ * @param props the properties, the item fastdfs.tracker_servers is required
+ */
+func NewConfigByProperties(props *properties.Properties) (*Config, error) {
+	var config = NewConfig()
+	if err := config.LoadProperties(props); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+/**
+ * override the settings found in properties
+ *
+ * @param props the properties, the item fastdfs.tracker_servers is required
+ */
+func (c *Config) LoadProperties(props *properties.Properties) error {
+	var trackerServersConf = props.GetProperty(PropKeyTrackerServers)
+
+	if trackerServersConf == "" || len(trackerServersConf) == 0 {
+		return fmt.Errorf("configure item %s is required", PropKeyTrackerServers)
+	}
+
+	trackerServers,err := ParseTrackerServers(trackerServersConf)
+	if err != nil {
+		return err
+	}
+	c.TrackerGroup = NewTrackerGroup(trackerServers)
+
+	connectTimeoutInSecondsConf := props.GetProperty(PropKeyConnectTimeoutInSeconds)
+	networkTimeoutInSecondsConf := props.GetProperty(PropKeyNetworkTimeoutInSeconds)
+	charsetConf := props.GetProperty(PropKeyCharset)
+	httpAntiStealTokenConf := props.GetProperty(PropKeyHttpAntiStealToken)
+	httpSecretKeyConf := props.GetProperty(PropKeyHttpSecretKey)
+	httpTrackerHttpPortConf := props.GetProperty(PropKeyHttpTrackerHttpPort)
+	poolEnabledConf := props.GetProperty(PropKeyConnectionPoolEnabled)
+	addressMapConf := props.GetProperty(PropKeyStorageAddressMap)
+	protocolVersionConf := props.GetProperty(PropKeyProtocolVersion)
+	poolMaxCountPerEntryConf := props.GetProperty(PropKeyConnectionPoolMaxCountPerEntry)
+	poolMaxIdleCountPerEntryConf := props.GetProperty(PropKeyConnectionPoolMaxIdleCountPerEntry)
+	poolMaxIdleTimeConf := props.GetProperty(PropKeyConnectionPoolMaxIdleTime)
+	poolMaxWaitTimeInMsConf := props.GetProperty(PropKeyConnectionPoolMaxWaitTimeInMs)
+	if connectTimeoutInSecondsConf != "" && len(strings.TrimSpace(connectTimeoutInSecondsConf)) != 0 {
+		if c.ConnectTimeout,err = strconv.Atoi(strings.TrimSpace(connectTimeoutInSecondsConf)); err != nil {
+			return err
+		}
+		c.ConnectTimeout *= 1000
+	}
+	if networkTimeoutInSecondsConf != "" && len(strings.TrimSpace(networkTimeoutInSecondsConf)) != 0 {
+		if c.NetworkTimeout,err = strconv.Atoi(strings.TrimSpace(networkTimeoutInSecondsConf)); err != nil {
+			return err
+		}
+		c.NetworkTimeout *= 1000
+	}
+	if charsetConf != "" && len(strings.TrimSpace(charsetConf)) != 0 {
+		c.Charset = strings.TrimSpace(charsetConf)
+	}
+	if httpAntiStealTokenConf != "" && len(strings.TrimSpace(httpAntiStealTokenConf)) != 0 {
+		if c.AntiStealToken,err = strconv.ParseBool(strings.TrimSpace(httpAntiStealTokenConf)); err != nil {
+			return err
+		}
+	}
+	if httpSecretKeyConf != "" && len(strings.TrimSpace(httpSecretKeyConf)) != 0 {
+		c.SecretKey = strings.TrimSpace(httpSecretKeyConf)
+	}
+	if httpTrackerHttpPortConf != "" && len(strings.TrimSpace(httpTrackerHttpPortConf)) != 0 {
+		if c.TrackerHttpPort,err = strconv.Atoi(strings.TrimSpace(httpTrackerHttpPortConf)); err != nil {
+			return err
+		}
+	}
+	if addressMapConf != "" && len(strings.TrimSpace(addressMapConf)) != 0 {
+		if c.AddressTranslator,err = ParseAddressTable(addressMapConf); err != nil {
+			return err
+		}
+	}
+	if protocolVersionConf != "" && len(strings.TrimSpace(protocolVersionConf)) != 0 {
+		if c.ProtocolVersion,err = ParseProtocolVersion(protocolVersionConf); err != nil {
+			return err
+		}
+	}
+	if poolEnabledConf != "" && len(strings.TrimSpace(poolEnabledConf)) != 0 {
+		if c.ConnectionPoolEnabled,err = strconv.ParseBool(strings.TrimSpace(poolEnabledConf)); err != nil {
+			return err
+		}
+	}
+	var maxCountPerEntry = DefaultConnectionPoolMaxCountPerEntry
+	var maxIdleCountPerEntry = DefaultConnectionPoolMaxIdleCountPerEntry
+	var maxIdleTime = DefaultConnectionPoolMaxIdleTime
+	var maxWaitTimeInMs = DefaultConnectionPoolMaxWaitTimeInMs
+	if c.ConnectionPool != nil {
+		// the items not found keep the settings of the current pool
+		maxCountPerEntry = c.ConnectionPool.maxCountPerEntry
+		maxIdleCountPerEntry = c.ConnectionPool.maxIdleCountPerEntry
+		maxIdleTime = int(c.ConnectionPool.maxIdleTime / time.Second)
+		maxWaitTimeInMs = int(c.ConnectionPool.maxWaitTime / time.Millisecond)
+	}
+	if poolMaxCountPerEntryConf != "" && len(strings.TrimSpace(poolMaxCountPerEntryConf)) != 0 {
+		if maxCountPerEntry,err = strconv.Atoi(strings.TrimSpace(poolMaxCountPerEntryConf)); err != nil {
+			return err
+		}
+	}
+	if poolMaxIdleCountPerEntryConf != "" && len(strings.TrimSpace(poolMaxIdleCountPerEntryConf)) != 0 {
+		if maxIdleCountPerEntry,err = strconv.Atoi(strings.TrimSpace(poolMaxIdleCountPerEntryConf)); err != nil {
+			return err
+		}
+	}
+	if poolMaxIdleTimeConf != "" && len(strings.TrimSpace(poolMaxIdleTimeConf)) != 0 {
+		if maxIdleTime,err = strconv.Atoi(strings.TrimSpace(poolMaxIdleTimeConf)); err != nil {
+			return err
+		}
+	}
+	if poolMaxWaitTimeInMsConf != "" && len(strings.TrimSpace(poolMaxWaitTimeInMsConf)) != 0 {
+		if maxWaitTimeInMs,err = strconv.Atoi(strings.TrimSpace(poolMaxWaitTimeInMsConf)); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(poolEnabledConf + poolMaxCountPerEntryConf + poolMaxIdleCountPerEntryConf + poolMaxIdleTimeConf + poolMaxWaitTimeInMsConf) != "" {
+		c.rebuildConnectionPool(c.ConnectionPoolEnabled, maxCountPerEntry, maxIdleCountPerEntry, time.Duration(maxIdleTime) * time.Second, time.Duration(maxWaitTimeInMs) * time.Millisecond)
+	}
+	c.applyPoolTimeout()
+
+	return nil
+}
+
+/**
+ * replace the connection pool, the idle connections of the pool replaced are closed
+ *
+ * @param enabled              if the connection pool is enabled, no pool is created when false
+ * @param maxCountPerEntry     max open connections per server, 0 for unlimited
+ * @param maxIdleCountPerEntry max idle connections kept per server
+ * @param maxIdleTime          idle connections older than it are closed
+ * @param maxWaitTime          max time to wait for a free connection when the entry is full
+ */
+func (c *Config) rebuildConnectionPool(enabled bool, maxCountPerEntry, maxIdleCountPerEntry int, maxIdleTime, maxWaitTime time.Duration) {
+	if c.ConnectionPool != nil {
+		c.ConnectionPool.Close()
+	}
+	c.ConnectionPoolEnabled = enabled
+	c.ConnectionPool = nil
+	if enabled {
+		c.ConnectionPool = NewConnectionPool(maxCountPerEntry, maxIdleCountPerEntry, maxIdleTime, maxWaitTime)
+	}
+}
+
+/**
+ * parse tracker servers
+ *
+ * @param trackerServers eg: "10.0.11.245:22122,10.0.11.246:22122"
+ *                       server的IP和端口用冒号':'分隔
+ *                       server之间用逗号','分隔
+ * @return the tracker server addresses
+ */
+func ParseTrackerServers(trackerServers string) ([]net.Addr, error) {
+	var arr = strings.Split(strings.TrimSpace(trackerServers), ",")
+	var list = make([]net.Addr, 0, len(arr))
+	for _,addrStr := range arr {
+		addr,err := parseTrackerServer(addrStr)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, addr)
+	}
+
+	return list, nil
+}
+
+/**
+ * parse the tracker server formatted as host:port, the IPv6 address is
+ * bracketed, such as [2001:db8::1]:22122
+ */
+func parseTrackerServer(trackerServer string) (net.Addr, error) {
+	host,portStr,err := net.SplitHostPort(strings.TrimSpace(trackerServer))
+	if err != nil {
+		return nil, errors.New("the value of item \"tracker_server\" is invalid, the correct format is host:port or [ipv6]:port")
+	}
+
+	port,err := strconv.Atoi(strings.TrimSpace(portStr))
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ResolveTCPAddr("tcp", net.JoinHostPort(strings.TrimSpace(host), strconv.Itoa(port)))
+}
+
+/**
+ * parse the protocol version, the major version of FastDFS such as 6 or 6.12
+ *
+ * @param version the version
+ * @return PROTO_VERSION_5 for 5.x and the earlier, PROTO_VERSION_6 for 6.x and the later
+ */
+func ParseProtocolVersion(version string) (proto.Version, error) {
+	var major = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(version)), "V")
+	if pos := strings.IndexByte(major, '.'); pos >= 0 {
+		major = major[:pos]
+	}
+	n,err := strconv.Atoi(major)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid protocol version %s", version)
+	}
+	if n >= int(PROTO_VERSION_6) {
+		return PROTO_VERSION_6, nil
+	}
+
+	return PROTO_VERSION_5, nil
+}
+
+/**
+ * @param timeout connect timeout
+ */
+func WithConnectTimeout(timeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ConnectTimeout = int(timeout / time.Millisecond)
+	}
+}
+
+/**
+ * @param timeout network timeout
+ */
+func WithNetworkTimeout(timeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.NetworkTimeout = int(timeout / time.Millisecond)
+	}
+}
+
+/**
+ * @param charset the charset of group name, filename and meta data
+ */
+func WithCharset(charset string) ConfigOption {
+	return func(c *Config) {
+		c.Charset = charset
+	}
+}
+
+/**
+ * enable anti-steal token
+ *
+ * @param secretKey generage token secret key
+ */
+func WithAntiStealToken(secretKey string) ConfigOption {
+	return func(c *Config) {
+		c.AntiStealToken = true
+		c.SecretKey = secretKey
+	}
+}
+
+/**
+ * @param port the tracker http port
+ */
+func WithTrackerHttpPort(port int) ConfigOption {
+	return func(c *Config) {
+		c.TrackerHttpPort = port
+	}
+}
+
+/**
+ * @param trackerServers the tracker server addresses
+ */
+func WithTrackerServers(trackerServers ...net.Addr) ConfigOption {
+	return func(c *Config) {
+		c.TrackerGroup = NewTrackerGroup(trackerServers)
+	}
+}
+
+/**
+ * @param trackerGroup the tracker group
+ */
+func WithTrackerGroup(trackerGroup *TrackerGroup) ConfigOption {
+	return func(c *Config) {
+		c.TrackerGroup = trackerGroup
+	}
+}
+
+/**
+ * @param pool the connection pool, nil to disable pooling
+ */
+func WithConnectionPool(pool *ConnectionPool) ConfigOption {
+	return func(c *Config) {
+		c.ConnectionPoolEnabled = pool != nil
+		c.ConnectionPool = pool
+	}
+}
+
+/**
+ * @param version the protocol version of the servers, PROTO_VERSION_6 for FastDFS 6.x with IPv6
+ */
+func WithProtocolVersion(version proto.Version) ConfigOption {
+	return func(c *Config) {
+		c.ProtocolVersion = version
+	}
+}
+
+/**
+ * @param policy the retry policy of storage operations, nil for no retry
+ */
+func WithRetryPolicy(policy *RetryPolicy) ConfigOption {
+	return func(c *Config) {
+		c.RetryPolicy = policy
+	}
+}
+
+func (c *Config) applyPoolTimeout() {
+	if c.ConnectionPool != nil {
+		c.ConnectionPool.SetTimeout(c.ConnectTimeout, c.NetworkTimeout)
+	}
+}
+
+func (c *Config) String() string {
+	var trackerServers = ""
+	if c.TrackerGroup != nil {
+		for _,addr := range c.TrackerGroup.TrackerServers {
+			if len(trackerServers) > 0 {
+				trackerServers += ","
+			}
+			trackerServers += addr.String()
+		}
+	}
+
+	return "{" +
+		"\n  ConnectTimeout(ms) = " + strconv.Itoa(c.ConnectTimeout) +
+		"\n  NetworkTimeout(ms) = " + strconv.Itoa(c.NetworkTimeout) +
+		"\n  Charset = " + c.Charset +
+		"\n  AntiStealToken = " + strconv.FormatBool(c.AntiStealToken) +
+		"\n  SecretKey = " + c.SecretKey +
+		"\n  TrackerHttpPort = " + strconv.Itoa(c.TrackerHttpPort) +
+		"\n  ConnectionPoolEnabled = " + strconv.FormatBool(c.ConnectionPoolEnabled) +
+		"\n  ProtocolVersion = " + c.getProtoVersion().String() +
+		"\n  trackerServers = " + trackerServers +
+		"\n}"
+}
+
+/*
+ * the methods below accept nil receiver which stands for the global settings.
+ */
+
+func (c *Config) getCharset() string {
+	if c == nil {
+		return GCharset
+	}
+
+	return c.Charset
+}
+
+/**
+ * convert the string to the bytes of the charset sent to the server
+ *
+ * @param str the string
+ * @return the converted string
+ */
+func (c *Config) encodeString(str string) (string, error) {
+	if str == "" {
+		return "", nil
+	}
+	bs,err := ConvertBytesToUTF8([]byte(str), c.getCharset())
+	if err != nil {
+		return "", err
+	}
+
+	return string(bs), nil
+}
+
+/**
+ * create the file reference with the charset sent to the server
+ *
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ */
+func (c *Config) fileRef(groupName, remoteFilename string) (proto.FileRef, error) {
+	var ref proto.FileRef
+	var err error
+	if ref.GroupName,err = c.encodeString(groupName); err != nil {
+		return ref, err
+	}
+	if ref.Filename,err = c.encodeString(remoteFilename); err != nil {
+		return ref, err
+	}
+
+	return ref, nil
+}
+
+func (c *Config) getProtoVersion() proto.Version {
+	if c == nil {
+		return GProtocolVersion
+	}
+
+	return c.ProtocolVersion
+}
+
+/**
+ * set the protocol version of the message of which the layout depends on it
+ */
+func (c *Config) setProtoVersion(msg proto.Message) {
+	if versioned,ok := msg.(proto.Versioned); ok {
+		versioned.SetVersion(c.getProtoVersion())
+	}
+}
+
+func (c *Config) getTrackerGroup() *TrackerGroup {
+	if c == nil {
+		return GTrackerGroup
+	}
+
+	return c.TrackerGroup
+}
+
+func (c *Config) getConnectTimeout() time.Duration {
+	if c == nil {
+		return time.Duration(GConnectTimeout) * time.Millisecond
+	}
+
+	return time.Duration(c.ConnectTimeout) * time.Millisecond
+}
+
+func (c *Config) getNetworkTimeout() time.Duration {
+	if c == nil {
+		return time.Duration(GNetworkTimeout) * time.Millisecond
+	}
+
+	return time.Duration(c.NetworkTimeout) * time.Millisecond
+}
+
+func (c *Config) getRetryPolicy() *RetryPolicy {
+	if c == nil {
+		return GRetryPolicy
+	}
+
+	return c.RetryPolicy
+}
+
+func (c *Config) getSocketAddrContext(ctx context.Context, addr net.Addr) (net.Conn, error) {
+	if c == nil {
+		return GetSocketAddrContext(ctx, addr)
+	}
+	if c.ConnectionPoolEnabled && c.ConnectionPool != nil {
+		return c.ConnectionPool.GetConnectionContext(ctx, addr)
+	}
+
+	var dialer = net.Dialer{Timeout:time.Duration(c.ConnectTimeout) * time.Millisecond}
+
+	return dialer.DialContext(ctx, "tcp", addr.String())
+}
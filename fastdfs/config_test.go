@@ -0,0 +1,175 @@
+package fastdfs
+
+import (
+	"testing"
+	"fmt"
+	"net"
+	"time"
+	"github.com/go/properties"
+)
+
+func TestNewConfigByIniFile(t *testing.T) {
+	config,err := NewConfigByIniFile("test/fdfs_client.conf.sample")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("config : " + config.String())
+
+	if config.ConnectTimeout != 2000 || config.NetworkTimeout != 30000 {
+		t.Fatalf("timeout %d %d", config.ConnectTimeout, config.NetworkTimeout)
+	}
+	if config.TrackerHttpPort != 8080 || len(config.TrackerGroup.TrackerServers) != 2 {
+		t.Fatalf("config %s", config)
+	}
+	if !config.ConnectionPoolEnabled || config.ConnectionPool == nil {
+		t.Fatalf("connection pool is not created")
+	}
+}
+
+func TestNewConfigByProperties(t *testing.T) {
+	config,err := NewConfigByPropertiesFile("test/fastdfs-client.properties.sample")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("config : " + config.String())
+
+	var props = properties.NewProperties()
+	if _,err = NewConfigByProperties(props); err == nil {
+		t.Fatalf("%s is required", PropKeyTrackerServers)
+	}
+	props.Put(PropKeyTrackerServers, "10.0.11.101:22122,10.0.11.102:22122")
+	props.Put(PropKeyCharset, "GBK")
+	props.Put(PropKeyConnectionPoolEnabled, "false")
+	if config,err = NewConfigByProperties(props); err != nil {
+		panic(err)
+	}
+	if config.Charset != "GBK" || config.ConnectionPoolEnabled || len(config.TrackerGroup.TrackerServers) != 2 {
+		t.Fatalf("config %s", config)
+	}
+	if config.ConnectTimeout != DefaultConnectTimeout * 1000 {
+		t.Fatalf("connect timeout %d, expect default", config.ConnectTimeout)
+	}
+
+	// the custom pool is kept without pool items
+	var pool = NewConnectionPool(10, 5, time.Minute, time.Second)
+	config = NewConfig(WithConnectionPool(pool))
+	props.Remove(PropKeyConnectionPoolEnabled)
+	if err = config.LoadProperties(props); err != nil {
+		t.Fatal(err)
+	}
+	if !config.ConnectionPoolEnabled || config.ConnectionPool != pool {
+		t.Fatalf("custom pool is replaced without pool items")
+	}
+	props.Put(PropKeyConnectionPoolMaxCountPerEntry, "20")
+	if err = config.LoadProperties(props); err != nil {
+		t.Fatal(err)
+	}
+	if config.ConnectionPool == pool || config.ConnectionPool.maxCountPerEntry != 20 || config.ConnectionPool.maxIdleCountPerEntry != 5 ||
+		config.ConnectionPool.maxIdleTime != time.Minute {
+		t.Fatalf("pool %+v, expect the items not found kept", config.ConnectionPool)
+	}
+}
+
+func TestParseTrackerServers(t *testing.T) {
+	addrs,err := ParseTrackerServers("[::1]:22122, 127.0.0.1:22123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0].String() != "[::1]:22122" || addrs[1].String() != "127.0.0.1:22123" {
+		t.Fatalf("tracker servers %v", addrs)
+	}
+	for _,servers := range []string{"::1:22122", "127.0.0.1", "[::1]:port", "127.0.0.1:22122,"} {
+		if _,err = ParseTrackerServers(servers); err == nil {
+			t.Errorf("parse invalid tracker servers %q succeeded", servers)
+		}
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	var cases = []struct {
+		version string
+		expect  int
+	}{
+		{"6", 6}, {"V6", 6}, {" 6.12 ", 6}, {"7", 6}, {"5", 5}, {"v5.11", 5}, {"4.08", 5},
+	}
+	for _,c := range cases {
+		if version,err := ParseProtocolVersion(c.version); err != nil || int(version) != c.expect {
+			t.Errorf("parse %q to %v, err %v, expect V%d", c.version, version, err, c.expect)
+		}
+	}
+	for _,version := range []string{"", "0", "-6", "six", "V"} {
+		if _,err := ParseProtocolVersion(version); err == nil {
+			t.Errorf("parse invalid version %q succeeded", version)
+		}
+	}
+
+	var props = properties.NewProperties()
+	props.Put(PropKeyTrackerServers, "[::1]:22122")
+	props.Put(PropKeyProtocolVersion, "6.12")
+	config,err := NewConfigByProperties(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.getProtoVersion() != PROTO_VERSION_6 || config.TrackerGroup.TrackerServers[0].String() != "[::1]:22122" {
+		t.Fatalf("config %s", config)
+	}
+	if (*Config)(nil).getProtoVersion() != GetGProtocolVersion() {
+		t.Fatalf("protocol version of the nil config is not the global one")
+	}
+	props.Put(PropKeyProtocolVersion, "x")
+	if _,err = NewConfigByProperties(props); err == nil {
+		t.Fatalf("invalid %s is accepted", PropKeyProtocolVersion)
+	}
+}
+
+func TestClient(t *testing.T) {
+	listener1,_ := startActiveTestServer(t)
+	defer listener1.Close()
+	listener2,_ := startActiveTestServer(t)
+	defer listener2.Close()
+
+	var newClient = func(addr net.Addr) *Client {
+		return NewClient(NewConfig(
+			WithTrackerServers(addr),
+			WithConnectTimeout(time.Second),
+			WithAntiStealToken("secret"),
+			WithConnectionPool(NewConnectionPool(10, 10, time.Minute, time.Second)),
+		))
+	}
+	var client1 = newClient(listener1.Addr())
+	defer client1.Close()
+	var client2 = newClient(listener2.Addr())
+	defer client2.Close()
+
+	for _,client := range []*Client{client1, client2} {
+		trackerServer,err := client.NewTrackerClient().GetConnection()
+		if err != nil {
+			panic(err)
+		}
+		conn,err := trackerServer.GetSocket()
+		if err != nil {
+			panic(err)
+		}
+		if ok,err := ActiveTest(conn); err != nil || !ok {
+			t.Fatalf("active test fail: %v", err)
+		}
+		trackerServer.Close()
+	}
+
+	var addr1 = listener1.Addr()
+	if idle,_ := client1.GetConfig().ConnectionPool.Stat(addr1); idle != 1 {
+		t.Fatalf("idle %d, expect 1", idle)
+	}
+	if idle,_ := client2.GetConfig().ConnectionPool.Stat(addr1); idle != 0 {
+		t.Fatalf("idle %d, expect 0", idle)
+	}
+
+	token,err := client1.GetToken("M00/00/00/test.jpg", 1500000000)
+	if err != nil {
+		panic(err)
+	}
+	expect,_ := GetTokenByCharset("M00/00/00/test.jpg", 1500000000, "secret", DefaultCharset)
+	if token != expect {
+		t.Fatalf("token %s, expect %s", token, expect)
+	}
+}
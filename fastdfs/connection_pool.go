@@ -0,0 +1,395 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+	"fmt"
+)
+
+const (
+	DefaultConnectionPoolEnabled = true
+	DefaultConnectionPoolMaxCountPerEntry = 500    //max open connections per server, 0 for unlimited
+	DefaultConnectionPoolMaxIdleCountPerEntry = 50 //max idle connections per server
+	DefaultConnectionPoolMaxIdleTime = 3600        //second
+	DefaultConnectionPoolMaxWaitTimeInMs = 1000    //millisecond
+)
+
+/**
+ * connection pool keyed by server address
+ */
+type ConnectionPool struct {
+	maxCountPerEntry       int
+	maxIdleCountPerEntry   int
+	maxIdleTime            time.Duration
+	maxWaitTime            time.Duration
+	connectTimeout         int //millisecond, 0 for the global setting
+	networkTimeout         int //millisecond, 0 for the global setting
+	managers               map[string]*connectionManager
+	lock                   sync.Mutex
+}
+
+/**
+ * Constructor
+ *
+ * @param maxCountPerEntry     max open connections per server, 0 for unlimited
+ * @param maxIdleCountPerEntry max idle connections kept per server
+ * @param maxIdleTime          idle connections older than it are closed
+ * @param maxWaitTime          max time to wait for a free connection when the entry is full
+ */
+func NewConnectionPool(maxCountPerEntry, maxIdleCountPerEntry int, maxIdleTime, maxWaitTime time.Duration) *ConnectionPool {
+	return &ConnectionPool{
+		maxCountPerEntry:maxCountPerEntry,
+		maxIdleCountPerEntry:maxIdleCountPerEntry,
+		maxIdleTime:maxIdleTime,
+		maxWaitTime:maxWaitTime,
+		managers:make(map[string]*connectionManager),
+	}
+}
+
+/**
+ * set the timeout used by dialing, active test and quit
+ *
+ * @param connectTimeout connect timeout in millisecond, 0 for the global setting
+ * @param networkTimeout network timeout in millisecond, 0 for the global setting
+ */
+func (p *ConnectionPool) SetTimeout(connectTimeout, networkTimeout int) {
+	p.lock.Lock()
+	p.connectTimeout = connectTimeout
+	p.networkTimeout = networkTimeout
+	p.lock.Unlock()
+}
+
+func (p *ConnectionPool) getConnectTimeout() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.connectTimeout > 0 {
+		return time.Duration(p.connectTimeout) * time.Millisecond
+	}
+
+	return time.Duration(GConnectTimeout) * time.Millisecond
+}
+
+func (p *ConnectionPool) getNetworkTimeout() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.networkTimeout > 0 {
+		return time.Duration(p.networkTimeout) * time.Millisecond
+	}
+
+	return time.Duration(GNetworkTimeout) * time.Millisecond
+}
+
+/**
+ * get a connection to the server, reuse the idle one if it pass the active test
+ *
+ * @param addr the server address
+ * @return connection, call Close() to give it back to the pool
+ */
+func (p *ConnectionPool) GetConnection(addr net.Addr) (net.Conn, error) {
+	return p.GetConnectionContext(context.Background(), addr)
+}
+
+/**
+ * get a connection to the server, dialing and waiting are aborted when ctx is done
+ *
+ * @param ctx  the context
+ * @param addr the server address
+ * @return connection, call Close() to give it back to the pool
+ */
+func (p *ConnectionPool) GetConnectionContext(ctx context.Context, addr net.Addr) (net.Conn, error) {
+	return p.getManager(addr.String()).getConnection(ctx)
+}
+
+/**
+ * close all idle connections, connections in use are closed when released
+ */
+func (p *ConnectionPool) Close() {
+	p.lock.Lock()
+	var managers = p.managers
+	p.managers = make(map[string]*connectionManager)
+	p.lock.Unlock()
+
+	for _,manager := range managers {
+		manager.closeIdle()
+	}
+}
+
+/**
+ * get idle and open connection count of the server
+ *
+ * @param addr the server address
+ * @return idle count, open count
+ */
+func (p *ConnectionPool) Stat(addr net.Addr) (idle int, open int) {
+	p.lock.Lock()
+	var manager = p.managers[addr.String()]
+	p.lock.Unlock()
+	if manager == nil {
+		return 0, 0
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	return len(manager.idle), manager.open
+}
+
+func (p *ConnectionPool) getManager(key string) *connectionManager {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var manager = p.managers[key]
+	if manager == nil {
+		manager = &connectionManager{
+			pool:p,
+			key:key,
+			released:make(chan struct{}),
+		}
+		p.managers[key] = manager
+	}
+
+	return manager
+}
+
+/**
+ * connections of one server
+ */
+type connectionManager struct {
+	pool       *ConnectionPool
+	key        string
+	idle       []*idleConn
+	open       int
+	closed     bool          //removed from the pool
+	released   chan struct{} //closed and renewed when a connection released
+	lock       sync.Mutex
+}
+
+type idleConn struct {
+	conn             net.Conn
+	lastAccessTime   time.Time
+}
+
+func (m *connectionManager) getConnection(ctx context.Context) (net.Conn, error) {
+	var deadline = time.Now().Add(m.pool.maxWaitTime)
+
+	for {
+		m.lock.Lock()
+		if n := len(m.idle); n > 0 {
+			var idle = m.idle[n - 1]
+			m.idle = m.idle[:n - 1]
+			m.lock.Unlock()
+
+			if time.Since(idle.lastAccessTime) > m.pool.maxIdleTime {
+				m.evict(idle.conn, false)
+				continue
+			}
+			if !activeTestConn(idle.conn, m.pool.getConnectTimeout()) {
+				m.evict(idle.conn, true)
+				continue
+			}
+			return &pooledConn{Conn:idle.conn, manager:m}, nil
+		}
+
+		if m.pool.maxCountPerEntry <= 0 || m.open < m.pool.maxCountPerEntry {
+			m.open++
+			m.lock.Unlock()
+
+			var dialer = net.Dialer{Timeout:m.pool.getConnectTimeout()}
+			conn,err := dialer.DialContext(ctx, "tcp", m.key)
+			if err != nil {
+				m.lock.Lock()
+				m.open--
+				m.notify()
+				m.lock.Unlock()
+				return nil, err
+			}
+
+			return &pooledConn{Conn:conn, manager:m}, nil
+		}
+
+		var released = m.released
+		m.lock.Unlock()
+
+		var wait = deadline.Sub(time.Now())
+		if wait <= 0 {
+			return nil, fmt.Errorf("connect to server %s fail, wait time %v exceeds, max count per entry: %d", m.key, m.pool.maxWaitTime, m.pool.maxCountPerEntry)
+		}
+		var timer = time.NewTimer(wait)
+		select {
+		case <-released:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+/**
+ * give the connection back, close it when broken or the idle list is full
+ */
+func (m *connectionManager) release(conn net.Conn, broken bool) {
+	m.lock.Lock()
+	if broken || m.closed || len(m.idle) >= m.pool.maxIdleCountPerEntry {
+		m.lock.Unlock()
+		m.evict(conn, broken)
+		return
+	}
+	m.idle = append(m.idle, &idleConn{conn:conn, lastAccessTime:time.Now()})
+	m.notify()
+	m.lock.Unlock()
+}
+
+/**
+ * send quit command (if not broken) and close the connection
+ */
+func (m *connectionManager) evict(conn net.Conn, broken bool) {
+	if broken {
+		conn.Close()
+	} else {
+		conn.SetDeadline(time.Now().Add(m.pool.getNetworkTimeout()))
+		CloseSocket(conn)
+	}
+
+	m.lock.Lock()
+	m.open--
+	m.notify()
+	m.lock.Unlock()
+}
+
+func (m *connectionManager) closeIdle() {
+	m.lock.Lock()
+	var idle = m.idle
+	m.idle = nil
+	m.closed = true
+	m.lock.Unlock()
+
+	for _,c := range idle {
+		m.evict(c.conn, false)
+	}
+}
+
+// must hold the lock.
+func (m *connectionManager) notify() {
+	close(m.released)
+	m.released = make(chan struct{})
+}
+
+/**
+ * send ACTIVE_TEST to check the idle connection before reuse,
+ * an alive server answers it as fast as accepting a new connection.
+ */
+func activeTestConn(conn net.Conn, timeout time.Duration) bool {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	if ok,err := ActiveTest(conn); err != nil || !ok {
+		return false
+	}
+
+	return conn.SetDeadline(time.Time{}) == nil
+}
+
+var errPooledConnClosed = errors.New("use of closed pooled connection")
+
+/**
+ * pooled connection, Close() gives it back to the pool
+ */
+type pooledConn struct {
+	net.Conn
+
+	manager   *connectionManager
+	broken    bool //io error occurred, must not be reused
+	closed    bool //given back, the underlying connection may be used by others
+	lock      sync.Mutex
+}
+
+func (c *pooledConn) Read(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, errPooledConnClosed
+	}
+	n,err := c.Conn.Read(b)
+	if err != nil {
+		c.markBroken()
+	}
+
+	return n, err
+}
+
+func (c *pooledConn) Write(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, errPooledConnClosed
+	}
+	n,err := c.Conn.Write(b)
+	if err != nil {
+		c.markBroken()
+	}
+
+	return n, err
+}
+
+func (c *pooledConn) SetDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errPooledConnClosed
+	}
+
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *pooledConn) SetReadDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errPooledConnClosed
+	}
+
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *pooledConn) SetWriteDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return errPooledConnClosed
+	}
+
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *pooledConn) Close() error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return nil
+	}
+	c.closed = true
+	// clear deadline set by the last user.
+	if err := c.Conn.SetDeadline(time.Time{}); err != nil {
+		c.broken = true
+	}
+	var broken = c.broken
+	c.lock.Unlock()
+
+	c.manager.release(c.Conn, broken)
+
+	return nil
+}
+
+func (c *pooledConn) markBroken() {
+	c.lock.Lock()
+	c.broken = true
+	c.lock.Unlock()
+}
+
+func (c *pooledConn) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.closed
+}
@@ -0,0 +1,116 @@
+package fastdfs
+
+import (
+	"testing"
+	"fmt"
+	"net"
+	"time"
+	"sync/atomic"
+)
+
+func startActiveTestServer(t *testing.T) (net.Listener, *int32) {
+	return startActiveTestServerAt(t, "127.0.0.1:")
+}
+
+func startActiveTestServerAt(t *testing.T, address string) (net.Listener, *int32) {
+	listener,err := net.Listen("tcp", address)
+	if err != nil {
+		panic(err)
+	}
+	var accepted int32
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					header,err := RecvHeader(conn, FDFS_PROTO_CMD_ACTIVE_TEST, 0)
+					if err != nil || header.Errno != 0 {
+						return
+					}
+					res,_ := PackHeader(TRACKER_PROTO_CMD_RESP, 0, 0)
+					if _,err = conn.Write(res); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener, &accepted
+}
+
+func TestConnectionPool(t *testing.T) {
+	listener,accepted := startActiveTestServer(t)
+	defer listener.Close()
+
+	var pool = NewConnectionPool(2, 1, time.Minute, 100 * time.Millisecond)
+	defer pool.Close()
+
+	conn,err := pool.GetConnection(listener.Addr())
+	if err != nil {
+		panic(err)
+	}
+	conn.Close()
+	conn.Close()
+
+	conn,err = pool.GetConnection(listener.Addr())
+	if err != nil {
+		panic(err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Fatalf("accepted %d connections, expect 1", n)
+	}
+
+	conn2,err := pool.GetConnection(listener.Addr())
+	if err != nil {
+		panic(err)
+	}
+	if _,err = pool.GetConnection(listener.Addr()); err == nil {
+		t.Fatal("expect wait timeout error")
+	} else {
+		fmt.Println(err)
+	}
+
+	conn.Close()
+	conn2.Close()
+	idle,open := pool.Stat(listener.Addr())
+	fmt.Println("idle:", idle, "open:", open)
+	if idle != 1 || open != 1 {
+		t.Fatalf("idle %d open %d, expect 1 1", idle, open)
+	}
+}
+
+func TestConnectionPoolBroken(t *testing.T) {
+	listener,accepted := startActiveTestServer(t)
+	defer listener.Close()
+
+	var pool = NewConnectionPool(0, 10, time.Minute, time.Second)
+	defer pool.Close()
+
+	conn,err := pool.GetConnection(listener.Addr())
+	if err != nil {
+		panic(err)
+	}
+	conn.SetReadDeadline(time.Now())
+	if _,err = conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expect timeout error")
+	}
+	conn.Close()
+
+	if idle,_ := pool.Stat(listener.Addr()); idle != 0 {
+		t.Fatalf("broken connection is reused")
+	}
+	if conn,err = pool.GetConnection(listener.Addr()); err != nil {
+		panic(err)
+	}
+	conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(accepted); n != 2 {
+		t.Fatalf("accepted %d connections, expect 2", n)
+	}
+}
@@ -0,0 +1,167 @@
+package fastdfs
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// deadline in the past, used to abort blocked read and write.
+var aLongTimeAgo = time.Unix(1, 0)
+
+/**
+ * bind a context to the connections used by one operation,
+ * the context deadline is applied to socket io and cancel aborts it.
+ */
+type connContext struct {
+	ctx       context.Context
+	conns     []net.Conn
+	aborted   bool
+	stop      chan struct{}
+	lock      sync.Mutex
+}
+
+func newConnContext(ctx context.Context) *connContext {
+	var c = &connContext{
+		ctx:ctx,
+		stop:make(chan struct{}),
+	}
+	if ctx.Done() != nil {
+		go c.watch()
+	}
+
+	return c
+}
+
+func (c *connContext) watch() {
+	select {
+	case <-c.ctx.Done():
+		c.lock.Lock()
+		c.aborted = true
+		for _,conn := range c.conns {
+			conn.SetDeadline(aLongTimeAgo)
+		}
+		c.lock.Unlock()
+	case <-c.stop:
+	}
+}
+
+/**
+ * apply the context to the connection
+ *
+ * @param conn the connection used by the operation
+ */
+func (c *connContext) bind(conn net.Conn) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.aborted {
+		return c.ctx.Err()
+	}
+	for _,bound := range c.conns {
+		if bound == conn {
+			return nil
+		}
+	}
+	if deadline,ok := c.ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	c.conns = append(c.conns, conn)
+
+	return nil
+}
+
+/**
+ * stop applying the context to the connection of the finished request, the
+ * long-lived operation such as StorageReader unbinds each connection before
+ * closing it. the aborted connection is marked broken.
+ *
+ * @param conn the connection bound by bind
+ */
+func (c *connContext) unbind(conn net.Conn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i,bound := range c.conns {
+		if bound != conn {
+			continue
+		}
+		c.conns = append(c.conns[:i], c.conns[i + 1:]...)
+		if c.aborted {
+			markBroken(conn)
+		} else {
+			conn.SetDeadline(time.Time{})
+		}
+		return
+	}
+}
+
+/**
+ * stop watching the context, the aborted connections are marked broken
+ * so that the pool discards them; the caller should close the others.
+ *
+ * @param err the error of the operation
+ * @return context error if the operation is aborted, otherwise err
+ */
+func (c *connContext) release(err error) error {
+	close(c.stop)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var ctxErr = c.ctx.Err()
+	if ctxErr == nil && err != nil {
+		// the socket deadline may expire before the context timer fires.
+		if deadline,ok := c.ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			ctxErr = context.DeadlineExceeded
+		}
+	}
+	if ctxErr != nil && (c.aborted || err != nil) {
+		for _,conn := range c.conns {
+			markBroken(conn)
+		}
+		return ctxErr
+	}
+	for _,conn := range c.conns {
+		conn.SetDeadline(time.Time{})
+	}
+
+	return err
+}
+
+/**
+ * mark the pooled connection as broken, it will be closed instead of reused
+ */
+func markBroken(conn net.Conn) {
+	if pc,ok := conn.(*pooledConn); ok {
+		pc.markBroken()
+	}
+}
+
+func contextOf(c *connContext) context.Context {
+	if c == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+/**
+ * construct Socket object with context
+ *
+ * @param ctx  the context for dialing
+ * @param addr InetSocketAddress object, including ip address and port
+ * @return connected Socket object
+ */
+func GetSocketAddrContext(ctx context.Context, addr net.Addr) (net.Conn, error) {
+	if GConnectionPoolEnabled && GConnectionPool != nil {
+		return GConnectionPool.GetConnectionContext(ctx, addr)
+	}
+
+	var dialer = net.Dialer{Timeout:time.Duration(GConnectTimeout) * time.Millisecond}
+
+	return dialer.DialContext(ctx, "tcp", addr.String())
+}
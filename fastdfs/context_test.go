@@ -0,0 +1,62 @@
+package fastdfs
+
+import (
+	"testing"
+	"context"
+	"net"
+	"time"
+)
+
+func TestTrackerClientCtx(t *testing.T) {
+	listener,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		panic(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// never answer
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var buf = make([]byte, 4096)
+				for {
+					if _,err := conn.Read(buf); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	var tracker = NewTrackerClientByGroup(NewTrackerGroup([]net.Addr{listener.Addr()}))
+
+	ctx,cancel := context.WithTimeout(context.Background(), 200 * time.Millisecond)
+	defer cancel()
+	var start = time.Now()
+	if _,err = tracker.ListGroupsCtx(ctx, nil); err != context.DeadlineExceeded {
+		t.Fatalf("err %v, expect %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(start) > 2 * time.Second {
+		t.Fatalf("deadline is not applied")
+	}
+
+	ctx,cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100 * time.Millisecond, cancel)
+	if _,err = tracker.ListGroupsCtx(ctx, nil); err != context.Canceled {
+		t.Fatalf("err %v, expect %v", err, context.Canceled)
+	}
+
+	if _,err = tracker.ListGroupsCtx(ctx, nil); err != context.Canceled {
+		t.Fatalf("err %v, expect %v", err, context.Canceled)
+	}
+
+	if GConnectionPoolEnabled {
+		if idle,_ := GConnectionPool.Stat(listener.Addr()); idle != 0 {
+			t.Fatalf("aborted connection is reused")
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package fastdfs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+/**
+ * error returned by the server (errno of the response) or detected by the client
+ */
+type Error struct {
+	Errno     byte   //the error code, one of ERR_NO_XXX or the errno of the server
+	Cmd       byte   //the command, 0 for not sent
+	Addr      string //the server address, empty for client side error
+	Message   string //detail of client side error
+}
+
+var (
+	ErrNotFound = &Error{Errno:ERR_NO_ENOENT}
+	ErrIO = &Error{Errno:ERR_NO_EIO}
+	ErrBusy = &Error{Errno:ERR_NO_EBUSY}
+	ErrInvalid = &Error{Errno:ERR_NO_EINVAL}
+	ErrNoSpace = &Error{Errno:ERR_NO_ENOSPC}
+	ErrConnRefused = &Error{Errno:ECONNREFUSED}
+	ErrAlready = &Error{Errno:ERR_NO_EALREADY}
+	ErrNotSupported = &Error{Errno:ERR_NO_EOPNOTSUPP}
+)
+
+var errnoText = map[byte]string{
+	ERR_NO_ENOENT:"no such file or directory",
+	ERR_NO_EIO:"input/output error",
+	ERR_NO_EBUSY:"device or resource busy",
+	ERR_NO_EINVAL:"invalid argument",
+	ERR_NO_ENOSPC:"no space left on device",
+	ECONNREFUSED:"connection refused",
+	ERR_NO_EALREADY:"operation already in progress",
+	ERR_NO_EOPNOTSUPP:"operation not supported",
+}
+
+/**
+ * Constructor of server error
+ *
+ * @param errno the errno of the response
+ * @param cmd   the command sent
+ * @param addr  the server address
+ */
+func newError(errno, cmd byte, addr net.Addr) *Error {
+	var e = &Error{
+		Errno:errno,
+		Cmd:cmd,
+	}
+	if addr != nil {
+		e.Addr = addr.String()
+	}
+
+	return e
+}
+
+/**
+ * Constructor of client side invalid argument error
+ *
+ * @param format the message format
+ * @param args   the message args
+ */
+func newInvalidError(format string, args ...interface{}) *Error {
+	return &Error{
+		Errno:ERR_NO_EINVAL,
+		Message:fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *Error) Error() string {
+	var text = errnoText[e.Errno]
+	if text == "" {
+		text = fmt.Sprintf("errno %d", e.Errno)
+	} else {
+		text = fmt.Sprintf("%s (errno %d)", text, e.Errno)
+	}
+	if e.Message != "" {
+		text = e.Message + ": " + text
+	}
+	if e.Addr != "" {
+		text = fmt.Sprintf("cmd %d to %s: %s", e.Cmd, e.Addr, text)
+	} else if e.Cmd != 0 {
+		text = fmt.Sprintf("cmd %d: %s", e.Cmd, text)
+	}
+
+	return "fastdfs: " + text
+}
+
+/**
+ * errors.Is support, the errors with the same errno match,
+ * the command and address are compared when set in target.
+ */
+func (e *Error) Is(target error) bool {
+	var t,ok = target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Errno == e.Errno && (t.Cmd == 0 || t.Cmd == e.Cmd) && (t.Addr == "" || t.Addr == e.Addr)
+}
+
+/**
+ * get the error code of the error
+ *
+ * @param err the error returned by the client
+ * @return 0 for nil, the errno of *Error, ERR_NO_EIO for the others
+ */
+func ErrorCode(err error) byte {
+	if err == nil {
+		return 0
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Errno
+	}
+
+	return ERR_NO_EIO
+}
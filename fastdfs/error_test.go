@@ -0,0 +1,106 @@
+package fastdfs
+
+import (
+	"testing"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// answer every request with the errno.
+func startErrnoServer(t *testing.T, errno byte) net.Listener {
+	listener,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var header = make([]byte, FDFS_PROTO_PKG_LEN_SIZE + 2)
+				for {
+					if _,err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					if header[PROTO_HEADER_CMD_INDEX] == FDFS_PROTO_CMD_QUIT {
+						return
+					}
+					if _,err := io.CopyN(io.Discard, conn, Buff2long(header, 0)); err != nil {
+						return
+					}
+					var status = errno
+					if header[PROTO_HEADER_CMD_INDEX] == FDFS_PROTO_CMD_ACTIVE_TEST {
+						status = 0
+					}
+					res,_ := PackHeader(TRACKER_PROTO_CMD_RESP, 0, status)
+					if _,err := conn.Write(res); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener
+}
+
+func TestError(t *testing.T) {
+	var err error = newError(ERR_NO_ENOENT, STORAGE_PROTO_CMD_DELETE_FILE, nil)
+	fmt.Println(err)
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrBusy) {
+		t.Fatalf("errors.Is mismatch: %v", err)
+	}
+	if !errors.Is(err, &Error{Errno:ERR_NO_ENOENT, Cmd:STORAGE_PROTO_CMD_DELETE_FILE}) {
+		t.Fatalf("errors.Is with command mismatch: %v", err)
+	}
+	if errors.Is(err, &Error{Errno:ERR_NO_ENOENT, Cmd:STORAGE_PROTO_CMD_UPLOAD_FILE}) {
+		t.Fatalf("errors.Is with other command match: %v", err)
+	}
+
+	var wrapped = fmt.Errorf("delete: %w", err)
+	if ErrorCode(wrapped) != ERR_NO_ENOENT || ErrorCode(nil) != 0 || ErrorCode(io.EOF) != ERR_NO_EIO {
+		t.Fatalf("ErrorCode mismatch")
+	}
+}
+
+func TestServerError(t *testing.T) {
+	var listener = startErrnoServer(t, ERR_NO_ENOSPC)
+	defer listener.Close()
+
+	var client = NewClient(NewConfig(WithTrackerServers(listener.Addr())))
+	defer client.Close()
+
+	var tracker = client.NewTrackerClient()
+	storageServer,err := tracker.GetStoreStorage(nil)
+	if storageServer != nil || !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("storage server %v, err %v, expect %v", storageServer, err, ErrNoSpace)
+	}
+	fmt.Println(err)
+	if tracker.GetErrorCode() != ERR_NO_ENOSPC {
+		t.Fatalf("errno %d, expect %d", tracker.GetErrorCode(), ERR_NO_ENOSPC)
+	}
+
+	var storageClient = client.NewStorageClient()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results,err := storageClient.UploadBuffer([]byte("hello"), "txt", nil)
+			if results != nil || !errors.Is(err, ErrNoSpace) {
+				t.Errorf("results %v, err %v, expect %v", results, err, ErrNoSpace)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if info,err := storageClient.GetFileInfo("group1", "short"); info != nil || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("info %v, err %v, expect %v", info, err, ErrInvalid)
+	}
+}
@@ -0,0 +1,468 @@
+/**
+ * Package exporter publishes the statistics of the FastDFS cluster in the
+ * Prometheus text format. The groups and the storage servers are polled from
+ * the tracker on an interval, the storage metrics are labeled by the group,
+ * the storage id, the ip address and the status.
+ *
+ *	var e = exporter.NewExporter(fastdfs.NewClient(config), exporter.WithInterval(30 * time.Second))
+ *	e.Start()
+ *	defer e.Close()
+ *	http.Handle("/metrics", e)
+ */
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"github.com/go/fastdfs"
+)
+
+const (
+	DefaultInterval = 15 * time.Second
+	DefaultNamespace = "fastdfs"
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+/**
+ * the statistics polled from the tracker
+ */
+type snapshot struct {
+	groups     []fastdfs.StructGroupStat
+	storages   [][]fastdfs.StructStorageStat //the storage servers of the groups
+	err        error
+	time       time.Time
+	duration   time.Duration
+}
+
+/**
+ * the exporter of the cluster statistics, it is the http handler of the metrics
+ */
+type Exporter struct {
+	client      *fastdfs.Client
+	interval    time.Duration
+	timeout     time.Duration
+	namespace   string
+	lock        sync.RWMutex
+	last        *snapshot
+	polling     sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+/**
+ * exporter option
+ */
+type Option func(e *Exporter)
+
+/**
+ * set the interval of polling the tracker
+ *
+ * @param interval the interval
+ */
+func WithInterval(interval time.Duration) Option {
+	return func(e *Exporter) {
+		e.interval = interval
+	}
+}
+
+/**
+ * set the timeout of polling the tracker
+ *
+ * @param timeout the timeout, 0 for the interval
+ */
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Exporter) {
+		e.timeout = timeout
+	}
+}
+
+/**
+ * set the prefix of the metric names
+ *
+ * @param namespace the prefix, fastdfs by default
+ */
+func WithNamespace(namespace string) Option {
+	return func(e *Exporter) {
+		e.namespace = namespace
+	}
+}
+
+/**
+ * Constructor
+ *
+ * @param client  the FastDFS client, nil for the global settings
+ * @param options the options to apply
+ */
+func NewExporter(client *fastdfs.Client, options ...Option) *Exporter {
+	if client == nil {
+		client = fastdfs.NewClient(nil)
+	}
+	var e = &Exporter{
+		client:client,
+		interval:DefaultInterval,
+		namespace:DefaultNamespace,
+	}
+	for _,option := range options {
+		option(e)
+	}
+	if e.timeout <= 0 {
+		e.timeout = e.interval
+	}
+
+	return e
+}
+
+/**
+ * start polling the tracker in background, the first poll is done before return.
+ * the handler polls the tracker on each request if not started.
+ */
+func (e *Exporter) Start() {
+	e.lock.Lock()
+	if e.stop != nil {
+		e.lock.Unlock()
+		return
+	}
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	e.lock.Unlock()
+
+	e.Poll(context.Background())
+	go e.run(e.stop, e.done)
+}
+
+func (e *Exporter) run(stop, done chan struct{}) {
+	defer close(done)
+	var ticker = time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			e.Poll(context.Background())
+		}
+	}
+}
+
+/**
+ * stop polling the tracker
+ */
+func (e *Exporter) Close() error {
+	e.lock.Lock()
+	var stop,done = e.stop, e.done
+	e.stop,e.done = nil, nil
+	e.lock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	return nil
+}
+
+/**
+ * poll the groups and the storage servers from the tracker
+ *
+ * @param ctx the context
+ * @return the error of polling
+ */
+func (e *Exporter) Poll(ctx context.Context) error {
+	e.polling.Lock()
+	defer e.polling.Unlock()
+
+	ctx,cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	var s = &snapshot{time:time.Now()}
+	s.err = e.poll(ctx, s)
+	s.duration = time.Since(s.time)
+	if s.err != nil {
+		s.groups,s.storages = nil, nil
+	}
+
+	e.lock.Lock()
+	e.last = s
+	e.lock.Unlock()
+
+	return s.err
+}
+
+func (e *Exporter) poll(ctx context.Context, s *snapshot) error {
+	var trackerClient = e.client.NewTrackerClient()
+	groups,err := trackerClient.ListGroupsCtx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	s.groups = groups
+	s.storages = make([][]fastdfs.StructStorageStat, len(groups))
+	for i := range groups {
+		if s.storages[i],err = trackerClient.ListStoragesCtx(ctx, nil, groups[i].GetGroupName()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * serve the metrics in the Prometheus text format
+ */
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.lock.RLock()
+	var started = e.stop != nil
+	var s = e.last
+	e.lock.RUnlock()
+	if !started || s == nil {
+		e.Poll(r.Context())
+		e.lock.RLock()
+		s = e.last
+		e.lock.RUnlock()
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	e.write(w, s)
+}
+
+/**
+ * the metric family of the storage servers
+ */
+type storageMetric struct {
+	name    string
+	help    string
+	typ     string
+	label   string //the extra label name, empty for none
+	values  []storageValue
+}
+
+type storageValue struct {
+	label  string //the extra label value
+	value  func(s *fastdfs.StructStorageStat) float64
+}
+
+func megabytes(mb int64) float64 {
+	return float64(mb) * 1024 * 1024
+}
+
+func timestamp(t time.Time) float64 {
+	if t.Unix() <= 0 {
+		return 0
+	}
+
+	return float64(t.Unix())
+}
+
+func count(fn func(s *fastdfs.StructStorageStat) int64) func(s *fastdfs.StructStorageStat) float64 {
+	return func(s *fastdfs.StructStorageStat) float64 {
+		return float64(fn(s))
+	}
+}
+
+var storageMetrics = []storageMetric{
+	{"storage_status", "The status code of the storage server.", "gauge", "", []storageValue{
+		{"", func(s *fastdfs.StructStorageStat) float64 { return float64(s.GetStatus()) }},
+	}},
+	{"storage_total_bytes", "The total space of the storage server.", "gauge", "", []storageValue{
+		{"", func(s *fastdfs.StructStorageStat) float64 { return megabytes(s.GetTotalMB()) }},
+	}},
+	{"storage_free_bytes", "The free space of the storage server.", "gauge", "", []storageValue{
+		{"", func(s *fastdfs.StructStorageStat) float64 { return megabytes(s.GetFreeMB()) }},
+	}},
+	{"storage_trunk_server", "Whether the storage server is the trunk server.", "gauge", "", []storageValue{
+		{"", func(s *fastdfs.StructStorageStat) float64 {
+			if s.IsTrunkServer() {
+				return 1
+			}
+			return 0
+		}},
+	}},
+	{"storage_operations_total", "The operations handled by the storage server.", "counter", "op", []storageValue{
+		{"upload", count((*fastdfs.StructStorageStat).GetTotalUploadCount)},
+		{"append", count((*fastdfs.StructStorageStat).GetTotalAppendCount)},
+		{"modify", count((*fastdfs.StructStorageStat).GetTotalModifyCount)},
+		{"truncate", count((*fastdfs.StructStorageStat).GetTotalTruncateCount)},
+		{"set_meta", count((*fastdfs.StructStorageStat).GetTotalSetMetaCount)},
+		{"delete", count((*fastdfs.StructStorageStat).GetTotalDeleteCount)},
+		{"download", count((*fastdfs.StructStorageStat).GetTotalDownloadCount)},
+		{"get_meta", count((*fastdfs.StructStorageStat).GetTotalGetMetaCount)},
+		{"create_link", count((*fastdfs.StructStorageStat).GetTotalCreateLinkCount)},
+		{"delete_link", count((*fastdfs.StructStorageStat).GetTotalDeleteLinkCount)},
+		{"file_open", count((*fastdfs.StructStorageStat).GetTotalFileOpenCount)},
+		{"file_read", count((*fastdfs.StructStorageStat).GetTotalFileReadCount)},
+		{"file_write", count((*fastdfs.StructStorageStat).GetTotalFileWriteCount)},
+	}},
+	{"storage_operations_success_total", "The operations succeeded on the storage server.", "counter", "op", []storageValue{
+		{"upload", count((*fastdfs.StructStorageStat).GetSuccessUploadCount)},
+		{"append", count((*fastdfs.StructStorageStat).GetSuccessAppendCount)},
+		{"modify", count((*fastdfs.StructStorageStat).GetSuccessModifyCount)},
+		{"truncate", count((*fastdfs.StructStorageStat).GetSuccessTruncateCount)},
+		{"set_meta", count((*fastdfs.StructStorageStat).GetSuccessSetMetaCount)},
+		{"delete", count((*fastdfs.StructStorageStat).GetSuccessDeleteCount)},
+		{"download", count((*fastdfs.StructStorageStat).GetSuccessDownloadCount)},
+		{"get_meta", count((*fastdfs.StructStorageStat).GetSuccessGetMetaCount)},
+		{"create_link", count((*fastdfs.StructStorageStat).GetSuccessCreateLinkCount)},
+		{"delete_link", count((*fastdfs.StructStorageStat).GetSuccessDeleteLinkCount)},
+		{"file_open", count((*fastdfs.StructStorageStat).GetSuccessFileOpenCount)},
+		{"file_read", count((*fastdfs.StructStorageStat).GetSuccessFileReadCount)},
+		{"file_write", count((*fastdfs.StructStorageStat).GetSuccessFileWriteCount)},
+	}},
+	{"storage_bytes_total", "The bytes transferred by the storage server.", "counter", "op", []storageValue{
+		{"upload", count((*fastdfs.StructStorageStat).GetTotalUploadBytes)},
+		{"append", count((*fastdfs.StructStorageStat).GetTotalAppendBytes)},
+		{"modify", count((*fastdfs.StructStorageStat).GetTotalModifyBytes)},
+		{"download", count((*fastdfs.StructStorageStat).GetTotalDownloadloadBytes)},
+		{"sync_in", count((*fastdfs.StructStorageStat).GetTotalSyncInBytes)},
+		{"sync_out", count((*fastdfs.StructStorageStat).GetTotalSyncOutBytes)},
+	}},
+	{"storage_bytes_success_total", "The bytes transferred successfully by the storage server.", "counter", "op", []storageValue{
+		{"upload", count((*fastdfs.StructStorageStat).GetSuccessUploadBytes)},
+		{"append", count((*fastdfs.StructStorageStat).GetSuccessAppendBytes)},
+		{"modify", count((*fastdfs.StructStorageStat).GetSuccessModifyBytes)},
+		{"download", count((*fastdfs.StructStorageStat).GetSuccessDownloadloadBytes)},
+		{"sync_in", count((*fastdfs.StructStorageStat).GetSuccessSyncInBytes)},
+		{"sync_out", count((*fastdfs.StructStorageStat).GetSuccessSyncOutBytes)},
+	}},
+	{"storage_connections", "The connections of the storage server.", "gauge", "state", []storageValue{
+		{"alloc", func(s *fastdfs.StructStorageStat) float64 { return float64(s.GetConnectionAllocCount()) }},
+		{"current", func(s *fastdfs.StructStorageStat) float64 { return float64(s.GetConnectionCurrentCount()) }},
+		{"max", func(s *fastdfs.StructStorageStat) float64 { return float64(s.GetConnectionMaxCount()) }},
+	}},
+	{"storage_timestamp_seconds", "The unix timestamps of the storage server events, 0 for none.", "gauge", "event", []storageValue{
+		{"join", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetJoinTime()) }},
+		{"up", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetUpTime()) }},
+		{"last_heart_beat", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetLastHeartBeatTime()) }},
+		{"last_source_update", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetLastSourceUpdate()) }},
+		{"last_sync_update", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetLastSyncUpdate()) }},
+		{"last_synced", func(s *fastdfs.StructStorageStat) float64 { return timestamp(s.GetLastSyncedTimestamp()) }},
+	}},
+}
+
+/**
+ * write the metrics of the snapshot in the Prometheus text format
+ */
+func (e *Exporter) write(w io.Writer, s *snapshot) {
+	var m = &metricWriter{w:w, namespace:e.namespace}
+	var up float64 = 1
+	if s.err != nil {
+		up = 0
+	}
+	m.family("up", "Whether the last poll of the tracker succeeded.", "gauge")
+	m.sample("up", nil, up)
+	m.family("poll_duration_seconds", "The duration of the last poll of the tracker.", "gauge")
+	m.sample("poll_duration_seconds", nil, s.duration.Seconds())
+	m.family("poll_timestamp_seconds", "The unix timestamp of the last poll of the tracker.", "gauge")
+	m.sample("poll_timestamp_seconds", nil, float64(s.time.Unix()))
+
+	var groupMetrics = []struct {
+		name   string
+		help   string
+		value  func(g *fastdfs.StructGroupStat) float64
+	}{
+		{"group_total_bytes", "The total space of the group.", func(g *fastdfs.StructGroupStat) float64 { return megabytes(g.GetTotalMB()) }},
+		{"group_free_bytes", "The free space of the group.", func(g *fastdfs.StructGroupStat) float64 { return megabytes(g.GetFreeMB()) }},
+		{"group_trunk_free_bytes", "The free space of the trunk files of the group.", func(g *fastdfs.StructGroupStat) float64 { return megabytes(g.GetTrunkFreeMB()) }},
+		{"group_storages", "The storage servers of the group.", func(g *fastdfs.StructGroupStat) float64 { return float64(g.GetStorageCount()) }},
+		{"group_active_storages", "The active storage servers of the group.", func(g *fastdfs.StructGroupStat) float64 { return float64(g.GetActiveCount()) }},
+	}
+	for _,metric := range groupMetrics {
+		if len(s.groups) == 0 {
+			break
+		}
+		m.family(metric.name, metric.help, "gauge")
+		for i := range s.groups {
+			m.sample(metric.name, []string{"group", s.groups[i].GetGroupName()}, metric.value(&s.groups[i]))
+		}
+	}
+
+	for _,metric := range storageMetrics {
+		if len(s.groups) == 0 {
+			break
+		}
+		m.family(metric.name, metric.help, metric.typ)
+		for i := range s.groups {
+			for j := range s.storages[i] {
+				var storage = &s.storages[i][j]
+				var labels = []string{
+					"group", s.groups[i].GetGroupName(),
+					"storage_id", storage.GetId(),
+					"ip", storage.GetIpAddr(),
+					"status", fastdfs.GetStorageStatusCaption(storage.GetStatus()),
+				}
+				for _,v := range metric.values {
+					if metric.label == "" {
+						m.sample(metric.name, labels, v.value(storage))
+					} else {
+						m.sample(metric.name, append(labels[:len(labels):len(labels)], metric.label, v.label), v.value(storage))
+					}
+				}
+			}
+		}
+	}
+}
+
+/**
+ * writer of the Prometheus text format
+ */
+type metricWriter struct {
+	w          io.Writer
+	namespace  string
+}
+
+func (m *metricWriter) family(name, help, typ string) {
+	fmt.Fprintf(m.w, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", m.namespace, name, help, m.namespace, name, typ)
+}
+
+/**
+ * write the sample
+ *
+ * @param name   the metric name without the namespace
+ * @param labels the label names and values
+ * @param value  the value
+ */
+func (m *metricWriter) sample(name string, labels []string, value float64) {
+	var sb strings.Builder
+	sb.WriteString(m.namespace)
+	sb.WriteByte('_')
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i + 1 < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(labels[i])
+			sb.WriteString("=\"")
+			sb.WriteString(escapeLabel(labels[i + 1]))
+			sb.WriteByte('"')
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(formatValue(value))
+	sb.WriteByte('\n')
+	io.WriteString(m.w, sb.String())
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"testing"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/fastdfstest"
+)
+
+func scrape(t *testing.T, handler http.Handler) map[string]string {
+	var recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("status %d, header %v", recorder.Code, recorder.Header())
+	}
+
+	body,_ := io.ReadAll(recorder.Body)
+	var samples = make(map[string]string)
+	for _,line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		var pos = strings.LastIndexByte(line, ' ')
+		samples[line[:pos]] = line[pos + 1:]
+	}
+
+	return samples
+}
+
+func TestExporter(t *testing.T) {
+	tracker,err := fastdfstest.NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	storage,err := tracker.AddStorage("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _,err = tracker.AddStorage("group2"); err != nil {
+		t.Fatal(err)
+	}
+	var client = fastdfs.NewClient(tracker.NewConfig())
+	if _,err = client.NewStorageClient().Upload(context.Background(), []byte("hello"), fastdfs.WithGroup("group1")); err != nil {
+		t.Fatal(err)
+	}
+
+	var e = NewExporter(client)
+	var samples = scrape(t, e)
+	var labels = `group="group1",storage_id="` + storage.GetId() + `",ip="` + storage.GetIpAddr() + `",status="ACTIVE"`
+	var expected = map[string]string{
+		"fastdfs_up":"1",
+		`fastdfs_group_storages{group="group1"}`:"1",
+		`fastdfs_group_active_storages{group="group2"}`:"1",
+		"fastdfs_storage_status{" + labels + "}":"7",
+		"fastdfs_storage_operations_total{" + labels + `,op="upload"}`:"1",
+		"fastdfs_storage_operations_success_total{" + labels + `,op="upload"}`:"1",
+		"fastdfs_storage_operations_total{" + labels + `,op="download"}`:"0",
+		"fastdfs_storage_trunk_server{" + labels + "}":"0",
+	}
+	for name,value := range expected {
+		if samples[name] != value {
+			t.Errorf("%s = %q, expect %s", name, samples[name], value)
+		}
+	}
+	if ts := samples["fastdfs_storage_timestamp_seconds{" + labels + `,event="last_heart_beat"}`]; ts == "" || ts == "0" || strings.ContainsAny(ts, "e.") {
+		t.Errorf("last heart beat %q", ts)
+	}
+
+	// polled in background
+	e = NewExporter(client, WithInterval(10 * time.Millisecond), WithNamespace("dfs"))
+	e.Start()
+	if _,err = client.NewStorageClient().Upload(context.Background(), []byte("hello"), fastdfs.WithGroup("group1")); err != nil {
+		t.Fatal(err)
+	}
+	var name = "dfs_storage_operations_total{" + labels + `,op="upload"}`
+	for start := time.Now(); scrape(t, e)[name] != "2"; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5 * time.Second {
+			t.Fatalf("%s is not updated", name)
+		}
+	}
+	if err = e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker.Close()
+	if err = e.Poll(context.Background()); err == nil {
+		t.Fatalf("poll the closed tracker")
+	}
+	if samples = scrape(t, e); samples["dfs_up"] != "0" || len(samples) != 3 {
+		t.Fatalf("samples of failed poll %v", samples)
+	}
+}
+
+func TestMetricWriter(t *testing.T) {
+	var sb strings.Builder
+	var m = &metricWriter{w:&sb, namespace:"fastdfs"}
+	m.family("test", "The test.", "gauge")
+	m.sample("test", []string{"a", "x\"y\\z\n", "b", ""}, 0.5)
+	m.sample("test", nil, 1e12)
+	var expected = "# HELP fastdfs_test The test.\n# TYPE fastdfs_test gauge\n" +
+		"fastdfs_test{a=\"x\\\"y\\\\z\\n\",b=\"\"} 0.5\nfastdfs_test 1000000000000\n"
+	if sb.String() != expected {
+		t.Fatalf("output %q, expect %q", sb.String(), expected)
+	}
+}
@@ -0,0 +1,260 @@
+/**
+ * Package fastdfstest provides an in-process fake FastDFS tracker and storage
+ * servers for tests, the files are kept in memory.
+ *
+ *	tracker,err := fastdfstest.NewTracker()
+ *	defer tracker.Close()
+ *	storage,err := tracker.AddStorage("group1")
+ *	var client = fastdfs.NewClient(tracker.NewConfig())
+ */
+package fastdfstest
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/proto"
+)
+
+const maxBodyLen = 1 << 30 //the request with larger body is rejected
+
+/**
+ * fault injected to the server, a request with the command takes the first
+ * matched fault
+ */
+type Fault struct {
+	Cmd          byte          //the command to fail, 0 for any command except QUIT and ACTIVE_TEST
+	Errno        byte          //answer the errno without handling the request
+	Delay        time.Duration //delay the response
+	Drop         bool          //close the connection before handling the request
+	LoseResponse bool          //handle the request, then close the connection without response
+	Times        int           //the count of requests to fail, <= 0 for every request
+}
+
+/**
+ * handle the decoded request
+ *
+ * @return the response and errno
+ */
+type handler func(req proto.Request) (proto.Message, byte)
+
+/**
+ * the server accepting connections and answering requests with the handler
+ */
+type server struct {
+	listener   net.Listener
+	handle     handler
+	version    proto.Version //the protocol version of the messages
+	faults     []*Fault
+	conns      map[net.Conn]struct{}
+	closed     chan struct{}
+	wg         sync.WaitGroup
+	lock       sync.Mutex
+}
+
+func newServer(address string, version proto.Version, handle handler) (*server, error) {
+	listener,err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	var s = &server{
+		listener:listener,
+		handle:handle,
+		version:version,
+		conns:make(map[net.Conn]struct{}),
+		closed:make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.accept()
+
+	return s, nil
+}
+
+/**
+ * get the listening address
+ *
+ * @return the address
+ */
+func (s *server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+/**
+ * inject the fault, the faults are matched in the order of injection
+ *
+ * @param fault the fault
+ */
+func (s *server) Inject(fault Fault) {
+	s.lock.Lock()
+	s.faults = append(s.faults, &fault)
+	s.lock.Unlock()
+}
+
+/**
+ * remove the injected faults
+ */
+func (s *server) ClearFaults() {
+	s.lock.Lock()
+	s.faults = nil
+	s.lock.Unlock()
+}
+
+/**
+ * stop listening and close the connections
+ */
+func (s *server) close() error {
+	s.lock.Lock()
+	select {
+	case <-s.closed:
+		s.lock.Unlock()
+		return nil
+	default:
+	}
+	close(s.closed)
+	var err = s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *server) accept() {
+	defer s.wg.Done()
+	for {
+		conn,err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		select {
+		case <-s.closed:
+			s.lock.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.lock.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+
+			s.lock.Lock()
+			delete(s.conns, conn)
+			s.lock.Unlock()
+		}()
+	}
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		header,err := proto.ReadHeader(conn)
+		if err != nil || header.BodyLen > maxBodyLen {
+			return
+		}
+		var body = make([]byte, header.BodyLen)
+		if _,err = io.ReadFull(conn, body); err != nil {
+			return
+		}
+		if header.Cmd == fastdfs.FDFS_PROTO_CMD_QUIT {
+			return
+		}
+
+		var fault = s.takeFault(header.Cmd)
+		if fault.Delay > 0 {
+			var timer = time.NewTimer(fault.Delay)
+			select {
+			case <-timer.C:
+			case <-s.closed:
+				timer.Stop()
+				return
+			}
+		}
+		if fault.Drop {
+			return
+		}
+
+		var res proto.Message
+		var errno = fault.Errno
+		if errno == 0 && header.Cmd != fastdfs.FDFS_PROTO_CMD_ACTIVE_TEST {
+			res,errno = s.dispatch(header.Cmd, body)
+		}
+		if fault.LoseResponse {
+			return
+		}
+
+		if v,ok := res.(proto.Versioned); ok {
+			v.SetVersion(s.version)
+		}
+		pkg,err := proto.EncodeResponse(res, errno)
+		if err != nil {
+			return
+		}
+		if _,err = conn.Write(pkg); err != nil {
+			return
+		}
+	}
+}
+
+/**
+ * decode the request body and handle it
+ *
+ * @return the response and errno, EINVAL if the request is invalid
+ */
+func (s *server) dispatch(cmd byte, body []byte) (proto.Message, byte) {
+	req,err := proto.NewRequest(cmd)
+	if err != nil {
+		return nil, fastdfs.ERR_NO_EINVAL
+	}
+	if v,ok := req.(proto.Versioned); ok {
+		v.SetVersion(s.version)
+	}
+	if err = req.UnmarshalBinary(body); err != nil {
+		return nil, fastdfs.ERR_NO_EINVAL
+	}
+
+	return s.handle(req)
+}
+
+/**
+ * take the first fault matching the command
+ *
+ * @return the fault, zero value for none
+ */
+func (s *server) takeFault(cmd byte) Fault {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i,fault := range s.faults {
+		if fault.Cmd != cmd && (fault.Cmd != 0 || cmd == fastdfs.FDFS_PROTO_CMD_ACTIVE_TEST) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i + 1:]...)
+			}
+		}
+		return *fault
+	}
+
+	return Fault{}
+}
+
+/**
+ * put the string to the fixed length field, the string is truncated if too long
+ */
+func putString(bs []byte, s string) {
+	copy(bs, s)
+}
@@ -0,0 +1,63 @@
+package fastdfstest
+
+import (
+	"testing"
+	"errors"
+	"time"
+	"github.com/go/fastdfs"
+)
+
+func TestFault(t *testing.T) {
+	tracker,err := NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	storage,err := tracker.AddStorage("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var storageClient = fastdfs.NewClient(tracker.NewConfig()).NewStorageClient()
+	results,err := storageClient.UploadAppenderBuffer([]byte("hello"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// errno without applying the request
+	storage.Inject(Fault{Cmd:fastdfs.STORAGE_PROTO_CMD_APPEND_FILE, Errno:fastdfs.ERR_NO_ENOSPC, Times:1})
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte("!")); !errors.Is(err, fastdfs.ErrNoSpace) {
+		t.Fatalf("append err %v, expect %v", err, fastdfs.ErrNoSpace)
+	}
+	if content,_ := storage.GetFile(results.GetFilename()); string(content) != "hello" {
+		t.Fatalf("content %q after failed append", content)
+	}
+
+	// the request is applied but the response is lost
+	storage.Inject(Fault{LoseResponse:true, Times:1})
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte("!")); err == nil {
+		t.Fatalf("append without response succeeded")
+	}
+	if content,_ := storage.GetFile(results.GetFilename()); string(content) != "hello!" {
+		t.Fatalf("content %q after lost response", content)
+	}
+
+	// the connection is dropped before handling
+	storage.Inject(Fault{Drop:true})
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte("!")); err == nil {
+		t.Fatalf("append on dropped connection succeeded")
+	}
+	if content,_ := storage.GetFile(results.GetFilename()); string(content) != "hello!" {
+		t.Fatalf("content %q after dropped connection", content)
+	}
+	storage.ClearFaults()
+
+	// slow response
+	tracker.Inject(Fault{Cmd:fastdfs.TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE, Delay:100 * time.Millisecond, Times:1})
+	var start = time.Now()
+	if _,err = storageClient.DownloadBuffer(results.GetGroupName(), results.GetFilename()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100 * time.Millisecond {
+		t.Fatalf("response in %v, expect delayed", elapsed)
+	}
+}
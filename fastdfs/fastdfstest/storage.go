@@ -0,0 +1,562 @@
+package fastdfstest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/proto"
+)
+
+/**
+ * the file stored in memory
+ */
+type file struct {
+	content          []byte
+	metadata         map[string]string
+	appender         bool
+	link             bool //the content is shared with the source file
+	createTimestamp  int64
+	sourceIpAddr     string
+}
+
+/**
+ * the files of a group, shared by the storage servers of the group as if they
+ * are synced at once
+ */
+type group struct {
+	name       string
+	storages   []*Storage
+	files      map[string]*file
+	lock       sync.Mutex
+}
+
+/**
+ * the operation counters of a storage server
+ */
+type storageCounters struct {
+	totalUpload, successUpload     int64
+	totalAppend, successAppend     int64
+	totalModify, successModify     int64
+	totalTruncate, successTruncate int64
+	totalSetMeta, successSetMeta   int64
+	totalDelete, successDelete     int64
+	totalDownload, successDownload int64
+	totalGetMeta, successGetMeta   int64
+	totalCreateLink, successCreateLink int64
+	totalDeleteLink, successDeleteLink int64
+}
+
+/**
+ * fake storage server, created by Tracker.AddStorage
+ */
+type Storage struct {
+	*server
+	id            string
+	group         *group
+	status        byte
+	joinTime      time.Time
+	trunkServer   bool
+	counters      storageCounters
+	lock          sync.Mutex
+}
+
+func newStorage(id string, g *group, address string, version proto.Version) (*Storage, error) {
+	var s = &Storage{
+		id:id,
+		group:g,
+		status:fastdfs.FDFS_STORAGE_STATUS_ACTIVE,
+		joinTime:time.Now(),
+	}
+	var err error
+	if s.server,err = newServer(address, version, s.handle); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+/**
+ * get the storage id
+ *
+ * @return the id
+ */
+func (s *Storage) GetId() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.id
+}
+
+/**
+ * check if the storage server is the trunk server of the group
+ *
+ * @return true for the trunk server
+ */
+func (s *Storage) IsTrunkServer() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.trunkServer
+}
+
+func (s *Storage) setTrunkServer(trunkServer bool) {
+	s.lock.Lock()
+	s.trunkServer = trunkServer
+	s.lock.Unlock()
+}
+
+/**
+ * check if the storage id or ip address is of the storage server
+ */
+func (s *Storage) match(storageId string) bool {
+	return storageId == s.GetId() || storageId == s.GetIpAddr()
+}
+
+/**
+ * get the group name
+ *
+ * @return the group name
+ */
+func (s *Storage) GetGroupName() string {
+	return s.group.name
+}
+
+/**
+ * get the ip address
+ *
+ * @return the ip address
+ */
+func (s *Storage) GetIpAddr() string {
+	return s.Addr().(*net.TCPAddr).IP.String()
+}
+
+/**
+ * get the port
+ *
+ * @return the port
+ */
+func (s *Storage) GetPort() int {
+	return s.Addr().(*net.TCPAddr).Port
+}
+
+/**
+ * get the status reported by the tracker
+ *
+ * @return the status, FDFS_STORAGE_STATUS_ACTIVE after added
+ */
+func (s *Storage) GetStatus() byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.status
+}
+
+/**
+ * set the status reported by the tracker, only the active storage servers are
+ * returned by the queries of the tracker
+ *
+ * @param status the status, such as FDFS_STORAGE_STATUS_OFFLINE
+ */
+func (s *Storage) SetStatus(status byte) {
+	s.lock.Lock()
+	s.status = status
+	s.lock.Unlock()
+}
+
+/**
+ * get the content of the file
+ *
+ * @param remoteFilename the filename
+ * @return the content, false if not exist
+ */
+func (s *Storage) GetFile(remoteFilename string) ([]byte, bool) {
+	s.group.lock.Lock()
+	defer s.group.lock.Unlock()
+
+	var f = s.group.files[remoteFilename]
+	if f == nil {
+		return nil, false
+	}
+
+	return append([]byte{}, f.content...), true
+}
+
+/**
+ * put the file to the group
+ *
+ * @param remoteFilename the filename
+ * @param content        the content
+ * @param appender       if the file is appender file
+ */
+func (s *Storage) PutFile(remoteFilename string, content []byte, appender bool) {
+	s.group.lock.Lock()
+	s.group.files[remoteFilename] = &file{
+		content:append([]byte{}, content...),
+		metadata:make(map[string]string),
+		appender:appender,
+		createTimestamp:time.Now().Unix(),
+		sourceIpAddr:s.GetIpAddr(),
+	}
+	s.group.lock.Unlock()
+}
+
+/**
+ * get the metadata of the file
+ *
+ * @param remoteFilename the filename
+ * @return the metadata, false if the file not exist
+ */
+func (s *Storage) GetMetadata(remoteFilename string) (map[string]string, bool) {
+	s.group.lock.Lock()
+	defer s.group.lock.Unlock()
+
+	var f = s.group.files[remoteFilename]
+	if f == nil {
+		return nil, false
+	}
+	var metadata = make(map[string]string, len(f.metadata))
+	for name,value := range f.metadata {
+		metadata[name] = value
+	}
+
+	return metadata, true
+}
+
+/**
+ * stop the storage server, it is still in the group
+ */
+func (s *Storage) Close() error {
+	return s.close()
+}
+
+func (s *Storage) handle(req proto.Request) (proto.Message, byte) {
+	var total,success *int64
+	switch req.Cmd() {
+	case fastdfs.STORAGE_PROTO_CMD_UPLOAD_FILE, fastdfs.STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, fastdfs.STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE:
+		total,success = &s.counters.totalUpload, &s.counters.successUpload
+	case fastdfs.STORAGE_PROTO_CMD_APPEND_FILE:
+		total,success = &s.counters.totalAppend, &s.counters.successAppend
+	case fastdfs.STORAGE_PROTO_CMD_MODIFY_FILE:
+		total,success = &s.counters.totalModify, &s.counters.successModify
+	case fastdfs.STORAGE_PROTO_CMD_TRUNCATE_FILE:
+		total,success = &s.counters.totalTruncate, &s.counters.successTruncate
+	case fastdfs.STORAGE_PROTO_CMD_SET_METADATA:
+		total,success = &s.counters.totalSetMeta, &s.counters.successSetMeta
+	case fastdfs.STORAGE_PROTO_CMD_DELETE_FILE:
+		// the link file is deleted by the delete file command too
+		if s.isLink(req.(*proto.DeleteFileRequest).FileRef) {
+			total,success = &s.counters.totalDeleteLink, &s.counters.successDeleteLink
+		} else {
+			total,success = &s.counters.totalDelete, &s.counters.successDelete
+		}
+	case fastdfs.STORAGE_PROTO_CMD_DOWNLOAD_FILE:
+		total,success = &s.counters.totalDownload, &s.counters.successDownload
+	case fastdfs.STORAGE_PROTO_CMD_GET_METADATA:
+		total,success = &s.counters.totalGetMeta, &s.counters.successGetMeta
+	case fastdfs.STORAGE_PROTO_CMD_CREATE_LINK:
+		total,success = &s.counters.totalCreateLink, &s.counters.successCreateLink
+	}
+
+	var res,errno = s.handleFile(req)
+	if total != nil {
+		s.lock.Lock()
+		*total++
+		if errno == 0 {
+			*success++
+		}
+		s.lock.Unlock()
+	}
+
+	return res, errno
+}
+
+func (s *Storage) handleFile(req proto.Request) (proto.Message, byte) {
+	var g = s.group
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	switch req := req.(type) {
+	case *proto.UploadFileRequest:
+		if int64(len(req.Content)) != req.FileSize {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		var content = append([]byte{}, req.Content...)
+		return s.store(s.newFilename(content, req.FileExtName, req.Appender), content, req.Appender)
+	case *proto.UploadSlaveFileRequest:
+		if int64(len(req.Content)) != req.FileSize {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		if g.files[req.MasterFilename] == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		var filename = slaveFilename(req.MasterFilename, req.PrefixName, req.FileExtName)
+		if g.files[filename] != nil {
+			return nil, fastdfs.ERR_NO_EALREADY
+		}
+		return s.store(filename, append([]byte{}, req.Content...), false)
+	case *proto.RegenerateAppenderFilenameRequest:
+		f,errno := s.appenderFile(req.AppenderFilename)
+		if errno != 0 {
+			return nil, errno
+		}
+		var filename = s.newFilename(f.content, extName(req.AppenderFilename), false)
+		delete(g.files, req.AppenderFilename)
+		f.appender = false
+		g.files[filename] = f
+		return &proto.UploadFileResponse{GroupName:g.name, Filename:filename}, 0
+	case *proto.CreateLinkRequest:
+		var source = g.files[req.SourceFilename]
+		if req.GroupName != g.name || source == nil || (req.MasterFilename != "" && g.files[req.MasterFilename] == nil) {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		var filename string
+		if req.MasterFilename != "" {
+			if filename = slaveFilename(req.MasterFilename, req.PrefixName, req.FileExtName); g.files[filename] != nil {
+				return nil, fastdfs.ERR_NO_EALREADY
+			}
+		} else {
+			filename = s.newFilename(source.content, req.FileExtName, false)
+		}
+		var res,errno = s.store(filename, source.content, false)
+		g.files[filename].link = true
+		return res, errno
+	case *proto.AppendFileRequest:
+		f,errno := s.appenderFile(req.AppenderFilename)
+		if errno != 0 {
+			return nil, errno
+		}
+		if int64(len(req.Content)) != req.FileSize {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		f.content = append(f.content, req.Content...)
+		return new(proto.EmptyResponse), 0
+	case *proto.ModifyFileRequest:
+		f,errno := s.appenderFile(req.AppenderFilename)
+		if errno != 0 {
+			return nil, errno
+		}
+		if int64(len(req.Content)) != req.FileSize || req.FileOffset > int64(len(f.content)) {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		if end := req.FileOffset + req.FileSize; end > int64(len(f.content)) {
+			f.content = append(f.content, make([]byte, end - int64(len(f.content)))...)
+		}
+		copy(f.content[req.FileOffset:], req.Content)
+		return new(proto.EmptyResponse), 0
+	case *proto.TruncateFileRequest:
+		f,errno := s.appenderFile(req.AppenderFilename)
+		if errno != 0 {
+			return nil, errno
+		}
+		if req.TruncatedFileSize > int64(len(f.content)) {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		f.content = f.content[:req.TruncatedFileSize]
+		return new(proto.EmptyResponse), 0
+	case *proto.DeleteFileRequest:
+		if _,errno := s.groupFile(req.FileRef); errno != 0 {
+			return nil, errno
+		}
+		delete(g.files, req.Filename)
+		return new(proto.EmptyResponse), 0
+	case *proto.DownloadFileRequest:
+		f,errno := s.groupFile(req.FileRef)
+		if errno != 0 {
+			return nil, errno
+		}
+		var offset,length = req.FileOffset, req.DownloadBytes
+		if length == 0 && offset <= int64(len(f.content)) {
+			length = int64(len(f.content)) - offset
+		}
+		if offset + length > int64(len(f.content)) {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		return &proto.DownloadFileResponse{Content:append([]byte{}, f.content[offset:offset + length]...)}, 0
+	case *proto.QueryFileInfoRequest:
+		f,errno := s.groupFile(req.FileRef)
+		if errno != 0 {
+			return nil, errno
+		}
+		return &proto.QueryFileInfoResponse{
+			FileSize:int64(len(f.content)),
+			CreateTimestamp:f.createTimestamp,
+			Crc32:int64(crc32.ChecksumIEEE(f.content)),
+			SourceIpAddr:f.sourceIpAddr,
+		}, 0
+	case *proto.SetMetadataRequest:
+		f,errno := s.groupFile(req.FileRef)
+		if errno != 0 {
+			return nil, errno
+		}
+		if req.OpFlag == fastdfs.STORAGE_SET_METADATA_FLAG_OVERWRITE {
+			f.metadata = make(map[string]string)
+		} else if req.OpFlag != fastdfs.STORAGE_SET_METADATA_FLAG_MERGE {
+			return nil, fastdfs.ERR_NO_EINVAL
+		}
+		if req.Metadata != "" {
+			for _,pair := range fastdfs.SplitMetadata(req.Metadata) {
+				f.metadata[pair.GetName()] = pair.GetValue()
+			}
+		}
+		return new(proto.EmptyResponse), 0
+	case *proto.GetMetadataRequest:
+		f,errno := s.groupFile(req.FileRef)
+		if errno != 0 {
+			return nil, errno
+		}
+		var metaList = make([]fastdfs.NameValuePair, 0, len(f.metadata))
+		for name,value := range f.metadata {
+			metaList = append(metaList, *fastdfs.NewNameValuePair(name, value))
+		}
+		return &proto.GetMetadataResponse{Metadata:fastdfs.PackMetadata(metaList)}, 0
+	}
+
+	return nil, fastdfs.ERR_NO_EINVAL
+}
+
+/**
+ * check if the file is a link file
+ */
+func (s *Storage) isLink(ref proto.FileRef) bool {
+	s.group.lock.Lock()
+	defer s.group.lock.Unlock()
+
+	var f = s.group.files[ref.Filename]
+	return ref.GroupName == s.group.name && f != nil && f.link
+}
+
+/**
+ * get the file of the group, must hold the group lock
+ */
+func (s *Storage) groupFile(ref proto.FileRef) (*file, byte) {
+	var f = s.group.files[ref.Filename]
+	if ref.GroupName != s.group.name || f == nil {
+		return nil, fastdfs.ERR_NO_ENOENT
+	}
+
+	return f, 0
+}
+
+/**
+ * get the appender file, must hold the group lock
+ */
+func (s *Storage) appenderFile(filename string) (*file, byte) {
+	var f = s.group.files[filename]
+	if f == nil {
+		return nil, fastdfs.ERR_NO_ENOENT
+	}
+	if !f.appender {
+		return nil, fastdfs.ERR_NO_EINVAL
+	}
+
+	return f, 0
+}
+
+/**
+ * store the file, must hold the group lock
+ *
+ * @return the group name and filename
+ */
+func (s *Storage) store(filename string, content []byte, appender bool) (proto.Message, byte) {
+	s.group.files[filename] = &file{
+		content:content,
+		metadata:make(map[string]string),
+		appender:appender,
+		createTimestamp:time.Now().Unix(),
+		sourceIpAddr:s.GetIpAddr(),
+	}
+
+	return &proto.UploadFileResponse{GroupName:s.group.name, Filename:filename}, 0
+}
+
+/**
+ * generate the filename as the storage server does, the base64 part encodes
+ * the source ip, the create timestamp, the file size and crc32,
+ * the source is the numeric storage id for PROTO_VERSION_6,
+ * must hold the group lock
+ */
+func (s *Storage) newFilename(content []byte, ext string, appender bool) string {
+	var source = []byte(s.Addr().(*net.TCPAddr).IP.To4())
+	if id,err := strconv.Atoi(s.GetId()); err == nil && s.version >= fastdfs.PROTO_VERSION_6 && id <= fastdfs.FDFS_MAX_SERVER_ID {
+		// int2buff(htonl(id)) of the storage server on the little endian host
+		source = []byte{byte(id), byte(id >> 8), byte(id >> 16), byte(id >> 24)}
+	}
+	for {
+		var buff = make([]byte, 4 * 2 + fastdfs.FDFS_PROTO_PKG_LEN_SIZE + 4)
+		copy(buff, source)
+		copy(buff[4:], fastdfs.Long2Buff(time.Now().Unix())[4:])
+		// the high 32 bits of the file size are random for unique filename
+		var fileSize = int64(len(content)) & 0xFFFFFFFF | int64(rand.Int31n(1 << 16)) << 32 | -1 << 63
+		if appender {
+			fileSize |= fastdfs.APPENDER_FILE_SIZE
+		}
+		copy(buff[4 * 2:], fastdfs.Long2Buff(fileSize))
+		copy(buff[4 * 2 + fastdfs.FDFS_PROTO_PKG_LEN_SIZE:], fastdfs.Long2Buff(int64(crc32.ChecksumIEEE(content)))[4:])
+
+		// the alphabet of the storage server is the same as the url encoding
+		var filename = fmt.Sprintf("M00/%02X/%02X/%s%s", rand.Intn(256), rand.Intn(256), base64.RawURLEncoding.EncodeToString(buff), formatExtName(ext))
+		if s.group.files[filename] == nil {
+			return filename
+		}
+	}
+}
+
+/**
+ * get the filename of the slave file, the ext name of the master file is used if not specified
+ */
+func slaveFilename(masterFilename, prefixName, fileExtName string) string {
+	var filename = masterFilename
+	var masterExt = ""
+	if dot := strings.LastIndexByte(filename, '.'); dot > strings.LastIndexByte(filename, '/') {
+		filename,masterExt = filename[:dot], filename[dot:]
+	}
+	filename += prefixName
+	if fileExtName != "" {
+		filename += "." + fileExtName
+	} else {
+		filename += masterExt
+	}
+
+	return filename
+}
+
+/**
+ * get the ext name of the filename without dot(.)
+ */
+func extName(filename string) string {
+	if dot := strings.LastIndexByte(filename, '.'); dot > strings.LastIndexByte(filename, '/') {
+		return filename[dot + 1:]
+	}
+
+	return ""
+}
+
+/**
+ * format the ext name as the storage server does, the ext name is prefixed by
+ * random digits to the fixed length FDFS_FILE_EXT_NAME_MAX_LEN + 1
+ */
+func formatExtName(ext string) string {
+	if len(ext) > fastdfs.FDFS_FILE_EXT_NAME_MAX_LEN {
+		ext = ext[:fastdfs.FDFS_FILE_EXT_NAME_MAX_LEN]
+	}
+	var padLen = fastdfs.FDFS_FILE_EXT_NAME_MAX_LEN - len(ext)
+	if ext == "" {
+		padLen++
+	}
+	var formatted = make([]byte, padLen, fastdfs.FDFS_FILE_EXT_NAME_MAX_LEN + 1)
+	for i := range formatted {
+		formatted[i] = byte('0' + rand.Intn(10))
+	}
+	if ext != "" {
+		formatted = append(append(formatted, '.'), ext...)
+	}
+
+	return string(formatted)
+}
@@ -0,0 +1,220 @@
+package fastdfstest
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"github.com/go/fastdfs"
+)
+
+func TestStorage(t *testing.T) {
+	tracker,err := NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	storage,err := tracker.AddStorage("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica,err := tracker.AddStorage("group1")
+	if err != nil {
+		t.Skipf("add storage fail: %v", err)
+	}
+
+	var storageClient = fastdfs.NewClient(tracker.NewConfig()).NewStorageClient()
+	var meta = []fastdfs.NameValuePair{*fastdfs.NewNameValuePair("width", "100")}
+	results,err := storageClient.UploadBuffer([]byte("hello world"), "txt", meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.GetGroupName() != "group1" {
+		t.Fatalf("group %s, expect group1", results.GetGroupName())
+	}
+	if content,ok := replica.GetFile(results.GetFilename()); !ok || string(content) != "hello world" {
+		t.Fatalf("file %q is not shared by the group", content)
+	}
+	data,err := storageClient.DownloadOffsetBuffer(results.GetGroupName(), results.GetFilename(), 6, 0)
+	if err != nil || string(data) != "world" {
+		t.Fatalf("download %q, err %v", data, err)
+	}
+
+	// the file info is decoded from the filename
+	fileInfo,err := storageClient.GetFileInfo(results.GetGroupName(), results.GetFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	queried,err := storageClient.QueryFileInfo(results.GetGroupName(), results.GetFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.GetFileSize() != 11 || fileInfo.GetSourceIpAddr() != queried.GetSourceIpAddr() || fileInfo.GetCrc32() != queried.GetCrc32() ||
+		fileInfo.GetCreateTimestamp().Unix() != queried.GetCreateTimestamp().Unix() {
+		t.Fatalf("file info %v, queried %v", fileInfo, queried)
+	}
+
+	if _,err = storageClient.SetMetadata(results.GetGroupName(), results.GetFilename(), []fastdfs.NameValuePair{*fastdfs.NewNameValuePair("height", "50")},
+		fastdfs.STORAGE_SET_METADATA_FLAG_MERGE); err != nil {
+		t.Fatal(err)
+	}
+	metaList,err := storageClient.GetMetadata(results.GetGroupName(), results.GetFilename())
+	if err != nil || len(metaList) != 2 {
+		t.Fatalf("metadata %v, err %v", metaList, err)
+	}
+	if metadata,_ := storage.GetMetadata(results.GetFilename()); metadata["width"] != "100" || metadata["height"] != "50" {
+		t.Fatalf("metadata %v", metadata)
+	}
+
+	slave,err := storageClient.UploadMasterBuffer(results.GetGroupName(), results.GetFilename(), "_small", []byte("hi"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slave.GetFilename() != results.GetFilename()[:len(results.GetFilename()) - 4] + "_small.txt" || slave.GetPrefixName() != "_small" || len(results.GetFilename()) != fastdfs.NORMAL_LOGIC_FILENAME_LENGTH {
+		t.Fatalf("slave filename %s", slave.GetFilename())
+	}
+
+	// appender file
+	appender,err := storageClient.UploadAppenderBuffer([]byte("hello"), "log", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _,err = storageClient.AppendBuffer(appender.GetGroupName(), appender.GetFilename(), []byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	if _,err = storageClient.ModifyBuffer(appender.GetGroupName(), appender.GetFilename(), 0, []byte("HELLO")); err != nil {
+		t.Fatal(err)
+	}
+	if _,err = storageClient.TruncateFileBySize(appender.GetGroupName(), appender.GetFilename(), 8); err != nil {
+		t.Fatal(err)
+	}
+	if data,err = storageClient.DownloadBuffer(appender.GetGroupName(), appender.GetFilename()); err != nil || !bytes.Equal(data, []byte("HELLO wo")) {
+		t.Fatalf("appender content %q, err %v", data, err)
+	}
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte("!")); !errors.Is(err, fastdfs.ErrInvalid) {
+		t.Fatalf("append to normal file err %v, expect %v", err, fastdfs.ErrInvalid)
+	}
+
+
+	// the appender file is renamed to a normal file
+	regenerated,err := storageClient.RegenerateAppenderFilename(appender.GetGroupName(), appender.GetFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regenerated.IsAppender() || regenerated.GetExtName() != "log" {
+		t.Fatalf("regenerated filename %s", regenerated)
+	}
+	if exists,err := storageClient.FileExists(appender.GetGroupName(), appender.GetFilename()); exists || err != nil {
+		t.Fatalf("appender file exists %v, err %v", exists, err)
+	}
+	if exists,err := storageClient.FileExists(regenerated.GetGroupName(), regenerated.GetFilename()); !exists || err != nil {
+		t.Fatalf("regenerated file exists %v, err %v", exists, err)
+	}
+	if _,err = storageClient.RegenerateAppenderFilename(regenerated.GetGroupName(), regenerated.GetFilename()); !errors.Is(err, fastdfs.ErrInvalid) {
+		t.Fatalf("regenerate normal file err %v, expect %v", err, fastdfs.ErrInvalid)
+	}
+
+	// the link file shares the content of the source file
+	link,err := storageClient.CreateLink(results.GetGroupName(), results.GetFilename(), nil, "", "", "txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slaveLink,err := storageClient.CreateLink(results.GetGroupName(), results.GetFilename(), nil, results.GetFilename(), "_link", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slaveLink.GetPrefixName() != "_link" || slaveLink.GetExtName() != "txt" {
+		t.Fatalf("slave link filename %s", slaveLink)
+	}
+	if _,err = storageClient.CreateLink(results.GetGroupName(), results.GetFilename() + "x", nil, "", "", ""); !errors.Is(err, fastdfs.ErrNotFound) {
+		t.Fatalf("link to missing file err %v, expect %v", err, fastdfs.ErrNotFound)
+	}
+	if _,err = storageClient.CreateLink(results.GetGroupName(), results.GetFilename(), nil, results.GetFilename(), "", ""); !errors.Is(err, fastdfs.ErrInvalid) {
+		t.Fatalf("slave link without prefix err %v, expect %v", err, fastdfs.ErrInvalid)
+	}
+	// the link file is deleted by the delete file command
+	if _,err = storageClient.DeleteFile(slaveLink.GetGroupName(), slaveLink.GetFilename()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _,err = storageClient.DeleteFile(results.GetGroupName(), results.GetFilename()); err != nil {
+		t.Fatal(err)
+	}
+	if data,err = storageClient.DownloadBuffer(link.GetGroupName(), link.GetFilename()); err != nil || string(data) != "hello world" {
+		t.Fatalf("link content %q, err %v", data, err)
+	}
+	if _,err = storageClient.DownloadBuffer(results.GetGroupName(), results.GetFilename()); !errors.Is(err, fastdfs.ErrNotFound) {
+		t.Fatalf("download deleted file err %v, expect %v", err, fastdfs.ErrNotFound)
+	}
+
+	var trackerClient = fastdfs.NewClient(tracker.NewConfig()).NewTrackerClient()
+	stats,err := trackerClient.ListStorages(nil, "group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uploads,successDownloads,createLinks,successCreateLinks,deleteLinks,deletes int64
+	for _,stat := range stats {
+		uploads += stat.GetTotalUploadCount()
+		successDownloads += stat.GetSuccessDownloadCount()
+		createLinks += stat.GetTotalCreateLinkCount()
+		successCreateLinks += stat.GetSuccessCreateLinkCount()
+		deleteLinks += stat.GetSuccessDeleteLinkCount()
+		deletes += stat.GetTotalDeleteCount()
+	}
+	if uploads != 3 || successDownloads != 3 {
+		t.Fatalf("%d uploads, %d success downloads", uploads, successDownloads)
+	}
+	if createLinks != 3 || successCreateLinks != 2 || deleteLinks != 1 || deletes != 1 {
+		t.Fatalf("%d create links, %d success create links, %d delete links, %d deletes", createLinks, successCreateLinks, deleteLinks, deletes)
+	}
+}
+
+func TestStorageConcurrent(t *testing.T) {
+	tracker,err := NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	if _,err = tracker.AddStorage("group1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the client is shared by the goroutines, with and without retry
+	var clients = []*fastdfs.StorageClient{
+		fastdfs.NewClient(tracker.NewConfig()).NewStorageClient(),
+		fastdfs.NewClient(tracker.NewConfig(fastdfs.WithRetryPolicy(fastdfs.NewRetryPolicy(3)))).NewStorageClient(),
+	}
+	for _,storageClient := range clients {
+		var wg sync.WaitGroup
+		var errs = make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var content = []byte(fmt.Sprintf("hello %d", i))
+				for j := 0; j < 10; j++ {
+					results,err := storageClient.UploadBuffer(content, "txt", nil)
+					if err != nil {
+						errs <- err
+						return
+					}
+					data,err := storageClient.DownloadBuffer(results.GetGroupName(), results.GetFilename())
+					if err != nil || !bytes.Equal(data, content) {
+						errs <- fmt.Errorf("download %q, expect %q, err %v", data, content, err)
+						return
+					}
+					if _,err = storageClient.DeleteFile(results.GetGroupName(), results.GetFilename()); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	}
+}
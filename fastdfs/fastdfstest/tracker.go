@@ -0,0 +1,482 @@
+package fastdfstest
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/proto"
+)
+
+// the sizes of the fields of a group stat record, in the order of StructGroupStat.
+func groupFieldSizes(version proto.Version) []int {
+	return []int{
+		version.GroupStatNameSize(), //group name
+		8, 8, 8,                     //total, free and trunk free MB
+		8, 8, 8, 8,                  //storage count, storage port, storage http port, active count
+		8, 8, 8, 8,                  //current write server, store path count, subdir count per path, current trunk file id
+	}
+}
+
+const (
+	storageFieldStatus = 0
+	storageFieldId = 1
+	storageFieldIpAddr = 2
+	storageFieldSrcIpAddr = 4
+	storageFieldVersion = 5
+	storageFieldJoinTime = 6
+	storageFieldUpTime = 7
+	storageFieldStorePathCount = 11
+	storageFieldSubdirCountPerPath = 12
+	storageFieldStoragePort = 14
+	storageFieldTotalUploadCount = 19
+	storageFieldLastHeartBeatTime = 60
+)
+
+// the sizes of the fields of a storage stat record, in the order of StructStorageStat.
+func storageFieldSizes(version proto.Version) []int {
+	var sizes = []int{
+		1,                               //status
+		fastdfs.FDFS_STORAGE_ID_MAX_SIZE, //id
+		version.IpAddrSize(),             //ip address
+		fastdfs.FDFS_DOMAIN_NAME_MAX_SIZE,
+		version.IpAddrSize(),             //source ip address
+		fastdfs.FDFS_VERSION_SIZE,
+	}
+	for i := 0; i < 10; i++ {
+		sizes = append(sizes, 8) //join time to storage http port
+	}
+	sizes = append(sizes, 4, 4, 4) //connection counts
+	for i := 0; i < 42; i++ {
+		sizes = append(sizes, 8) //operation counts to last heart beat time
+	}
+
+	return append(sizes, 1) //if trunk server
+}
+
+/**
+ * fake tracker server, the storage servers are added to the groups of it
+ */
+type Tracker struct {
+	*server
+	groups       map[string]*group
+	nextId       int
+	storeIndex   int
+	leader       bool
+	startTime    time.Time
+	lock         sync.Mutex
+}
+
+/**
+ * start the tracker server on a random port of 127.0.0.1
+ *
+ * @return the tracker server
+ */
+func NewTracker() (*Tracker, error) {
+	return NewTrackerByVersion(fastdfs.PROTO_VERSION_5)
+}
+
+/**
+ * start the tracker server speaking the protocol version on a random port of
+ * 127.0.0.1, the storage servers added speak the version too
+ *
+ * @param version the protocol version
+ * @return the tracker server
+ */
+func NewTrackerByVersion(version proto.Version) (*Tracker, error) {
+	var t = &Tracker{
+		groups:make(map[string]*group),
+		nextId:100001,
+		leader:true,
+		startTime:time.Now(),
+	}
+	var err error
+	if t.server,err = newServer("127.0.0.1:0", version, t.handle); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+/**
+ * start a storage server in the group, the group is created if not exist,
+ * the storage servers of a group share the files. as the storage servers of a
+ * group listen on the same port, the first one listens on 127.0.0.1 and the
+ * others on 127.0.0.2, 127.0.0.3 and so on, which needs the loopback addresses
+ * other than 127.0.0.1 on some systems.
+ *
+ * @param groupName the group name
+ * @return the storage server
+ */
+func (t *Tracker) AddStorage(groupName string) (*Storage, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var g = t.groups[groupName]
+	if g == nil {
+		g = &group{name:groupName, files:make(map[string]*file)}
+	}
+	var address = "127.0.0.1:0"
+	if len(g.storages) > 0 {
+		var last = g.storages[len(g.storages) - 1].Addr().(*net.TCPAddr)
+		var ip = append(net.IP{}, last.IP.To4()...)
+		ip[3]++
+		address = net.JoinHostPort(ip.String(), strconv.Itoa(last.Port))
+	}
+	storage,err := newStorage(strconv.Itoa(t.nextId), g, address, t.version)
+	if err != nil {
+		return nil, err
+	}
+	t.nextId++
+	t.groups[groupName] = g
+	g.storages = append(g.storages, storage)
+
+	return storage, nil
+}
+
+/**
+ * get the storage servers of the group
+ *
+ * @param groupName the group name
+ * @return the storage servers
+ */
+func (t *Tracker) GetStorages(groupName string) []*Storage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if g := t.groups[groupName]; g != nil {
+		return append([]*Storage{}, g.storages...)
+	}
+
+	return nil
+}
+
+/**
+ * set if the tracker is the leader of the trackers, the tracker is the leader
+ * after started. only the leader accepts the setting of trunk server
+ *
+ * @param leader true for the leader
+ */
+func (t *Tracker) SetLeader(leader bool) {
+	t.lock.Lock()
+	t.leader = leader
+	t.lock.Unlock()
+}
+
+/**
+ * create the client settings connecting to the tracker without connection pool
+ *
+ * @param options the other settings
+ * @return the settings
+ */
+func (t *Tracker) NewConfig(options ...fastdfs.ConfigOption) *fastdfs.Config {
+	var defaults = []fastdfs.ConfigOption{
+		fastdfs.WithTrackerServers(t.Addr()),
+		fastdfs.WithConnectionPool(nil),
+		fastdfs.WithProtocolVersion(t.version),
+	}
+
+	return fastdfs.NewConfig(append(defaults, options...)...)
+}
+
+/**
+ * stop the tracker server and the storage servers
+ */
+func (t *Tracker) Close() error {
+	var err = t.close()
+
+	t.lock.Lock()
+	var groups = t.groups
+	t.lock.Unlock()
+	for _,g := range groups {
+		for _,storage := range g.storages {
+			storage.close()
+		}
+	}
+
+	return err
+}
+
+func (t *Tracker) handle(req proto.Request) (proto.Message, byte) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch req := req.(type) {
+	case *proto.QueryStoreRequest:
+		return t.queryStore(req.GroupName, false)
+	case *proto.QueryStoreAllRequest:
+		return t.queryStore(req.GroupName, true)
+	case *proto.QueryFetchRequest:
+		return t.queryFetch(req.GroupName, false)
+	case *proto.QueryUpdateRequest:
+		return t.queryFetch(req.GroupName, false)
+	case *proto.QueryFetchAllRequest:
+		return t.queryFetch(req.GroupName, true)
+	case *proto.ListGroupsRequest:
+		var res = new(proto.ListGroupsResponse)
+		for _,name := range t.groupNames() {
+			res.Groups = append(res.Groups, t.groupStat(t.groups[name]))
+		}
+		return res, 0
+	case *proto.ListStoragesRequest:
+		var g = t.groups[req.GroupName]
+		if g == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		var res = new(proto.ListStoragesResponse)
+		for _,storage := range g.storages {
+			if req.StorageId == "" || storage.match(req.StorageId) {
+				res.Storages = append(res.Storages, storage.stat())
+			}
+		}
+		if req.StorageId != "" && len(res.Storages) == 0 {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		return res, 0
+	case *proto.DeleteStorageRequest:
+		var g = t.groups[req.GroupName]
+		if g == nil || req.StorageId == "" {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		for i,storage := range g.storages {
+			if !storage.match(req.StorageId) {
+				continue
+			}
+			if status := storage.GetStatus(); status == fastdfs.FDFS_STORAGE_STATUS_ONLINE || status == fastdfs.FDFS_STORAGE_STATUS_ACTIVE {
+				return nil, fastdfs.ERR_NO_EBUSY
+			}
+			g.storages = append(g.storages[:i:i], g.storages[i + 1:]...)
+			storage.close()
+			return new(proto.EmptyResponse), 0
+		}
+		return nil, fastdfs.ERR_NO_ENOENT
+	case *proto.ListOneGroupRequest:
+		var g = t.groups[req.GroupName]
+		if g == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		return &proto.ListOneGroupResponse{Group:t.groupStat(g)}, 0
+	case *proto.DeleteGroupRequest:
+		var g = t.groups[req.GroupName]
+		if g == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		if len(g.storages) > 0 {
+			return nil, fastdfs.ERR_NO_EBUSY
+		}
+		delete(t.groups, req.GroupName)
+		return new(proto.EmptyResponse), 0
+	case *proto.SetTrunkServerRequest:
+		if !t.leader {
+			return nil, fastdfs.ERR_NO_EOPNOTSUPP
+		}
+		var g = t.groups[req.GroupName]
+		if g == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		var trunkServer *Storage
+		for _,storage := range t.activeStorages(req.GroupName) {
+			if req.StorageId == "" || storage.match(req.StorageId) {
+				trunkServer = storage
+				break
+			}
+		}
+		if trunkServer == nil {
+			return nil, fastdfs.ERR_NO_ENOENT
+		}
+		for _,storage := range g.storages {
+			storage.setTrunkServer(storage == trunkServer)
+		}
+		return &proto.SetTrunkServerResponse{StorageId:trunkServer.GetId()}, 0
+	case *proto.TrackerStatusRequest:
+		return &proto.TrackerStatusResponse{IsLeader:t.leader, RunningTime:int64(time.Since(t.startTime) / time.Second)}, 0
+	}
+
+	return nil, fastdfs.ERR_NO_EINVAL
+}
+
+/**
+ * answer the query of storage servers to upload file, must hold the lock
+ *
+ * @param groupName the group name, empty for any group
+ * @param all       answer all storage servers, otherwise one by round robin
+ */
+func (t *Tracker) queryStore(groupName string, all bool) (proto.Message, byte) {
+	if groupName == "" {
+		for _,name := range t.groupNames() {
+			if res,errno := t.queryStore(name, all); errno == 0 {
+				return res, 0
+			}
+		}
+		return nil, fastdfs.ERR_NO_ENOSPC
+	}
+
+	var storages = t.activeStorages(groupName)
+	if len(storages) == 0 {
+		return nil, fastdfs.ERR_NO_ENOENT
+	}
+	if !all {
+		// round robin
+		t.storeIndex++
+		var storage = storages[t.storeIndex % len(storages)]
+		return &proto.QueryStoreResponse{
+			GroupName:groupName,
+			ServerAddr:proto.ServerAddr{IpAddr:storage.GetIpAddr(), Port:storage.GetPort()},
+		}, 0
+	}
+
+	var res = &proto.QueryStoreAllResponse{GroupName:groupName}
+	for _,storage := range storages {
+		res.Servers = append(res.Servers, proto.ServerAddr{IpAddr:storage.GetIpAddr(), Port:storage.GetPort()})
+	}
+
+	return res, 0
+}
+
+/**
+ * answer the query of storage servers to download or update file, must hold the lock
+ *
+ * @param groupName the group name
+ * @param all       answer all storage servers, otherwise the first one
+ */
+func (t *Tracker) queryFetch(groupName string, all bool) (proto.Message, byte) {
+	var storages = t.activeStorages(groupName)
+	if len(storages) == 0 {
+		return nil, fastdfs.ERR_NO_ENOENT
+	}
+	if !all {
+		storages = storages[:1]
+	}
+
+	var res = &proto.QueryFetchResponse{GroupName:groupName, Port:storages[0].GetPort()}
+	for _,storage := range storages {
+		res.IpAddrs = append(res.IpAddrs, storage.GetIpAddr())
+	}
+
+	return res, 0
+}
+
+/**
+ * get the active storage servers of the group, must hold the lock
+ */
+func (t *Tracker) activeStorages(groupName string) []*Storage {
+	var g = t.groups[groupName]
+	if g == nil {
+		return nil
+	}
+	var storages []*Storage
+	for _,storage := range g.storages {
+		if storage.GetStatus() == fastdfs.FDFS_STORAGE_STATUS_ACTIVE {
+			storages = append(storages, storage)
+		}
+	}
+
+	return storages
+}
+
+/**
+ * get the sorted group names, must hold the lock
+ */
+func (t *Tracker) groupNames() []string {
+	var names = make([]string, 0, len(t.groups))
+	for name := range t.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+/**
+ * encode the group stat record, must hold the lock
+ */
+func (t *Tracker) groupStat(g *group) []byte {
+	var fields = newRecord(groupFieldSizes(t.version))
+	putString(fields.field(0), g.name)
+	var activeCount = 0
+	for _,storage := range g.storages {
+		if storage.GetStatus() == fastdfs.FDFS_STORAGE_STATUS_ACTIVE {
+			activeCount++
+		}
+	}
+	fields.putLong(4, int64(len(g.storages)))
+	if len(g.storages) > 0 {
+		fields.putLong(5, int64(g.storages[0].GetPort()))
+	}
+	fields.putLong(7, int64(activeCount))
+	fields.putLong(9, 1)   //store path count
+	fields.putLong(10, 256) //subdir count per path
+
+	return fields.bs
+}
+
+/**
+ * encode the storage stat record
+ */
+func (s *Storage) stat() []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var fields = newRecord(storageFieldSizes(s.version))
+	fields.field(storageFieldStatus)[0] = s.status
+	putString(fields.field(storageFieldId), s.id)
+	putString(fields.field(storageFieldIpAddr), s.GetIpAddr())
+	putString(fields.field(storageFieldSrcIpAddr), s.GetIpAddr())
+	putString(fields.field(storageFieldVersion), "6.07")
+	fields.putLong(storageFieldJoinTime, s.joinTime.Unix())
+	fields.putLong(storageFieldUpTime, s.joinTime.Unix())
+	fields.putLong(storageFieldStorePathCount, 1)
+	fields.putLong(storageFieldSubdirCountPerPath, 256)
+	fields.putLong(storageFieldStoragePort, int64(s.GetPort()))
+	var counters = []int64{
+		s.counters.totalUpload, s.counters.successUpload,
+		s.counters.totalAppend, s.counters.successAppend,
+		s.counters.totalModify, s.counters.successModify,
+		s.counters.totalTruncate, s.counters.successTruncate,
+		s.counters.totalSetMeta, s.counters.successSetMeta,
+		s.counters.totalDelete, s.counters.successDelete,
+		s.counters.totalDownload, s.counters.successDownload,
+		s.counters.totalGetMeta, s.counters.successGetMeta,
+		s.counters.totalCreateLink, s.counters.successCreateLink,
+		s.counters.totalDeleteLink, s.counters.successDeleteLink,
+	}
+	for i,n := range counters {
+		fields.putLong(storageFieldTotalUploadCount + i, n)
+	}
+	fields.putLong(storageFieldLastHeartBeatTime, time.Now().Unix())
+	if s.trunkServer {
+		fields.field(len(fields.sizes) - 1)[0] = 1
+	}
+
+	return fields.bs
+}
+
+/**
+ * fixed length fields of a stat record
+ */
+type record struct {
+	bs        []byte
+	offsets   []int
+	sizes     []int
+}
+
+func newRecord(sizes []int) *record {
+	var offsets = make([]int, len(sizes))
+	var offset = 0
+	for i,size := range sizes {
+		offsets[i] = offset
+		offset += size
+	}
+
+	return &record{bs:make([]byte, offset), offsets:offsets, sizes:sizes}
+}
+
+func (r *record) field(index int) []byte {
+	return r.bs[r.offsets[index]:r.offsets[index] + r.sizes[index]]
+}
+
+func (r *record) putLong(index int, n int64) {
+	copy(r.field(index), fastdfs.Long2Buff(n))
+}
@@ -0,0 +1,164 @@
+package fastdfstest
+
+import (
+	"testing"
+	"errors"
+	"net"
+	"github.com/go/fastdfs"
+)
+
+func TestTracker(t *testing.T) {
+	tracker,err := NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	var storages = make([]*Storage, 3)
+	for i,groupName := range []string{"group1", "group1", "group2"} {
+		if storages[i],err = tracker.AddStorage(groupName); err != nil {
+			t.Skipf("add storage fail: %v", err)
+		}
+	}
+
+	var trackerClient = fastdfs.NewClient(tracker.NewConfig()).NewTrackerClient()
+	groups,err := trackerClient.ListGroups(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].GetGroupName() != "group1" || groups[0].GetStorageCount() != 2 || groups[1].GetActiveCount() != 1 {
+		t.Fatalf("groups %+v", groups)
+	}
+	stats,err := trackerClient.ListStorages(nil, "group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].GetId() != storages[0].GetId() || stats[1].GetStoragePort() != storages[1].GetPort() ||
+		stats[1].GetIpAddr() != "127.0.0.2" || stats[0].GetStatus() != fastdfs.FDFS_STORAGE_STATUS_ACTIVE {
+		t.Fatalf("storages %+v", stats)
+	}
+
+	servers,err := trackerClient.GetStoreStorages(nil, "group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 {
+		t.Fatalf("%d store storages, expect 2", len(servers))
+	}
+	for _,server := range servers {
+		server.Close()
+	}
+	fetches,err := trackerClient.GetFetchStorages(nil, "group1", "M00/00/00/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetches) != 2 || fetches[1].GetIpAddr() != storages[1].GetIpAddr() || fetches[1].GetPort() != storages[1].GetPort() {
+		t.Fatalf("fetch storages %+v", fetches)
+	}
+
+	// the offline storage is not queried and can be deleted
+	storages[0].SetStatus(fastdfs.FDFS_STORAGE_STATUS_OFFLINE)
+	if fetches,err = trackerClient.GetFetchStorages(nil, "group1", "M00/00/00/test"); err != nil || len(fetches) != 1 || fetches[0].GetIpAddr() != storages[1].GetIpAddr() {
+		t.Fatalf("fetch storages %+v, err %v", fetches, err)
+	}
+	if _,err = trackerClient.DeleteStorage("group1", storages[1].GetId()); !errors.Is(err, fastdfs.ErrBusy) {
+		t.Fatalf("delete active storage err %v, expect %v", err, fastdfs.ErrBusy)
+	}
+	if ok,err := trackerClient.DeleteStorage("group1", storages[0].GetId()); !ok || err != nil {
+		t.Fatalf("delete offline storage %v, err %v", ok, err)
+	}
+	if storages := tracker.GetStorages("group1"); len(storages) != 1 {
+		t.Fatalf("%d storages after delete, expect 1", len(storages))
+	}
+	if _,err = trackerClient.ListStorages(nil, "group3"); !errors.Is(err, fastdfs.ErrNotFound) {
+		t.Fatalf("list unknown group err %v, expect %v", err, fastdfs.ErrNotFound)
+	}
+
+	group2,err := trackerClient.ListOneGroup(nil, "group2")
+	if err != nil || group2.GetGroupName() != "group2" || group2.GetStorageCount() != 1 {
+		t.Fatalf("group2 %v, err %v", group2, err)
+	}
+
+	// the follower tracker is tried first, only the leader sets the trunk server
+	follower,err := NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+	follower.SetLeader(false)
+	var trackerGroup = fastdfs.NewTrackerGroup([]net.Addr{follower.Addr(), tracker.Addr()})
+	status,err := trackerClient.ListTrackerStatusByTrackerGroup(trackerGroup)
+	if err != nil || len(status) != 2 || status[0].IsLeader() || !status[1].IsLeader() || status[1].GetAddress() != tracker.Addr().String() {
+		t.Fatalf("tracker status %v, err %v", status, err)
+	}
+	leader,err := trackerClient.GetLeaderTrackerByTrackerGroup(trackerGroup)
+	if err != nil || leader.GetAddress() != tracker.Addr().String() {
+		t.Fatalf("leader %v, err %v", leader, err)
+	}
+	trunkServer,err := trackerClient.SetTrunkServerByTrackerGroup(trackerGroup, "group1", "")
+	if err != nil || trunkServer != storages[1].GetId() || !storages[1].IsTrunkServer() {
+		t.Fatalf("trunk server %s, err %v", trunkServer, err)
+	}
+	if stats,err = trackerClient.ListStorages(nil, "group1"); err != nil || !stats[0].IsTrunkServer() {
+		t.Fatalf("storages %+v, err %v", stats, err)
+	}
+	follower.SetLeader(true)
+	if _,err = trackerClient.SetTrunkServerByTrackerGroup(trackerGroup, "group1", ""); !errors.Is(err, fastdfs.ErrNotFound) {
+		t.Fatalf("set trunk server of unknown group err %v, expect %v", err, fastdfs.ErrNotFound)
+	}
+
+	// the group is deleted after the storage servers are deleted
+	if _,err = trackerClient.DeleteGroup("group2"); !errors.Is(err, fastdfs.ErrBusy) {
+		t.Fatalf("delete group with storages err %v, expect %v", err, fastdfs.ErrBusy)
+	}
+	storages[2].SetStatus(fastdfs.FDFS_STORAGE_STATUS_OFFLINE)
+	if _,err = trackerClient.DeleteStorage("group2", storages[2].GetId()); err != nil {
+		t.Fatal(err)
+	}
+	if ok,err := trackerClient.DeleteGroup("group2"); !ok || err != nil {
+		t.Fatalf("delete group %v, err %v", ok, err)
+	}
+	if _,err = trackerClient.DeleteGroup("group2"); !errors.Is(err, fastdfs.ErrNotFound) {
+		t.Fatalf("delete deleted group err %v, expect %v", err, fastdfs.ErrNotFound)
+	}
+}
+
+func TestTrackerV6(t *testing.T) {
+	tracker,err := NewTrackerByVersion(fastdfs.PROTO_VERSION_6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	storage,err := tracker.AddStorage("group1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var client = fastdfs.NewClient(tracker.NewConfig())
+	fileId,err := client.NewStorageClient().UploadBuffer([]byte("hello"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileId.GetSourceStorageId() != storage.GetId() {
+		t.Fatalf("source storage id %q, expect %s", fileId.GetSourceStorageId(), storage.GetId())
+	}
+	info,err := client.NewStorageClient().GetFileInfo(fileId.GetGroupName(), fileId.GetFilename())
+	if err != nil || info.GetFileSize() != 5 || info.GetSourceIpAddr() != storage.GetIpAddr() {
+		t.Fatalf("file info %v, err %v", info, err)
+	}
+
+	var trackerClient = client.NewTrackerClient()
+	groups,err := trackerClient.ListGroups(nil)
+	if err != nil || len(groups) != 1 || groups[0].GetGroupName() != "group1" || groups[0].GetStorageCount() != 1 {
+		t.Fatalf("groups %+v, err %v", groups, err)
+	}
+	stats,err := trackerClient.ListStorages(nil, "group1")
+	if err != nil || len(stats) != 1 || stats[0].GetIpAddr() != storage.GetIpAddr() || stats[0].GetStoragePort() != storage.GetPort() ||
+		stats[0].GetTotalUploadCount() != 1 {
+		t.Fatalf("storages %+v, err %v", stats, err)
+	}
+
+	// the client speaking V5 rejects the V6 responses
+	if _,err = fastdfs.NewClient(tracker.NewConfig(fastdfs.WithProtocolVersion(fastdfs.PROTO_VERSION_5))).NewTrackerClient().ListGroups(nil); err == nil {
+		t.Fatal("list groups of V6 with V5 succeeded")
+	}
+}
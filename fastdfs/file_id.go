@@ -0,0 +1,367 @@
+package fastdfs
+
+import (
+	"strings"
+	"strconv"
+	"time"
+)
+
+const (
+	FDFS_STORE_PATH_PREFIX = "M"
+	FDFS_FILENAME_INFO_LEN = 4 * 2 + FDFS_PROTO_PKG_LEN_SIZE + 4 //source ip, timestamp, file size and crc32
+	FDFS_TRUNK_INFO_LEN = 4 * 3 //trunk file id, offset and size
+)
+
+/**
+ * the location of the file stored in a trunk file
+ */
+type TrunkInfo struct {
+	TrunkFileId  int
+	Offset       int
+	Size         int
+}
+
+/**
+ * parsed file id: the group name and the remote filename generated by the storage server,
+ * the remote filename is formatted as:<br>
+ * M&lt;store path index&gt;/&lt;sub dir&gt;/&lt;sub dir&gt;/&lt;payload&gt;[&lt;trunk info&gt;][&lt;suffix&gt;][.&lt;ext name&gt;]<br>
+ * the payload is the base64 encoded source ip, create timestamp, file size and crc32,
+ * the source is the numeric storage id instead of the ip address when the servers use
+ * storage ids (the only choice for IPv6),
+ * the trunk info exists for the trunk file only, and the suffix holds the random padding
+ * of the ext name and the prefix of the slave file.
+ * the file id is immutable.
+ */
+type FileID struct {
+	groupName        string
+	storePathIndex   int
+	subDirs          [2]int
+	payload          string
+	trunkInfo        string
+	suffix           string
+	extName          string
+	sourceIpAddr     string
+	sourceId         string //the numeric storage id of the source storage server, empty for the ip address
+	createTimestamp  int64
+	fileSize         int64 //the file size with the appender and trunk flags
+	crc32            int
+}
+
+/**
+ * parse the file id
+ *
+ * @param file_id the file id (including group name and filename)
+ * @return the parsed file id
+ */
+func ParseFileID(fileId string) (*FileID, error) {
+	var parts = make([]string, 2)
+	if errno := SplitFileId(fileId, parts); errno != 0 {
+		return nil, newInvalidError("invalid file id %s", fileId)
+	}
+
+	return NewFileID(parts[0], parts[1])
+}
+
+/**
+ * parse the remote filename of the group
+ *
+ * @param group_name      the group name
+ * @param remote_filename the filename on the storage server
+ * @return the parsed file id
+ */
+func NewFileID(groupName, remoteFilename string) (*FileID, error) {
+	if groupName == "" || len(groupName) > FDFS_GROUP_NAME_MAX_LEN || len(remoteFilename) < FDFS_FILE_PATH_LEN + FDFS_FILENAME_BASE64_LENGTH {
+		return nil, newInvalidError("invalid filename %s/%s", groupName, remoteFilename)
+	}
+
+	var f = &FileID{groupName:groupName}
+	var ok = strings.HasPrefix(remoteFilename, FDFS_STORE_PATH_PREFIX)
+	var path = remoteFilename[len(FDFS_STORE_PATH_PREFIX):FDFS_FILE_PATH_LEN]
+	for i := 0; ok && i < 3; i++ {
+		var n int
+		if n,ok = parseHexByte(path[i * 3:]); ok {
+			ok = path[i * 3 + 2] == '/'
+		}
+		if i == 0 {
+			f.storePathIndex = n
+		} else {
+			f.subDirs[i - 1] = n
+		}
+	}
+	if !ok {
+		return nil, newInvalidError("invalid filename %s/%s", groupName, remoteFilename)
+	}
+
+	var name = remoteFilename[FDFS_FILE_PATH_LEN:]
+	f.payload = name[:FDFS_FILENAME_BASE64_LENGTH]
+	buff,err := base64.DecodeAuto(f.payload)
+	if err != nil || len(buff) < FDFS_FILENAME_INFO_LEN {
+		return nil, newInvalidError("invalid filename %s/%s", groupName, remoteFilename)
+	}
+	// the storage server writes the source as int2buff(htonl(x)), the ip address
+	// is in the dotted order and the storage id is little endian
+	if source := decodeSourceId(buff); source > 0 && source <= FDFS_MAX_SERVER_ID {
+		f.sourceId = strconv.Itoa(source)
+	} else {
+		f.sourceIpAddr = GetIpAddress(buff, 0)
+	}
+	f.createTimestamp = int64(Buff2int32(buff, 4))
+	f.fileSize = Buff2long(buff, 4 * 2)
+	f.crc32 = int(Buff2int32(buff, 4 * 2 + FDFS_PROTO_PKG_LEN_SIZE))
+
+	name = name[FDFS_FILENAME_BASE64_LENGTH:]
+	if f.IsTrunk() {
+		if len(name) < FDFS_TRUNK_FILE_INFO_LEN {
+			return nil, newInvalidError("invalid trunk filename %s/%s", groupName, remoteFilename)
+		}
+		f.trunkInfo = name[:FDFS_TRUNK_FILE_INFO_LEN]
+		if buff,err = base64.DecodeAuto(f.trunkInfo); err != nil || len(buff) < FDFS_TRUNK_INFO_LEN {
+			return nil, newInvalidError("invalid trunk filename %s/%s", groupName, remoteFilename)
+		}
+		name = name[FDFS_TRUNK_FILE_INFO_LEN:]
+	}
+
+	if pos := strings.LastIndexByte(name, '.'); pos >= 0 {
+		f.suffix,f.extName = name[:pos], name[pos + 1:]
+	} else {
+		f.suffix = name
+	}
+	if strings.Contains(name, "/") || len(f.extName) > FDFS_FILE_EXT_NAME_MAX_LEN {
+		return nil, newInvalidError("invalid filename %s/%s", groupName, remoteFilename)
+	}
+
+	return f, nil
+}
+
+/**
+ * parse 2 upper case hex digits
+ */
+func parseHexByte(s string) (int, bool) {
+	var n = 0
+	for i := 0; i < 2; i++ {
+		var c = s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			n = n * 16 + int(c - '0')
+		case c >= 'A' && c <= 'F':
+			n = n * 16 + int(c - 'A') + 10
+		default:
+			return 0, false
+		}
+	}
+
+	return n, true
+}
+
+/**
+ * decode the storage id from the first 4 bytes of the filename info
+ */
+func decodeSourceId(buff []byte) int {
+	return int(buff[0]) | int(buff[1]) << 8 | int(buff[2]) << 16 | int(buff[3]) << 24
+}
+
+/**
+ * get the group name
+ *
+ * @return the group name
+ */
+func (f *FileID) GetGroupName() string {
+	return f.groupName
+}
+
+/**
+ * get the remote filename, the file id without the group name
+ *
+ * @return the remote filename
+ */
+func (f *FileID) GetFilename() string {
+	var sb strings.Builder
+	sb.WriteString(FDFS_STORE_PATH_PREFIX)
+	for i,n := range []int{f.storePathIndex, f.subDirs[0], f.subDirs[1]} {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		sb.WriteByte("0123456789ABCDEF"[n >> 4])
+		sb.WriteByte("0123456789ABCDEF"[n & 0xF])
+	}
+	sb.WriteByte('/')
+	sb.WriteString(f.payload)
+	sb.WriteString(f.trunkInfo)
+	sb.WriteString(f.suffix)
+	if f.extName != "" {
+		sb.WriteByte('.')
+		sb.WriteString(f.extName)
+	}
+
+	return sb.String()
+}
+
+/**
+ * format the file id, the group name and the remote filename
+ *
+ * @return the file id
+ */
+func (f *FileID) String() string {
+	return f.groupName + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + f.GetFilename()
+}
+
+/**
+ * get the store path index
+ *
+ * @return the store path index, the number of M00
+ */
+func (f *FileID) GetStorePathIndex() int {
+	return f.storePathIndex
+}
+
+/**
+ * get the two level sub dirs under the store path
+ *
+ * @return the sub dirs
+ */
+func (f *FileID) GetSubDirs() (int, int) {
+	return f.subDirs[0], f.subDirs[1]
+}
+
+/**
+ * get the base64 encoded payload of the file attributes
+ *
+ * @return the payload
+ */
+func (f *FileID) GetPayload() string {
+	return f.payload
+}
+
+/**
+ * get the ext name
+ *
+ * @return the ext name without dot(.), empty for none
+ */
+func (f *FileID) GetExtName() string {
+	return f.extName
+}
+
+/**
+ * get the prefix name of the slave file. the prefix follows the random digits
+ * padding the ext name of the master file, so the leading digits of the prefix
+ * are taken as the padding.
+ *
+ * @return the prefix name, empty for the master file
+ */
+func (f *FileID) GetPrefixName() string {
+	if !f.IsSlave() {
+		return ""
+	}
+
+	var i = 0
+	for i < len(f.suffix) - 1 && i <= FDFS_FILE_EXT_NAME_MAX_LEN && f.suffix[i] >= '0' && f.suffix[i] <= '9' {
+		i++
+	}
+
+	return f.suffix[i:]
+}
+
+/**
+ * get the ip address of the source storage server the file uploaded to
+ *
+ * @return the source ip address
+ */
+func (f *FileID) GetSourceIpAddr() string {
+	return f.sourceIpAddr
+}
+
+/**
+ * get the storage id of the source storage server the file uploaded to
+ *
+ * @return the source storage id, empty when the filename holds the ip address
+ */
+func (f *FileID) GetSourceStorageId() string {
+	return f.sourceId
+}
+
+/**
+ * get the create timestamp of the file
+ *
+ * @return the create timestamp
+ */
+func (f *FileID) GetCreateTimestamp() time.Time {
+	return time.Unix(f.createTimestamp, 0)
+}
+
+/**
+ * get the file size encoded in the filename, the size when created for the appender file
+ *
+ * @return the file size
+ */
+func (f *FileID) GetFileSize() int64 {
+	if f.fileSize >> 63 != 0 || f.IsTrunk() {
+		return f.fileSize & 0xFFFFFFFF //low 32 bits is file size
+	}
+
+	return f.fileSize
+}
+
+/**
+ * get the crc32 signature encoded in the filename
+ *
+ * @return the crc32 signature
+ */
+func (f *FileID) GetCrc32() int {
+	return f.crc32
+}
+
+/**
+ * check if the file is an appender file
+ *
+ * @return true for the appender file
+ */
+func (f *FileID) IsAppender() bool {
+	return f.fileSize & APPENDER_FILE_SIZE != 0
+}
+
+/**
+ * check if the file is stored in a trunk file
+ *
+ * @return true for the trunk file
+ */
+func (f *FileID) IsTrunk() bool {
+	return f.fileSize & TRUNK_FILE_MARK_SIZE != 0
+}
+
+/**
+ * check if the file is a slave file, the filename is longer than the master one
+ *
+ * @return true for the slave file
+ */
+func (f *FileID) IsSlave() bool {
+	var length = len(f.GetFilename())
+	return length > TRUNK_LOGIC_FILENAME_LENGTH || (length > NORMAL_LOGIC_FILENAME_LENGTH && !f.IsTrunk())
+}
+
+/**
+ * get the location in the trunk file
+ *
+ * @return the trunk info, null for the file not in a trunk file
+ */
+func (f *FileID) GetTrunkInfo() *TrunkInfo {
+	if f.trunkInfo == "" {
+		return nil
+	}
+
+	var buff,_ = base64.DecodeAuto(f.trunkInfo)
+	return &TrunkInfo{
+		TrunkFileId:int(Buff2int32(buff, 0)),
+		Offset:int(Buff2int32(buff, 4)),
+		Size:int(Buff2int32(buff, 4 * 2)),
+	}
+}
+
+/**
+ * get the file info decoded from the filename. the file size of the appender file
+ * and the attributes of the slave file are not reliable, query the storage server for them
+ *
+ * @return the file info
+ */
+func (f *FileID) GetFileInfo() *FileInfo {
+	return NewFileInfo(f.GetFileSize(), f.createTimestamp, f.crc32, f.sourceIpAddr)
+}
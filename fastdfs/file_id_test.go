@@ -0,0 +1,127 @@
+package fastdfs
+
+import (
+	"testing"
+	stdbase64 "encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/**
+ * build the remote filename as the storage server does
+ */
+func testFilename(storePathIndex int, fileSize int64, crc32 int, trunk *TrunkInfo, suffix, ext string) string {
+	var buff = make([]byte, FDFS_FILENAME_INFO_LEN)
+	copy(buff, []byte{192, 168, 1, 100})
+	copy(buff[4:], Long2Buff(1600000000)[4:])
+	copy(buff[4 * 2:], Long2Buff(fileSize))
+	copy(buff[4 * 2 + FDFS_PROTO_PKG_LEN_SIZE:], Long2Buff(int64(crc32))[4:])
+	var filename = fmt.Sprintf("M%02X/0A/FF/%s", storePathIndex, stdbase64.RawURLEncoding.EncodeToString(buff))
+	if trunk != nil {
+		var info = make([]byte, FDFS_TRUNK_INFO_LEN)
+		copy(info, Long2Buff(int64(trunk.TrunkFileId))[4:])
+		copy(info[4:], Long2Buff(int64(trunk.Offset))[4:])
+		copy(info[4 * 2:], Long2Buff(int64(trunk.Size))[4:])
+		filename += stdbase64.RawURLEncoding.EncodeToString(info)
+	}
+	if ext != "" {
+		return filename + suffix + "." + ext
+	}
+
+	return filename + suffix
+}
+
+func TestFileID(t *testing.T) {
+	var filename = testFilename(1, 1024, 0x12345678, nil, "379", "jpg")
+	fileId,err := ParseFileID("group1/" + filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileId.String() != "group1/" + filename || fileId.GetGroupName() != "group1" || fileId.GetFilename() != filename {
+		t.Fatalf("file id %s, expect group1/%s", fileId, filename)
+	}
+	if len(filename) != NORMAL_LOGIC_FILENAME_LENGTH || fileId.GetPayload() != filename[FDFS_FILE_PATH_LEN:FDFS_FILE_PATH_LEN + FDFS_FILENAME_BASE64_LENGTH] {
+		t.Fatalf("payload %s of %s", fileId.GetPayload(), filename)
+	}
+	if dir1,dir2 := fileId.GetSubDirs(); fileId.GetStorePathIndex() != 1 || dir1 != 0x0A || dir2 != 0xFF {
+		t.Fatalf("store path %d, sub dirs %d/%d", fileId.GetStorePathIndex(), dir1, dir2)
+	}
+	if fileId.GetSourceIpAddr() != "192.168.1.100" || fileId.GetCreateTimestamp().Unix() != 1600000000 ||
+		fileId.GetFileSize() != 1024 || fileId.GetCrc32() != 0x12345678 || fileId.GetExtName() != "jpg" {
+		t.Fatalf("file info %s, ext %s", fileId.GetFileInfo(), fileId.GetExtName())
+	}
+	if fileId.IsAppender() || fileId.IsTrunk() || fileId.IsSlave() || fileId.GetTrunkInfo() != nil || fileId.GetPrefixName() != "" {
+		t.Fatalf("flags of normal file %s", fileId)
+	}
+
+	// slave file of the master without ext name
+	filename = testFilename(0, 100, 0, nil, "1234567_150x150", "png")
+	if fileId,err = NewFileID("group1", filename); err != nil {
+		t.Fatal(err)
+	}
+	if !fileId.IsSlave() || fileId.GetPrefixName() != "_150x150" || fileId.GetExtName() != "png" || fileId.GetFilename() != filename {
+		t.Fatalf("slave %s, prefix %s", fileId, fileId.GetPrefixName())
+	}
+
+	// appender file with the random high bits of the size
+	if fileId,err = NewFileID("group1", testFilename(0, -1 << 63 | 0x1234 << 32 | APPENDER_FILE_SIZE | 5, 0, nil, "0000000", "")); err != nil {
+		t.Fatal(err)
+	}
+	if !fileId.IsAppender() || fileId.IsTrunk() || fileId.GetFileSize() != 5 || fileId.GetExtName() != "" {
+		t.Fatalf("appender %s, size %d", fileId, fileId.GetFileSize())
+	}
+
+	// trunk file
+	var trunk = TrunkInfo{TrunkFileId:3, Offset:4096, Size:2048}
+	filename = testFilename(0, TRUNK_FILE_MARK_SIZE | 2000, 0, &trunk, "12", "jpeg")
+	if fileId,err = NewFileID("group1", filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(filename) != TRUNK_LOGIC_FILENAME_LENGTH || !fileId.IsTrunk() || fileId.IsSlave() || fileId.GetFileSize() != 2000 {
+		t.Fatalf("trunk %s, size %d", fileId, fileId.GetFileSize())
+	}
+	if info := fileId.GetTrunkInfo(); info == nil || *info != trunk {
+		t.Fatalf("trunk info %v, expect %v", info, trunk)
+	}
+	if fileId.GetFilename() != filename {
+		t.Fatalf("trunk filename %s, expect %s", fileId.GetFilename(), filename)
+	}
+
+	// the source is the storage id 100001, written by the storage server as
+	// int2buff(htonl(100001)) = A1 86 01 00, the payload of the 6.x servers with
+	// storage ids starts with "oYYBA"
+	if fileId,err = ParseFileID("group1/M00/00/00/oYYBAGVT8QCAABI0AAAABTYQpoY.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if fileId.GetSourceStorageId() != "100001" || fileId.GetSourceIpAddr() != "" || fileId.GetCreateTimestamp().Unix() != 1700000000 ||
+		fileId.GetFileSize() != 5 || fileId.GetCrc32() != 0x3610A686 {
+		t.Fatalf("source storage id %q, ip %q, file info %s", fileId.GetSourceStorageId(), fileId.GetSourceIpAddr(), fileId.GetFileInfo())
+	}
+	if fileId,err = ParseFileID("group1/" + testFilename(0, 1024, 0, nil, "379", "jpg")); err != nil || fileId.GetSourceStorageId() != "" {
+		t.Fatalf("source storage id %q of the ip address, err %v", fileId.GetSourceStorageId(), err)
+	}
+}
+
+func TestFileIDInvalid(t *testing.T) {
+	var filename = testFilename(0, 1024, 0, nil, "379", "jpg")
+	var invalid = []string{
+		"",
+		"group1",
+		"/" + filename,
+		"group1/short",
+		"group1/" + strings.Replace(filename, "M00", "m00", 1),
+		"group1/" + strings.Replace(filename, "M00/0A", "M00/0a", 1),
+		"group1/" + strings.Replace(filename, "M00/0A/", "M00/0A-", 1),
+		"group1/" + filename[:FDFS_FILE_PATH_LEN] + "!" + filename[FDFS_FILE_PATH_LEN + 1:],
+		"group1/" + filename + "/x",
+		"group1/" + filename[:len(filename) - 4] + ".toolong",
+		"group_name_too_long/" + filename,
+		"group1/" + testFilename(0, TRUNK_FILE_MARK_SIZE | 1024, 0, nil, "", "jpg"),
+	}
+	for _,fileId := range invalid {
+		if _,err := ParseFileID(fileId); !errors.Is(err, ErrInvalid) {
+			t.Errorf("parse %q err %v, expect %v", fileId, err, ErrInvalid)
+		}
+	}
+}
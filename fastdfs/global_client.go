@@ -1,11 +1,10 @@
 package fastdfs
 
 import (
-	"errors"
+	"context"
 	"net"
-	"strings"
-	"fmt"
 	"strconv"
+	"github.com/go/fastdfs/proto"
 	"github.com/go/properties"
 	"os"
 	"time"
@@ -19,6 +18,13 @@ const (
 	ConfKeyHttpSecretKey        = "http.secret_key"
 	ConfKeyHttpTrackerHttpPort = "http.tracker_http_port"
 	ConfKeyTrackerServer        = "tracker_server"
+	ConfKeyConnectionPoolEnabled = "connection_pool.enabled"
+	ConfKeyConnectionPoolMaxCountPerEntry = "connection_pool.max_count_per_entry"
+	ConfKeyConnectionPoolMaxIdleCountPerEntry = "connection_pool.max_idle_count_per_entry"
+	ConfKeyConnectionPoolMaxIdleTime = "connection_pool.max_idle_time"
+	ConfKeyConnectionPoolMaxWaitTimeInMs = "connection_pool.max_wait_time_in_ms"
+	ConfKeyStorageAddressMap = "storage_address_map"  //one rule "from to" per item, see AddressTable.AddRule
+	ConfKeyProtocolVersion = "protocol_version"  //the major version of the servers, 6 for FastDFS 6.x with IPv6
 )
 
 const (
@@ -29,6 +35,13 @@ const (
 	PropKeyHttpSecretKey            = "fastdfs.http_secret_key"
 	PropKeyHttpTrackerHttpPort     = "fastdfs.http_tracker_http_port"
 	PropKeyTrackerServers           = "fastdfs.tracker_servers"
+	PropKeyConnectionPoolEnabled = "fastdfs.connection_pool.enabled"
+	PropKeyConnectionPoolMaxCountPerEntry = "fastdfs.connection_pool.max_count_per_entry"
+	PropKeyConnectionPoolMaxIdleCountPerEntry = "fastdfs.connection_pool.max_idle_count_per_entry"
+	PropKeyConnectionPoolMaxIdleTime = "fastdfs.connection_pool.max_idle_time"
+	PropKeyConnectionPoolMaxWaitTimeInMs = "fastdfs.connection_pool.max_wait_time_in_ms"
+	PropKeyStorageAddressMap = "fastdfs.storage_address_map"  //the rules "from to" separated by comma
+	PropKeyProtocolVersion = "fastdfs.protocol_version"
 )
 
 const (
@@ -48,69 +61,28 @@ var (
 	GSecretKey = DefaultHttpSecretKey //generage token secret key
 	GTrackerHttpPort = DefaultHttpTrackerHttpPort
 	GTrackerGroup *TrackerGroup
+	GConnectionPoolEnabled = DefaultConnectionPoolEnabled
+	GConnectionPool = NewConnectionPool(DefaultConnectionPoolMaxCountPerEntry, DefaultConnectionPoolMaxIdleCountPerEntry,
+		DefaultConnectionPoolMaxIdleTime * time.Second, DefaultConnectionPoolMaxWaitTimeInMs * time.Millisecond)
+	GRetryPolicy *RetryPolicy //nil for no retry
+	GLogger Logger //nil for no logging
+	GInterceptors []Interceptor //the interceptors of the operations
+	GTracer Tracer //nil for no tracing
+	GAddressTranslator AddressTranslator //nil for no address translation
+	GProtocolVersion = PROTO_VERSION_5
 )
 
 /**
- * load global variables
+ * load global variables, the settings without ini item keep the current value
  *
  * @param conf_filename config filename
  */
 func Init(confFilename string) error {
-	var iniReader *IniFileReader
-	var szTrackerServers []string
-	var parts []string
-
-	iniReader,err := NewIniFileReader(confFilename)
-	if err != nil {
+	var config = GetDefaultConfig()
+	if err := config.LoadIniFile(confFilename); err != nil {
 		return err
 	}
-
-	GConnectTimeout = iniReader.GetIntValue("connect_timeout", DefaultConnectTimeout)
-	if GConnectTimeout < 0 {
-		GConnectTimeout = DefaultConnectTimeout
-	}
-	GConnectTimeout *= 1000 //millisecond
-
-	GNetworkTimeout = iniReader.GetIntValue("network_timeout", DefaultNetworkTimeout)
-	if GNetworkTimeout < 0 {
-		GNetworkTimeout = DefaultNetworkTimeout
-	}
-	GNetworkTimeout *= 1000 //millisecond
-
-	GCharset = iniReader.GetStrValue("charset")
-	if GCharset == "" || len(GCharset) == 0 {
-		GCharset = "ISO8859-1"
-	}
-
-	szTrackerServers = iniReader.GetValues("tracker_server")
-	if szTrackerServers == nil {
-		return errors.New("item \"tracker_server\" in " + confFilename + " not found")
-	}
-
-	var trackerServers = make([]net.Addr, len(szTrackerServers))
-	for i := 0; i < len(szTrackerServers); i++ {
-		parts = strings.SplitN(szTrackerServers[i], ":", 2)
-		if len(parts) != 2 {
-			return errors.New("the value of item \"tracker_server\" is invalid, the correct format is host:port")
-		}
-
-		port,err := strconv.Atoi(strings.TrimSpace(parts[1]))
-		if err != nil {
-			return err
-		}
-		trackerServers[i],err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", strings.TrimSpace(parts[0]), port))
-		if err != nil {
-			return err
-		}
-	}
-
-	GTrackerGroup = NewTrackerGroup(trackerServers)
-
-	GTrackerHttpPort = iniReader.GetIntValue("http.tracker_http_port", 80)
-	GAntiStealToken = iniReader.GetBoolValue("http.anti_steal_token", false)
-	if GAntiStealToken {
-		GSecretKey = iniReader.GetStrValue("http.secret_key")
-	}
+	SetDefaultConfig(config)
 
 	return nil
 }
@@ -142,52 +114,17 @@ func InitByPropertiesFile(propsFilePath string) error {
 	return InitByProperties(props)
 }
 
+/**
+ * load from properties, the items not found keep the current value
+ *
+ * @param props the properties, the item fastdfs.tracker_servers is required
+ */
 func InitByProperties(props *properties.Properties) error {
-	var trackerServersConf = props.GetProperty(PropKeyTrackerServers)
-
-	if trackerServersConf == "" || len(trackerServersConf) == 0 {
-		return fmt.Errorf("configure item %s is required", PropKeyTrackerServers)
-	}
-
-	if err := InitByTrackers(strings.TrimSpace(trackerServersConf)); err != nil {
+	var config = GetDefaultConfig()
+	if err := config.LoadProperties(props); err != nil {
 		return err
 	}
-
-	connectTimeoutInSecondsConf := props.GetProperty(PropKeyConnectTimeoutInSeconds)
-	networkTimeoutInSecondsConf := props.GetProperty(PropKeyNetworkTimeoutInSeconds)
-	charsetConf := props.GetProperty(PropKeyCharset)
-	httpAntiStealTokenConf := props.GetProperty(PropKeyHttpAntiStealToken)
-	httpSecretKeyConf := props.GetProperty(PropKeyHttpSecretKey)
-	httpTrackerHttpPortConf := props.GetProperty(PropKeyHttpTrackerHttpPort)
-	var err error
-	if connectTimeoutInSecondsConf != "" && len(strings.TrimSpace(connectTimeoutInSecondsConf)) != 0 {
-		if GConnectTimeout,err = strconv.Atoi(strings.TrimSpace(connectTimeoutInSecondsConf)); err != nil {
-			return err
-		}
-		GConnectTimeout *= 1000
-	}
-	if networkTimeoutInSecondsConf != "" && len(strings.TrimSpace(networkTimeoutInSecondsConf)) != 0 {
-		if GNetworkTimeout,err = strconv.Atoi(strings.TrimSpace(networkTimeoutInSecondsConf)); err != nil {
-			return err
-		}
-		GNetworkTimeout *= 1000
-	}
-	if charsetConf != "" && len(strings.TrimSpace(charsetConf)) != 0 {
-		GCharset = strings.TrimSpace(charsetConf)
-	}
-	if httpAntiStealTokenConf != "" && len(strings.TrimSpace(httpAntiStealTokenConf)) != 0 {
-		if GAntiStealToken,err = strconv.ParseBool(httpAntiStealTokenConf); err != nil {
-			return err
-		}
-	}
-	if httpSecretKeyConf != "" && len(strings.TrimSpace(httpSecretKeyConf)) != 0 {
-		GSecretKey = strings.TrimSpace(httpSecretKeyConf)
-	}
-	if httpTrackerHttpPortConf != "" && len(strings.TrimSpace(httpTrackerHttpPortConf)) != 0 {
-		if GTrackerHttpPort,err = strconv.Atoi(strings.TrimSpace(httpTrackerHttpPortConf)); err != nil {
-			return err
-		}
-	}
+	SetDefaultConfig(config)
 
 	return nil
 }
@@ -200,22 +137,9 @@ func InitByProperties(props *properties.Properties) error {
  *                       server之间用逗号','分隔
  */
 func InitByTrackers(trackerServers string) error {
-	var list = make([]net.Addr, 0, len(trackerServers))
-	var spr1 = ","
-	var spr2 = ":"
-	var arr1 = strings.Split(strings.TrimSpace(trackerServers), spr1)
-	for _,addrStr := range arr1 {
-		var arr2 = strings.Split(strings.TrimSpace(addrStr), spr2)
-		var host = strings.TrimSpace(arr2[0])
-		var port,err = strconv.Atoi(strings.TrimSpace(arr2[1]))
-		if err != nil {
-			return err
-		}
-		addr,err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", host, port))
-		if err != nil {
-			return err
-		}
-		list = append(list, addr)
+	list,err := ParseTrackerServers(trackerServers)
+	if err != nil {
+		return err
 	}
 
 	return InitByTrackersAddr(list)
@@ -227,6 +151,63 @@ func InitByTrackersAddr(trackerAddresses []net.Addr) error {
 	return nil
 }
 
+/**
+ * get the settings of the default client
+ *
+ * @return a copy of the global settings
+ */
+func GetDefaultConfig() *Config {
+	return &Config{
+		ConnectTimeout:GConnectTimeout,
+		NetworkTimeout:GNetworkTimeout,
+		Charset:GCharset,
+		AntiStealToken:GAntiStealToken,
+		SecretKey:GSecretKey,
+		TrackerHttpPort:GTrackerHttpPort,
+		TrackerGroup:GTrackerGroup,
+		ConnectionPoolEnabled:GConnectionPoolEnabled,
+		ConnectionPool:GConnectionPool,
+		RetryPolicy:GRetryPolicy,
+		Logger:GLogger,
+		Interceptors:GInterceptors,
+		Tracer:GTracer,
+		AddressTranslator:GAddressTranslator,
+		ProtocolVersion:GProtocolVersion,
+	}
+}
+
+/**
+ * replace the settings of the default client
+ *
+ * @param config the new settings
+ */
+func SetDefaultConfig(config *Config) {
+	GConnectTimeout = config.ConnectTimeout
+	GNetworkTimeout = config.NetworkTimeout
+	GCharset = config.Charset
+	GAntiStealToken = config.AntiStealToken
+	GSecretKey = config.SecretKey
+	GTrackerHttpPort = config.TrackerHttpPort
+	GTrackerGroup = config.TrackerGroup
+	GConnectionPoolEnabled = config.ConnectionPoolEnabled
+	GRetryPolicy = config.RetryPolicy
+	GLogger = config.Logger
+	GInterceptors = config.Interceptors
+	GTracer = config.Tracer
+	GAddressTranslator = config.AddressTranslator
+	GProtocolVersion = config.ProtocolVersion
+	if config.ConnectionPool != nil {
+		SetGConnectionPool(config.ConnectionPool)
+	}
+}
+
+/**
+ * the default client, works with the global settings
+ */
+func DefaultClient() *Client {
+	return NewClient(nil)
+}
+
 /**
  * construct Socket object
  *
@@ -235,13 +216,12 @@ func InitByTrackersAddr(trackerAddresses []net.Addr) error {
  * @return connected Socket object
 */
 func GetSocket(ipAddr string, port int) (net.Conn, error) {
-	conn,err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", ipAddr, port), time.Duration(GConnectTimeout) * time.Microsecond)
+	addr,err := net.ResolveTCPAddr("tcp", net.JoinHostPort(ipAddr, strconv.Itoa(port)))
 	if err != nil {
 		return nil, err
 	}
-	// TODO set read timeout.
 
-	return conn, nil
+	return GetSocketAddr(addr)
 }
 
 /**
@@ -251,14 +231,7 @@ func GetSocket(ipAddr string, port int) (net.Conn, error) {
  * @return connected Socket object
  */
 func GetSocketAddr(addr net.Addr) (net.Conn, error) {
-	conn,err := net.DialTimeout("tcp", addr.String(), time.Duration(GConnectTimeout) * time.Microsecond)
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO set read timeout
-
-	return conn, nil
+	return GetSocketAddrContext(context.Background(), addr)
 }
 
 func GetGConnectTimeout() int {
@@ -313,6 +286,79 @@ func SetGSecretKey(secretKey string) {
 	GSecretKey = secretKey
 }
 
+func GetGConnectionPoolEnabled() bool {
+	return GConnectionPoolEnabled
+}
+
+func SetGConnectionPoolEnabled(enabled bool) {
+	GConnectionPoolEnabled = enabled
+}
+
+func GetGConnectionPool() *ConnectionPool {
+	return GConnectionPool
+}
+
+/**
+ * replace the global connection pool, idle connections of the old pool are closed
+ *
+ * @param pool the new connection pool
+ */
+func SetGConnectionPool(pool *ConnectionPool) {
+	var old = GConnectionPool
+	GConnectionPool = pool
+	if old != nil && old != pool {
+		old.Close()
+	}
+}
+
+func GetGRetryPolicy() *RetryPolicy {
+	return GRetryPolicy
+}
+
+func SetGRetryPolicy(policy *RetryPolicy) {
+	GRetryPolicy = policy
+}
+
+func GetGLogger() Logger {
+	return GLogger
+}
+
+func SetGLogger(logger Logger) {
+	GLogger = logger
+}
+
+func GetGInterceptors() []Interceptor {
+	return GInterceptors
+}
+
+func SetGInterceptors(interceptors ...Interceptor) {
+	GInterceptors = interceptors
+}
+
+func GetGTracer() Tracer {
+	return GTracer
+}
+
+func SetGTracer(tracer Tracer) {
+	GTracer = tracer
+}
+
+func GetGAddressTranslator() AddressTranslator {
+	return GAddressTranslator
+}
+
+func SetGAddressTranslator(translator AddressTranslator) {
+	GAddressTranslator = translator
+}
+
+func GetGProtocolVersion() proto.Version {
+	return GProtocolVersion
+}
+
+func SetGProtocolVersion(version proto.Version) {
+	GProtocolVersion = version
+}
+
 func GetGTrackerGroup() *TrackerGroup {
 	return GTrackerGroup
 }
@@ -340,6 +386,7 @@ func ConfigInfo() string {
 		"\n  GAntiStealToken = " + strconv.FormatBool(GAntiStealToken) +
 		"\n  GSecretKey = " + GSecretKey +
 		"\n  GTrackerHttpPort = " + strconv.Itoa(GTrackerHttpPort) +
+		"\n  GConnectionPoolEnabled = " + strconv.FormatBool(GConnectionPoolEnabled) +
 		"\n  trackerServers = " + trackerServers +
 		"\n}"
 }
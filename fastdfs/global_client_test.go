@@ -3,7 +3,7 @@ package fastdfs
 import (
 	"testing"
 	"fmt"
-	"properties"
+	"github.com/go/properties"
 )
 
 func TestGlobalClient(t *testing.T) {
@@ -25,4 +25,23 @@ func TestGlobalClient(t *testing.T) {
 		panic(err)
 	}
 	fmt.Println("ClientGlobal.configInfo() : " + ConfigInfo())
-}
\ No newline at end of file
+}
+
+func TestInitKeepsSettings(t *testing.T) {
+	var saved = GetDefaultConfig()
+	defer SetDefaultConfig(saved)
+
+	var logger = new(recordLogger)
+	var policy = NewRetryPolicy(3)
+	SetGLogger(logger)
+	SetGRetryPolicy(policy)
+	if err := Init("test/fdfs_client.conf.sample"); err != nil {
+		t.Fatal(err)
+	}
+	if GetGLogger() != logger || GetGRetryPolicy() != policy {
+		t.Fatalf("the logger and the retry policy are reset by Init")
+	}
+	if GConnectTimeout != 2000 || GTrackerHttpPort != 8080 || len(GTrackerGroup.TrackerServers) != 2 {
+		t.Fatalf("config %s", GetDefaultConfig())
+	}
+}
@@ -0,0 +1,338 @@
+/**
+ * Package httpfs serves the files of FastDFS over HTTP. The URL path is the
+ * file id, such as /group1/M00/00/00/wKgBaF1xxx.jpg, the file is streamed from
+ * the storage server by the offset download without loading into memory.
+ *
+ *	http.Handle("/", httpfs.NewHandler(fastdfs.NewClient(config)))
+ *	http.Handle("/files/", http.StripPrefix("/files", httpfs.NewHandler(nil)))
+ */
+package httpfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/go/fastdfs"
+)
+
+const DefaultTokenTTL = fastdfs.DefaultURLTTL
+
+/**
+ * the handler of the FastDFS files
+ */
+type Handler struct {
+	client     *fastdfs.Client
+	tokenTTL   time.Duration
+	signer     *fastdfs.URLSigner //nil for checking the token by the settings of the client
+}
+
+/**
+ * handler option
+ */
+type Option func(h *Handler)
+
+/**
+ * set the time to live of the anti-steal token, the token generated longer
+ * than ttl ago is rejected
+ *
+ * @param ttl the time to live, 0 for no expiration
+ */
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(h *Handler) {
+		h.tokenTTL = ttl
+	}
+}
+
+/**
+ * verify the anti-steal token by the signer, such as the signer with several
+ * secret keys when rotating the key. the token is required even though the
+ * anti-steal token is disabled by the settings of the client.
+ *
+ * @param signer the URL signer
+ */
+func WithURLSigner(signer *fastdfs.URLSigner) Option {
+	return func(h *Handler) {
+		h.signer = signer
+	}
+}
+
+/**
+ * Constructor
+ *
+ * @param client  the FastDFS client, nil for the global settings
+ * @param options the options to apply
+ */
+func NewHandler(client *fastdfs.Client, options ...Option) *Handler {
+	if client == nil {
+		client = fastdfs.NewClient(nil)
+	}
+	var h = &Handler{
+		client:client,
+		tokenTTL:DefaultTokenTTL,
+	}
+	for _,option := range options {
+		option(h)
+	}
+
+	return h
+}
+
+/**
+ * serve the file of the file id in the URL path. the anti-steal token is
+ * required by the settings of the client, it is passed by the query
+ * parameters token and ts as the nginx module of FastDFS.
+ */
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	fileId,err := fastdfs.ParseFileID(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.checkToken(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	var ctx = r.Context()
+	var storageClient = h.client.NewStorageClient()
+	var fileInfo *fastdfs.FileInfo
+	if r.Method == http.MethodHead {
+		// no download follows to find the missing file
+		fileInfo,err = storageClient.QueryFileInfoCtx(ctx, fileId.GetGroupName(), fileId.GetFilename())
+	} else {
+		fileInfo,err = storageClient.GetFileInfoCtx(ctx, fileId.GetGroupName(), fileId.GetFilename())
+	}
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	var size = fileInfo.GetFileSize()
+	var etag = fmt.Sprintf("\"%08x\"", uint32(fileInfo.GetCrc32()))
+	var modtime = fileInfo.GetCreateTimestamp()
+	var header = w.Header()
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("ETag", etag)
+	header.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	if notModified(r, etag, modtime) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Type", contentType(fileId.GetExtName()))
+
+	var offset,length,status = int64(0), size, http.StatusOK
+	if rangeSpec := r.Header.Get("Range"); rangeSpec != "" && ifRange(r, etag, modtime) {
+		start,end,ok := parseRange(rangeSpec, size)
+		if !ok {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			http.Error(w, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if start >= 0 {
+			offset,length,status = start, end - start + 1, http.StatusPartialContent
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		}
+	}
+	header.Set("Content-Length", strconv.FormatInt(length, 10))
+	if r.Method == http.MethodHead || length == 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	var out = &lazyWriter{w:w, status:status}
+	if err = storageClient.Download(ctx, fileId.GetGroupName(), fileId.GetFilename(), out, fastdfs.WithRange(offset, length)); err != nil {
+		if !out.written {
+			serveError(w, err)
+			return
+		}
+		// the response is partially sent
+		panic(http.ErrAbortHandler)
+	}
+}
+
+/**
+ * check the anti-steal token of the request if required
+ */
+func (h *Handler) checkToken(r *http.Request) bool {
+	var signer = h.signer
+	if signer == nil {
+		if !h.client.GetConfig().AntiStealToken {
+			return true
+		}
+		signer = h.client.NewURLSigner(fastdfs.WithURLTTL(h.tokenTTL))
+	}
+
+	return signer.VerifyURL(r.URL) == nil
+}
+
+/**
+ * write the response of the error, the headers of the content are removed
+ */
+func serveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.Canceled) {
+		return //the client is gone
+	}
+	var header = w.Header()
+	for _,name := range []string{"Accept-Ranges", "Content-Length", "Content-Range", "ETag", "Last-Modified"} {
+		header.Del(name)
+	}
+
+	switch {
+	case errors.Is(err, fastdfs.ErrNotFound):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fastdfs.ErrInvalid):
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+	default:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	}
+}
+
+/**
+ * guess the content type by the ext name
+ *
+ * @param extName the ext name without dot(.)
+ * @return the content type, application/octet-stream for unknown
+ */
+func contentType(extName string) string {
+	if extName != "" {
+		if t := mime.TypeByExtension("." + extName); t != "" {
+			return t
+		}
+	}
+
+	return "application/octet-stream"
+}
+
+/**
+ * check the conditional headers If-None-Match and If-Modified-Since
+ *
+ * @return true if the file is not modified
+ */
+func notModified(r *http.Request, etag string, modtime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t,err := http.ParseTime(ims)
+		return err == nil && !modtime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+/**
+ * check the If-Range header
+ *
+ * @return true if the range applies
+ */
+func ifRange(r *http.Request, etag string, modtime time.Time) bool {
+	var ir = r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, "\"") || strings.HasPrefix(ir, "W/") {
+		return ir == etag //strong comparison
+	}
+	t,err := http.ParseTime(ir)
+
+	return err == nil && modtime.Truncate(time.Second).Equal(t)
+}
+
+/**
+ * check if the etag is in the list of If-None-Match, weak comparison
+ */
+func etagMatch(list, etag string) bool {
+	for _,item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+ * parse the Range header of single byte range, the multiple ranges and the
+ * invalid header are ignored
+ *
+ * @param spec the Range header
+ * @param size the file size
+ * @return the first and last byte positions, -1 for the whole file;
+ * false if the range is not satisfiable
+ */
+func parseRange(spec string, size int64) (int64, int64, bool) {
+	if !strings.HasPrefix(spec, "bytes=") || strings.Contains(spec, ",") {
+		return -1, -1, true
+	}
+	var pos = strings.IndexByte(spec, '-')
+	if pos < 0 {
+		return -1, -1, true
+	}
+	var first,last = strings.TrimSpace(spec[len("bytes="):pos]), strings.TrimSpace(spec[pos + 1:])
+	if first == "" {
+		// the suffix range
+		n,err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return -1, -1, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start,err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return -1, -1, true
+	}
+	var end = size - 1
+	if last != "" {
+		if end,err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return -1, -1, true
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
+
+/**
+ * the response writer sending the status at the first write, the error
+ * response can be sent instead if nothing is downloaded
+ */
+type lazyWriter struct {
+	w         http.ResponseWriter
+	status    int
+	written   bool
+}
+
+func (l *lazyWriter) Write(p []byte) (int, error) {
+	if !l.written {
+		l.written = true
+		l.w.WriteHeader(l.status)
+	}
+
+	return l.w.Write(p)
+}
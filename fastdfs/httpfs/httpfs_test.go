@@ -0,0 +1,197 @@
+package httpfs
+
+import (
+	"testing"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/go/fastdfs"
+	"github.com/go/fastdfs/fastdfstest"
+)
+
+func get(t *testing.T, server *httptest.Server, method, path string, header map[string]string) (*http.Response, string) {
+	req,err := http.NewRequest(method, server.URL + path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name,value := range header {
+		req.Header.Set(name, value)
+	}
+	res,err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body,err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, string(body)
+}
+
+func TestHandler(t *testing.T) {
+	tracker,err := fastdfstest.NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	if _,err = tracker.AddStorage("group1"); err != nil {
+		t.Fatal(err)
+	}
+	var client = fastdfs.NewClient(tracker.NewConfig())
+	fileId,err := client.NewStorageClient().UploadBuffer([]byte("hello world"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path = "/" + fileId.String()
+	var server = httptest.NewServer(NewHandler(client))
+	defer server.Close()
+
+	res,body := get(t, server, http.MethodGet, path, nil)
+	if res.StatusCode != http.StatusOK || body != "hello world" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") ||
+		res.Header.Get("Accept-Ranges") != "bytes" || res.Header.Get("Content-Length") != "11" {
+		t.Fatalf("status %d, body %q, header %v", res.StatusCode, body, res.Header)
+	}
+	var etag,lastModified = res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	if etag != fmt.Sprintf("\"%08x\"", uint32(fileId.GetCrc32())) {
+		t.Fatalf("etag %s", etag)
+	}
+	if modtime,err := http.ParseTime(lastModified); err != nil || !modtime.Equal(fileId.GetCreateTimestamp()) {
+		t.Fatalf("last modified %s, err %v", lastModified, err)
+	}
+
+	var ranges = []struct {
+		spec      string
+		status    int
+		body      string
+		content   string
+	}{
+		{"bytes=6-", http.StatusPartialContent, "world", "bytes 6-10/11"},
+		{"bytes=0-4", http.StatusPartialContent, "hello", "bytes 0-4/11"},
+		{"bytes=-3", http.StatusPartialContent, "rld", "bytes 8-10/11"},
+		{"bytes=4-100", http.StatusPartialContent, "o world", "bytes 4-10/11"},
+		{"bytes=0-1,3-4", http.StatusOK, "hello world", ""},
+		{"items=0-1", http.StatusOK, "hello world", ""},
+		{"bytes=11-", http.StatusRequestedRangeNotSatisfiable, "", "bytes */11"},
+	}
+	for _,r := range ranges {
+		res,body = get(t, server, http.MethodGet, path, map[string]string{"Range":r.spec})
+		if res.StatusCode != r.status || (r.body != "" && body != r.body) || res.Header.Get("Content-Range") != r.content {
+			t.Errorf("range %s: status %d, body %q, content range %s", r.spec, res.StatusCode, body, res.Header.Get("Content-Range"))
+		}
+	}
+	if res,body = get(t, server, http.MethodGet, path, map[string]string{"Range":"bytes=0-4", "If-Range":"\"0\""}); res.StatusCode != http.StatusOK {
+		t.Errorf("range with stale If-Range status %d", res.StatusCode)
+	}
+
+	// conditional requests
+	if res,_ = get(t, server, http.MethodGet, path, map[string]string{"If-None-Match":etag}); res.StatusCode != http.StatusNotModified {
+		t.Errorf("If-None-Match status %d", res.StatusCode)
+	}
+	if res,_ = get(t, server, http.MethodGet, path, map[string]string{"If-Modified-Since":lastModified}); res.StatusCode != http.StatusNotModified {
+		t.Errorf("If-Modified-Since status %d", res.StatusCode)
+	}
+	if res,_ = get(t, server, http.MethodGet, path, map[string]string{"If-None-Match":"\"0\"", "If-Modified-Since":lastModified}); res.StatusCode != http.StatusOK {
+		t.Errorf("If-None-Match mismatch status %d", res.StatusCode)
+	}
+
+	if res,body = get(t, server, http.MethodHead, path, nil); res.StatusCode != http.StatusOK || res.ContentLength != 11 || body != "" {
+		t.Errorf("head status %d, length %d", res.StatusCode, res.ContentLength)
+	}
+	if res,_ = get(t, server, http.MethodPost, path, nil); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("post status %d", res.StatusCode)
+	}
+	if res,_ = get(t, server, http.MethodGet, "/group1/invalid", nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("invalid file id status %d", res.StatusCode)
+	}
+
+	// the missing file is found by the download
+	if _,err = client.NewStorageClient().DeleteFile(fileId.GetGroupName(), fileId.GetFilename()); err != nil {
+		t.Fatal(err)
+	}
+	if res,_ = get(t, server, http.MethodGet, path, nil); res.StatusCode != http.StatusNotFound || res.Header.Get("ETag") != "" {
+		t.Errorf("missing file status %d, header %v", res.StatusCode, res.Header)
+	}
+	if res,_ = get(t, server, http.MethodHead, path, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("head missing file status %d", res.StatusCode)
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	tracker,err := fastdfstest.NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	if _,err = tracker.AddStorage("group1"); err != nil {
+		t.Fatal(err)
+	}
+	var client = fastdfs.NewClient(tracker.NewConfig(fastdfs.WithAntiStealToken("secret")))
+	fileId,err := client.NewStorageClient().UploadBuffer([]byte("<html></html>"), "html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server = httptest.NewServer(NewHandler(client, WithTokenTTL(time.Minute)))
+	defer server.Close()
+
+	var query = func(ts int64) string {
+		token,err := fastdfs.GetToken(fileId.GetFilename(), int(ts), "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "?token=" + token + "&ts=" + strconv.FormatInt(ts, 10)
+	}
+	var now = time.Now().Unix()
+	res,body := get(t, server, http.MethodGet, "/" + fileId.String() + query(now), nil)
+	if res.StatusCode != http.StatusOK || body != "<html></html>" || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("status %d, body %q", res.StatusCode, body)
+	}
+	for _,q := range []string{"", query(now - 120), "?token=0&ts=" + strconv.FormatInt(now, 10), "?token=0&ts=x"} {
+		if res,_ = get(t, server, http.MethodGet, "/" + fileId.String() + q, nil); res.StatusCode != http.StatusForbidden {
+			t.Errorf("query %q status %d", q, res.StatusCode)
+		}
+	}
+}
+
+func TestHandlerURLSigner(t *testing.T) {
+	tracker,err := fastdfstest.NewTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracker.Close()
+	if _,err = tracker.AddStorage("group1"); err != nil {
+		t.Fatal(err)
+	}
+	var client = fastdfs.NewClient(tracker.NewConfig())
+	fileId,err := client.NewStorageClient().UploadBuffer([]byte("signed"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signer = client.NewURLSigner(fastdfs.WithSecretKeys("new", "old"))
+	var server = httptest.NewServer(http.StripPrefix("/files", NewHandler(client, WithURLSigner(signer))))
+	defer server.Close()
+
+	for _,secretKey := range []string{"old", "new"} {
+		u,err := client.NewURLSigner(fastdfs.WithSecretKeys(secretKey)).BuildFetchURL(context.Background(), client.NewTrackerClient(), fileId.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed,err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res,body := get(t, server, http.MethodGet, "/files" + parsed.RequestURI(), nil); res.StatusCode != http.StatusOK || body != "signed" {
+			t.Errorf("key %s status %d, body %q", secretKey, res.StatusCode, body)
+		}
+	}
+	if res,_ := get(t, server, http.MethodGet, "/files/" + fileId.String(), nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("status %d without token", res.StatusCode)
+	}
+}
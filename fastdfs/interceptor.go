@@ -0,0 +1,157 @@
+package fastdfs
+
+import (
+	"context"
+	"github.com/go/fastdfs/proto"
+)
+
+/**
+ * the storage or tracker operation seen by the interceptors, the interceptor
+ * may change the fields before invoking the operation.<br>
+ * the storage operations read GroupName, Filename, MasterFilename, PrefixName,
+ * FileExtName, Metadata and Flag when invoked, the downloads and TruncateFile
+ * read Offset and Size too, the size of the uploads is fixed by the content.<br>
+ * the tracker operations send Request, it may be replaced by one of the same
+ * command, GroupName and Filename are copied from the request for reading.
+ */
+type Operation struct {
+	Cmd             byte           //the command, STORAGE_PROTO_CMD_XXX or TRACKER_PROTO_CMD_XXX
+	GroupName       string
+	Filename        string         //the remote filename, the appender filename or the source filename of the link
+	MasterFilename  string         //the master filename of the slave file
+	PrefixName      string         //the prefix name of the slave file
+	FileExtName     string
+	Metadata        []NameValuePair
+	Flag            byte           //the op flag of SetMetadata
+	Offset          int64          //the file offset of the download and the modify
+	Size            int64          //the bytes to send or download, the file size after TruncateFile
+	Request         proto.Request  //the request of the tracker operation, nil for storage operations
+	Result          interface{}    //the result when the operation succeeds
+}
+
+/**
+ * invoke the operation, or the next interceptor of the chain
+ *
+ * @param op the operation
+ * @return the error of the operation
+ */
+type Invoker func(op *Operation) error
+
+/**
+ * intercept the operation, the interceptor calls invoke to run the operation
+ * and sees op.Result and the error afterwards; it rejects the operation by
+ * returning an error without calling invoke.
+ * the operation is invoked once for the retries of the storage operation.
+ *
+ * @param ctx    the context of the operation, the operation runs with it
+ * @param op     the operation
+ * @param invoke the next interceptor or the operation
+ * @return the error of the operation
+ */
+type Interceptor func(ctx context.Context, op *Operation, invoke Invoker) error
+
+/**
+ * @param interceptors the interceptors of the operations, the first is the outermost
+ */
+func WithInterceptors(interceptors ...Interceptor) ConfigOption {
+	return func(c *Config) {
+		c.Interceptors = append(c.Interceptors, interceptors...)
+	}
+}
+
+/**
+ * get the interceptors of the settings
+ */
+func (c *Config) getInterceptors() []Interceptor {
+	if c == nil {
+		return GInterceptors
+	}
+
+	return c.Interceptors
+}
+
+/**
+ * run the operation through the interceptors of the settings
+ *
+ * @param ctx    the context of the operation
+ * @param op     the operation
+ * @param invoke the operation
+ * @return the error of the operation
+ */
+func (c *Config) intercept(ctx context.Context, op *Operation, invoke Invoker) error {
+	var interceptors = c.getInterceptors()
+	if len(interceptors) == 0 {
+		return invoke(op)
+	}
+
+	var next func(i int) Invoker
+	next = func(i int) Invoker {
+		if i == len(interceptors) {
+			return invoke
+		}
+		return func(op *Operation) error {
+			return interceptors[i](ctx, op, next(i + 1))
+		}
+	}
+
+	return next(0)(op)
+}
+
+/**
+ * run the storage operation through the interceptors, the error code is set
+ * from the error when an interceptor rejects the operation, the result is
+ * cleared when the operation fails
+ *
+ * @param op     the operation
+ * @param invoke the operation
+ * @return the error of the operation
+ */
+func (s *StorageClient) intercept(op *Operation, invoke Invoker) error {
+	var invoked = false
+	var err = s.config.intercept(contextOf(s.connCtx), op, func(op *Operation) error {
+		invoked = true
+		var err = invoke(op)
+		if err != nil {
+			op.Result = nil
+		}
+		return err
+	})
+	if !invoked {
+		s.setErrno(ErrorCode(err))
+	}
+
+	return err
+}
+
+/**
+ * create the tracker operation of the request
+ *
+ * @param req the tracker request
+ */
+func newTrackerOperation(req proto.Request) *Operation {
+	var op = &Operation{Cmd:req.Cmd(), Request:req}
+	switch r := req.(type) {
+	case *proto.QueryStoreRequest:
+		op.GroupName = r.GroupName
+	case *proto.QueryStoreAllRequest:
+		op.GroupName = r.GroupName
+	case *proto.QueryFetchRequest:
+		op.GroupName,op.Filename = r.GroupName, r.Filename
+	case *proto.QueryUpdateRequest:
+		op.GroupName,op.Filename = r.GroupName, r.Filename
+	case *proto.QueryFetchAllRequest:
+		op.GroupName,op.Filename = r.GroupName, r.Filename
+	case *proto.ListStoragesRequest:
+		op.GroupName = r.GroupName
+	case *proto.DeleteStorageRequest:
+		op.GroupName = r.GroupName
+	case *proto.SetTrunkServerRequest:
+		op.GroupName = r.GroupName
+	case *proto.ListOneGroupRequest:
+		op.GroupName = r.GroupName
+	case *proto.DeleteGroupRequest:
+		op.GroupName = r.GroupName
+	}
+
+	return op
+}
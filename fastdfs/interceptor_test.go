@@ -0,0 +1,171 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"github.com/go/fastdfs/proto"
+)
+
+// record the operations seen by the interceptor
+type opRecorder struct {
+	ops   []Operation
+	errs  []error
+	lock  sync.Mutex
+}
+
+func (r *opRecorder) intercept(ctx context.Context, op *Operation, invoke Invoker) error {
+	var err = invoke(op)
+	r.lock.Lock()
+	r.ops = append(r.ops, *op)
+	r.errs = append(r.errs, err)
+	r.lock.Unlock()
+
+	return err
+}
+
+func (r *opRecorder) find(cmd byte) (*Operation, error, int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var found = -1
+	var count = 0
+	for i := range r.ops {
+		if r.ops[i].Cmd == cmd {
+			found = i
+			count++
+		}
+	}
+	if found < 0 {
+		return nil, nil, 0
+	}
+
+	return &r.ops[found], r.errs[found], count
+}
+
+func TestInterceptors(t *testing.T) {
+	listener,server := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+	var tracker = startQueryTracker(t, []string{"127.0.0.1"}, []string{"127.0.0.1"}, listener.Addr().(*net.TCPAddr).Port)
+	defer tracker.Close()
+
+	var recorder = &opRecorder{}
+	var order []string
+	// reject the large uploads, lower the ext name
+	var policy = func(ctx context.Context, op *Operation, invoke Invoker) error {
+		order = append(order, "policy")
+		if op.Cmd == STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE {
+			if op.Size > 10 {
+				return newInvalidError("file size %d exceeds 10", op.Size)
+			}
+			op.FileExtName = strings.ToLower(op.FileExtName)
+		}
+		if op.Cmd == TRACKER_PROTO_CMD_SERVER_LIST_GROUP {
+			return errors.New("list groups denied")
+		}
+		return invoke(op)
+	}
+	var first = func(ctx context.Context, op *Operation, invoke Invoker) error {
+		order = append(order, "first")
+		return invoke(op)
+	}
+	var policyRetry = NewRetryPolicy(3)
+	policyRetry.InitialBackoff = time.Millisecond
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil), WithRetryPolicy(policyRetry),
+		WithInterceptors(first, policy), WithInterceptors(recorder.intercept)))
+	var storageClient = client.NewStorageClient()
+
+	// the request is changed by the interceptor
+	fileId,err := storageClient.UploadAppenderBuffer([]byte("hello"), "TXT", []NameValuePair{*NewNameValuePair("k", "v")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(fileId.GetFilename(), ".txt") {
+		t.Fatalf("filename %s, expect the ext name txt", fileId.GetFilename())
+	}
+	if len(order) < 2 || order[0] != "first" || order[1] != "policy" {
+		t.Fatalf("interceptor order %v", order)
+	}
+	op,opErr,_ := recorder.find(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE)
+	if op == nil || opErr != nil || op.Size != 5 || op.FileExtName != "txt" || len(op.Metadata) != 1 || op.Result.(*FileID) != fileId {
+		t.Fatalf("upload operation %+v, err %v", op, opErr)
+	}
+	// the tracker query is an operation too
+	op,opErr,_ = recorder.find(TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE)
+	if op == nil || opErr != nil || op.Request == nil {
+		t.Fatalf("query store operation %+v, err %v", op, opErr)
+	}
+	if _,ok := op.Result.(*proto.QueryStoreResponse); !ok {
+		t.Fatalf("query store result %T", op.Result)
+	}
+
+	// the upload is rejected before sending
+	if _,err = storageClient.UploadAppenderBuffer([]byte("hello world"), "txt", nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("upload err %v, expect %v", err, ErrInvalid)
+	}
+	if storageClient.GetErrorCode() != ERR_NO_EINVAL {
+		t.Fatalf("error code %d, expect %d", storageClient.GetErrorCode(), ERR_NO_EINVAL)
+	}
+	if _,_,count := recorder.find(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE); count != 1 {
+		t.Fatalf("rejected upload is invoked")
+	}
+
+	// the retries are invoked once
+	server.fail(1, 0)
+	if _,err = storageClient.AppendBuffer(fileId.GetGroupName(), fileId.GetFilename(), []byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	op,opErr,count := recorder.find(STORAGE_PROTO_CMD_APPEND_FILE)
+	if count != 1 || opErr != nil || op.Filename != fileId.GetFilename() || op.Size != 6 || op.Result.(int) != 0 {
+		t.Fatalf("append operation %+v, err %v, count %d", op, opErr, count)
+	}
+
+	// the failed operation has no result
+	if _,err = storageClient.DownloadOffsetBuffer(fileId.GetGroupName(), fileId.GetFilename(), 100, 1); err == nil {
+		t.Fatalf("download out of range succeeded")
+	}
+	if op,opErr,_ = recorder.find(STORAGE_PROTO_CMD_DOWNLOAD_FILE); op.Result != nil || opErr == nil || op.Offset != 100 || op.Size != 1 {
+		t.Fatalf("download operation %+v, err %v", op, opErr)
+	}
+	data,err := storageClient.DownloadBuffer(fileId.GetGroupName(), fileId.GetFilename())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op,_,_ = recorder.find(STORAGE_PROTO_CMD_DOWNLOAD_FILE); !bytes.Equal(op.Result.([]byte), data) {
+		t.Fatalf("download result %q, expect %q", op.Result, data)
+	}
+
+	// the tracker operation is rejected
+	if _,err = client.NewTrackerClient().ListGroups(nil); err == nil || err.Error() != "list groups denied" {
+		t.Fatalf("list groups err %v", err)
+	}
+}
+
+func TestInterceptorContext(t *testing.T) {
+	listener,_ := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+	var tracker = startQueryTracker(t, []string{"127.0.0.1"}, []string{"127.0.0.1"}, listener.Addr().(*net.TCPAddr).Port)
+	defer tracker.Close()
+
+	type key struct{}
+	var values = make(chan interface{}, 2)
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil),
+		WithInterceptors(func(ctx context.Context, op *Operation, invoke Invoker) error {
+			values <- ctx.Value(key{})
+			return invoke(op)
+		})))
+	var ctx = context.WithValue(context.Background(), key{}, "traced")
+	if _,err := client.NewStorageClient().QueryFileInfoCtx(ctx, "group1", "M00/00/00/none"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("query err %v, expect %v", err, ErrNotFound)
+	}
+	// the storage operation and the tracker query
+	for i := 0; i < 2; i++ {
+		if value := <-values; value != "traced" {
+			t.Fatalf("context value %v", value)
+		}
+	}
+}
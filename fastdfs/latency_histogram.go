@@ -0,0 +1,183 @@
+package fastdfs
+
+import (
+	"context"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+/**
+ * the default upper bounds of the histogram buckets
+ */
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+/**
+ * the tracer collecting the latencies of the phases in memory, one histogram
+ * for each phase, command and server address
+ */
+type LatencyHistogram struct {
+	bounds   []time.Duration
+	series   map[latencyKey]*LatencyStat
+	lock     sync.Mutex
+}
+
+type latencyKey struct {
+	phase  Phase
+	cmd    byte
+	addr   string
+}
+
+/**
+ * the histogram of a phase, command and server address
+ */
+type LatencyStat struct {
+	Phase    Phase
+	Cmd      byte
+	Addr     string
+	Count    int64
+	Errors   int64           //the spans ended with error
+	Bytes    int64           //the bytes sent or received
+	Sum      time.Duration
+	Max      time.Duration
+	Bounds   []time.Duration //the upper bounds of the buckets
+	Counts   []int64         //the counts of the buckets, the last one counts the latencies above the bounds
+}
+
+/**
+ * Constructor
+ *
+ * @param bounds the ascending upper bounds of the buckets, DefaultLatencyBuckets if empty
+ */
+func NewLatencyHistogram(bounds ...time.Duration) *LatencyHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i] < bounds[j]
+	})
+
+	return &LatencyHistogram{bounds:bounds, series:make(map[latencyKey]*LatencyStat)}
+}
+
+type latencySpan struct {
+	histogram  *LatencyHistogram
+	key        latencyKey
+	start      time.Time
+}
+
+func (h *LatencyHistogram) StartSpan(ctx context.Context, phase Phase, cmd byte, addr net.Addr) Span {
+	return &latencySpan{histogram:h, key:latencyKey{phase:phase, cmd:cmd, addr:addrString(addr)}, start:time.Now()}
+}
+
+func (s *latencySpan) End(bytes int64, err error) {
+	s.histogram.observe(s.key, time.Since(s.start), bytes, err)
+}
+
+/**
+ * record the latency of the phase
+ */
+func (h *LatencyHistogram) observe(key latencyKey, latency time.Duration, bytes int64, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var stat = h.series[key]
+	if stat == nil {
+		stat = &LatencyStat{Phase:key.phase, Cmd:key.cmd, Addr:key.addr, Bounds:h.bounds, Counts:make([]int64, len(h.bounds) + 1)}
+		h.series[key] = stat
+	}
+	stat.Count++
+	if err != nil {
+		stat.Errors++
+	}
+	stat.Bytes += bytes
+	stat.Sum += latency
+	if latency > stat.Max {
+		stat.Max = latency
+	}
+	stat.Counts[sort.Search(len(h.bounds), func(i int) bool {
+		return latency <= h.bounds[i]
+	})]++
+}
+
+/**
+ * get a copy of the histograms, ordered by the phase, the command and the address
+ */
+func (h *LatencyHistogram) Snapshot() []LatencyStat {
+	h.lock.Lock()
+	var stats = make([]LatencyStat, 0, len(h.series))
+	for _,stat := range h.series {
+		var s = *stat
+		s.Counts = append([]int64(nil), stat.Counts...)
+		stats = append(stats, s)
+	}
+	h.lock.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Phase != stats[j].Phase {
+			return stats[i].Phase < stats[j].Phase
+		}
+		if stats[i].Cmd != stats[j].Cmd {
+			return stats[i].Cmd < stats[j].Cmd
+		}
+		return stats[i].Addr < stats[j].Addr
+	})
+
+	return stats
+}
+
+/**
+ * clear the histograms
+ */
+func (h *LatencyHistogram) Reset() {
+	h.lock.Lock()
+	h.series = make(map[latencyKey]*LatencyStat)
+	h.lock.Unlock()
+}
+
+/**
+ * get the mean latency
+ */
+func (s *LatencyStat) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.Sum / time.Duration(s.Count)
+}
+
+/**
+ * estimate the quantile by the upper bound of the bucket it falls in
+ *
+ * @param q the quantile, 0 to 1
+ * @return the upper bound of the bucket, Max for the last bucket
+ */
+func (s *LatencyStat) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	var rank = int64(math.Ceil(q * float64(s.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var total int64
+	for i,count := range s.Counts {
+		total += count
+		if total >= rank {
+			if i < len(s.Bounds) && s.Bounds[i] < s.Max {
+				return s.Bounds[i]
+			}
+			return s.Max
+		}
+	}
+
+	return s.Max
+}
@@ -0,0 +1,59 @@
+package fastdfs
+
+import (
+	"testing"
+	"errors"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var histogram = NewLatencyHistogram(10 * time.Millisecond, time.Millisecond, 100 * time.Millisecond)
+	var key = latencyKey{phase:PhaseResponseWait, cmd:STORAGE_PROTO_CMD_UPLOAD_FILE, addr:"127.0.0.1:23000"}
+	for _,latency := range []time.Duration{500 * time.Microsecond, 2 * time.Millisecond, 5 * time.Millisecond, 50 * time.Millisecond} {
+		histogram.observe(key, latency, 10, nil)
+	}
+	histogram.observe(key, time.Second, 0, errors.New("timeout"))
+	histogram.observe(latencyKey{phase:PhaseConnect, addr:"127.0.0.1:23000"}, time.Millisecond, 0, nil)
+
+	var stats = histogram.Snapshot()
+	if len(stats) != 2 || stats[0].Phase != PhaseConnect {
+		t.Fatalf("stats %+v", stats)
+	}
+	var stat = stats[1]
+	if stat.Count != 5 || stat.Errors != 1 || stat.Bytes != 40 || stat.Max != time.Second || stat.Addr != key.addr {
+		t.Fatalf("stat %+v", stat)
+	}
+	var expected = []int64{1, 2, 1, 1}
+	for i,count := range expected {
+		if stat.Counts[i] != count {
+			t.Fatalf("bucket %d count %d, expect %d", i, stat.Counts[i], count)
+		}
+	}
+	if mean := stat.Mean(); mean != (1057500 * time.Microsecond) / 5 {
+		t.Fatalf("mean %v", mean)
+	}
+	var quantiles = []struct {
+		q        float64
+		latency  time.Duration
+	}{
+		{0, time.Millisecond},
+		{0.5, 10 * time.Millisecond},
+		{0.8, 100 * time.Millisecond},
+		{0.99, time.Second},
+	}
+	for _,e := range quantiles {
+		if latency := stat.Quantile(e.q); latency != e.latency {
+			t.Fatalf("quantile %v: %v, expect %v", e.q, latency, e.latency)
+		}
+	}
+
+	// the snapshot is a copy
+	stat.Counts[0] = 100
+	if histogram.Snapshot()[1].Counts[0] != 1 {
+		t.Fatalf("snapshot shares the counts")
+	}
+	histogram.Reset()
+	if len(histogram.Snapshot()) != 0 {
+		t.Fatalf("stats after reset")
+	}
+}
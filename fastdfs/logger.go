@@ -0,0 +1,127 @@
+package fastdfs
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+/**
+ * the logger of the client, *slog.Logger satisfies it. the args are the
+ * alternating keys and values of the event attributes:<br>
+ * cmd, addr, errno, duration, bytes, attempt and error.<br>
+ * the client logs nothing without the logger.
+ */
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{}) {}
+func (nopLogger) Warn(msg string, args ...interface{}) {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+/**
+ * @param logger the logger of the client events, nil for none
+ */
+func WithLogger(logger Logger) ConfigOption {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+/**
+ * get the logger of the settings, never nil
+ */
+func (c *Config) getLogger() Logger {
+	var logger = GLogger
+	if c != nil {
+		logger = c.Logger
+	}
+	if logger == nil {
+		return nopLogger{}
+	}
+
+	return logger
+}
+
+/**
+ * connect to the server, log and trace the connection
+ *
+ * @param ctx  the context, dialing is aborted when ctx is done
+ * @param cmd  the command of the operation, 0 for none
+ * @param addr the server address
+ * @return the connection
+ */
+func (c *Config) connect(ctx context.Context, cmd byte, addr net.Addr) (net.Conn, error) {
+	var start = time.Now()
+	var span = c.startSpan(ctx, PhaseConnect, cmd, addr)
+	conn,err := c.getSocketAddrContext(ctx, addr)
+	span.End(0, err)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.getLogger().Warn("fastdfs: connect failed", "cmd", cmd, "addr", addr.String(), "duration", time.Since(start), "error", err)
+		}
+		return nil, err
+	}
+	c.getLogger().Debug("fastdfs: connected", "cmd", cmd, "addr", addr.String(), "duration", time.Since(start))
+
+	return conn, nil
+}
+
+/**
+ * close the connection of the server, log the failure
+ *
+ * @param server the tracker server or the storage server
+ */
+func (c *Config) closeServer(server interface{ GetAddress() net.Addr; Close() error }) {
+	if err := server.Close(); err != nil {
+		c.getLogger().Warn("fastdfs: close connection failed", "addr", addrString(server.GetAddress()), "error", err)
+	}
+}
+
+/**
+ * log the failure of sending the request
+ *
+ * @param cmd  the command
+ * @param addr the server address
+ * @param err  the error
+ */
+func (c *Config) logRequestError(cmd byte, addr net.Addr, err error) {
+	c.getLogger().Error("fastdfs: send request failed", "cmd", cmd, "addr", addrString(addr), "error", err)
+}
+
+/**
+ * log the response of the server
+ *
+ * @param cmd      the command
+ * @param addr     the server address
+ * @param errno    the errno of the response
+ * @param duration the time waiting for the response
+ * @param bytes    the length of the response body
+ * @param err      the error of receiving the response
+ */
+func (c *Config) logResponse(cmd byte, addr net.Addr, errno byte, duration time.Duration, bytes int64, err error) {
+	var logger = c.getLogger()
+	switch {
+	case err != nil:
+		logger.Error("fastdfs: recv response failed", "cmd", cmd, "addr", addrString(addr), "duration", duration, "error", err)
+	case errno != 0:
+		logger.Debug("fastdfs: server error", "cmd", cmd, "addr", addrString(addr), "errno", errno, "duration", duration)
+	default:
+		logger.Debug("fastdfs: response", "cmd", cmd, "addr", addrString(addr), "duration", duration, "bytes", bytes)
+	}
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+
+	return addr.String()
+}
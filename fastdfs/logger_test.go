@@ -0,0 +1,101 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+)
+
+type logEvent struct {
+	level  string
+	msg    string
+	attrs  map[string]interface{}
+}
+
+// record the events of the client
+type recordLogger struct {
+	events  []logEvent
+	lock    sync.Mutex
+}
+
+func (r *recordLogger) log(level, msg string, args []interface{}) {
+	var event = logEvent{level:level, msg:msg, attrs:make(map[string]interface{})}
+	for i := 0; i + 1 < len(args); i += 2 {
+		event.attrs[args[i].(string)] = args[i + 1]
+	}
+	r.lock.Lock()
+	r.events = append(r.events, event)
+	r.lock.Unlock()
+}
+
+func (r *recordLogger) Debug(msg string, args ...interface{}) { r.log("debug", msg, args) }
+func (r *recordLogger) Info(msg string, args ...interface{}) { r.log("info", msg, args) }
+func (r *recordLogger) Warn(msg string, args ...interface{}) { r.log("warn", msg, args) }
+func (r *recordLogger) Error(msg string, args ...interface{}) { r.log("error", msg, args) }
+
+func (r *recordLogger) find(msg string) *logEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := range r.events {
+		if r.events[i].msg == msg {
+			return &r.events[i]
+		}
+	}
+
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	var listener = startErrnoServer(t, ERR_NO_ENOENT)
+	defer listener.Close()
+	closed,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	var logger = &recordLogger{}
+	var client = NewClient(NewConfig(WithTrackerServers(listener.Addr(), closed.Addr()), WithConnectionPool(nil), WithLogger(logger)))
+	if _,err = client.NewTrackerClient().GetStoreStorage(nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err %v, expect %v", err, ErrNotFound)
+	}
+
+	var expected = []struct {
+		level  string
+		msg    string
+		addr   net.Addr
+	}{
+		{"warn", "fastdfs: connect failed", closed.Addr()},
+		{"warn", "fastdfs: tracker failover", closed.Addr()},
+		{"debug", "fastdfs: connected", listener.Addr()},
+		{"debug", "fastdfs: server error", listener.Addr()},
+	}
+	for _,e := range expected {
+		var event = logger.find(e.msg)
+		if event == nil || event.level != e.level || event.attrs["addr"] != e.addr.String() {
+			t.Fatalf("event %s: %+v", e.msg, event)
+		}
+	}
+	if event := logger.find("fastdfs: server error"); event.attrs["errno"] != byte(ERR_NO_ENOENT) ||
+		event.attrs["cmd"] != byte(TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE) {
+		t.Fatalf("server error event %+v", event)
+	}
+
+	// slog.Logger is the logger
+	var buff bytes.Buffer
+	client = NewClient(NewConfig(WithTrackerServers(listener.Addr()), WithConnectionPool(nil),
+		WithLogger(slog.New(slog.NewTextHandler(&buff, &slog.HandlerOptions{Level:slog.LevelDebug})))))
+	client.NewTrackerClient().GetStoreStorage(nil)
+	if !strings.Contains(buff.String(), `msg="fastdfs: server error"`) || !strings.Contains(buff.String(), "errno=2") {
+		t.Fatalf("slog output %s", buff.String())
+	}
+
+	// nothing is logged without the logger
+	if _,ok := NewConfig().getLogger().(nopLogger); !ok {
+		t.Fatalf("default logger %T", NewConfig().getLogger())
+	}
+}
@@ -0,0 +1,251 @@
+package fastdfs
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	DEFAULT_DOWNLOAD_RANGE_SIZE = 8 * 1024 * 1024 //the default bytes of each range
+	DEFAULT_DOWNLOAD_CONCURRENCY = 4               //the default count of ranges downloading at the same time
+)
+
+/**
+ * download file by ranges in parallel, the ranges are spread across the
+ * storage servers holding the file and a failed range is retried on another one.
+ */
+type ParallelDownloader struct {
+	storageClient   *StorageClient
+	rangeSize       int
+	concurrency     int
+}
+
+/**
+ * Constructor
+ *
+ * @param storageClient the storage client, the storage server of the client
+ * is the only replica when it is set
+ */
+func NewParallelDownloader(storageClient *StorageClient) *ParallelDownloader {
+	return &ParallelDownloader{
+		storageClient:storageClient,
+		rangeSize:DEFAULT_DOWNLOAD_RANGE_SIZE,
+		concurrency:DEFAULT_DOWNLOAD_CONCURRENCY,
+	}
+}
+
+/**
+ * set the bytes of each range
+ *
+ * @param rangeSize the range size, <= 0 for the default
+ */
+func (d *ParallelDownloader) SetRangeSize(rangeSize int) {
+	if rangeSize <= 0 {
+		rangeSize = DEFAULT_DOWNLOAD_RANGE_SIZE
+	}
+	d.rangeSize = rangeSize
+}
+
+/**
+ * set the count of ranges downloading at the same time
+ *
+ * @param concurrency the concurrency, <= 0 for the default
+ */
+func (d *ParallelDownloader) SetConcurrency(concurrency int) {
+	if concurrency <= 0 {
+		concurrency = DEFAULT_DOWNLOAD_CONCURRENCY
+	}
+	d.concurrency = concurrency
+}
+
+/**
+ * download file from storage servers, the crc32 of the file is verified
+ * except for appender file, the local file is removed if fail
+ *
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @param local_filename  filename on local
+ */
+func (d *ParallelDownloader) Download(groupName, remoteFilename, localFilename string) error {
+	return d.download(d.storageClient, groupName, remoteFilename, localFilename)
+}
+
+/**
+ * context version of Download
+ */
+func (d *ParallelDownloader) DownloadCtx(ctx context.Context, groupName, remoteFilename, localFilename string) error {
+	return d.storageClient.withContext(ctx, func(c *StorageClient) error {
+		return d.download(c, groupName, remoteFilename, localFilename)
+	})
+}
+
+func (d *ParallelDownloader) download(c *StorageClient, groupName, remoteFilename, localFilename string) (err error) {
+	fileInfo,err := c.QueryFileInfo(groupName, remoteFilename)
+	if err != nil {
+		return err
+	}
+	replicas,err := d.getReplicas(c, groupName, remoteFilename)
+	if err != nil {
+		return err
+	}
+
+	file,err := os.Create(localFilename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(localFilename)
+		}
+	}()
+	var fileSize = fileInfo.GetFileSize()
+	if err = file.Truncate(fileSize); err != nil {
+		return err
+	}
+
+	var rangeCount = int((fileSize + int64(d.rangeSize) - 1) / int64(d.rangeSize))
+	var ranges = make(chan int, rangeCount)
+	for i := 0; i < rangeCount; i++ {
+		ranges <- i
+	}
+	close(ranges)
+
+	var workers = d.concurrency
+	if workers > rangeCount {
+		workers = rangeCount
+	}
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var failed = make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range ranges {
+				select {
+				case <-failed:
+					return
+				default:
+				}
+				var offset = int64(index) * int64(d.rangeSize)
+				var length = fileSize - offset
+				if length > int64(d.rangeSize) {
+					length = int64(d.rangeSize)
+				}
+				if rangeErr := d.downloadRange(c, replicas, index, groupName, remoteFilename, file, offset, length); rangeErr != nil {
+					lock.Lock()
+					if err == nil {
+						err = rangeErr
+						close(failed)
+					}
+					lock.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	if fileInfo.GetCrc32() == 0 || isAppenderFilename(groupName, remoteFilename) {
+		return nil
+	}
+	var hash = crc32.NewIEEE()
+	if _,err = io.Copy(hash, io.NewSectionReader(file, 0, fileSize)); err != nil {
+		return err
+	}
+	if hash.Sum32() != uint32(fileInfo.GetCrc32()) {
+		c.setErrno(ERR_NO_EIO)
+		return &Error{Errno:ERR_NO_EIO, Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE,
+			Message:fmt.Sprintf("crc32 %08x != %08x", hash.Sum32(), uint32(fileInfo.GetCrc32()))}
+	}
+
+	return nil
+}
+
+/**
+ * get the storage servers holding the file
+ *
+ * @return the server addresses
+ */
+func (d *ParallelDownloader) getReplicas(c *StorageClient, groupName, remoteFilename string) ([]net.Addr, error) {
+	if c.storageServer != nil {
+		return []net.Addr{c.storageServer.GetAddress()}, nil
+	}
+
+	servers,err := c.newTrackerClient().GetFetchStorages(c.trackerServer, groupName, remoteFilename)
+	if err != nil {
+		return nil, err
+	}
+	var replicas = make([]net.Addr, 0, len(servers))
+	for _,server := range servers {
+		addr,err := net.ResolveTCPAddr("tcp", net.JoinHostPort(server.GetIpAddr(), strconv.Itoa(server.GetPort())))
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, addr)
+	}
+
+	return replicas, nil
+}
+
+/**
+ * download the range from the replica of the index, try the next replica if fail
+ *
+ * @return the error of the last replica
+ */
+func (d *ParallelDownloader) downloadRange(c *StorageClient, replicas []net.Addr, index int, groupName, remoteFilename string, file *os.File, offset, length int64) error {
+	var err error
+	for i := 0; i < len(replicas); i++ {
+		var reader = &StorageReader{
+			client:c,
+			groupName:groupName,
+			remoteFilename:remoteFilename,
+			addr:replicas[(index + i) % len(replicas)],
+			offset:offset,
+			size:length,
+		}
+		_,err = io.Copy(&offsetWriter{file:file, offset:offset}, reader)
+		reader.closeStream()
+		if err == nil {
+			return nil
+		}
+		if c.connCtx != nil && c.connCtx.ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+/**
+ * check the appender flag of the file size in the filename
+ */
+func isAppenderFilename(groupName, remoteFilename string) bool {
+	fileId,err := NewFileID(groupName, remoteFilename)
+	return err == nil && fileId.IsAppender()
+}
+
+// write the file at the offset, the offset moves forward.
+type offsetWriter struct {
+	file     *os.File
+	offset   int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n,err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+
+	return n, err
+}
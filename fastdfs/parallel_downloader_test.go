@@ -0,0 +1,133 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// answer query storage requests, the first address is returned for the query of one server.
+func startQueryTracker(t *testing.T, storeIpAddrs, fetchIpAddrs []string, port int) net.Listener {
+	listener,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var header = make([]byte, FDFS_PROTO_PKG_LEN_SIZE + 2)
+				for {
+					if _,err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					if _,err := io.CopyN(io.Discard, conn, Buff2long(header, 0)); err != nil {
+						return
+					}
+					var ips []string
+					var store = false
+					switch header[PROTO_HEADER_CMD_INDEX] {
+					case FDFS_PROTO_CMD_QUIT:
+						return
+					case TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE:
+						ips = fetchIpAddrs[:1]
+					case TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL:
+						ips = fetchIpAddrs
+					case TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE:
+						ips,store = storeIpAddrs[:1], true
+					default:
+						ips,store = storeIpAddrs, true
+					}
+					var body = []byte("group1")
+					body = append(body, make([]byte, FDFS_GROUP_NAME_MAX_LEN - len(body))...)
+					for i,ip := range ips {
+						var bs = make([]byte, FDFS_IPADDR_SIZE - 1)
+						copy(bs, ip)
+						body = append(body, bs...)
+						if i == 0 || store {
+							body = append(body, Long2Buff(int64(port))...)
+						}
+					}
+					if store {
+						body = append(body, 0)
+					}
+					res,_ := PackHeader(TRACKER_PROTO_CMD_RESP, int64(len(body)), 0)
+					if _,err := conn.Write(append(res, body...)); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener
+}
+
+func TestParallelDownloader(t *testing.T) {
+	var content = make([]byte, 100000)
+	for i := range content {
+		content[i] = byte(i * 11)
+	}
+	var listener = startDownloadServer(t, "127.0.0.1:", content, crc32.ChecksumIEEE(content))
+	defer listener.Close()
+	var port = listener.Addr().(*net.TCPAddr).Port
+
+	// the other replica drops the connections
+	badListener,err := net.Listen("tcp", "127.0.0.2:" + strconv.Itoa(port))
+	if err != nil {
+		t.Skipf("listen on 127.0.0.2 fail: %v", err)
+	}
+	defer badListener.Close()
+	go func() {
+		for {
+			conn,err := badListener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var tracker = startQueryTracker(t, nil, []string{"127.0.0.1", "127.0.0.2"}, port)
+	defer tracker.Close()
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil)))
+	var downloader = NewParallelDownloader(client.NewStorageClient())
+	downloader.SetRangeSize(7000)
+	downloader.SetConcurrency(3)
+
+	var localFilename = filepath.Join(t.TempDir(), "local.bin")
+	if err = downloader.Download("group1", "M00/00/00/test", localFilename); err != nil {
+		t.Fatal(err)
+	}
+	if data,_ := os.ReadFile(localFilename); !bytes.Equal(data, content) {
+		t.Fatalf("content mismatch, len %d", len(data))
+	}
+
+	// crc32 mismatch
+	var badCrc = startDownloadServer(t, "127.0.0.1:", content, 1)
+	defer badCrc.Close()
+	var addr = badCrc.Addr().(*net.TCPAddr)
+	storageServer,err := NewStorageServer(addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	downloader = NewParallelDownloader(NewStorageClientByServer(nil, storageServer))
+	downloader.SetRangeSize(30000)
+	if err = downloader.Download("group1", "M00/00/00/test", localFilename); !errors.Is(err, ErrIO) {
+		t.Fatalf("download err %v, expect %v", err, ErrIO)
+	}
+	if _,err = os.Stat(localFilename); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("local file is not removed, err %v", err)
+	}
+}
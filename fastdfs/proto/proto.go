@@ -0,0 +1,433 @@
+/**
+ * Package proto defines the wire format of the FastDFS tracker and storage
+ * protocol, one struct for each command request and response.
+ *
+ * A package is a 10 bytes header followed by the body, the requests and
+ * responses marshal the body only:
+ *
+ *	pkg,err := proto.Encode(&proto.DeleteFileRequest{FileRef:proto.FileRef{GroupName:"group1", Filename:name}})
+ *
+ * The string fields are raw bytes, the charset conversion is left to the caller.
+ */
+package proto
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FDFS_PROTO_CMD_QUIT = 82
+	TRACKER_PROTO_CMD_TRACKER_GET_STATUS = 64
+	TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP = 90
+	TRACKER_PROTO_CMD_SERVER_LIST_GROUP = 91
+	TRACKER_PROTO_CMD_SERVER_LIST_STORAGE = 92
+	TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE = 93
+	TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER = 94
+	TRACKER_PROTO_CMD_SERVER_DELETE_GROUP = 95
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE = 101
+	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE = 102
+	TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE = 103
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE = 104
+	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL = 105
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL = 106
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL = 107
+	TRACKER_PROTO_CMD_RESP = 100
+	FDFS_PROTO_CMD_ACTIVE_TEST = 111
+	STORAGE_PROTO_CMD_UPLOAD_FILE = 11
+	STORAGE_PROTO_CMD_DELETE_FILE = 12
+	STORAGE_PROTO_CMD_SET_METADATA = 13
+	STORAGE_PROTO_CMD_DOWNLOAD_FILE = 14
+	STORAGE_PROTO_CMD_GET_METADATA = 15
+	STORAGE_PROTO_CMD_CREATE_LINK = 20
+	STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE = 21
+	STORAGE_PROTO_CMD_QUERY_FILE_INFO = 22
+	STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE = 23  //create appender file
+	STORAGE_PROTO_CMD_APPEND_FILE = 24  //append file
+	STORAGE_PROTO_CMD_MODIFY_FILE = 34  //modify appender file
+	STORAGE_PROTO_CMD_TRUNCATE_FILE = 36  //truncate appender file
+	STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME = 38  //rename appender file to normal file
+	STORAGE_PROTO_CMD_RESP = TRACKER_PROTO_CMD_RESP
+)
+
+const (
+	STORAGE_SET_METADATA_FLAG_OVERWRITE = 'O'
+	STORAGE_SET_METADATA_FLAG_MERGE = 'M'
+	FDFS_PROTO_PKG_LEN_SIZE = 8
+	FDFS_PROTO_CMD_SIZE = 1
+	FDFS_GROUP_NAME_MAX_LEN = 16
+	FDFS_IPADDR_SIZE = 16
+	FDFS_DOMAIN_NAME_MAX_SIZE = 128
+	FDFS_VERSION_SIZE = 6
+	FDFS_STORAGE_ID_MAX_SIZE = 16
+	FDFS_FILE_EXT_NAME_MAX_LEN = 6
+	FDFS_FILE_PREFIX_MAX_LEN = 16
+	TRACKER_QUERY_STORAGE_FETCH_BODY_LEN = FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1 + FDFS_PROTO_PKG_LEN_SIZE
+	TRACKER_QUERY_STORAGE_STORE_BODY_LEN = FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE + FDFS_PROTO_PKG_LEN_SIZE
+	PROTO_HEADER_CMD_INDEX = FDFS_PROTO_PKG_LEN_SIZE
+	PROTO_HEADER_STATUS_INDEX = FDFS_PROTO_PKG_LEN_SIZE + 1
+	PROTO_HEADER_SIZE = FDFS_PROTO_PKG_LEN_SIZE + 2
+	GROUP_STAT_SIZE = FDFS_GROUP_NAME_MAX_LEN + 1 + 11 * FDFS_PROTO_PKG_LEN_SIZE  //the size of a group stat record of V5
+	STORAGE_STAT_SIZE = 1 + FDFS_STORAGE_ID_MAX_SIZE + 2 * FDFS_IPADDR_SIZE + FDFS_DOMAIN_NAME_MAX_SIZE +
+		FDFS_VERSION_SIZE + 52 * FDFS_PROTO_PKG_LEN_SIZE + 3 * 4 + 1  //the size of a storage stat record of V5
+)
+
+/**
+ * the message body, MarshalBinary and UnmarshalBinary are symmetric
+ */
+type Message interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+/**
+ * the request of a command
+ */
+type Request interface {
+	Message
+
+	/**
+	 * get the command code
+	 *
+	 * @return the command code
+	 */
+	Cmd() byte
+
+	/**
+	 * get the length of the content sent after the marshaled body
+	 *
+	 * @return the content length, 0 for none
+	 */
+	StreamLen() int64
+}
+
+/**
+ * error of the invalid field or body
+ */
+type Error struct {
+	Field     string //the invalid field, empty for the whole body
+	Message   string
+}
+
+func (e *Error) Error() string {
+	if e.Field == "" {
+		return "proto: " + e.Message
+	}
+
+	return "proto: " + e.Field + " " + e.Message
+}
+
+/**
+ * Constructor of the field error
+ *
+ * @param field  the field name
+ * @param format the message format
+ * @param args   the message args
+ */
+func fieldError(field, format string, args ...interface{}) *Error {
+	return &Error{
+		Field:field,
+		Message:fmt.Sprintf(format, args...),
+	}
+}
+
+/**
+ * Constructor of the body length error
+ *
+ * @param length the body length
+ */
+func bodyLengthError(length int) *Error {
+	return &Error{
+		Message:fmt.Sprintf("invalid body length: %d", length),
+	}
+}
+
+/**
+ * the package header
+ */
+type Header struct {
+	BodyLen   int64 //the body length, including the streamed content
+	Cmd       byte
+	Status    byte  //the errno of the response, 0 for success
+}
+
+func (h *Header) MarshalBinary() ([]byte, error) {
+	if h.BodyLen < 0 {
+		return nil, fieldError("BodyLen", "%d < 0", h.BodyLen)
+	}
+	var bs = make([]byte, PROTO_HEADER_SIZE)
+	putInt64(bs, h.BodyLen)
+	bs[PROTO_HEADER_CMD_INDEX] = h.Cmd
+	bs[PROTO_HEADER_STATUS_INDEX] = h.Status
+
+	return bs, nil
+}
+
+func (h *Header) UnmarshalBinary(data []byte) error {
+	if len(data) != PROTO_HEADER_SIZE {
+		return fieldError("header", "length %d != %d", len(data), PROTO_HEADER_SIZE)
+	}
+	var bodyLen = getInt64(data)
+	if bodyLen < 0 {
+		return fieldError("BodyLen", "%d < 0", bodyLen)
+	}
+	h.BodyLen = bodyLen
+	h.Cmd = data[PROTO_HEADER_CMD_INDEX]
+	h.Status = data[PROTO_HEADER_STATUS_INDEX]
+
+	return nil
+}
+
+/**
+ * read the package header
+ *
+ * @param r the reader
+ * @return the header
+ */
+func ReadHeader(r io.Reader) (*Header, error) {
+	var bs = make([]byte, PROTO_HEADER_SIZE)
+	if _,err := io.ReadFull(r, bs); err != nil {
+		return nil, err
+	}
+	var h = new(Header)
+	if err := h.UnmarshalBinary(bs); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+/**
+ * pack the header and the body of the request, the header counts the
+ * content streamed after it
+ *
+ * @param req the request
+ * @return the package
+ */
+func Encode(req Request) ([]byte, error) {
+	body,err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var h = Header{BodyLen:int64(len(body)) + req.StreamLen(), Cmd:req.Cmd()}
+	pkg,err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(pkg, body...), nil
+}
+
+/**
+ * pack the header and the body of the response
+ *
+ * @param res    the response, can be nil when status is not 0
+ * @param status the errno, 0 for success
+ * @return the package
+ */
+func EncodeResponse(res Message, status byte) ([]byte, error) {
+	var body []byte
+	if res != nil && status == 0 {
+		var err error
+		if body,err = res.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	var h = Header{BodyLen:int64(len(body)), Cmd:TRACKER_PROTO_CMD_RESP, Status:status}
+	pkg,err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(pkg, body...), nil
+}
+
+/**
+ * create the empty request of the command, to unmarshal the received body
+ *
+ * @param cmd the command code
+ * @return the request
+ */
+func NewRequest(cmd byte) (Request, error) {
+	switch cmd {
+	case FDFS_PROTO_CMD_QUIT:
+		return new(QuitRequest), nil
+	case FDFS_PROTO_CMD_ACTIVE_TEST:
+		return new(ActiveTestRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_LIST_GROUP:
+		return new(ListGroupsRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_LIST_STORAGE:
+		return new(ListStoragesRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE:
+		return new(DeleteStorageRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP:
+		return new(ListOneGroupRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_DELETE_GROUP:
+		return new(DeleteGroupRequest), nil
+	case TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER:
+		return new(SetTrunkServerRequest), nil
+	case TRACKER_PROTO_CMD_TRACKER_GET_STATUS:
+		return new(TrackerStatusRequest), nil
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE:
+		return new(QueryStoreRequest), nil
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL:
+		return new(QueryStoreAllRequest), nil
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE:
+		return new(QueryFetchRequest), nil
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE:
+		return new(QueryUpdateRequest), nil
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL:
+		return new(QueryFetchAllRequest), nil
+	case STORAGE_PROTO_CMD_UPLOAD_FILE:
+		return new(UploadFileRequest), nil
+	case STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE:
+		return &UploadFileRequest{Appender:true}, nil
+	case STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE:
+		return new(UploadSlaveFileRequest), nil
+	case STORAGE_PROTO_CMD_APPEND_FILE:
+		return new(AppendFileRequest), nil
+	case STORAGE_PROTO_CMD_MODIFY_FILE:
+		return new(ModifyFileRequest), nil
+	case STORAGE_PROTO_CMD_TRUNCATE_FILE:
+		return new(TruncateFileRequest), nil
+	case STORAGE_PROTO_CMD_DELETE_FILE:
+		return new(DeleteFileRequest), nil
+	case STORAGE_PROTO_CMD_SET_METADATA:
+		return new(SetMetadataRequest), nil
+	case STORAGE_PROTO_CMD_GET_METADATA:
+		return new(GetMetadataRequest), nil
+	case STORAGE_PROTO_CMD_DOWNLOAD_FILE:
+		return new(DownloadFileRequest), nil
+	case STORAGE_PROTO_CMD_QUERY_FILE_INFO:
+		return new(QueryFileInfoRequest), nil
+	case STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME:
+		return new(RegenerateAppenderFilenameRequest), nil
+	case STORAGE_PROTO_CMD_CREATE_LINK:
+		return new(CreateLinkRequest), nil
+	default:
+		return nil, fieldError("cmd", "%d is not supported", cmd)
+	}
+}
+
+/**
+ * the response without body
+ */
+type EmptyResponse struct {
+}
+
+func (r *EmptyResponse) MarshalBinary() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (r *EmptyResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != 0 {
+		return bodyLengthError(len(data))
+	}
+
+	return nil
+}
+
+func putInt64(bs []byte, n int64) {
+	binary.BigEndian.PutUint64(bs, uint64(n))
+}
+
+func getInt64(bs []byte) int64 {
+	return int64(binary.BigEndian.Uint64(bs))
+}
+
+/**
+ * put the string to the fixed length field, padded with 0
+ *
+ * @param bs    the field
+ * @param field the field name
+ * @param s     the string, at most len(bs) bytes
+ */
+func putString(bs []byte, field, s string) error {
+	if len(s) > len(bs) {
+		return fieldError(field, "length %d > %d", len(s), len(bs))
+	}
+	copy(bs, s)
+
+	return nil
+}
+
+/**
+ * get the string of the fixed length field, the padding is trimmed
+ */
+func getString(bs []byte) string {
+	return strings.Trim(string(bs), " \x00")
+}
+
+/**
+ * check the length of the string field
+ *
+ * @param field  the field name
+ * @param s      the string
+ * @param maxLen the max length
+ */
+func checkLen(field, s string, maxLen int) error {
+	if len(s) > maxLen {
+		return fieldError(field, "length %d > %d", len(s), maxLen)
+	}
+
+	return nil
+}
+
+/**
+ * check the size field is not negative
+ */
+func checkSize(field string, n int64) error {
+	if n < 0 {
+		return fieldError(field, "%d < 0", n)
+	}
+
+	return nil
+}
+
+/**
+ * split the content streamed after the head, the content is empty or of
+ * the size
+ *
+ * @param data    the body
+ * @param headLen the length of the head
+ * @param size    the content size
+ * @return the content, nil if not included
+ */
+func splitContent(data []byte, headLen int, size int64) ([]byte, error) {
+	if len(data) == headLen {
+		return nil, nil
+	}
+	if int64(len(data) - headLen) != size {
+		return nil, bodyLengthError(len(data))
+	}
+
+	return data[headLen:], nil
+}
+
+/**
+ * get the stream length of the request with the content
+ */
+func streamLen(content []byte, size int64) int64 {
+	if content != nil {
+		return 0
+	}
+
+	return size
+}
+
+/**
+ * append the content of the request, it must be of the size
+ */
+func appendContent(bs, content []byte, size int64) ([]byte, error) {
+	if content == nil {
+		return bs, nil
+	}
+	if int64(len(content)) != size {
+		return nil, fieldError("Content", "length %d != %d", len(content), size)
+	}
+
+	return append(bs, content...), nil
+}
@@ -0,0 +1,140 @@
+package proto
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"reflect"
+)
+
+/**
+ * marshal the message, unmarshal it to a new one of the same type and compare
+ */
+func roundTrip(t *testing.T, msg Message) []byte {
+	t.Helper()
+	data,err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal %T: %v", msg, err)
+	}
+	var decoded = reflect.New(reflect.TypeOf(msg).Elem()).Interface().(Message)
+	if req,ok := msg.(*UploadFileRequest); ok {
+		decoded.(*UploadFileRequest).Appender = req.Appender
+	}
+	if version := reflect.ValueOf(msg).Elem().FieldByName("Version"); version.IsValid() {
+		decoded.(Versioned).SetVersion(version.Interface().(Version))
+	}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal %T: %v", msg, err)
+	}
+	if !reflect.DeepEqual(msg, decoded) {
+		t.Fatalf("%T: %+v != %+v", msg, decoded, msg)
+	}
+
+	return data
+}
+
+func TestHeader(t *testing.T) {
+	var h = Header{BodyLen:0x1FFFFFFFFFFFFFFF, Cmd:0xAA, Status:0xBB}
+	data,err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != PROTO_HEADER_SIZE || data[PROTO_HEADER_CMD_INDEX] != 0xAA || data[PROTO_HEADER_STATUS_INDEX] != 0xBB {
+		t.Fatalf("header % X", data)
+	}
+	decoded,err := ReadHeader(bytes.NewReader(data))
+	if err != nil || *decoded != h {
+		t.Fatalf("header %+v, err %v", decoded, err)
+	}
+
+	h.BodyLen = -1
+	if _,err = h.MarshalBinary(); err == nil {
+		t.Fatalf("negative body length is marshaled")
+	}
+	if _,err = ReadHeader(bytes.NewReader(data[:5])); err == nil {
+		t.Fatalf("short header is read")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// the streamed content is counted by the header
+	pkg,err := Encode(&AppendFileRequest{AppenderFilename:"M00/00/00/a.txt", FileSize:100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h Header
+	if err = h.UnmarshalBinary(pkg[:PROTO_HEADER_SIZE]); err != nil {
+		t.Fatal(err)
+	}
+	var bodyLen = int64(len(pkg) - PROTO_HEADER_SIZE)
+	if h.Cmd != STORAGE_PROTO_CMD_APPEND_FILE || h.BodyLen != bodyLen + 100 {
+		t.Fatalf("header %+v, body length %d", h, bodyLen)
+	}
+
+	// the included content is not counted twice
+	pkg,err = Encode(&AppendFileRequest{AppenderFilename:"M00/00/00/a.txt", FileSize:5, Content:[]byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.UnmarshalBinary(pkg[:PROTO_HEADER_SIZE]); err != nil || h.BodyLen != int64(len(pkg) - PROTO_HEADER_SIZE) {
+		t.Fatalf("header %+v, package length %d", h, len(pkg))
+	}
+
+	_,err = Encode(&DeleteFileRequest{FileRef{GroupName:"group_name_too_long", Filename:"a"}})
+	var protoErr *Error
+	if !errors.As(err, &protoErr) || protoErr.Field != "GroupName" {
+		t.Fatalf("encode err %v, expect GroupName error", err)
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	pkg,err := EncodeResponse(&GetMetadataResponse{Metadata:"a\x02b"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkg[PROTO_HEADER_SIZE:], []byte("a\x02b")) || pkg[PROTO_HEADER_CMD_INDEX] != TRACKER_PROTO_CMD_RESP {
+		t.Fatalf("package % X", pkg)
+	}
+
+	// the body is omitted for errors
+	if pkg,err = EncodeResponse(&GetMetadataResponse{Metadata:"a\x02b"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg) != PROTO_HEADER_SIZE || pkg[PROTO_HEADER_STATUS_INDEX] != 2 {
+		t.Fatalf("package % X", pkg)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	var cmds = []byte{
+		FDFS_PROTO_CMD_QUIT, FDFS_PROTO_CMD_ACTIVE_TEST,
+		TRACKER_PROTO_CMD_SERVER_LIST_GROUP, TRACKER_PROTO_CMD_SERVER_LIST_STORAGE, TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE,
+		TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP, TRACKER_PROTO_CMD_SERVER_DELETE_GROUP, TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER,
+		TRACKER_PROTO_CMD_TRACKER_GET_STATUS,
+		TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE,
+		TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL,
+		TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL,
+		STORAGE_PROTO_CMD_UPLOAD_FILE, STORAGE_PROTO_CMD_DELETE_FILE, STORAGE_PROTO_CMD_SET_METADATA,
+		STORAGE_PROTO_CMD_DOWNLOAD_FILE, STORAGE_PROTO_CMD_GET_METADATA, STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE,
+		STORAGE_PROTO_CMD_QUERY_FILE_INFO, STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, STORAGE_PROTO_CMD_APPEND_FILE,
+		STORAGE_PROTO_CMD_MODIFY_FILE, STORAGE_PROTO_CMD_TRUNCATE_FILE,
+		STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME, STORAGE_PROTO_CMD_CREATE_LINK,
+	}
+	for _,cmd := range cmds {
+		req,err := NewRequest(cmd)
+		if err != nil {
+			t.Fatalf("cmd %d: %v", cmd, err)
+		}
+		if req.Cmd() != cmd {
+			t.Errorf("request of cmd %d has cmd %d", cmd, req.Cmd())
+		}
+	}
+	if _,err := NewRequest(TRACKER_PROTO_CMD_RESP); err == nil {
+		t.Fatalf("request of the response cmd is created")
+	}
+
+	var empty EmptyResponse
+	if err := empty.UnmarshalBinary([]byte{0}); err == nil {
+		t.Fatalf("non-empty body is accepted")
+	}
+}
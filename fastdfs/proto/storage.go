@@ -0,0 +1,620 @@
+package proto
+
+/**
+ * upload file, answered by UploadFileResponse
+ */
+type UploadFileRequest struct {
+	StorePathIndex   byte
+	FileSize         int64
+	FileExtName      string //without dot(.), at most FDFS_FILE_EXT_NAME_MAX_LEN bytes
+	Appender         bool   //upload appender file
+	Content          []byte //the file content, nil to stream FileSize bytes after the request
+}
+
+const uploadFileHeadLen = 1 + FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_EXT_NAME_MAX_LEN
+
+func (r *UploadFileRequest) Cmd() byte {
+	if r.Appender {
+		return STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE
+	}
+
+	return STORAGE_PROTO_CMD_UPLOAD_FILE
+}
+
+func (r *UploadFileRequest) StreamLen() int64 {
+	return streamLen(r.Content, r.FileSize)
+}
+
+func (r *UploadFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("FileSize", r.FileSize); err != nil {
+		return nil, err
+	}
+	var bs = make([]byte, uploadFileHeadLen, uploadFileHeadLen + len(r.Content))
+	bs[0] = r.StorePathIndex
+	putInt64(bs[1:], r.FileSize)
+	if err := putString(bs[1 + FDFS_PROTO_PKG_LEN_SIZE:], "FileExtName", r.FileExtName); err != nil {
+		return nil, err
+	}
+
+	return appendContent(bs, r.Content, r.FileSize)
+}
+
+func (r *UploadFileRequest) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < uploadFileHeadLen {
+		return bodyLengthError(len(data))
+	}
+	r.StorePathIndex = data[0]
+	if r.FileSize = getInt64(data[1:]); r.FileSize < 0 {
+		return fieldError("FileSize", "%d < 0", r.FileSize)
+	}
+	r.FileExtName = getString(data[1 + FDFS_PROTO_PKG_LEN_SIZE:uploadFileHeadLen])
+	r.Content,err = splitContent(data, uploadFileHeadLen, r.FileSize)
+
+	return err
+}
+
+/**
+ * upload slave file of the master file, answered by UploadFileResponse
+ */
+type UploadSlaveFileRequest struct {
+	MasterFilename   string
+	PrefixName       string //at most FDFS_FILE_PREFIX_MAX_LEN bytes
+	FileExtName      string //without dot(.), at most FDFS_FILE_EXT_NAME_MAX_LEN bytes
+	FileSize         int64
+	Content          []byte //the file content, nil to stream FileSize bytes after the request
+}
+
+const uploadSlaveFileFixedLen = 2 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_PREFIX_MAX_LEN + FDFS_FILE_EXT_NAME_MAX_LEN
+
+func (r *UploadSlaveFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE
+}
+
+func (r *UploadSlaveFileRequest) StreamLen() int64 {
+	return streamLen(r.Content, r.FileSize)
+}
+
+func (r *UploadSlaveFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("FileSize", r.FileSize); err != nil {
+		return nil, err
+	}
+	var headLen = uploadSlaveFileFixedLen + len(r.MasterFilename)
+	var bs = make([]byte, headLen, headLen + len(r.Content))
+	putInt64(bs, int64(len(r.MasterFilename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.FileSize)
+	var offset = 2 * FDFS_PROTO_PKG_LEN_SIZE
+	if err := putString(bs[offset:offset + FDFS_FILE_PREFIX_MAX_LEN], "PrefixName", r.PrefixName); err != nil {
+		return nil, err
+	}
+	offset += FDFS_FILE_PREFIX_MAX_LEN
+	if err := putString(bs[offset:offset + FDFS_FILE_EXT_NAME_MAX_LEN], "FileExtName", r.FileExtName); err != nil {
+		return nil, err
+	}
+	copy(bs[uploadSlaveFileFixedLen:], r.MasterFilename)
+
+	return appendContent(bs, r.Content, r.FileSize)
+}
+
+func (r *UploadSlaveFileRequest) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < uploadSlaveFileFixedLen {
+		return bodyLengthError(len(data))
+	}
+	var masterLen = getInt64(data)
+	if masterLen < 0 || masterLen > int64(len(data) - uploadSlaveFileFixedLen) {
+		return fieldError("MasterFilename", "length %d is invalid", masterLen)
+	}
+	if r.FileSize = getInt64(data[FDFS_PROTO_PKG_LEN_SIZE:]); r.FileSize < 0 {
+		return fieldError("FileSize", "%d < 0", r.FileSize)
+	}
+	var offset = 2 * FDFS_PROTO_PKG_LEN_SIZE
+	r.PrefixName = getString(data[offset:offset + FDFS_FILE_PREFIX_MAX_LEN])
+	offset += FDFS_FILE_PREFIX_MAX_LEN
+	r.FileExtName = getString(data[offset:offset + FDFS_FILE_EXT_NAME_MAX_LEN])
+	var headLen = uploadSlaveFileFixedLen + int(masterLen)
+	r.MasterFilename = string(data[uploadSlaveFileFixedLen:headLen])
+	r.Content,err = splitContent(data, headLen, r.FileSize)
+
+	return err
+}
+
+/**
+ * the uploaded file
+ */
+type UploadFileResponse struct {
+	GroupName   string
+	Filename    string
+}
+
+func (r *UploadFileResponse) MarshalBinary() ([]byte, error) {
+	if r.Filename == "" {
+		return nil, fieldError("Filename", "is empty")
+	}
+	var ref = FileRef{GroupName:r.GroupName, Filename:r.Filename}
+
+	return ref.MarshalBinary()
+}
+
+func (r *UploadFileResponse) UnmarshalBinary(data []byte) error {
+	if len(data) <= FDFS_GROUP_NAME_MAX_LEN {
+		return bodyLengthError(len(data))
+	}
+	var ref FileRef
+	if err := ref.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	r.GroupName,r.Filename = ref.GroupName, ref.Filename
+
+	return nil
+}
+
+/**
+ * append to the appender file, answered by EmptyResponse
+ */
+type AppendFileRequest struct {
+	AppenderFilename   string
+	FileSize           int64  //the size of the appended content
+	Content            []byte //the appended content, nil to stream FileSize bytes after the request
+}
+
+func (r *AppendFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_APPEND_FILE
+}
+
+func (r *AppendFileRequest) StreamLen() int64 {
+	return streamLen(r.Content, r.FileSize)
+}
+
+func (r *AppendFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("FileSize", r.FileSize); err != nil {
+		return nil, err
+	}
+	var headLen = 2 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename)
+	var bs = make([]byte, headLen, headLen + len(r.Content))
+	putInt64(bs, int64(len(r.AppenderFilename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.FileSize)
+	copy(bs[2 * FDFS_PROTO_PKG_LEN_SIZE:], r.AppenderFilename)
+
+	return appendContent(bs, r.Content, r.FileSize)
+}
+
+func (r *AppendFileRequest) UnmarshalBinary(data []byte) (err error) {
+	var sizes []int64
+	if sizes,r.AppenderFilename,err = getNamedHead(data, 2); err != nil {
+		return err
+	}
+	r.FileSize = sizes[0]
+	r.Content,err = splitContent(data, 2 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename), r.FileSize)
+
+	return err
+}
+
+/**
+ * modify the appender file, answered by EmptyResponse
+ */
+type ModifyFileRequest struct {
+	AppenderFilename   string
+	FileOffset         int64  //the offset to modify from
+	FileSize           int64  //the size of the modified content
+	Content            []byte //the modified content, nil to stream FileSize bytes after the request
+}
+
+func (r *ModifyFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_MODIFY_FILE
+}
+
+func (r *ModifyFileRequest) StreamLen() int64 {
+	return streamLen(r.Content, r.FileSize)
+}
+
+func (r *ModifyFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("FileOffset", r.FileOffset); err != nil {
+		return nil, err
+	}
+	if err := checkSize("FileSize", r.FileSize); err != nil {
+		return nil, err
+	}
+	var headLen = 3 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename)
+	var bs = make([]byte, headLen, headLen + len(r.Content))
+	putInt64(bs, int64(len(r.AppenderFilename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.FileOffset)
+	putInt64(bs[2 * FDFS_PROTO_PKG_LEN_SIZE:], r.FileSize)
+	copy(bs[3 * FDFS_PROTO_PKG_LEN_SIZE:], r.AppenderFilename)
+
+	return appendContent(bs, r.Content, r.FileSize)
+}
+
+func (r *ModifyFileRequest) UnmarshalBinary(data []byte) (err error) {
+	var sizes []int64
+	if sizes,r.AppenderFilename,err = getNamedHead(data, 3); err != nil {
+		return err
+	}
+	r.FileOffset,r.FileSize = sizes[0], sizes[1]
+	r.Content,err = splitContent(data, 3 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename), r.FileSize)
+
+	return err
+}
+
+/**
+ * truncate the appender file, answered by EmptyResponse
+ */
+type TruncateFileRequest struct {
+	AppenderFilename    string
+	TruncatedFileSize   int64
+}
+
+func (r *TruncateFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_TRUNCATE_FILE
+}
+
+func (r *TruncateFileRequest) StreamLen() int64 {
+	return 0
+}
+
+func (r *TruncateFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("TruncatedFileSize", r.TruncatedFileSize); err != nil {
+		return nil, err
+	}
+	var bs = make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename))
+	putInt64(bs, int64(len(r.AppenderFilename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.TruncatedFileSize)
+	copy(bs[2 * FDFS_PROTO_PKG_LEN_SIZE:], r.AppenderFilename)
+
+	return bs, nil
+}
+
+func (r *TruncateFileRequest) UnmarshalBinary(data []byte) (err error) {
+	var sizes []int64
+	if sizes,r.AppenderFilename,err = getNamedHead(data, 2); err != nil {
+		return err
+	}
+	if len(data) != 2 * FDFS_PROTO_PKG_LEN_SIZE + len(r.AppenderFilename) {
+		return bodyLengthError(len(data))
+	}
+	r.TruncatedFileSize = sizes[0]
+
+	return nil
+}
+
+/**
+ * rename the appender file to a normal file, answered by UploadFileResponse
+ */
+type RegenerateAppenderFilenameRequest struct {
+	AppenderFilename   string
+}
+
+func (r *RegenerateAppenderFilenameRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME
+}
+
+func (r *RegenerateAppenderFilenameRequest) StreamLen() int64 {
+	return 0
+}
+
+func (r *RegenerateAppenderFilenameRequest) MarshalBinary() ([]byte, error) {
+	if r.AppenderFilename == "" {
+		return nil, fieldError("AppenderFilename", "is empty")
+	}
+
+	return []byte(r.AppenderFilename), nil
+}
+
+func (r *RegenerateAppenderFilenameRequest) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return bodyLengthError(len(data))
+	}
+	r.AppenderFilename = string(data)
+
+	return nil
+}
+
+/**
+ * create the link file to the source file, the link is the slave file of the master
+ * file when the master filename is given. answered by UploadFileResponse
+ */
+type CreateLinkRequest struct {
+	GroupName         string
+	SourceFilename    string
+	SourceSignature   []byte //the signature of the source file content
+	MasterFilename    string //can be empty
+	PrefixName        string //at most FDFS_FILE_PREFIX_MAX_LEN bytes
+	FileExtName       string //without dot(.), at most FDFS_FILE_EXT_NAME_MAX_LEN bytes
+}
+
+const createLinkFixedLen = 3 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_GROUP_NAME_MAX_LEN + FDFS_FILE_PREFIX_MAX_LEN + FDFS_FILE_EXT_NAME_MAX_LEN
+
+func (r *CreateLinkRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_CREATE_LINK
+}
+
+func (r *CreateLinkRequest) StreamLen() int64 {
+	return 0
+}
+
+func (r *CreateLinkRequest) MarshalBinary() ([]byte, error) {
+	if r.SourceFilename == "" {
+		return nil, fieldError("SourceFilename", "is empty")
+	}
+	var bs = make([]byte, createLinkFixedLen, createLinkFixedLen + len(r.MasterFilename) + len(r.SourceFilename) + len(r.SourceSignature))
+	putInt64(bs, int64(len(r.MasterFilename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], int64(len(r.SourceFilename)))
+	putInt64(bs[2 * FDFS_PROTO_PKG_LEN_SIZE:], int64(len(r.SourceSignature)))
+	var offset = 3 * FDFS_PROTO_PKG_LEN_SIZE
+	if err := putString(bs[offset:offset + FDFS_GROUP_NAME_MAX_LEN], "GroupName", r.GroupName); err != nil {
+		return nil, err
+	}
+	offset += FDFS_GROUP_NAME_MAX_LEN
+	if err := putString(bs[offset:offset + FDFS_FILE_PREFIX_MAX_LEN], "PrefixName", r.PrefixName); err != nil {
+		return nil, err
+	}
+	offset += FDFS_FILE_PREFIX_MAX_LEN
+	if err := putString(bs[offset:offset + FDFS_FILE_EXT_NAME_MAX_LEN], "FileExtName", r.FileExtName); err != nil {
+		return nil, err
+	}
+	bs = append(append(append(bs, r.MasterFilename...), r.SourceFilename...), r.SourceSignature...)
+
+	return bs, nil
+}
+
+func (r *CreateLinkRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < createLinkFixedLen {
+		return bodyLengthError(len(data))
+	}
+	var lens = make([]int64, 3)
+	var total = int64(createLinkFixedLen)
+	for i := range lens {
+		if lens[i] = getInt64(data[i * FDFS_PROTO_PKG_LEN_SIZE:]); lens[i] < 0 || lens[i] > int64(len(data)) {
+			return fieldError("length", "%d is invalid", lens[i])
+		}
+		total += lens[i]
+	}
+	if total != int64(len(data)) || lens[1] == 0 {
+		return bodyLengthError(len(data))
+	}
+	var offset = 3 * FDFS_PROTO_PKG_LEN_SIZE
+	r.GroupName = getString(data[offset:offset + FDFS_GROUP_NAME_MAX_LEN])
+	offset += FDFS_GROUP_NAME_MAX_LEN
+	r.PrefixName = getString(data[offset:offset + FDFS_FILE_PREFIX_MAX_LEN])
+	offset += FDFS_FILE_PREFIX_MAX_LEN
+	r.FileExtName = getString(data[offset:offset + FDFS_FILE_EXT_NAME_MAX_LEN])
+	offset += FDFS_FILE_EXT_NAME_MAX_LEN
+	r.MasterFilename = string(data[offset:offset + int(lens[0])])
+	offset += int(lens[0])
+	r.SourceFilename = string(data[offset:offset + int(lens[1])])
+	offset += int(lens[1])
+	r.SourceSignature = nil
+	if lens[2] > 0 {
+		r.SourceSignature = append([]byte{}, data[offset:]...)
+	}
+
+	return nil
+}
+
+/**
+ * get the head of the appender file request: the filename length, the
+ * sizes and the filename
+ *
+ * @param data  the body
+ * @param count the count of the 8 bytes fields, including the filename length
+ * @return the sizes following the filename length, and the filename
+ */
+func getNamedHead(data []byte, count int) ([]int64, string, error) {
+	var fixedLen = count * FDFS_PROTO_PKG_LEN_SIZE
+	if len(data) < fixedLen {
+		return nil, "", bodyLengthError(len(data))
+	}
+	var nameLen = getInt64(data)
+	if nameLen < 0 || nameLen > int64(len(data) - fixedLen) {
+		return nil, "", fieldError("AppenderFilename", "length %d is invalid", nameLen)
+	}
+	var sizes = make([]int64, count - 1)
+	for i := range sizes {
+		if sizes[i] = getInt64(data[(i + 1) * FDFS_PROTO_PKG_LEN_SIZE:]); sizes[i] < 0 {
+			return nil, "", fieldError("size", "%d < 0", sizes[i])
+		}
+	}
+
+	return sizes, string(data[fixedLen:fixedLen + int(nameLen)]), nil
+}
+
+/**
+ * delete file, answered by EmptyResponse
+ */
+type DeleteFileRequest struct {
+	FileRef
+}
+
+func (r *DeleteFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_DELETE_FILE
+}
+
+/**
+ * set the metadata of the file, answered by EmptyResponse
+ */
+type SetMetadataRequest struct {
+	FileRef
+	OpFlag     byte   //STORAGE_SET_METADATA_FLAG_OVERWRITE or STORAGE_SET_METADATA_FLAG_MERGE
+	Metadata   string //the packed metadata
+}
+
+const setMetadataFixedLen = 2 * FDFS_PROTO_PKG_LEN_SIZE + 1 + FDFS_GROUP_NAME_MAX_LEN
+
+func (r *SetMetadataRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_SET_METADATA
+}
+
+func (r *SetMetadataRequest) MarshalBinary() ([]byte, error) {
+	if r.OpFlag != STORAGE_SET_METADATA_FLAG_OVERWRITE && r.OpFlag != STORAGE_SET_METADATA_FLAG_MERGE {
+		return nil, fieldError("OpFlag", "%q is invalid", r.OpFlag)
+	}
+	var bs = make([]byte, setMetadataFixedLen + len(r.Filename) + len(r.Metadata))
+	putInt64(bs, int64(len(r.Filename)))
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], int64(len(r.Metadata)))
+	bs[2 * FDFS_PROTO_PKG_LEN_SIZE] = r.OpFlag
+	if err := putString(bs[2 * FDFS_PROTO_PKG_LEN_SIZE + 1:setMetadataFixedLen], "GroupName", r.GroupName); err != nil {
+		return nil, err
+	}
+	copy(bs[setMetadataFixedLen:], r.Filename)
+	copy(bs[setMetadataFixedLen + len(r.Filename):], r.Metadata)
+
+	return bs, nil
+}
+
+func (r *SetMetadataRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < setMetadataFixedLen {
+		return bodyLengthError(len(data))
+	}
+	var nameLen = getInt64(data)
+	var metaLen = getInt64(data[FDFS_PROTO_PKG_LEN_SIZE:])
+	if nameLen < 0 || metaLen < 0 || nameLen + metaLen != int64(len(data) - setMetadataFixedLen) {
+		return bodyLengthError(len(data))
+	}
+	r.OpFlag = data[2 * FDFS_PROTO_PKG_LEN_SIZE]
+	r.GroupName = getString(data[2 * FDFS_PROTO_PKG_LEN_SIZE + 1:setMetadataFixedLen])
+	r.Filename = string(data[setMetadataFixedLen:setMetadataFixedLen + int(nameLen)])
+	r.Metadata = string(data[setMetadataFixedLen + int(nameLen):])
+
+	return nil
+}
+
+/**
+ * get the metadata of the file, answered by GetMetadataResponse
+ */
+type GetMetadataRequest struct {
+	FileRef
+}
+
+func (r *GetMetadataRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_GET_METADATA
+}
+
+/**
+ * the packed metadata of the file
+ */
+type GetMetadataResponse struct {
+	Metadata   string
+}
+
+func (r *GetMetadataResponse) MarshalBinary() ([]byte, error) {
+	return []byte(r.Metadata), nil
+}
+
+func (r *GetMetadataResponse) UnmarshalBinary(data []byte) error {
+	r.Metadata = string(data)
+	return nil
+}
+
+/**
+ * download the file, answered by DownloadFileResponse
+ */
+type DownloadFileRequest struct {
+	FileOffset      int64 //the start offset of the file
+	DownloadBytes   int64 //download bytes, 0 for remain bytes from offset
+	FileRef
+}
+
+func (r *DownloadFileRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_DOWNLOAD_FILE
+}
+
+func (r *DownloadFileRequest) MarshalBinary() ([]byte, error) {
+	if err := checkSize("FileOffset", r.FileOffset); err != nil {
+		return nil, err
+	}
+	if err := checkSize("DownloadBytes", r.DownloadBytes); err != nil {
+		return nil, err
+	}
+	ref,err := r.FileRef.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var bs = make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE, 2 * FDFS_PROTO_PKG_LEN_SIZE + len(ref))
+	putInt64(bs, r.FileOffset)
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.DownloadBytes)
+
+	return append(bs, ref...), nil
+}
+
+func (r *DownloadFileRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 * FDFS_PROTO_PKG_LEN_SIZE {
+		return bodyLengthError(len(data))
+	}
+	if r.FileOffset = getInt64(data); r.FileOffset < 0 {
+		return fieldError("FileOffset", "%d < 0", r.FileOffset)
+	}
+	if r.DownloadBytes = getInt64(data[FDFS_PROTO_PKG_LEN_SIZE:]); r.DownloadBytes < 0 {
+		return fieldError("DownloadBytes", "%d < 0", r.DownloadBytes)
+	}
+
+	return r.FileRef.UnmarshalBinary(data[2 * FDFS_PROTO_PKG_LEN_SIZE:])
+}
+
+/**
+ * the downloaded content, usually streamed by the client instead
+ */
+type DownloadFileResponse struct {
+	Content   []byte
+}
+
+func (r *DownloadFileResponse) MarshalBinary() ([]byte, error) {
+	return r.Content, nil
+}
+
+func (r *DownloadFileResponse) UnmarshalBinary(data []byte) error {
+	r.Content = data
+	return nil
+}
+
+/**
+ * query the file info from the storage server, answered by QueryFileInfoResponse
+ */
+type QueryFileInfoRequest struct {
+	FileRef
+}
+
+func (r *QueryFileInfoRequest) Cmd() byte {
+	return STORAGE_PROTO_CMD_QUERY_FILE_INFO
+}
+
+/**
+ * the file info
+ */
+type QueryFileInfoResponse struct {
+	FileSize          int64
+	CreateTimestamp   int64
+	Crc32             int64
+	SourceIpAddr      string
+	Version           Version //the protocol version of the layout
+}
+
+func (r *QueryFileInfoResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *QueryFileInfoResponse) bodyLen() int {
+	return 3 * FDFS_PROTO_PKG_LEN_SIZE + r.Version.IpAddrSize()
+}
+
+func (r *QueryFileInfoResponse) MarshalBinary() ([]byte, error) {
+	var queryFileInfoBodyLen = r.bodyLen()
+	var bs = make([]byte, queryFileInfoBodyLen)
+	putInt64(bs, r.FileSize)
+	putInt64(bs[FDFS_PROTO_PKG_LEN_SIZE:], r.CreateTimestamp)
+	putInt64(bs[2 * FDFS_PROTO_PKG_LEN_SIZE:], r.Crc32)
+	if err := putString(bs[3 * FDFS_PROTO_PKG_LEN_SIZE:queryFileInfoBodyLen - 1], "SourceIpAddr", r.SourceIpAddr); err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+func (r *QueryFileInfoResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != r.bodyLen() {
+		return bodyLengthError(len(data))
+	}
+	r.FileSize = getInt64(data)
+	r.CreateTimestamp = getInt64(data[FDFS_PROTO_PKG_LEN_SIZE:])
+	r.Crc32 = getInt64(data[2 * FDFS_PROTO_PKG_LEN_SIZE:])
+	r.SourceIpAddr = getString(data[3 * FDFS_PROTO_PKG_LEN_SIZE:])
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package proto
+
+import (
+	"testing"
+)
+
+func TestStorageMessages(t *testing.T) {
+	var ref = FileRef{GroupName:"group1", Filename:"M00/00/00/wKgBaF1.txt"}
+	var messages = []Message{
+		&UploadFileRequest{StorePathIndex:1, FileSize:1024, FileExtName:"jpg"},
+		&UploadFileRequest{FileSize:5, FileExtName:"txt", Appender:true, Content:[]byte("hello")},
+		&UploadSlaveFileRequest{MasterFilename:ref.Filename, PrefixName:"_150x150", FileExtName:"jpg", FileSize:10},
+		&UploadSlaveFileRequest{MasterFilename:ref.Filename, PrefixName:"-s", FileSize:2, Content:[]byte("hi")},
+		&UploadFileResponse{GroupName:"group1", Filename:ref.Filename},
+		&AppendFileRequest{AppenderFilename:ref.Filename, FileSize:100},
+		&AppendFileRequest{AppenderFilename:ref.Filename, FileSize:3, Content:[]byte("abc")},
+		&ModifyFileRequest{AppenderFilename:ref.Filename, FileOffset:10, FileSize:3, Content:[]byte("abc")},
+		&TruncateFileRequest{AppenderFilename:ref.Filename, TruncatedFileSize:7},
+		&DeleteFileRequest{ref},
+		&SetMetadataRequest{FileRef:ref, OpFlag:STORAGE_SET_METADATA_FLAG_MERGE, Metadata:"width\x02100\x01height\x0250"},
+		&SetMetadataRequest{FileRef:ref, OpFlag:STORAGE_SET_METADATA_FLAG_OVERWRITE},
+		&GetMetadataRequest{ref},
+		&GetMetadataResponse{Metadata:"width\x02100"},
+		&DownloadFileRequest{FileOffset:100, DownloadBytes:200, FileRef:ref},
+		&DownloadFileResponse{Content:[]byte("content")},
+		&QueryFileInfoRequest{ref},
+		&QueryFileInfoResponse{FileSize:1024, CreateTimestamp:1600000000, Crc32:0x12345678, SourceIpAddr:"10.0.0.1"},
+		&EmptyResponse{},
+		&RegenerateAppenderFilenameRequest{AppenderFilename:ref.Filename},
+		&CreateLinkRequest{GroupName:"group1", SourceFilename:ref.Filename, SourceSignature:[]byte{1, 2, 3}},
+		&CreateLinkRequest{GroupName:"group1", SourceFilename:ref.Filename, MasterFilename:ref.Filename, PrefixName:"_link", FileExtName:"jpg"},
+	}
+	for _,msg := range messages {
+		roundTrip(t, msg)
+	}
+
+	data := roundTrip(t, &UploadFileRequest{StorePathIndex:1, FileSize:1024, FileExtName:"jpg"})
+	if len(data) != 1 + FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_EXT_NAME_MAX_LEN {
+		t.Errorf("upload request length %d", len(data))
+	}
+}
+
+func TestStorageMessagesInvalid(t *testing.T) {
+	var invalid = []Message{
+		&UploadFileRequest{FileSize:1, FileExtName:"toolong"},
+		&UploadFileRequest{FileSize:-1},
+		&UploadFileRequest{FileSize:5, Content:[]byte("hi")},
+		&UploadSlaveFileRequest{MasterFilename:"a", PrefixName:"prefix_name_too_long", FileSize:1},
+		&UploadSlaveFileRequest{MasterFilename:"a", PrefixName:"-s", FileExtName:"toolong", FileSize:1},
+		&UploadFileResponse{GroupName:"group1"},
+		&ModifyFileRequest{AppenderFilename:"a", FileOffset:-1},
+		&TruncateFileRequest{AppenderFilename:"a", TruncatedFileSize:-1},
+		&SetMetadataRequest{FileRef:FileRef{GroupName:"group1", Filename:"a"}, OpFlag:'X'},
+		&DownloadFileRequest{DownloadBytes:-1, FileRef:FileRef{GroupName:"group1", Filename:"a"}},
+		&QueryFileInfoResponse{SourceIpAddr:"1234:5678:9abc::1"},
+		&RegenerateAppenderFilenameRequest{},
+		&CreateLinkRequest{GroupName:"group1"},
+		&CreateLinkRequest{GroupName:"group1", SourceFilename:"a", PrefixName:"prefix_name_too_long"},
+	}
+	for _,msg := range invalid {
+		if _,err := msg.MarshalBinary(); err == nil {
+			t.Errorf("invalid %T %+v is marshaled", msg, msg)
+		}
+	}
+
+	// the content must be absent or of the file size
+	data,_ := (&AppendFileRequest{AppenderFilename:"a", FileSize:3, Content:[]byte("abc")}).MarshalBinary()
+	var bodies = []struct {
+		msg    Message
+		body   []byte
+	}{
+		{&AppendFileRequest{}, data[:len(data) - 1]},
+		{&AppendFileRequest{}, append(data, 'd')},
+		{&AppendFileRequest{}, data[:2 * FDFS_PROTO_PKG_LEN_SIZE - 1]},
+		{&UploadFileRequest{}, make([]byte, 5)},
+		{&TruncateFileRequest{}, append(make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE), 'x')},
+		{&SetMetadataRequest{}, make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE + 1 + FDFS_GROUP_NAME_MAX_LEN + 1)},
+		{&UploadFileResponse{}, make([]byte, FDFS_GROUP_NAME_MAX_LEN)},
+		{&QueryFileInfoResponse{}, make([]byte, 3 * FDFS_PROTO_PKG_LEN_SIZE)},
+		{&RegenerateAppenderFilenameRequest{}, nil},
+		{&CreateLinkRequest{}, make([]byte, createLinkFixedLen)},
+		{&CreateLinkRequest{}, append(make([]byte, createLinkFixedLen), 'x')},
+	}
+	for _,b := range bodies {
+		if err := b.msg.UnmarshalBinary(b.body); err == nil {
+			t.Errorf("%T of body length %d is unmarshaled", b.msg, len(b.body))
+		}
+	}
+}
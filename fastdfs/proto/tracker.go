@@ -0,0 +1,668 @@
+package proto
+
+/**
+ * the group name and the filename of a file, marshaled as the group name
+ * padded to FDFS_GROUP_NAME_MAX_LEN bytes followed by the filename
+ */
+type FileRef struct {
+	GroupName   string
+	Filename    string
+}
+
+func (f *FileRef) MarshalBinary() ([]byte, error) {
+	var bs = make([]byte, FDFS_GROUP_NAME_MAX_LEN + len(f.Filename))
+	if err := putString(bs[:FDFS_GROUP_NAME_MAX_LEN], "GroupName", f.GroupName); err != nil {
+		return nil, err
+	}
+	copy(bs[FDFS_GROUP_NAME_MAX_LEN:], f.Filename)
+
+	return bs, nil
+}
+
+func (f *FileRef) UnmarshalBinary(data []byte) error {
+	if len(data) < FDFS_GROUP_NAME_MAX_LEN {
+		return bodyLengthError(len(data))
+	}
+	f.GroupName = getString(data[:FDFS_GROUP_NAME_MAX_LEN])
+	f.Filename = string(data[FDFS_GROUP_NAME_MAX_LEN:])
+
+	return nil
+}
+
+func (f *FileRef) StreamLen() int64 {
+	return 0
+}
+
+/**
+ * the request without body
+ */
+type emptyRequest struct {
+}
+
+func (r *emptyRequest) MarshalBinary() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (r *emptyRequest) UnmarshalBinary(data []byte) error {
+	if len(data) != 0 {
+		return bodyLengthError(len(data))
+	}
+
+	return nil
+}
+
+func (r *emptyRequest) StreamLen() int64 {
+	return 0
+}
+
+/**
+ * close the connection, no response
+ */
+type QuitRequest struct {
+	emptyRequest
+}
+
+func (r *QuitRequest) Cmd() byte {
+	return FDFS_PROTO_CMD_QUIT
+}
+
+/**
+ * test the connection, answered by EmptyResponse
+ */
+type ActiveTestRequest struct {
+	emptyRequest
+}
+
+func (r *ActiveTestRequest) Cmd() byte {
+	return FDFS_PROTO_CMD_ACTIVE_TEST
+}
+
+/**
+ * optional group name, marshaled as FDFS_GROUP_NAME_MAX_LEN bytes, or
+ * nothing when empty
+ */
+type optionalGroup struct {
+	GroupName   string //the group name, empty for any group
+}
+
+func (g *optionalGroup) MarshalBinary() ([]byte, error) {
+	if g.GroupName == "" {
+		return []byte{}, nil
+	}
+	var bs = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
+	if err := putString(bs, "GroupName", g.GroupName); err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+func (g *optionalGroup) UnmarshalBinary(data []byte) error {
+	if len(data) != 0 && len(data) != FDFS_GROUP_NAME_MAX_LEN {
+		return bodyLengthError(len(data))
+	}
+	g.GroupName = getString(data)
+
+	return nil
+}
+
+func (g *optionalGroup) StreamLen() int64 {
+	return 0
+}
+
+/**
+ * query a storage server to upload file, answered by QueryStoreResponse
+ */
+type QueryStoreRequest struct {
+	optionalGroup
+}
+
+func (r *QueryStoreRequest) Cmd() byte {
+	if r.GroupName == "" {
+		return TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE
+	}
+
+	return TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE
+}
+
+/**
+ * query all storage servers to upload file, answered by QueryStoreAllResponse
+ */
+type QueryStoreAllRequest struct {
+	optionalGroup
+}
+
+func (r *QueryStoreAllRequest) Cmd() byte {
+	if r.GroupName == "" {
+		return TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL
+	}
+
+	return TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL
+}
+
+/**
+ * the ip address and the port of a server
+ */
+type ServerAddr struct {
+	IpAddr   string
+	Port     int
+}
+
+/**
+ * put the ip address and the port to the record of the ip address size - 1 + 8 bytes
+ */
+func (a *ServerAddr) put(bs []byte, version Version) error {
+	var ipAddrLen = version.IpAddrSize() - 1
+	if err := putString(bs[:ipAddrLen], "IpAddr", a.IpAddr); err != nil {
+		return err
+	}
+	putInt64(bs[ipAddrLen:], int64(a.Port))
+
+	return nil
+}
+
+func (a *ServerAddr) get(bs []byte, version Version) {
+	var ipAddrLen = version.IpAddrSize() - 1
+	a.IpAddr = getString(bs[:ipAddrLen])
+	a.Port = int(getInt64(bs[ipAddrLen:]))
+}
+
+/**
+ * the storage server to upload file
+ */
+type QueryStoreResponse struct {
+	GroupName        string
+	ServerAddr
+	StorePathIndex   byte
+	Version          Version //the protocol version of the layout
+}
+
+func (r *QueryStoreResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *QueryStoreResponse) MarshalBinary() ([]byte, error) {
+	var bodyLen = r.Version.storeBodyLen()
+	var bs = make([]byte, bodyLen)
+	if err := putString(bs[:FDFS_GROUP_NAME_MAX_LEN], "GroupName", r.GroupName); err != nil {
+		return nil, err
+	}
+	if err := r.put(bs[FDFS_GROUP_NAME_MAX_LEN:], r.Version); err != nil {
+		return nil, err
+	}
+	bs[bodyLen - 1] = r.StorePathIndex
+
+	return bs, nil
+}
+
+func (r *QueryStoreResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != r.Version.storeBodyLen() {
+		return bodyLengthError(len(data))
+	}
+	r.GroupName = getString(data[:FDFS_GROUP_NAME_MAX_LEN])
+	r.get(data[FDFS_GROUP_NAME_MAX_LEN:], r.Version)
+	r.StorePathIndex = data[len(data) - 1]
+
+	return nil
+}
+
+/**
+ * the storage servers to upload file
+ */
+type QueryStoreAllResponse struct {
+	GroupName        string
+	Servers          []ServerAddr //at least one
+	StorePathIndex   byte
+	Version          Version //the protocol version of the layout
+}
+
+func (r *QueryStoreAllResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *QueryStoreAllResponse) MarshalBinary() ([]byte, error) {
+	if len(r.Servers) == 0 {
+		return nil, fieldError("Servers", "is empty")
+	}
+	var serverAddrLen = r.Version.serverAddrLen()
+	var bs = make([]byte, FDFS_GROUP_NAME_MAX_LEN + len(r.Servers) * serverAddrLen + 1)
+	if err := putString(bs[:FDFS_GROUP_NAME_MAX_LEN], "GroupName", r.GroupName); err != nil {
+		return nil, err
+	}
+	var offset = FDFS_GROUP_NAME_MAX_LEN
+	for i := range r.Servers {
+		if err := r.Servers[i].put(bs[offset:], r.Version); err != nil {
+			return nil, err
+		}
+		offset += serverAddrLen
+	}
+	bs[offset] = r.StorePathIndex
+
+	return bs, nil
+}
+
+func (r *QueryStoreAllResponse) UnmarshalBinary(data []byte) error {
+	var serverAddrLen = r.Version.serverAddrLen()
+	var ipPortLen = len(data) - (FDFS_GROUP_NAME_MAX_LEN + 1)
+	if ipPortLen <= 0 || ipPortLen % serverAddrLen != 0 {
+		return bodyLengthError(len(data))
+	}
+	r.GroupName = getString(data[:FDFS_GROUP_NAME_MAX_LEN])
+	r.Servers = make([]ServerAddr, ipPortLen / serverAddrLen)
+	var offset = FDFS_GROUP_NAME_MAX_LEN
+	for i := range r.Servers {
+		r.Servers[i].get(data[offset:], r.Version)
+		offset += serverAddrLen
+	}
+	r.StorePathIndex = data[offset]
+
+	return nil
+}
+
+/**
+ * query a storage server to download file, answered by QueryFetchResponse
+ */
+type QueryFetchRequest struct {
+	FileRef
+}
+
+func (r *QueryFetchRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE
+}
+
+/**
+ * query the storage server to update file, answered by QueryFetchResponse
+ */
+type QueryUpdateRequest struct {
+	FileRef
+}
+
+func (r *QueryUpdateRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE
+}
+
+/**
+ * query all storage servers to download file, answered by QueryFetchResponse
+ */
+type QueryFetchAllRequest struct {
+	FileRef
+}
+
+func (r *QueryFetchAllRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL
+}
+
+/**
+ * the storage servers to download or update file, they share the port
+ */
+type QueryFetchResponse struct {
+	GroupName   string
+	IpAddrs     []string //at least one, only one for QueryFetchRequest and QueryUpdateRequest
+	Port        int
+	Version     Version  //the protocol version of the layout
+}
+
+func (r *QueryFetchResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *QueryFetchResponse) MarshalBinary() ([]byte, error) {
+	if len(r.IpAddrs) == 0 {
+		return nil, fieldError("IpAddrs", "is empty")
+	}
+	var bodyLen = r.Version.fetchBodyLen()
+	var ipAddrLen = r.Version.IpAddrSize() - 1
+	var bs = make([]byte, bodyLen + (len(r.IpAddrs) - 1) * ipAddrLen)
+	if err := putString(bs[:FDFS_GROUP_NAME_MAX_LEN], "GroupName", r.GroupName); err != nil {
+		return nil, err
+	}
+	var first = ServerAddr{IpAddr:r.IpAddrs[0], Port:r.Port}
+	if err := first.put(bs[FDFS_GROUP_NAME_MAX_LEN:], r.Version); err != nil {
+		return nil, err
+	}
+	var offset = bodyLen
+	for _,ipAddr := range r.IpAddrs[1:] {
+		if err := putString(bs[offset:offset + ipAddrLen], "IpAddrs", ipAddr); err != nil {
+			return nil, err
+		}
+		offset += ipAddrLen
+	}
+
+	return bs, nil
+}
+
+func (r *QueryFetchResponse) UnmarshalBinary(data []byte) error {
+	var bodyLen = r.Version.fetchBodyLen()
+	var ipAddrLen = r.Version.IpAddrSize() - 1
+	if len(data) < bodyLen || (len(data) - bodyLen) % ipAddrLen != 0 {
+		return bodyLengthError(len(data))
+	}
+	var first ServerAddr
+	first.get(data[FDFS_GROUP_NAME_MAX_LEN:], r.Version)
+	r.GroupName = getString(data[:FDFS_GROUP_NAME_MAX_LEN])
+	r.Port = first.Port
+	r.IpAddrs = []string{first.IpAddr}
+	for offset := bodyLen; offset < len(data); offset += ipAddrLen {
+		r.IpAddrs = append(r.IpAddrs, getString(data[offset:offset + ipAddrLen]))
+	}
+
+	return nil
+}
+
+/**
+ * list the groups, answered by ListGroupsResponse
+ */
+type ListGroupsRequest struct {
+	emptyRequest
+}
+
+func (r *ListGroupsRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_LIST_GROUP
+}
+
+/**
+ * the group name followed by an optional storage id or ip address
+ */
+type groupStorage struct {
+	GroupName   string
+	StorageId   string  //the storage id or ip address, at most FDFS_STORAGE_ID_MAX_SIZE - 1 bytes, or the ip address size - 1 bytes of V6
+	Version     Version //the protocol version of the layout
+}
+
+func (g *groupStorage) SetVersion(version Version) {
+	g.Version = version
+}
+
+/**
+ * get the max length of the storage id, the IPv6 address is longer than the storage id
+ */
+func (g *groupStorage) maxStorageIdLen() int {
+	if g.Version >= V6 && g.Version.IpAddrSize() > FDFS_STORAGE_ID_MAX_SIZE {
+		return g.Version.IpAddrSize() - 1
+	}
+
+	return FDFS_STORAGE_ID_MAX_SIZE - 1
+}
+
+func (g *groupStorage) MarshalBinary() ([]byte, error) {
+	if err := checkLen("StorageId", g.StorageId, g.maxStorageIdLen()); err != nil {
+		return nil, err
+	}
+	var bs = make([]byte, FDFS_GROUP_NAME_MAX_LEN, FDFS_GROUP_NAME_MAX_LEN + len(g.StorageId))
+	if err := putString(bs, "GroupName", g.GroupName); err != nil {
+		return nil, err
+	}
+
+	return append(bs, g.StorageId...), nil
+}
+
+func (g *groupStorage) UnmarshalBinary(data []byte) error {
+	if len(data) < FDFS_GROUP_NAME_MAX_LEN || len(data) > FDFS_GROUP_NAME_MAX_LEN + g.maxStorageIdLen() {
+		return bodyLengthError(len(data))
+	}
+	g.GroupName = getString(data[:FDFS_GROUP_NAME_MAX_LEN])
+	g.StorageId = getString(data[FDFS_GROUP_NAME_MAX_LEN:])
+
+	return nil
+}
+
+func (g *groupStorage) StreamLen() int64 {
+	return 0
+}
+
+/**
+ * list the storage servers of the group, answered by ListStoragesResponse
+ */
+type ListStoragesRequest struct {
+	groupStorage
+}
+
+func (r *ListStoragesRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_LIST_STORAGE
+}
+
+/**
+ * delete the storage server from the group, answered by EmptyResponse
+ */
+type DeleteStorageRequest struct {
+	groupStorage
+}
+
+func (r *DeleteStorageRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE
+}
+
+/**
+ * the group name, marshaled as FDFS_GROUP_NAME_MAX_LEN bytes
+ */
+type groupOnly struct {
+	GroupName   string
+}
+
+func (g *groupOnly) MarshalBinary() ([]byte, error) {
+	if g.GroupName == "" {
+		return nil, fieldError("GroupName", "is empty")
+	}
+	var bs = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
+	if err := putString(bs, "GroupName", g.GroupName); err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+func (g *groupOnly) UnmarshalBinary(data []byte) error {
+	if len(data) != FDFS_GROUP_NAME_MAX_LEN {
+		return bodyLengthError(len(data))
+	}
+	g.GroupName = getString(data)
+
+	return nil
+}
+
+func (g *groupOnly) StreamLen() int64 {
+	return 0
+}
+
+/**
+ * list the group, answered by ListOneGroupResponse
+ */
+type ListOneGroupRequest struct {
+	groupOnly
+}
+
+func (r *ListOneGroupRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP
+}
+
+/**
+ * delete the group without storage servers, answered by EmptyResponse
+ */
+type DeleteGroupRequest struct {
+	groupOnly
+}
+
+func (r *DeleteGroupRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_DELETE_GROUP
+}
+
+/**
+ * set the trunk server of the group, answered by SetTrunkServerResponse.
+ * the tracker selects the trunk server when the storage id is empty,
+ * only the leader tracker accepts the request
+ */
+type SetTrunkServerRequest struct {
+	groupStorage
+}
+
+func (r *SetTrunkServerRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER
+}
+
+/**
+ * the id of the new trunk server
+ */
+type SetTrunkServerResponse struct {
+	StorageId   string
+}
+
+func (r *SetTrunkServerResponse) MarshalBinary() ([]byte, error) {
+	if err := checkLen("StorageId", r.StorageId, FDFS_STORAGE_ID_MAX_SIZE - 1); err != nil {
+		return nil, err
+	}
+
+	return []byte(r.StorageId), nil
+}
+
+func (r *SetTrunkServerResponse) UnmarshalBinary(data []byte) error {
+	if len(data) >= FDFS_STORAGE_ID_MAX_SIZE {
+		return bodyLengthError(len(data))
+	}
+	r.StorageId = getString(data)
+
+	return nil
+}
+
+/**
+ * get the status of the tracker server, answered by TrackerStatusResponse
+ */
+type TrackerStatusRequest struct {
+	emptyRequest
+}
+
+func (r *TrackerStatusRequest) Cmd() byte {
+	return TRACKER_PROTO_CMD_TRACKER_GET_STATUS
+}
+
+/**
+ * the status of the tracker server
+ */
+type TrackerStatusResponse struct {
+	IsLeader          bool
+	RunningTime       int64 //in seconds
+	RestartInterval   int64 //the seconds since the last restart of the leader, 0 for none
+}
+
+const trackerStatusLen = 1 + 2 * FDFS_PROTO_PKG_LEN_SIZE
+
+func (r *TrackerStatusResponse) MarshalBinary() ([]byte, error) {
+	var bs = make([]byte, trackerStatusLen)
+	if r.IsLeader {
+		bs[0] = 1
+	}
+	putInt64(bs[1:], r.RunningTime)
+	putInt64(bs[1 + FDFS_PROTO_PKG_LEN_SIZE:], r.RestartInterval)
+
+	return bs, nil
+}
+
+func (r *TrackerStatusResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != trackerStatusLen {
+		return bodyLengthError(len(data))
+	}
+	r.IsLeader = data[0] != 0
+	r.RunningTime = getInt64(data[1:])
+	r.RestartInterval = getInt64(data[1 + FDFS_PROTO_PKG_LEN_SIZE:])
+
+	return nil
+}
+
+/**
+ * split the body to the records of the size
+ */
+func splitRecords(data []byte, size int) ([][]byte, error) {
+	if len(data) % size != 0 {
+		return nil, bodyLengthError(len(data))
+	}
+	var records = make([][]byte, len(data) / size)
+	for i := range records {
+		records[i] = data[i * size:(i + 1) * size]
+	}
+
+	return records, nil
+}
+
+/**
+ * join the records of the size
+ */
+func joinRecords(field string, records [][]byte, size int) ([]byte, error) {
+	var bs = make([]byte, 0, len(records) * size)
+	for _,record := range records {
+		if len(record) != size {
+			return nil, fieldError(field, "record length %d != %d", len(record), size)
+		}
+		bs = append(bs, record...)
+	}
+
+	return bs, nil
+}
+
+/**
+ * the group stat records of Version.GroupStatSize() bytes, decoded by the
+ * caller with the charset of the string fields
+ */
+type ListGroupsResponse struct {
+	Groups    [][]byte
+	Version   Version //the protocol version of the layout
+}
+
+func (r *ListGroupsResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *ListGroupsResponse) MarshalBinary() ([]byte, error) {
+	return joinRecords("Groups", r.Groups, r.Version.GroupStatSize())
+}
+
+func (r *ListGroupsResponse) UnmarshalBinary(data []byte) (err error) {
+	r.Groups,err = splitRecords(data, r.Version.GroupStatSize())
+	return err
+}
+
+/**
+ * the storage stat records of Version.StorageStatSize() bytes, decoded by
+ * the caller with the charset of the string fields
+ */
+type ListStoragesResponse struct {
+	Storages   [][]byte
+	Version    Version //the protocol version of the layout
+}
+
+func (r *ListStoragesResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *ListStoragesResponse) MarshalBinary() ([]byte, error) {
+	return joinRecords("Storages", r.Storages, r.Version.StorageStatSize())
+}
+
+func (r *ListStoragesResponse) UnmarshalBinary(data []byte) (err error) {
+	r.Storages,err = splitRecords(data, r.Version.StorageStatSize())
+	return err
+}
+
+/**
+ * the group stat record of Version.GroupStatSize() bytes, decoded by the
+ * caller with the charset of the string fields
+ */
+type ListOneGroupResponse struct {
+	Group     []byte
+	Version   Version //the protocol version of the layout
+}
+
+func (r *ListOneGroupResponse) SetVersion(version Version) {
+	r.Version = version
+}
+
+func (r *ListOneGroupResponse) MarshalBinary() ([]byte, error) {
+	return joinRecords("Group", [][]byte{r.Group}, r.Version.GroupStatSize())
+}
+
+func (r *ListOneGroupResponse) UnmarshalBinary(data []byte) error {
+	if len(data) != r.Version.GroupStatSize() {
+		return bodyLengthError(len(data))
+	}
+	r.Group = data
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package proto
+
+import (
+	"testing"
+	"bytes"
+)
+
+func TestTrackerMessages(t *testing.T) {
+	var ref = FileRef{GroupName:"group1", Filename:"M00/00/00/wKgBaF1.txt"}
+	var messages = []Message{
+		&QueryStoreRequest{},
+		&QueryStoreRequest{optionalGroup{GroupName:"group1"}},
+		&QueryStoreAllRequest{optionalGroup{GroupName:"group1"}},
+		&QueryFetchRequest{ref},
+		&QueryUpdateRequest{ref},
+		&QueryFetchAllRequest{ref},
+		&ListGroupsRequest{},
+		&ListStoragesRequest{groupStorage{GroupName:"group1"}},
+		&ListStoragesRequest{groupStorage{GroupName:"group1", StorageId:"192.168.100.200"}},
+		&DeleteStorageRequest{groupStorage{GroupName:"group1", StorageId:"100001"}},
+		&QueryStoreResponse{GroupName:"group1", ServerAddr:ServerAddr{IpAddr:"10.0.0.1", Port:23000}, StorePathIndex:2},
+		&QueryStoreAllResponse{GroupName:"group1", Servers:[]ServerAddr{{"10.0.0.1", 23000}, {"10.0.0.2", 23001}}, StorePathIndex:1},
+		&QueryFetchResponse{GroupName:"group1", IpAddrs:[]string{"10.0.0.1"}, Port:23000},
+		&QueryFetchResponse{GroupName:"group1", IpAddrs:[]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, Port:23000},
+		&ListGroupsResponse{Groups:[][]byte{make([]byte, GROUP_STAT_SIZE), bytes.Repeat([]byte{1}, GROUP_STAT_SIZE)}},
+		&ListStoragesResponse{Storages:[][]byte{make([]byte, STORAGE_STAT_SIZE)}},
+		&ListOneGroupRequest{groupOnly{GroupName:"group1"}},
+		&ListOneGroupResponse{Group:bytes.Repeat([]byte{2}, GROUP_STAT_SIZE)},
+		&DeleteGroupRequest{groupOnly{GroupName:"group1"}},
+		&SetTrunkServerRequest{groupStorage{GroupName:"group1"}},
+		&SetTrunkServerRequest{groupStorage{GroupName:"group1", StorageId:"100001"}},
+		&SetTrunkServerResponse{StorageId:"100001"},
+		&TrackerStatusRequest{},
+		&TrackerStatusResponse{IsLeader:true, RunningTime:3600, RestartInterval:10},
+	}
+	for _,msg := range messages {
+		roundTrip(t, msg)
+	}
+
+	if cmd := (&QueryStoreRequest{}).Cmd(); cmd != TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE {
+		t.Errorf("query store cmd %d without group", cmd)
+	}
+	if cmd := (&QueryStoreAllRequest{optionalGroup{GroupName:"group1"}}).Cmd(); cmd != TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL {
+		t.Errorf("query store all cmd %d with group", cmd)
+	}
+	data,_ := (&QueryStoreResponse{GroupName:"group1", ServerAddr:ServerAddr{IpAddr:"10.0.0.1", Port:23000}}).MarshalBinary()
+	if len(data) != TRACKER_QUERY_STORAGE_STORE_BODY_LEN {
+		t.Errorf("query store response length %d", len(data))
+	}
+}
+
+func TestTrackerMessagesInvalid(t *testing.T) {
+	var invalid = []Message{
+		&QueryStoreRequest{optionalGroup{GroupName:"group_name_too_long"}},
+		&QueryFetchRequest{FileRef{GroupName:"group_name_too_long", Filename:"a"}},
+		&ListStoragesRequest{groupStorage{GroupName:"group1", StorageId:"storage_id_too_long"}},
+		&QueryStoreResponse{GroupName:"group1", ServerAddr:ServerAddr{IpAddr:"1234:5678:9abc::1", Port:23000}},
+		&QueryStoreAllResponse{GroupName:"group1"},
+		&QueryFetchResponse{GroupName:"group1"},
+		&ListGroupsResponse{Groups:[][]byte{make([]byte, GROUP_STAT_SIZE - 1)}},
+		&ListOneGroupRequest{},
+		&ListOneGroupResponse{},
+		&SetTrunkServerResponse{StorageId:"storage_id_too_long"},
+	}
+	for _,msg := range invalid {
+		if _,err := msg.MarshalBinary(); err == nil {
+			t.Errorf("invalid %T %+v is marshaled", msg, msg)
+		}
+	}
+
+	var bodies = []struct {
+		msg    Message
+		body   []byte
+	}{
+		{&QueryStoreRequest{}, make([]byte, 3)},
+		{&QueryFetchRequest{}, make([]byte, FDFS_GROUP_NAME_MAX_LEN - 1)},
+		{&DeleteStorageRequest{}, make([]byte, FDFS_GROUP_NAME_MAX_LEN + FDFS_STORAGE_ID_MAX_SIZE)},
+		{&QueryStoreResponse{}, make([]byte, TRACKER_QUERY_STORAGE_STORE_BODY_LEN - 1)},
+		{&QueryStoreAllResponse{}, make([]byte, FDFS_GROUP_NAME_MAX_LEN + 1)},
+		{&QueryStoreAllResponse{}, make([]byte, TRACKER_QUERY_STORAGE_STORE_BODY_LEN + 1)},
+		{&QueryFetchResponse{}, make([]byte, TRACKER_QUERY_STORAGE_FETCH_BODY_LEN + 1)},
+		{&ListStoragesResponse{}, make([]byte, STORAGE_STAT_SIZE + 1)},
+		{&ListGroupsRequest{}, make([]byte, 1)},
+		{&DeleteGroupRequest{}, make([]byte, FDFS_GROUP_NAME_MAX_LEN + 1)},
+		{&ListOneGroupResponse{}, make([]byte, GROUP_STAT_SIZE * 2)},
+		{&SetTrunkServerResponse{}, make([]byte, FDFS_STORAGE_ID_MAX_SIZE)},
+		{&TrackerStatusResponse{}, make([]byte, trackerStatusLen - 1)},
+	}
+	for _,b := range bodies {
+		if err := b.msg.UnmarshalBinary(b.body); err == nil {
+			t.Errorf("%T of body length %d is unmarshaled", b.msg, len(b.body))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package proto
+
+import (
+	"strconv"
+)
+
+/**
+ * the protocol version, switches the sizes of the ip address fields and the
+ * stat records, the zero value stands for V5
+ */
+type Version int
+
+const (
+	V5 Version = 5 //FastDFS 5.x and the earlier, the ip address fields hold IPv4 only
+	V6 Version = 6 //FastDFS 6.x, the ip address fields are widened for IPv6
+)
+
+const (
+	IPV6_ADDRESS_SIZE = 46 //INET6_ADDRSTRLEN, the size of the ip address fields of V6
+	GROUP_STAT_NAME_SIZE_V6 = FDFS_GROUP_NAME_MAX_LEN + 8 //the group name of the group stat record is 8 bytes aligned
+)
+
+/**
+ * the message of which the layout depends on the protocol version, the
+ * version is set before marshaling and unmarshaling
+ */
+type Versioned interface {
+	SetVersion(version Version)
+}
+
+func (v Version) String() string {
+	if v == 0 {
+		return V5.String()
+	}
+
+	return "V" + strconv.Itoa(int(v))
+}
+
+/**
+ * get the size of the ip address fields
+ *
+ * @return FDFS_IPADDR_SIZE for V5, IPV6_ADDRESS_SIZE for V6
+ */
+func (v Version) IpAddrSize() int {
+	if v >= V6 {
+		return IPV6_ADDRESS_SIZE
+	}
+
+	return FDFS_IPADDR_SIZE
+}
+
+/**
+ * get the size of the group name field of the group stat record
+ */
+func (v Version) GroupStatNameSize() int {
+	if v >= V6 {
+		return GROUP_STAT_NAME_SIZE_V6
+	}
+
+	return FDFS_GROUP_NAME_MAX_LEN + 1
+}
+
+/**
+ * get the size of a group stat record
+ */
+func (v Version) GroupStatSize() int {
+	return GROUP_STAT_SIZE - (FDFS_GROUP_NAME_MAX_LEN + 1) + v.GroupStatNameSize()
+}
+
+/**
+ * get the size of a storage stat record, it holds the ip address and the
+ * source ip address
+ */
+func (v Version) StorageStatSize() int {
+	return STORAGE_STAT_SIZE + 2 * (v.IpAddrSize() - FDFS_IPADDR_SIZE)
+}
+
+/**
+ * the size of the ip address and the port
+ */
+func (v Version) serverAddrLen() int {
+	return v.IpAddrSize() - 1 + FDFS_PROTO_PKG_LEN_SIZE
+}
+
+/**
+ * the body length of QueryStoreResponse
+ */
+func (v Version) storeBodyLen() int {
+	return FDFS_GROUP_NAME_MAX_LEN + v.IpAddrSize() + FDFS_PROTO_PKG_LEN_SIZE
+}
+
+/**
+ * the body length of QueryFetchResponse with one ip address
+ */
+func (v Version) fetchBodyLen() int {
+	return FDFS_GROUP_NAME_MAX_LEN + v.IpAddrSize() - 1 + FDFS_PROTO_PKG_LEN_SIZE
+}
@@ -0,0 +1,63 @@
+package proto
+
+import (
+	"testing"
+	"bytes"
+)
+
+func TestVersion(t *testing.T) {
+	if Version(0).IpAddrSize() != FDFS_IPADDR_SIZE || Version(0).GroupStatSize() != GROUP_STAT_SIZE || Version(0).StorageStatSize() != STORAGE_STAT_SIZE {
+		t.Fatalf("the zero version is not V5")
+	}
+	if V6.IpAddrSize() != IPV6_ADDRESS_SIZE || V6.GroupStatSize() != GROUP_STAT_SIZE + 7 || V6.StorageStatSize() != STORAGE_STAT_SIZE + 60 {
+		t.Fatalf("sizes of V6 %d %d %d", V6.IpAddrSize(), V6.GroupStatSize(), V6.StorageStatSize())
+	}
+	if Version(0).String() != "V5" || V6.String() != "V6" {
+		t.Fatalf("version names %s %s", Version(0), V6)
+	}
+}
+
+func TestV6Messages(t *testing.T) {
+	const ipv6 = "2001:db8:85a3:8d3:1319:8a2e:370:7348"
+	var messages = []Message{
+		&QueryStoreResponse{GroupName:"group1", ServerAddr:ServerAddr{IpAddr:ipv6, Port:23000}, StorePathIndex:2, Version:V6},
+		&QueryStoreAllResponse{GroupName:"group1", Servers:[]ServerAddr{{ipv6, 23000}, {"10.0.0.2", 23001}}, StorePathIndex:1, Version:V6},
+		&QueryFetchResponse{GroupName:"group1", IpAddrs:[]string{ipv6, "fe80::1", "10.0.0.3"}, Port:23000, Version:V6},
+		&ListGroupsResponse{Groups:[][]byte{bytes.Repeat([]byte{1}, V6.GroupStatSize())}, Version:V6},
+		&ListStoragesResponse{Storages:[][]byte{make([]byte, V6.StorageStatSize()), make([]byte, V6.StorageStatSize())}, Version:V6},
+		&ListOneGroupResponse{Group:make([]byte, V6.GroupStatSize()), Version:V6},
+		&ListStoragesRequest{groupStorage{GroupName:"group1", StorageId:ipv6, Version:V6}},
+		&DeleteStorageRequest{groupStorage{GroupName:"group1", StorageId:ipv6, Version:V6}},
+		&QueryFileInfoResponse{FileSize:100, CreateTimestamp:1600000000, Crc32:0x12345678, SourceIpAddr:ipv6, Version:V6},
+	}
+	for _,msg := range messages {
+		roundTrip(t, msg)
+	}
+
+	data,_ := (&QueryStoreResponse{GroupName:"group1", ServerAddr:ServerAddr{IpAddr:ipv6, Port:23000}, Version:V6}).MarshalBinary()
+	if len(data) != TRACKER_QUERY_STORAGE_STORE_BODY_LEN + IPV6_ADDRESS_SIZE - FDFS_IPADDR_SIZE {
+		t.Errorf("query store response length %d", len(data))
+	}
+
+	// the layouts of the versions differ
+	var bodies = []struct {
+		msg    Message
+		body   []byte
+	}{
+		{&QueryStoreResponse{Version:V6}, make([]byte, TRACKER_QUERY_STORAGE_STORE_BODY_LEN)},
+		{&QueryFetchResponse{Version:V6}, make([]byte, TRACKER_QUERY_STORAGE_FETCH_BODY_LEN)},
+		{&ListOneGroupResponse{Version:V6}, make([]byte, GROUP_STAT_SIZE)},
+		{&ListStoragesResponse{Version:V6}, make([]byte, STORAGE_STAT_SIZE)},
+		{&QueryFileInfoResponse{Version:V6}, make([]byte, 3 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_IPADDR_SIZE)},
+		{&QueryFileInfoResponse{}, make([]byte, 3 * FDFS_PROTO_PKG_LEN_SIZE + IPV6_ADDRESS_SIZE)},
+		{&ListStoragesRequest{}, append(make([]byte, FDFS_GROUP_NAME_MAX_LEN), ipv6...)},
+	}
+	for _,b := range bodies {
+		if err := b.msg.UnmarshalBinary(b.body); err == nil {
+			t.Errorf("%T of body length %d is unmarshaled", b.msg, len(b.body))
+		}
+	}
+	if _,err := (&ListStoragesRequest{groupStorage{GroupName:"group1", StorageId:ipv6}}).MarshalBinary(); err == nil {
+		t.Errorf("the IPv6 address is marshaled by V5")
+	}
+}
@@ -1,6 +1,7 @@
 package fastdfs
 
 import (
+	"context"
 	"io"
 	"fmt"
 	"strings"
@@ -8,34 +9,41 @@ import (
 	"net"
 	"strconv"
 	"crypto/md5"
+	"github.com/go/fastdfs/proto"
 )
 
 const (
-	FDFS_PROTO_CMD_QUIT = 82
-	TRACKER_PROTO_CMD_SERVER_LIST_GROUP = 91
-	TRACKER_PROTO_CMD_SERVER_LIST_STORAGE = 92
-	TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE = 93
-	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE = 101
-	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE = 102
-	TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE = 103
-	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE = 104
-	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL = 105
-	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL = 106
-	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL = 107
-	TRACKER_PROTO_CMD_RESP = 100
-	FDFS_PROTO_CMD_ACTIVE_TEST = 111
-	STORAGE_PROTO_CMD_UPLOAD_FILE = 11
-	STORAGE_PROTO_CMD_DELETE_FILE = 12
-	STORAGE_PROTO_CMD_SET_METADATA = 13
-	STORAGE_PROTO_CMD_DOWNLOAD_FILE = 14
-	STORAGE_PROTO_CMD_GET_METADATA = 15
-	STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE = 21
-	STORAGE_PROTO_CMD_QUERY_FILE_INFO = 22
-	STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE = 23  //create appender fil
-	STORAGE_PROTO_CMD_APPEND_FILE = 24  //append file
-	STORAGE_PROTO_CMD_MODIFY_FILE = 34  //modify appender file
-	STORAGE_PROTO_CMD_TRUNCATE_FILE = 36  //truncate appender file
-	STORAGE_PROTO_CMD_RESP = TRACKER_PROTO_CMD_RESP
+	FDFS_PROTO_CMD_QUIT = proto.FDFS_PROTO_CMD_QUIT
+	TRACKER_PROTO_CMD_SERVER_LIST_GROUP = proto.TRACKER_PROTO_CMD_SERVER_LIST_GROUP
+	TRACKER_PROTO_CMD_SERVER_LIST_STORAGE = proto.TRACKER_PROTO_CMD_SERVER_LIST_STORAGE
+	TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE = proto.TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE
+	TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP = proto.TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP
+	TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER = proto.TRACKER_PROTO_CMD_SERVER_SET_TRUNK_SERVER
+	TRACKER_PROTO_CMD_SERVER_DELETE_GROUP = proto.TRACKER_PROTO_CMD_SERVER_DELETE_GROUP
+	TRACKER_PROTO_CMD_TRACKER_GET_STATUS = proto.TRACKER_PROTO_CMD_TRACKER_GET_STATUS
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE
+	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE
+	TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE
+	TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL
+	TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL = proto.TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL
+	TRACKER_PROTO_CMD_RESP = proto.TRACKER_PROTO_CMD_RESP
+	FDFS_PROTO_CMD_ACTIVE_TEST = proto.FDFS_PROTO_CMD_ACTIVE_TEST
+	STORAGE_PROTO_CMD_UPLOAD_FILE = proto.STORAGE_PROTO_CMD_UPLOAD_FILE
+	STORAGE_PROTO_CMD_DELETE_FILE = proto.STORAGE_PROTO_CMD_DELETE_FILE
+	STORAGE_PROTO_CMD_SET_METADATA = proto.STORAGE_PROTO_CMD_SET_METADATA
+	STORAGE_PROTO_CMD_DOWNLOAD_FILE = proto.STORAGE_PROTO_CMD_DOWNLOAD_FILE
+	STORAGE_PROTO_CMD_GET_METADATA = proto.STORAGE_PROTO_CMD_GET_METADATA
+	STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE = proto.STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE
+	STORAGE_PROTO_CMD_QUERY_FILE_INFO = proto.STORAGE_PROTO_CMD_QUERY_FILE_INFO
+	STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE = proto.STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE
+	STORAGE_PROTO_CMD_APPEND_FILE = proto.STORAGE_PROTO_CMD_APPEND_FILE
+	STORAGE_PROTO_CMD_MODIFY_FILE = proto.STORAGE_PROTO_CMD_MODIFY_FILE
+	STORAGE_PROTO_CMD_TRUNCATE_FILE = proto.STORAGE_PROTO_CMD_TRUNCATE_FILE
+	STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME = proto.STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME
+	STORAGE_PROTO_CMD_CREATE_LINK = proto.STORAGE_PROTO_CMD_CREATE_LINK
+	STORAGE_PROTO_CMD_RESP = proto.STORAGE_PROTO_CMD_RESP
 	FDFS_STORAGE_STATUS_INIT = 0
 	FDFS_STORAGE_STATUS_WAIT_SYNC = 1
 	FDFS_STORAGE_STATUS_SYNCING = 2
@@ -50,27 +58,30 @@ const (
 /**
  * for overwrite all old metadata
  */
-const STORAGE_SET_METADATA_FLAG_OVERWRITE = 'O'
+const STORAGE_SET_METADATA_FLAG_OVERWRITE = proto.STORAGE_SET_METADATA_FLAG_OVERWRITE
 
 /**
  * for replace, insert when the meta item not exist, otherwise update it
  */
 const (
-	STORAGE_SET_METADATA_FLAG_MERGE = 'M'
-	FDFS_PROTO_PKG_LEN_SIZE = 8
-	FDFS_PROTO_CMD_SIZE = 1
-	FDFS_GROUP_NAME_MAX_LEN = 16
-	FDFS_IPADDR_SIZE = 16
-	FDFS_DOMAIN_NAME_MAX_SIZE = 128
-	FDFS_VERSION_SIZE = 6
-	FDFS_STORAGE_ID_MAX_SIZE = 16
+	STORAGE_SET_METADATA_FLAG_MERGE = proto.STORAGE_SET_METADATA_FLAG_MERGE
+	FDFS_PROTO_PKG_LEN_SIZE = proto.FDFS_PROTO_PKG_LEN_SIZE
+	FDFS_PROTO_CMD_SIZE = proto.FDFS_PROTO_CMD_SIZE
+	FDFS_GROUP_NAME_MAX_LEN = proto.FDFS_GROUP_NAME_MAX_LEN
+	FDFS_IPADDR_SIZE = proto.FDFS_IPADDR_SIZE
+	FDFS_DOMAIN_NAME_MAX_SIZE = proto.FDFS_DOMAIN_NAME_MAX_SIZE
+	FDFS_VERSION_SIZE = proto.FDFS_VERSION_SIZE
+	FDFS_STORAGE_ID_MAX_SIZE = proto.FDFS_STORAGE_ID_MAX_SIZE
+	IPV6_ADDRESS_SIZE = proto.IPV6_ADDRESS_SIZE
+	GROUP_STAT_NAME_SIZE_V6 = proto.GROUP_STAT_NAME_SIZE_V6
+	FDFS_MAX_SERVER_ID = (1 << 24) - 1 //the source encoded in the filename is the storage id when not greater than it
 	FDFS_RECORD_SEPERATOR = "\u0001"
 	FDFS_FIELD_SEPERATOR = "\u0002"
-	TRACKER_QUERY_STORAGE_FETCH_BODY_LEN = FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1 + FDFS_PROTO_PKG_LEN_SIZE
-	TRACKER_QUERY_STORAGE_STORE_BODY_LEN = FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE + FDFS_PROTO_PKG_LEN_SIZE
+	TRACKER_QUERY_STORAGE_FETCH_BODY_LEN = proto.TRACKER_QUERY_STORAGE_FETCH_BODY_LEN
+	TRACKER_QUERY_STORAGE_STORE_BODY_LEN = proto.TRACKER_QUERY_STORAGE_STORE_BODY_LEN
 
-	FDFS_FILE_EXT_NAME_MAX_LEN = 6
-	FDFS_FILE_PREFIX_MAX_LEN = 16
+	FDFS_FILE_EXT_NAME_MAX_LEN = proto.FDFS_FILE_EXT_NAME_MAX_LEN
+	FDFS_FILE_PREFIX_MAX_LEN = proto.FDFS_FILE_PREFIX_MAX_LEN
 	FDFS_FILE_PATH_LEN = 10
 	FDFS_FILENAME_BASE64_LENGTH = 27
 	FDFS_TRUNK_FILE_INFO_LEN = 16
@@ -80,14 +91,23 @@ const (
 	ERR_NO_EINVAL = 22
 	ERR_NO_ENOSPC = 28
 	ECONNREFUSED = 61
+	ERR_NO_EOPNOTSUPP = 95
 	ERR_NO_EALREADY = 114
 	INFINITE_FILE_SIZE = 256 * 1024 * 1024 * 1024 * 1024 * 1024
 	APPENDER_FILE_SIZE = INFINITE_FILE_SIZE
 	TRUNK_FILE_MARK_SIZE = 512 * 1024 * 1024 * 1024 * 1024 * 1024
 	NORMAL_LOGIC_FILENAME_LENGTH = FDFS_FILE_PATH_LEN + FDFS_FILENAME_BASE64_LENGTH + FDFS_FILE_EXT_NAME_MAX_LEN + 1
 	TRUNK_LOGIC_FILENAME_LENGTH = NORMAL_LOGIC_FILENAME_LENGTH + FDFS_TRUNK_FILE_INFO_LEN
-	PROTO_HEADER_CMD_INDEX = FDFS_PROTO_PKG_LEN_SIZE
-	PROTO_HEADER_STATUS_INDEX = FDFS_PROTO_PKG_LEN_SIZE + 1
+	PROTO_HEADER_CMD_INDEX = proto.PROTO_HEADER_CMD_INDEX
+	PROTO_HEADER_STATUS_INDEX = proto.PROTO_HEADER_STATUS_INDEX
+)
+
+/**
+ * the protocol versions, switch the sizes of the ip address fields and the stat records
+ */
+const (
+	PROTO_VERSION_5 = proto.V5 //FastDFS 5.x and the earlier, IPv4 only
+	PROTO_VERSION_6 = proto.V6 //FastDFS 6.x with IPv6
 )
 
 func GetStorageStatusCaption(status byte) string {
@@ -223,6 +243,65 @@ func RecvPackage(in io.Reader,  expectCmd byte, expectBodyLen int64) (*RecvPacka
 	return NewRecvPackageInfo(0, body), nil
 }
 
+/**
+ * send the request, the content of the stream request is sent by the caller
+ * after it, trace the request write phase
+ *
+ * @param ctx  the context of the operation
+ * @param addr the server address
+ * @param out  the output stream
+ * @param req  the request
+ * @return EINVAL error if the request is invalid
+ */
+func (c *Config) writeRequest(ctx context.Context, addr net.Addr, out io.Writer, req proto.Request) error {
+	c.setProtoVersion(req)
+	pkg,err := proto.Encode(req)
+	if err != nil {
+		return &Error{Errno:ERR_NO_EINVAL, Cmd:req.Cmd(), Message:err.Error()}
+	}
+	var span = c.startSpan(ctx, PhaseRequestWrite, req.Cmd(), addr)
+	bytes,err := out.Write(pkg)
+	span.End(int64(bytes), err)
+
+	return err
+}
+
+/**
+ * receive the response, the body is decoded when the errno is 0,
+ * trace the response wait and the response read phases
+ *
+ * @param ctx  the context of the operation
+ * @param cmd  the command sent
+ * @param addr the server address
+ * @param in   input stream
+ * @param res  the response to decode the body to
+ * @return the errno and the body length of the response
+ */
+func (c *Config) readResponse(ctx context.Context, cmd byte, addr net.Addr, in io.Reader, res proto.Message) (byte, int64, error) {
+	var span = c.startSpan(ctx, PhaseResponseWait, cmd, addr)
+	header,err := RecvHeader(in, TRACKER_PROTO_CMD_RESP, -1)
+	span.End(0, err)
+	if err != nil {
+		return 0, 0, err
+	}
+	if header.Errno != 0 {
+		return header.Errno, 0, nil
+	}
+
+	span = c.startSpan(ctx, PhaseResponseRead, cmd, addr)
+	var body = make([]byte, header.BodyLen)
+	bytes,err := io.ReadFull(in, body)
+	if err != nil {
+		err = fmt.Errorf("recv package size %d != %d", bytes, header.BodyLen)
+	} else {
+		c.setProtoVersion(res)
+		err = res.UnmarshalBinary(body)
+	}
+	span.End(int64(bytes), err)
+
+	return 0, int64(bytes), err
+}
+
 /**
  * split metadata to name value pair array
  *
@@ -475,15 +554,28 @@ func Md5(source []byte) string {
  * @return token string
  */
 func GetToken(remoteFilename string, ts int, secretKey string) (string, error) {
-	bsFilename,err := ConvertUTF8ToBytes([]byte(remoteFilename), GCharset)
+	return GetTokenByCharset(remoteFilename, ts, secretKey, GCharset)
+}
+
+/**
+ * get token for file URL
+ *
+ * @param remote_filename the filename return by FastDFS server
+ * @param ts              unix timestamp, unit: second
+ * @param secret_key      the secret key
+ * @param charset         the charset to encode
+ * @return token string
+ */
+func GetTokenByCharset(remoteFilename string, ts int, secretKey string, charset string) (string, error) {
+	bsFilename,err := ConvertUTF8ToBytes([]byte(remoteFilename), charset)
 	if err != nil {
 		return "", err
 	}
-	bsKey,err := ConvertUTF8ToBytes([]byte(secretKey), GCharset)
+	bsKey,err := ConvertUTF8ToBytes([]byte(secretKey), charset)
 	if err != nil {
 		return "", err
 	}
-	bsTimestamp,err := ConvertUTF8ToBytes([]byte(strconv.Itoa(ts)), GCharset)
+	bsTimestamp,err := ConvertUTF8ToBytes([]byte(strconv.Itoa(ts)), charset)
 	if err != nil {
 		return "", err
 	}
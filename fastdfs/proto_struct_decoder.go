@@ -3,16 +3,50 @@ package fastdfs
 import (
 	"reflect"
 	"fmt"
+	"github.com/go/fastdfs/proto"
 )
 
 type ProtoStructDecoder struct {
-
+	charset string //empty for the global setting
+	version proto.Version //the protocol version of the record layout, 0 for PROTO_VERSION_5
 }
 
 func NewProtoStructDecoder() *ProtoStructDecoder {
 	return new(ProtoStructDecoder)
 }
 
+/**
+ * Constructor
+ *
+ * @param charset the charset of string fields
+ */
+func NewProtoStructDecoderByCharset(charset string) *ProtoStructDecoder {
+	return &ProtoStructDecoder{
+		charset:charset,
+	}
+}
+
+/**
+ * Constructor
+ *
+ * @param charset the charset of string fields
+ * @param version the protocol version of the record layout
+ */
+func NewProtoStructDecoderByVersion(charset string, version proto.Version) *ProtoStructDecoder {
+	return &ProtoStructDecoder{
+		charset:charset,
+		version:version,
+	}
+}
+
+/**
+ * decode byte array to structs
+ *
+ * @param bs              the byte array
+ * @param types           the struct (or pointer of it) implements StructBaseInterface
+ * @param fieldsTotalSize the byte size of one struct
+ * @return the structs, with the same kind (struct or pointer) as types
+ */
 func (p *ProtoStructDecoder) Decode(bs []byte, types interface{}, fieldsTotalSize int) ([]interface{}, error) {
 	if len(bs) % fieldsTotalSize != 0 {
 		return nil, fmt.Errorf("byte array length: %d is invalid", len(bs))
@@ -21,13 +55,29 @@ func (p *ProtoStructDecoder) Decode(bs []byte, types interface{}, fieldsTotalSiz
 	var count = len(bs) / fieldsTotalSize
 	var offset int
 	var results = make([]interface{}, count)
+	var typ = reflect.TypeOf(types)
+	var isPtr = typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
 
 	offset = 0
 	for i := 0; i < len(results); i++ {
-		results[i] = reflect.New(reflect.TypeOf(types).Elem()).Interface()
-		results[i].(*StructBase).SetFields(bs, offset)
+		var val = reflect.New(typ)
+		var s,ok = val.Interface().(StructBaseInterface)
+		if !ok {
+			return nil, fmt.Errorf("type %s does not implement StructBaseInterface", typ)
+		}
+		s.setCharset(p.charset)
+		s.setProtoVersion(p.version)
+		s.SetFields(bs, offset)
+		if isPtr {
+			results[i] = val.Interface()
+		} else {
+			results[i] = val.Elem().Interface()
+		}
 		offset += fieldsTotalSize
 	}
 
 	return results, nil
-}
\ No newline at end of file
+}
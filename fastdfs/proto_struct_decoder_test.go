@@ -16,3 +16,56 @@ func TestNewProtoStructDecoder(t *testing.T) {
 		fmt.Println(aaa[i])
 	}
 }
+
+func TestProtoStructDecoderGroupStat(t *testing.T) {
+	var buf = make([]byte, GetGroupFieldsTotalSize() * 2)
+	copy(buf, "group1")
+	copy(buf[GetGroupFieldsTotalSize():], "group2")
+
+	stats,err := NewProtoStructDecoderByCharset(DefaultCharset).Decode(buf, StructGroupStat{}, GetGroupFieldsTotalSize())
+	if err != nil {
+		panic(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("count %d, expect 2", len(stats))
+	}
+	var stat = stats[1].(StructGroupStat)
+	if name := stat.GetGroupName(); name != "group2" {
+		t.Fatalf("group name %q, expect group2", name)
+	}
+}
+
+func TestProtoStructDecoderV6(t *testing.T) {
+	// the layouts agree with the proto package
+	if GetStorageFieldsTotalSizeByVersion(PROTO_VERSION_6) != PROTO_VERSION_6.StorageStatSize() ||
+		GetGroupFieldsTotalSizeByVersion(PROTO_VERSION_6) != PROTO_VERSION_6.GroupStatSize() ||
+		GetStorageFieldsTotalSizeByVersion(PROTO_VERSION_5) != PROTO_VERSION_5.StorageStatSize() {
+		t.Fatalf("fields total size %d %d", GetStorageFieldsTotalSizeByVersion(PROTO_VERSION_6), GetGroupFieldsTotalSizeByVersion(PROTO_VERSION_6))
+	}
+
+	var size = GetStorageFieldsTotalSizeByVersion(PROTO_VERSION_6)
+	var buf = make([]byte, size)
+	var fields = storageFieldsOf(PROTO_VERSION_6)
+	copy(buf[fields[FIELD_INDEX_IP_ADDR].offset:], "2001:db8::1")
+	copy(buf[fields[FIELD_INDEX_SRC_IP_ADDR].offset:], "2001:db8::2")
+	copy(buf[fields[FIELD_INDEX_STORAGE_PORT].offset:], Long2Buff(23000))
+	stats,err := NewProtoStructDecoderByVersion(DefaultCharset, PROTO_VERSION_6).Decode(buf, &StructStorageStat{}, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stat = stats[0].(*StructStorageStat)
+	if stat.GetIpAddr() != "2001:db8::1" || stat.GetSrcIpAddr() != "2001:db8::2" || stat.GetStoragePort() != 23000 {
+		t.Fatalf("storage %s %s:%d", stat.GetSrcIpAddr(), stat.GetIpAddr(), stat.GetStoragePort())
+	}
+
+	size = GetGroupFieldsTotalSizeByVersion(PROTO_VERSION_6)
+	buf = make([]byte, size)
+	copy(buf, "group1")
+	copy(buf[groupFieldsOf(PROTO_VERSION_6)[GROUP_FIELD_INDEX_TOTAL_MB].offset:], Long2Buff(1024))
+	if stats,err = NewProtoStructDecoderByVersion(DefaultCharset, PROTO_VERSION_6).Decode(buf, StructGroupStat{}, size); err != nil {
+		t.Fatal(err)
+	}
+	if group := stats[0].(StructGroupStat); group.GetGroupName() != "group1" || group.GetTotalMB() != 1024 {
+		t.Fatalf("group %s total %d", group.GetGroupName(), group.GetTotalMB())
+	}
+}
@@ -0,0 +1,263 @@
+package fastdfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// the default chunk size of resumable upload
+const DEFAULT_UPLOAD_CHUNK_SIZE = 4 * 1024 * 1024
+
+/**
+ * progress of resumable upload, saved in the checkpoint file
+ */
+type UploadCheckpoint struct {
+	GroupName       string   `json:"group_name"`
+	RemoteFilename  string   `json:"remote_filename"`
+	FileSize        int64    `json:"file_size"`  //size of the local file
+	ModTime         int64    `json:"mod_time"`   //modify time of the local file, unit: nanosecond
+	ChunkSize       int      `json:"chunk_size"`
+	Offset          int64    `json:"offset"`     //bytes uploaded
+	Chunks          []uint32 `json:"chunks"`     //crc32 of the uploaded chunks
+}
+
+/**
+ * upload local file by chunks to an appender file, the progress is saved in
+ * the checkpoint file after each chunk, so that a failed upload continues
+ * from the last good chunk by calling Upload again.
+ */
+type ResumableUploader struct {
+	storageClient       *StorageClient
+	localFilename       string
+	checkpointFilename  string
+	chunkSize           int
+}
+
+/**
+ * Constructor
+ *
+ * @param storageClient      the storage client
+ * @param localFilename      local filename to upload
+ * @param checkpointFilename the file to save the progress
+ */
+func NewResumableUploader(storageClient *StorageClient, localFilename, checkpointFilename string) *ResumableUploader {
+	return &ResumableUploader{
+		storageClient:storageClient,
+		localFilename:localFilename,
+		checkpointFilename:checkpointFilename,
+		chunkSize:DEFAULT_UPLOAD_CHUNK_SIZE,
+	}
+}
+
+/**
+ * set the chunk size, the chunk size of the checkpoint is used when resuming
+ *
+ * @param chunkSize the chunk size, <= 0 for the default
+ */
+func (u *ResumableUploader) SetChunkSize(chunkSize int) {
+	if chunkSize <= 0 {
+		chunkSize = DEFAULT_UPLOAD_CHUNK_SIZE
+	}
+	u.chunkSize = chunkSize
+}
+
+/**
+ * load the checkpoint file
+ *
+ * @return the checkpoint, nil when the file not exists
+ */
+func (u *ResumableUploader) LoadCheckpoint() (*UploadCheckpoint, error) {
+	data,err := os.ReadFile(u.checkpointFilename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var checkpoint = new(UploadCheckpoint)
+	if err = json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+/**
+ * upload the file or continue the upload recorded in the checkpoint file,
+ * the checkpoint file is removed when the upload completes
+ *
+ * @param group_name    the group name to upload file to, can be empty
+ * @param file_ext_name file ext name, do not include dot(.)
+ * @param meta_list     meta info array
+ * @return the file id of the uploaded file if success, return null if fail
+ */
+func (u *ResumableUploader) Upload(groupName, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return u.upload(u.storageClient, groupName, fileExtName, metaList)
+}
+
+/**
+ * context version of Upload
+ */
+func (u *ResumableUploader) UploadCtx(ctx context.Context, groupName, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var fileId *FileID
+	var err = u.storageClient.withContext(ctx, func(c *StorageClient) (err error) {
+		fileId,err = u.upload(c, groupName, fileExtName, metaList)
+		return err
+	})
+
+	return fileId, err
+}
+
+func (u *ResumableUploader) upload(c *StorageClient, groupName, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	file,err := os.Open(u.localFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat,err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint,err := u.LoadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != nil && (checkpoint.FileSize != stat.Size() || checkpoint.ModTime != stat.ModTime().UnixNano()) {
+		// the local file is changed, the uploaded content is useless.
+		c.DeleteFile(checkpoint.GroupName, checkpoint.RemoteFilename)
+		checkpoint = nil
+	}
+	if checkpoint != nil {
+		if err = u.verify(c, file, checkpoint); err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			checkpoint = nil
+		}
+	}
+
+	var buff []byte
+	if checkpoint == nil {
+		checkpoint = &UploadCheckpoint{
+			FileSize:stat.Size(),
+			ModTime:stat.ModTime().UnixNano(),
+			ChunkSize:u.chunkSize,
+		}
+		if buff,err = u.readChunk(file, checkpoint, 0); err != nil {
+			return nil, err
+		}
+		fileId,err := c.UploadAppenderBufferByGroup(groupName, buff, fileExtName, metaList)
+		if err != nil {
+			return nil, err
+		}
+		checkpoint.GroupName = fileId.GetGroupName()
+		checkpoint.RemoteFilename = fileId.GetFilename()
+		checkpoint.Offset = int64(len(buff))
+		checkpoint.Chunks = append(checkpoint.Chunks, crc32.ChecksumIEEE(buff))
+		if err = u.saveCheckpoint(checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	for checkpoint.Offset < checkpoint.FileSize {
+		if buff,err = u.readChunk(file, checkpoint, checkpoint.Offset); err != nil {
+			return nil, err
+		}
+		if _,err = c.AppendBuffer(checkpoint.GroupName, checkpoint.RemoteFilename, buff); err != nil {
+			return nil, err
+		}
+		checkpoint.Offset += int64(len(buff))
+		checkpoint.Chunks = append(checkpoint.Chunks, crc32.ChecksumIEEE(buff))
+		if err = u.saveCheckpoint(checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = os.Remove(u.checkpointFilename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return NewFileID(checkpoint.GroupName, checkpoint.RemoteFilename)
+}
+
+/**
+ * verify the remote file with the checkpoint, the content after the last
+ * good chunk is truncated and the checkpoint is rolled back to it
+ *
+ * @param file       the local file
+ * @param checkpoint the checkpoint to verify
+ */
+func (u *ResumableUploader) verify(c *StorageClient, file *os.File, checkpoint *UploadCheckpoint) error {
+	fileInfo,err := c.QueryFileInfo(checkpoint.GroupName, checkpoint.RemoteFilename)
+	if err != nil {
+		return err
+	}
+
+	var remoteSize = fileInfo.GetFileSize()
+	for len(checkpoint.Chunks) > 0 {
+		var index = len(checkpoint.Chunks) - 1
+		var chunkOffset = int64(index) * int64(checkpoint.ChunkSize)
+		if checkpoint.Offset <= remoteSize {
+			buff,err := c.DownloadOffsetBuffer(checkpoint.GroupName, checkpoint.RemoteFilename, int(chunkOffset), int(checkpoint.Offset - chunkOffset))
+			if err != nil {
+				return err
+			}
+			if crc32.ChecksumIEEE(buff) == checkpoint.Chunks[index] {
+				break
+			}
+		}
+		checkpoint.Chunks = checkpoint.Chunks[:index]
+		checkpoint.Offset = chunkOffset
+	}
+	if len(checkpoint.Chunks) == 0 {
+		// the first chunk is bad, upload again.
+		c.DeleteFile(checkpoint.GroupName, checkpoint.RemoteFilename)
+		return ErrNotFound
+	}
+
+	if remoteSize > checkpoint.Offset {
+		if _,err = c.TruncateFileBySize(checkpoint.GroupName, checkpoint.RemoteFilename, int(checkpoint.Offset)); err != nil {
+			return err
+		}
+	}
+
+	return u.saveCheckpoint(checkpoint)
+}
+
+/**
+ * read the chunk at the offset of the local file
+ */
+func (u *ResumableUploader) readChunk(file *os.File, checkpoint *UploadCheckpoint, offset int64) ([]byte, error) {
+	var length = checkpoint.FileSize - offset
+	if length > int64(checkpoint.ChunkSize) {
+		length = int64(checkpoint.ChunkSize)
+	}
+	var buff = make([]byte, length)
+	if _,err := file.ReadAt(buff, offset); err != nil && !(err == io.EOF && length == 0) {
+		return nil, err
+	}
+
+	return buff, nil
+}
+
+/**
+ * save the checkpoint file, the file is replaced by rename so that
+ * a crash leaves the old or the new checkpoint
+ */
+func (u *ResumableUploader) saveCheckpoint(checkpoint *UploadCheckpoint) error {
+	data,err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	var tmpFilename = u.checkpointFilename + ".tmp"
+	if err = os.WriteFile(tmpFilename, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFilename, u.checkpointFilename)
+}
@@ -0,0 +1,84 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+func TestResumableUploader(t *testing.T) {
+	listener,server := startAppenderServer(t, 25000)
+	defer listener.Close()
+
+	var addr = listener.Addr().(*net.TCPAddr)
+	storageServer,err := NewStorageServer(addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	var storageClient = NewStorageClientByServer(nil, storageServer)
+
+	var dir = t.TempDir()
+	var localFilename = filepath.Join(dir, "local.bin")
+	var checkpointFilename = filepath.Join(dir, "local.bin.checkpoint")
+	var content = make([]byte, 60000)
+	for i := range content {
+		content[i] = byte(i * 5)
+	}
+	if err = os.WriteFile(localFilename, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var uploader = NewResumableUploader(storageClient, localFilename, checkpointFilename)
+	uploader.SetChunkSize(10000)
+	if _,err = uploader.Upload("", "bin", nil); !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("upload err %v, expect %v", err, ErrNoSpace)
+	}
+	checkpoint,err := uploader.LoadCheckpoint()
+	if err != nil || checkpoint == nil || checkpoint.Offset != 20000 || len(checkpoint.Chunks) != 2 {
+		t.Fatalf("checkpoint %+v, err %v", checkpoint, err)
+	}
+
+	// the appended chunk is lost and the last recorded chunk is corrupted
+	var remote = append([]byte{}, content[:25000]...)
+	remote[15000]++
+	server.set(checkpoint.RemoteFilename, remote, 1 << 20)
+
+	// the chunk size of the checkpoint is used
+	uploader = NewResumableUploader(storageClient, localFilename, checkpointFilename)
+	results,err := uploader.Upload("", "bin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results.GetGroupName() != "group1" || results.GetFilename() != checkpoint.RemoteFilename {
+		t.Fatalf("results %v, expect %s", results, checkpoint.RemoteFilename)
+	}
+	if data,_ := server.get(results.GetFilename()); !bytes.Equal(data, content) {
+		t.Fatalf("content mismatch, len %d", len(data))
+	}
+	if _,err = os.Stat(checkpointFilename); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("checkpoint file is not removed, err %v", err)
+	}
+
+	// the remote file is deleted, upload again
+	uploader.SetChunkSize(40000)
+	server.set("unused", nil, 50000)
+	if _,err = uploader.Upload("", "bin", nil); !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("upload err %v, expect %v", err, ErrNoSpace)
+	}
+	checkpoint,_ = uploader.LoadCheckpoint()
+	storageClient.DeleteFile(checkpoint.GroupName, checkpoint.RemoteFilename)
+	server.set("unused", nil, 1 << 20)
+	if results,err = uploader.Upload("", "bin", nil); err != nil {
+		t.Fatal(err)
+	}
+	if results.GetFilename() == checkpoint.RemoteFilename {
+		t.Fatalf("deleted remote file %s is reused", results.GetFilename())
+	}
+	if data,_ := server.get(results.GetFilename()); !bytes.Equal(data, content) {
+		t.Fatalf("content mismatch, len %d", len(data))
+	}
+}
@@ -0,0 +1,281 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff     = 2 * time.Second
+	DefaultRetryMultiplier     = 2.0
+	DefaultRetryJitter         = 0.2
+)
+
+/**
+ * retry policy of storage operations, a failed operation is retried with
+ * exponential backoff, reads and uploads fail over to another storage server.
+ * AppendFile and ModifyFile are replayed only when the request is known not
+ * applied by the server, the uploads and downloads with a callback are replayed
+ * only when the callback can be rewound or has not received data.
+ */
+type RetryPolicy struct {
+	MaxAttempts      int           //attempts including the first one
+	InitialBackoff   time.Duration //backoff before the second attempt
+	MaxBackoff       time.Duration
+	Multiplier       float64       //backoff multiplier of each attempt
+	Jitter           float64       //0 to 1, the random part of the backoff
+	RetryableErrnos  []byte        //ERR_NO_EIO for network errors, ECONNREFUSED for refused connections
+}
+
+/**
+ * Constructor with default backoff, retry busy, refused and io errors
+ *
+ * @param maxAttempts attempts including the first one, <= 0 for the default
+ */
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+
+	return &RetryPolicy{
+		MaxAttempts:maxAttempts,
+		InitialBackoff:DefaultRetryInitialBackoff,
+		MaxBackoff:DefaultRetryMaxBackoff,
+		Multiplier:DefaultRetryMultiplier,
+		Jitter:DefaultRetryJitter,
+		RetryableErrnos:[]byte{ERR_NO_EBUSY, ECONNREFUSED, ERR_NO_EIO},
+	}
+}
+
+/**
+ * check if the error is retryable
+ *
+ * @param err the error of the operation
+ * @return true if the errno of the error is retryable
+ */
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var errno byte
+	var e *Error
+	var netErr net.Error
+	if errors.As(err, &e) {
+		errno = e.Errno
+	} else if errors.Is(err, syscall.ECONNREFUSED) {
+		errno = ECONNREFUSED
+	} else if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		errno = ERR_NO_EIO
+	} else {
+		// local error, such as file not exist
+		return false
+	}
+	for _,retryable := range p.RetryableErrnos {
+		if retryable == errno {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+ * get the backoff before the attempt
+ *
+ * @param attempt the attempt, start from 2
+ * @return the backoff
+ */
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	var backoff = float64(p.InitialBackoff)
+	for i := 2; i < attempt && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(backoff)
+}
+
+/**
+ * run the operation with the retry policy of the settings, each attempt runs
+ * on a copy of the client which keeps the state of the attempt and avoids
+ * the storage servers failed before, the client itself is not changed.
+ *
+ * @param cmd       the command of the operation
+ * @param canReplay check if the failed attempt can be replayed, nil for idempotent operation
+ * @param fn        the operation
+ * @return the error of the last attempt
+ */
+func (s *StorageClient) withRetry(cmd byte, canReplay func(c *StorageClient, err error) bool, fn func(c *StorageClient) error) error {
+	var policy = s.config.getRetryPolicy()
+	if policy == nil || policy.MaxAttempts <= 1 {
+		var c = s.fork(s.connCtx)
+		c.cmd = cmd
+		var err = fn(c)
+		s.setErrno(c.GetErrorCode())
+		return err
+	}
+
+	var excludes []net.Addr
+	for attempt := 1; ; attempt++ {
+		var c = s.fork(s.connCtx)
+		c.cmd = cmd
+		c.excludes = excludes
+		var err = fn(c)
+		s.setErrno(c.GetErrorCode())
+		if err == nil || attempt >= policy.MaxAttempts || !policy.IsRetryable(err) {
+			return err
+		}
+		if s.storageServer != nil && !isServerError(err) {
+			// the connection of the server is out of sync
+			return err
+		}
+		if canReplay != nil && !canReplay(c, err) {
+			return err
+		}
+		var addr = failedAddr(c, err)
+		if addr != nil {
+			excludes = append(excludes, addr)
+		}
+
+		var backoff = policy.Backoff(attempt + 1)
+		s.config.getLogger().Warn("fastdfs: retry", "attempt", attempt, "addr", addrString(addr), "errno", ErrorCode(err),
+			"backoff", backoff, "error", err)
+		var timer = time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-contextOf(s.connCtx).Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+/**
+ * get the storage server failed the attempt
+ *
+ * @return the server connected or dialed, nil for none
+ */
+func failedAddr(c *StorageClient, err error) net.Addr {
+	if c.storageAddr != nil {
+		return c.storageAddr
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return opErr.Addr
+	}
+
+	return nil
+}
+
+/**
+ * check if the failed request is not sent to storage server
+ */
+func notSent(c *StorageClient, err error) bool {
+	return c.storageAddr == nil
+}
+
+/**
+ * check if the failed request is not applied by storage server,
+ * the server answers the request with an errno
+ */
+func notApplied(c *StorageClient, err error) bool {
+	return c.storageAddr == nil || isServerError(err)
+}
+
+/**
+ * check if the error is the errno returned by the server
+ */
+func isServerError(err error) bool {
+	var e *Error
+
+	return errors.As(err, &e) && e.Addr != ""
+}
+
+/**
+ * get the replay check of the operation sending the upload callback, the
+ * callback is rewound before replaying, the callback which can not be
+ * rewound is replayed only when it is not sent
+ *
+ * @param callback the upload callback
+ * @param check    the replay check of the operation, such as notApplied
+ */
+func replayUpload(callback UploadCallback, check func(c *StorageClient, err error) bool) func(c *StorageClient, err error) bool {
+	var rewind = rewindFunc(callback)
+
+	return func(c *StorageClient, err error) bool {
+		if rewind == nil {
+			return notSent(c, err)
+		}
+		return check(c, err) && rewind() == nil
+	}
+}
+
+/**
+ * get the function to rewind the upload callback before replaying
+ *
+ * @param callback the upload callback
+ * @return the rewind function, nil if the callback can not be rewound
+ */
+func rewindFunc(callback UploadCallback) func() error {
+	switch cb := callback.(type) {
+	case *UploadBuff:
+		return func() error {
+			return nil
+		}
+	case *UploadStream:
+		var seeker,ok = cb.inputStream.(io.Seeker)
+		if !ok {
+			return nil
+		}
+		pos,err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil
+		}
+		return func() error {
+			_,err := seeker.Seek(pos, io.SeekStart)
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * check if the address is one of the addresses
+ */
+func containsAddr(addrs []net.Addr, addr net.Addr) bool {
+	for _,a := range addrs {
+		if a.String() == addr.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// download callback counting the received bytes, the failed download is not
+// replayed after the callback received data.
+type countingDownloadCallback struct {
+	callback    DownloadCallback
+	received    bool
+}
+
+func (d *countingDownloadCallback) Recv(fileSize int, data []byte, bytes int) (int, error) {
+	d.received = true
+
+	return d.callback.Recv(fileSize, data, bytes)
+}
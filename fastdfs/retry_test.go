@@ -0,0 +1,142 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var policy = NewRetryPolicy(0)
+	if policy.MaxAttempts != DefaultRetryMaxAttempts {
+		t.Fatalf("max attempts %d != %d", policy.MaxAttempts, DefaultRetryMaxAttempts)
+	}
+	var retryable = []error{newError(ERR_NO_EBUSY, STORAGE_PROTO_CMD_UPLOAD_FILE, nil), io.EOF,
+		&net.OpError{Op:"dial", Err:os.ErrDeadlineExceeded}}
+	for _,err := range retryable {
+		if !policy.IsRetryable(err) {
+			t.Errorf("%v is not retryable", err)
+		}
+	}
+	var notRetryable = []error{nil, ErrNotFound, os.ErrNotExist, context.Canceled, context.DeadlineExceeded}
+	for _,err := range notRetryable {
+		if policy.IsRetryable(err) {
+			t.Errorf("%v is retryable", err)
+		}
+	}
+
+	for attempt := 2; attempt < 10; attempt++ {
+		var backoff = policy.Backoff(attempt)
+		if backoff > policy.MaxBackoff || backoff < time.Duration(float64(policy.InitialBackoff) * (1 - policy.Jitter)) {
+			t.Errorf("backoff %v of attempt %d out of range", backoff, attempt)
+		}
+	}
+	if policy.Backoff(3) < policy.Backoff(2) && policy.Jitter == 0 {
+		t.Errorf("backoff is not increased")
+	}
+}
+
+func TestStorageClientRetry(t *testing.T) {
+	listener,server := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+	var port = listener.Addr().(*net.TCPAddr).Port
+
+	// nothing listens on 127.0.0.2, the connection is refused
+	var tracker = startQueryTracker(t, []string{"127.0.0.2", "127.0.0.1"}, []string{"127.0.0.1", "127.0.0.2"}, port)
+	defer tracker.Close()
+	var policy = NewRetryPolicy(3)
+	policy.InitialBackoff = time.Millisecond
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil), WithRetryPolicy(policy)))
+	var storageClient = client.NewStorageClient()
+
+	// upload fails over to the other server
+	results,err := storageClient.UploadAppenderBuffer([]byte("hello"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upload is not replayed when the response is lost, the stored file is not duplicated
+	server.fail(0, 1)
+	server.lock.Lock()
+	var count = server.count
+	server.lock.Unlock()
+	if _,err = storageClient.UploadAppenderBuffer([]byte("hello"), "txt", nil); err == nil {
+		t.Fatalf("upload without response succeeded")
+	}
+	server.lock.Lock()
+	count = server.count - count
+	server.lock.Unlock()
+	if count != 1 {
+		t.Fatalf("%d files stored by the lost upload, expect 1", count)
+	}
+
+	// append is replayed when the server answers busy
+	server.fail(1, 0)
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	if data,_ := server.get(results.GetFilename()); string(data) != "hello world" {
+		t.Fatalf("content %q after append", data)
+	}
+
+	// append is not replayed when the response is lost
+	server.fail(0, 1)
+	if _,err = storageClient.AppendBuffer(results.GetGroupName(), results.GetFilename(), []byte("!")); err == nil {
+		t.Fatalf("append without response succeeded")
+	}
+	if data,_ := server.get(results.GetFilename()); string(data) != "hello world!" {
+		t.Fatalf("content %q after lost append", data)
+	}
+
+	// download is replayed when the response is lost
+	server.fail(0, 1)
+	data,err := storageClient.DownloadBuffer(results.GetGroupName(), results.GetFilename())
+	if err != nil || !bytes.Equal(data, []byte("hello world!")) {
+		t.Fatalf("download %q, err %v", data, err)
+	}
+
+	// the reader is opened again when the server answers busy
+	server.fail(1, 0)
+	reader,err := storageClient.OpenReader(results.GetGroupName(), results.GetFilename(), 6, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data,err = io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "world!" {
+		t.Fatalf("read %q, err %v", data, err)
+	}
+
+	// delete is replayed, the file deleted by the lost request is not found then
+	server.fail(0, 1)
+	if _,err = storageClient.DeleteFile(results.GetGroupName(), results.GetFilename()); err != nil {
+		t.Fatal(err)
+	}
+	if _,err = storageClient.DeleteFile(results.GetGroupName(), results.GetFilename()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete err %v, expect %v", err, ErrNotFound)
+	}
+
+	// the attempts are limited
+	storageServer,err := NewStorageServer("127.0.0.1", port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	storageClient = client.NewStorageClientByServer(nil, storageServer)
+	server.fail(5, 0)
+	if _,err = storageClient.UploadAppenderBuffer([]byte("hello"), "txt", nil); !errors.Is(err, ErrBusy) {
+		t.Fatalf("upload err %v, expect %v", err, ErrBusy)
+	}
+	server.lock.Lock()
+	var busy = server.busy
+	server.lock.Unlock()
+	if busy != 5 - policy.MaxAttempts {
+		t.Fatalf("%d attempts, expect %d", 5 - busy, policy.MaxAttempts)
+	}
+	server.fail(0, 0)
+}
@@ -2,7 +2,7 @@ package fastdfs
 
 import (
 	"net"
-	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -51,5 +51,5 @@ func (s *ServerInfo) Connect() (net.Conn, error) {
 	// TODO set address reuse.
 	// TODO set read timeout.
 
-	return net.DialTimeout("tcp", fmt.Sprintf("%s:%d", s.ipAddr	, s.port), time.Duration(GConnectTimeout) * time.Microsecond)
+	return net.DialTimeout("tcp", net.JoinHostPort(s.ipAddr, strconv.Itoa(s.port)), time.Duration(GConnectTimeout) * time.Microsecond)
 }
\ No newline at end of file
@@ -1,13 +1,15 @@
 package fastdfs
 
 import (
-	"os"
-	"strings"
 	"errors"
+	"os"
+	"strconv"
+	"sync/atomic"
 	"io"
 	"net"
 	"fmt"
-	"runtime/debug"
+	"time"
+	"github.com/go/fastdfs/proto"
 )
 
 var base64 = NewBase64ByDetailed('-', '_', '.', 0)
@@ -15,18 +17,27 @@ var base64 = NewBase64ByDetailed('-', '_', '.', 0)
 type StorageClient struct {
 	trackerServer   *TrackerServer
 	storageServer   *StorageServer
-	errno           byte
+	errno           int32        //error code of last call, accessed atomically
+	connCtx         *connContext //context of the running operation, nil for none
+	config          *Config      //nil for the global settings
+	excludes        []net.Addr   //the storage servers failed before, set on the copy running one attempt
+	storageAddr     net.Addr     //the storage server connected by the attempt, set on the copy running it
+	cmd             byte         //command of the running operation, set on the copy running it
 }
 
 /**
- * constructor using global settings in class ClientGlobal
+ * constructor using global settings in class ClientGlobal,
+ * every operation runs on a copy of the client which keeps the state of the
+ * operation, so the client is safe for concurrent use
  */
 func NewStorageClient() *StorageClient {
 	return new(StorageClient)
 }
 
 /**
- * constructor with tracker server and storage server
+ * constructor with tracker server and storage server,
+ * the connections of the servers are shared by the operations,
+ * so the client is safe for concurrent use only when both are null
  *
  * @param trackerServer the tracker server, can be null
  * @param storageServer the storage server, can be null
@@ -45,77 +56,36 @@ func NewStorageClientByServer(trackerServer *TrackerServer, storageServer *Stora
  * @return the error code of last call
  */
 func (s *StorageClient) GetErrorCode() byte {
-	return s.errno
+	return byte(atomic.LoadInt32(&s.errno))
 }
 
-/**
- * upload file to storage server (by file name)
- *
- * @param local_filename local filename to upload
- * @param file_ext_name  file ext name, do not include dot(.), null to extract ext name from the local filename
- * @param meta_list      meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
- */
-func (s *StorageClient) UploadFile(localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.uploadFileByGroup(groupName, localFilename, fileExtName, metaList)
+func (s *StorageClient) setErrno(errno byte) {
+	atomic.StoreInt32(&s.errno, int32(errno))
 }
 
 /**
  * upload file to storage server (by file name)
  *
- * @param group_name     the group name to upload file to, can be empty
  * @param local_filename local filename to upload
  * @param file_ext_name  file ext name, do not include dot(.), null to extract ext name from the local filename
  * @param meta_list      meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) uploadFileByGroup(groupName, localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const cmd = STORAGE_PROTO_CMD_UPLOAD_FILE
-
-	return s.uploadFileByCmd(cmd, groupName, localFilename, fileExtName, metaList)
+func (s *StorageClient) UploadFile(localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(localFilename, WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
  * upload file to storage server (by file name)
  *
- * @param cmd            the command
  * @param group_name     the group name to upload file to, can be empty
  * @param local_filename local filename to upload
  * @param file_ext_name  file ext name, do not include dot(.), null to extract ext name from the local filename
  * @param meta_list      meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) uploadFileByCmd(cmd byte, groupName, localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	file,err := os.Open(localFilename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	if fileExtName == "" {
-		var nPos = strings.LastIndexByte(localFilename, '.')
-		if nPos > 0 && len(localFilename) - nPos <= FDFS_FILE_EXT_NAME_MAX_LEN + 1 {
-			fileExtName = localFilename[nPos + 1:]
-		}
-	}
-
-	stat,err := file.Stat()
-	if err != nil {
-		return nil, err
-	}
-
-	return s.doUploadFile(cmd, groupName, "", "", fileExtName, int(stat.Size()), NewUploadStream(file, int(stat.Size())), metaList)
+func (s *StorageClient) uploadFileByGroup(groupName, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(localFilename, WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -126,15 +96,10 @@ func (s *StorageClient) uploadFileByCmd(cmd byte, groupName, localFilename, file
  * @param length        the length of buff to upload
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadBufferOffset(fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.UploadBufferOffsetByGroup(groupName, fileBuff, offset, length, fileExtName, metaList)
+func (s *StorageClient) UploadBufferOffset(fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff[offset:offset + length], WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -146,13 +111,10 @@ func (s *StorageClient) UploadBufferOffset(fileBuff []byte, offset int, length i
  * @param length        the length of buff to upload
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadBufferOffsetByGroup(groupName string, fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_FILE, groupName, "", "", fileExtName, length, NewUploadBuff(fileBuff, offset, length), metaList)
+func (s *StorageClient) UploadBufferOffsetByGroup(groupName string, fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff[offset:offset + length], WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -161,15 +123,10 @@ func (s *StorageClient) UploadBufferOffsetByGroup(groupName string, fileBuff []b
  * @param file_buff     file content/buff
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadBuffer(fileBuff []byte, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.UploadBufferByGroup(groupName, fileBuff, fileExtName, metaList)
+func (s *StorageClient) UploadBuffer(fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff, WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -179,13 +136,10 @@ func (s *StorageClient) UploadBuffer(fileBuff []byte, fileExtName string, metaLi
  * @param file_buff     file content/buff
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadBufferByGroup(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_FILE, groupName, "", "", fileExtName, len(fileBuff), NewUploadBuff(fileBuff, 0, len(fileBuff)), metaList)
+func (s *StorageClient) UploadBufferByGroup(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff, WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -196,16 +150,10 @@ func (s *StorageClient) UploadBufferByGroup(groupName string, fileBuff []byte, f
  * @param callback      the write data callback object
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadCallback(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const masterFilename = ""
-	const prefixName = ""
-
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_FILE, groupName, masterFilename, prefixName, fileExtName, fileSize, callback, metaList)
+func (s *StorageClient) UploadCallback(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(callback, WithGroup(groupName), WithSize(int64(fileSize)), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -217,35 +165,10 @@ func (s *StorageClient) UploadCallback(groupName string, fileSize int, callback
  * @param local_filename  local filename to upload
  * @param file_ext_name   file ext name, do not include dot(.), null to extract ext name from the local filename
  * @param meta_list       meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadMasterFile(groupName, masterFilename, prefixName, localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	if groupName == "" || len(groupName) == 0 || masterFilename == "" || len(masterFilename) == 0 || prefixName == "" {
-		return nil, errors.New("invalid argument")
-	}
-
-	file,err := os.Open(localFilename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	if fileExtName == "" {
-		var nPos = strings.LastIndexByte(localFilename, '.')
-		if nPos > 0 && len(localFilename) - nPos <= FDFS_FILE_EXT_NAME_MAX_LEN + 1 {
-			fileExtName = localFilename[nPos + 1:]
-		}
-	}
-
-	stat,err := file.Stat()
-	if err != nil {
-		return nil, err
-	}
-
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE, groupName, masterFilename, prefixName, fileExtName, int(stat.Size()), NewUploadStream(file, int(stat.Size())), metaList)
+func (s *StorageClient) UploadMasterFile(groupName, masterFilename, prefixName, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(localFilename, WithSlave(groupName, masterFilename, prefixName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -257,17 +180,10 @@ func (s *StorageClient) UploadMasterFile(groupName, masterFilename, prefixName,
  * @param file_buff       file content/buff
  * @param file_ext_name   file ext name, do not include dot(.)
  * @param meta_list       meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadMasterBuffer(groupName, masterFilename, prefixName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	if groupName == "" || len(groupName) == 0 || masterFilename == "" || len(masterFilename) == 0 || prefixName == "" {
-		return nil, errors.New("invalid argument")
-	}
-
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE, groupName, masterFilename, prefixName, fileExtName, len(fileBuff), NewUploadBuff(fileBuff, 0, len(fileBuff)), metaList)
+func (s *StorageClient) UploadMasterBuffer(groupName, masterFilename, prefixName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff, WithSlave(groupName, masterFilename, prefixName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -281,17 +197,10 @@ func (s *StorageClient) UploadMasterBuffer(groupName, masterFilename, prefixName
  * @param length          the length of buff to upload
  * @param file_ext_name   file ext name, do not include dot(.)
  * @param meta_list       meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadMasterOffsetBuffer(groupName, masterFilename, prefixName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	if groupName == "" || len(groupName) == 0 || masterFilename == "" || len(masterFilename) == 0 || prefixName == "" {
-		return nil, errors.New("invalid argument")
-	}
-
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE, groupName, masterFilename, prefixName, fileExtName, length, NewUploadBuff(fileBuff, offset, length), metaList)
+func (s *StorageClient) UploadMasterOffsetBuffer(groupName, masterFilename, prefixName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff[offset:offset + length], WithSlave(groupName, masterFilename, prefixName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -304,13 +213,10 @@ func (s *StorageClient) UploadMasterOffsetBuffer(groupName, masterFilename, pref
  * @param callback        the write data callback object
  * @param file_ext_name   file ext name, do not include dot(.)
  * @param meta_list       meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadMasterCallback(groupName, masterFilename, prefixName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE, groupName, masterFilename, prefixName, fileExtName, fileSize, callback, metaList)
+func (s *StorageClient) UploadMasterCallback(groupName, masterFilename, prefixName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(callback, WithSlave(groupName, masterFilename, prefixName), WithSize(int64(fileSize)), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -319,15 +225,10 @@ func (s *StorageClient) UploadMasterCallback(groupName, masterFilename, prefixNa
  * @param local_filename local filename to upload
  * @param file_ext_name  file ext name, do not include dot(.), null to extract ext name from the local filename
  * @param meta_list      meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderFile(localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.UploadAppenderFileByGroup(groupName, localFilename, fileExtName, metaList)
+func (s *StorageClient) UploadAppenderFile(localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(localFilename, WithAppender(), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -337,15 +238,10 @@ func (s *StorageClient) UploadAppenderFile(localFilename, fileExtName string, me
  * @param local_filename local filename to upload
  * @param file_ext_name  file ext name, do not include dot(.), null to extract ext name from the local filename
  * @param meta_list      meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file </li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderFileByGroup(groupName, localFilename, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const cmd = STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE
-
-	return s.uploadFileByCmd(cmd, groupName, localFilename, fileExtName, metaList)
+func (s *StorageClient) UploadAppenderFileByGroup(groupName, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(localFilename, WithAppender(), WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -356,15 +252,10 @@ func (s *StorageClient) UploadAppenderFileByGroup(groupName, localFilename, file
  * @param length        the length of buff to upload
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderOffsetBuffer(fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.UploadAppenderOffsetBufferByGroup(groupName, fileBuff, offset, length, fileExtName, metaList)
+func (s *StorageClient) UploadAppenderOffsetBuffer(fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff[offset:offset + length], WithAppender(), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -376,13 +267,10 @@ func (s *StorageClient) UploadAppenderOffsetBuffer(fileBuff []byte, offset, leng
  * @param length        the length of buff to upload
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderOffsetBufferByGroup(groupName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, groupName, "", "", fileExtName, length, NewUploadBuff(fileBuff, offset, length), metaList)
+func (s *StorageClient) UploadAppenderOffsetBufferByGroup(groupName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff[offset:offset + length], WithAppender(), WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -391,15 +279,10 @@ func (s *StorageClient) UploadAppenderOffsetBufferByGroup(groupName string, file
  * @param file_buff     file content/buff
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderBuffer(fileBuff []byte, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const groupName = ""
-
-	return s.UploadAppenderOffsetBufferByGroup(groupName, fileBuff, 0, len(fileBuff)	, fileExtName, metaList)
+func (s *StorageClient) UploadAppenderBuffer(fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff, WithAppender(), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -409,13 +292,10 @@ func (s *StorageClient) UploadAppenderBuffer(fileBuff []byte, fileExtName string
  * @param file_buff     file content/buff
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderBufferByGroup(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, groupName, "", "", fileExtName, len(fileBuff), NewUploadBuff(fileBuff, 0, len(fileBuff)), metaList)
+func (s *StorageClient) UploadAppenderBufferByGroup(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(fileBuff, WithAppender(), WithGroup(groupName), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -426,16 +306,10 @@ func (s *StorageClient) UploadAppenderBufferByGroup(groupName string, fileBuff [
  * @param callback      the write data callback object
  * @param file_ext_name file ext name, do not include dot(.)
  * @param meta_list     meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li>results[0]: the group name to store the file</li></ul>
- * <ul><li>results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) UploadAppenderCallback(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) ([]string, error) {
-	const masterFilename = ""
-	const prefixName = ""
-
-	return s.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, groupName, masterFilename, prefixName, fileExtName, fileSize, callback, metaList)
+func (s *StorageClient) UploadAppenderCallback(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	return s.upload(callback, WithAppender(), WithGroup(groupName), WithSize(int64(fileSize)), WithExtName(fileExtName), WithMetadata(metaList...))
 }
 
 /**
@@ -447,18 +321,7 @@ func (s *StorageClient) UploadAppenderCallback(groupName string, fileSize int, c
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) AppendFile(groupName, appenderFilename, localFilename string) (int, error) {
-	file,err := os.Open(localFilename)
-	if err != nil {
-		return 0, err
-	}
-	defer file.Close()
-
-	stat,err := file.Stat()
-	if err != nil {
-		return 0, err
-	}
-
-	return s.doAppendFile(groupName, appenderFilename, int(stat.Size()), NewUploadStream(file, int(stat.Size())))
+	return resultCode(s.appendFile(groupName, appenderFilename, localFilename))
 }
 
 /**
@@ -470,7 +333,7 @@ func (s *StorageClient) AppendFile(groupName, appenderFilename, localFilename st
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) AppendBuffer(groupName, appenderFilename string, fileBuff []byte) (int, error) {
-	return s.doAppendFile(groupName, appenderFilename, len(fileBuff), NewUploadBuff(fileBuff, 0, len(fileBuff)))
+	return resultCode(s.appendFile(groupName, appenderFilename, fileBuff))
 }
 
 /**
@@ -484,7 +347,7 @@ func (s *StorageClient) AppendBuffer(groupName, appenderFilename string, fileBuf
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) AppendOffsetBuffer(groupName, appenderFilename string, fileBuff []byte, offset, length int) (int, error) {
-	return s.doAppendFile(groupName, appenderFilename, length, NewUploadBuff(fileBuff, offset, length))
+	return resultCode(s.appendFile(groupName, appenderFilename, fileBuff[offset:offset + length]))
 }
 
 /**
@@ -497,7 +360,7 @@ func (s *StorageClient) AppendOffsetBuffer(groupName, appenderFilename string, f
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) AppendCallback(groupName, appenderFilename string, fileSize int, callback UploadCallback) (int, error) {
-	return s.doAppendFile(groupName, appenderFilename, fileSize, callback)
+	return resultCode(s.appendFile(groupName, appenderFilename, callback, WithSize(int64(fileSize))))
 }
 
 /**
@@ -510,18 +373,7 @@ func (s *StorageClient) AppendCallback(groupName, appenderFilename string, fileS
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) ModifyFile(groupName, appenderFilename string, fileOffset int, localFilename string) (int, error) {
-	file,err := os.Open(localFilename)
-	if err != nil {
-		return 0, err
-	}
-	defer file.Close()
-
-	stat,err := file.Stat()
-	if err != nil {
-		return 0, err
-	}
-
-	return s.doModifyFile(groupName, appenderFilename, fileOffset, int(stat.Size()), NewUploadStream(file, int(stat.Size())))
+	return resultCode(s.modifyFile(groupName, appenderFilename, int64(fileOffset), localFilename))
 }
 
 /**
@@ -534,7 +386,7 @@ func (s *StorageClient) ModifyFile(groupName, appenderFilename string, fileOffse
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) ModifyBuffer(groupName, appenderFilename string, fileOffset int, fileBuff []byte) (int, error) {
-	return s.doModifyFile(groupName, appenderFilename, fileOffset, len(fileBuff), NewUploadBuff(fileBuff, 0, len(fileBuff)))
+	return resultCode(s.modifyFile(groupName, appenderFilename, int64(fileOffset), fileBuff))
 }
 
 /**
@@ -549,7 +401,7 @@ func (s *StorageClient) ModifyBuffer(groupName, appenderFilename string, fileOff
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) ModifyOffsetBuffer(groupName, appenderFilename string, fileOffset int, fileBuff []byte, bufferOffset, bufferLength int) (int, error) {
-	return s.doModifyFile(groupName, appenderFilename, fileOffset, bufferLength, NewUploadBuff(fileBuff, bufferOffset, bufferLength))
+	return resultCode(s.modifyFile(groupName, appenderFilename, int64(fileOffset), fileBuff[bufferOffset:bufferOffset + bufferLength]))
 }
 
 /**
@@ -563,7 +415,7 @@ func (s *StorageClient) ModifyOffsetBuffer(groupName, appenderFilename string, f
  * @return 0 for success, != 0 for error (error no)
  */
 func (s *StorageClient) ModifyCallback(groupName, appenderFilename string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
-	return s.doModifyFile(groupName, appenderFilename, fileOffset, modifySize, callback)
+	return resultCode(s.modifyFile(groupName, appenderFilename, int64(fileOffset), callback, WithSize(int64(modifySize))))
 }
 
 /**
@@ -577,166 +429,109 @@ func (s *StorageClient) ModifyCallback(groupName, appenderFilename string, fileO
  * @param file_size       the file size
  * @param callback        the write data callback object
  * @param meta_list       meta info array
- * @return 2 elements string array if success:<br>
- * <ul><li> results[0]: the group name to store the file</li></ul>
- * <ul><li> results[1]: the new created filename</li></ul>
- * return null if fail
+ * @return the file id of the new created file if success, return null if fail
  */
-func (s *StorageClient) doUploadFile(cmd byte, groupName, masterFilename, prefixName, fileExtName string, fileSize int, callback UploadCallback, metaList []NameValuePair) ([]string, error) {
+func (s *StorageClient) doUploadFile(cmd byte, groupName, masterFilename, prefixName, fileExtName string, fileSize int, callback UploadCallback, metaList []NameValuePair) (*FileID, error) {
+	var fileId *FileID
+	var op = &Operation{Cmd:cmd, GroupName:groupName, MasterFilename:masterFilename, PrefixName:prefixName,
+		FileExtName:fileExtName, Metadata:metaList, Size:int64(fileSize)}
+	var err = s.intercept(op, func(op *Operation) error {
+		// the file stored by the request without response is orphaned when
+		// replaying, so the upload is replayed only when it is not applied
+		return s.withRetry(op.Cmd, replayUpload(callback, notApplied), func(c *StorageClient) (err error) {
+			fileId,err = c.doUploadFileOnce(cmd, op.GroupName, op.MasterFilename, op.PrefixName, op.FileExtName, fileSize, callback, op.Metadata)
+			op.Result = fileId
+			return err
+		})
+	})
+
+	return fileId, err
+}
+
+/**
+ * one attempt of doUploadFile
+ */
+func (s *StorageClient) doUploadFileOnce(cmd byte, groupName, masterFilename, prefixName, fileExtName string, fileSize int, callback UploadCallback, metaList []NameValuePair) (*FileID, error) {
 	var (
-		header []byte
-		extNameBs []byte
-		newGroupName string
-		remoteFilename string
+		storageServer *StorageServer
 		bNewConnection bool
 		storageSocket  net.Conn
-		sizeBytes []byte
-		hexLenBytes []byte
-		masterFilenameBytes []byte
+		req proto.Request
 		bUploadSlave bool
-		offset int
-		bodyLen int
 	)
 	var err error
 
 	bUploadSlave = (groupName != "" && len(groupName) > 0) && (masterFilename != "" && len(masterFilename) > 0) && (prefixName != "")
 	if bUploadSlave {
-		bNewConnection,err = s.newUpdatableStorageConnection(groupName, masterFilename)
+		storageServer,bNewConnection,err = s.newUpdatableStorageConnection(groupName, masterFilename)
 	} else {
-		bNewConnection,err = s.newWritableStorageConnection(groupName)
+		storageServer,bNewConnection,err = s.newWritableStorageConnection(groupName)
 	}
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
 
-	if storageSocket,err = s.storageServer.GetSocket(); err != nil {
+	if storageSocket,err = s.getSocket(storageServer); err != nil {
 		return nil, err
 	}
 
-	extNameBs = make([]byte, FDFS_FILE_EXT_NAME_MAX_LEN)
-	if fileExtName != "" && len(fileExtName) > 0 {
-		var bs,err = ConvertBytesToUTF8([]byte(fileExtName), GCharset)
-		if err != nil {
-			return nil, err
-		}
-		var extNameLen = len(bs)
-		if extNameLen > FDFS_FILE_EXT_NAME_MAX_LEN {
-			extNameLen = FDFS_FILE_EXT_NAME_MAX_LEN
-		}
-		copy(extNameBs, bs[:extNameLen])
-	}
-
-	if bUploadSlave {
-		if masterFilenameBytes,err = ConvertBytesToUTF8([]byte(masterFilename), GCharset); err != nil {
-			return nil, err
-		}
-		sizeBytes = make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE)
-		bodyLen = len(sizeBytes) + FDFS_FILE_PREFIX_MAX_LEN + FDFS_FILE_EXT_NAME_MAX_LEN + len(masterFilenameBytes) + fileSize
-
-		hexLenBytes = Long2Buff(int64(len(masterFilename)))
-		copy(sizeBytes, hexLenBytes)
-		offset = len(hexLenBytes)
-	} else {
-		masterFilename = ""
-		sizeBytes = make([]byte, 1 + 1 * FDFS_PROTO_PKG_LEN_SIZE)
-		bodyLen = len(sizeBytes) + FDFS_FILE_EXT_NAME_MAX_LEN + fileSize
-
-		sizeBytes[0] = byte(s.storageServer.GetStorePathIndex())
-		offset = 1
-	}
-
-	hexLenBytes = Long2Buff(int64(fileSize))
-	copy(sizeBytes[offset:], hexLenBytes)
-
-	if header,err = PackHeader(cmd, int64(bodyLen), 0); err != nil {
+	extName,err := s.config.encodeString(fileExtName)
+	if err != nil {
 		return nil, err
 	}
-	var wholePkg = make([]byte, len(header) + bodyLen - fileSize)
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], sizeBytes)
-	offset = len(header) + len(sizeBytes)
 	if bUploadSlave {
-		var prefixNameBs = make([]byte, FDFS_FILE_PREFIX_MAX_LEN)
-		var bs,err = ConvertBytesToUTF8([]byte(prefixName), GCharset)
-		if err != nil {
+		var slaveReq = &proto.UploadSlaveFileRequest{FileExtName:extName, FileSize:int64(fileSize)}
+		if slaveReq.MasterFilename,err = s.config.encodeString(masterFilename); err != nil {
 			return nil, err
 		}
-		var prefixNameLen = len(bs)
-		if prefixNameLen > FDFS_FILE_PREFIX_MAX_LEN {
-			prefixNameLen = FDFS_FILE_PREFIX_MAX_LEN
+		if slaveReq.PrefixName,err = s.config.encodeString(prefixName); err != nil {
+			return nil, err
 		}
-		if prefixNameLen > 0 {
-			copy(prefixNameBs, bs)
+		req = slaveReq
+	} else {
+		req = &proto.UploadFileRequest{
+			StorePathIndex:byte(storageServer.GetStorePathIndex()),
+			FileSize:int64(fileSize),
+			FileExtName:extName,
+			Appender:cmd == STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE,
 		}
-
-		copy(wholePkg[offset:], prefixNameBs)
-		offset += len(prefixNameBs)
 	}
 
-	copy(wholePkg[offset:], extNameBs)
-	offset += len(extNameBs)
-
-	if bUploadSlave {
-		copy(wholePkg[offset:], masterFilenameBytes)
-		offset += len(masterFilenameBytes)
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return nil, err
 	}
 
-	if _,err = storageSocket.Write(wholePkg); err != nil {
+	if err = s.sendCallback(storageSocket, callback, cmd); err != nil {
 		return nil, err
 	}
 
-	errno,_ := callback.Send(storageSocket)
-	s.errno = byte(errno)
-	if s.errno != 0 {
-		return nil, nil
+	var res proto.UploadFileResponse
+	if err = s.recvResponse(storageServer, storageSocket, cmd, &res); err != nil {
+		return nil, err
 	}
-
-	pkgInfo,err := RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, -1)
+	fileId,err := NewFileID(res.GroupName, res.Filename)
 	if err != nil {
+		s.setErrno(ERR_NO_EINVAL)
+		s.deleteFile(storageServer, res.GroupName, res.Filename)
 		return nil, err
 	}
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		return nil, nil
-	}
-
-	if len(pkgInfo.Body) <= FDFS_GROUP_NAME_MAX_LEN {
-		return nil, fmt.Errorf("body length: %d <= %d", len(pkgInfo.Body), FDFS_GROUP_NAME_MAX_LEN)
-	}
-
-	newGroupName = strings.Trim(string(pkgInfo.Body[:FDFS_GROUP_NAME_MAX_LEN]), " \x00")
-	remoteFilename = string(pkgInfo.Body[FDFS_GROUP_NAME_MAX_LEN: FDFS_GROUP_NAME_MAX_LEN + len(pkgInfo.Body) - FDFS_GROUP_NAME_MAX_LEN])
-	var results = make([]string, 2)
-	results[0] = newGroupName
-	results[1] = remoteFilename
 
 	if metaList == nil || len(metaList) == 0 {
-		return results, nil
+		return fileId, nil
 	}
 
-	var result = 0
-	result,err = s.SetMetadata(newGroupName, remoteFilename, metaList, STORAGE_SET_METADATA_FLAG_OVERWRITE)
-	if err != nil || result != 0 {
-		if err != nil {
-			result = 5
-		}
-		s.errno = byte(result)
-		s.DeleteFile(newGroupName, remoteFilename)
-		// todo return nil or error?
-		// java is return null
+	if _,err = s.setMetadata(storageServer, res.GroupName, res.Filename, metaList, STORAGE_SET_METADATA_FLAG_OVERWRITE); err != nil {
+		s.setErrno(ErrorCode(err))
+		s.deleteFile(storageServer, res.GroupName, res.Filename)
 		return nil, err
 	}
 
-	return results, nil
+	return fileId, nil
 }
 
 /**
@@ -749,77 +544,61 @@ func (s *StorageClient) doUploadFile(cmd byte, groupName, masterFilename, prefix
  * @return return true for success, false for fail
  */
 func (s *StorageClient) doAppendFile(groupName, appenderFilename string, fileSize int, callback UploadCallback) (int, error) {
+	var result int
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_APPEND_FILE, GroupName:groupName, Filename:appenderFilename, Size:int64(fileSize)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, replayUpload(callback, notApplied), func(c *StorageClient) (err error) {
+			result,err = c.doAppendFileOnce(op.GroupName, op.Filename, fileSize, callback)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of doAppendFile
+ */
+func (s *StorageClient) doAppendFileOnce(groupName, appenderFilename string, fileSize int, callback UploadCallback) (int, error) {
 	var (
-		header []byte
+		storageServer *StorageServer
 		bNewConnection bool
 		storageSocket net.Conn
-		hexLenBytes []byte
-		appenderFilenameBytes []byte
-		offset int
-		bodyLen int
 	)
 	var err error
 
 	if groupName == "" || len(groupName) == 0 || appenderFilename == "" || len(appenderFilename) == 0 {
-		s.errno = ERR_NO_EINVAL
-		return int(s.errno), errors.New("ERR_NO_EINVAL")
+		s.setErrno(ERR_NO_EINVAL)
+		return ERR_NO_EINVAL, newInvalidError("group name and appender filename are required")
 	}
 
-	if bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
+	if storageServer,bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
 
-	if storageSocket,err = s.storageServer.GetSocket(); err != nil {
+	if storageSocket,err = s.getSocket(storageServer); err != nil {
 		return -1, err
 	}
 
-	if appenderFilenameBytes,err = ConvertBytesToUTF8([]byte(appenderFilename), GCharset); err != nil {
+	var req = &proto.AppendFileRequest{FileSize:int64(fileSize)}
+	if req.AppenderFilename,err = s.config.encodeString(appenderFilename); err != nil {
 		return -1, err
 	}
-	bodyLen = 2 * FDFS_PROTO_PKG_LEN_SIZE + len(appenderFilenameBytes) + fileSize
-
-	if header,err = PackHeader(STORAGE_PROTO_CMD_APPEND_FILE, int64(bodyLen), 0); err != nil {
-		return -1, err
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return resultOf(err), err
 	}
-	var wholePkg = make([]byte, len(header) + bodyLen - fileSize)
-	copy(wholePkg, header)
-	offset = len(header)
-
-	hexLenBytes = Long2Buff(int64(len(appenderFilename)))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	hexLenBytes = Long2Buff(int64(fileSize))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	copy(wholePkg[offset:], appenderFilenameBytes)
-	offset += len(appenderFilenameBytes)
-
-	if _,err = storageSocket.Write(wholePkg); err != nil {
-		return -1, err
-	}
-	if n,err := callback.Send(storageSocket); err != nil {
-		s.errno = byte(n)
-		return n, err
+	if err = s.sendCallback(storageSocket, callback, STORAGE_PROTO_CMD_APPEND_FILE); err != nil {
+		return int(ErrorCode(err)), err
 	}
 
-	pkgInfo,err := RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 0)
-	if err != nil {
-		return -1, err
-	}
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_APPEND_FILE, new(proto.EmptyResponse)); err != nil {
+		return resultOf(err), err
 	}
 
 	return 0, nil
@@ -836,81 +615,62 @@ func (s *StorageClient) doAppendFile(groupName, appenderFilename string, fileSiz
  * @return return true for success, false for fail
  */
 func (s *StorageClient) doModifyFile(groupName, appenderFilename string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
+	var result int
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_MODIFY_FILE, GroupName:groupName, Filename:appenderFilename,
+		Offset:int64(fileOffset), Size:int64(modifySize)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, replayUpload(callback, notApplied), func(c *StorageClient) (err error) {
+			result,err = c.doModifyFileOnce(op.GroupName, op.Filename, int(op.Offset), modifySize, callback)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of doModifyFile
+ */
+func (s *StorageClient) doModifyFileOnce(groupName, appenderFilename string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
 	var (
-		header []byte
+		storageServer *StorageServer
 		bNewConnection bool
 		storageSocket net.Conn
-		hexLenBytes []byte
-		appenderFilenameBytes []byte
-		offset int
-		bodyLen int
 	)
 	var err error
 
 	if groupName == "" || len(groupName) == 0 || appenderFilename == "" || len(appenderFilename) == 0 {
-		s.errno = ERR_NO_EINVAL
-		return int(s.errno), errors.New("ERR_NO_EINVAL")
+		s.setErrno(ERR_NO_EINVAL)
+		return ERR_NO_EINVAL, newInvalidError("group name and appender filename are required")
 	}
 
-	if bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
+	if storageServer,bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
 
-	if storageSocket,err = s.storageServer.GetSocket(); err != nil {
+	if storageSocket,err = s.getSocket(storageServer); err != nil {
 		return -1, err
 	}
 
-	if appenderFilenameBytes,err = ConvertBytesToUTF8([]byte(appenderFilename), GCharset); err != nil {
+	var req = &proto.ModifyFileRequest{FileOffset:int64(fileOffset), FileSize:int64(modifySize)}
+	if req.AppenderFilename,err = s.config.encodeString(appenderFilename); err != nil {
 		return -1, err
 	}
-	bodyLen = 3 * FDFS_PROTO_PKG_LEN_SIZE + len(appenderFilenameBytes) + modifySize
-
-	if header,err = PackHeader(STORAGE_PROTO_CMD_MODIFY_FILE, int64(bodyLen), 0); err != nil {
-		return -1, err
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return resultOf(err), err
 	}
-	var wholePkg = make([]byte, len(header) + bodyLen - modifySize)
-	copy(wholePkg, header)
-	offset = len(header)
-
-	hexLenBytes = Long2Buff(int64(len(appenderFilename)))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	hexLenBytes = Long2Buff(int64(fileOffset))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	hexLenBytes = Long2Buff(int64(modifySize))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	copy(wholePkg[offset:], appenderFilenameBytes)
-	offset += len(appenderFilenameBytes)
-
-	if _,err = storageSocket.Write(wholePkg); err != nil {
-		return -1, err
-	}
-	if n,err := callback.Send(storageSocket); err != nil {
-		s.errno = byte(n)
-		return n, err
+	if err = s.sendCallback(storageSocket, callback, STORAGE_PROTO_CMD_MODIFY_FILE); err != nil {
+		return int(ErrorCode(err)), err
 	}
 
-	pkgInfo,err := RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 0)
-	if err != nil {
-		return -1, err
-	}
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_MODIFY_FILE, new(proto.EmptyResponse)); err != nil {
+		return resultOf(err), err
 	}
 
 	return 0, nil
@@ -924,34 +684,71 @@ func (s *StorageClient) doModifyFile(groupName, appenderFilename string, fileOff
  * @return 0 for success, none zero for fail (error code)
  */
 func (s *StorageClient) DeleteFile(groupName, remoteFilename string) (int, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var result int
+	var maybeDeleted = false
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_DELETE_FILE, GroupName:groupName, Filename:remoteFilename}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, func(c *StorageClient, err error) bool {
+			maybeDeleted = maybeDeleted || !notApplied(c, err)
+			return true
+		}, func(c *StorageClient) (err error) {
+			result,err = c.deleteFileOnce(op.GroupName, op.Filename)
+			op.Result = result
+			if err != nil && maybeDeleted && errors.Is(err, ErrNotFound) {
+				// deleted by the failed attempt
+				c.setErrno(0)
+				return nil
+			}
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of DeleteFile
+ */
+func (s *StorageClient) deleteFileOnce(groupName, remoteFilename string) (int, error) {
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+
+	return s.deleteFile(storageServer, groupName, remoteFilename)
+}
+
+/**
+ * delete file from the storage server
+ *
+ * @param storageServer   the storage server
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @return 0 for success, none zero for fail (error code)
+ */
+func (s *StorageClient) deleteFile(storageServer *StorageServer, groupName, remoteFilename string) (int, error) {
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return -1, err
 	}
 
-	if err = s.sendPackage(STORAGE_PROTO_CMD_DELETE_FILE, groupName, remoteFilename); err != nil {
+	var req = new(proto.DeleteFileRequest)
+	if req.FileRef,err = s.config.fileRef(groupName, remoteFilename); err != nil {
 		return -1, err
 	}
-	pkgInfo,err := RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 0)
-	if err != nil {
-		return -1, err
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return resultOf(err), err
+	}
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_DELETE_FILE, new(proto.EmptyResponse)); err != nil {
+		return resultOf(err), err
 	}
 
-	s.errno = pkgInfo.Errno
-	return int(pkgInfo.Errno), nil
+	return 0, nil
 }
 
 /**
@@ -976,73 +773,58 @@ func (s *StorageClient) TruncateFile(groupName, appenderFilename string) (int, e
  * @return 0 for success, none zero for fail (error code)
  */
 func (s *StorageClient) TruncateFileBySize(groupName, appenderFilename string, truncatedFileSize int) (int, error) {
+	var result int
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_TRUNCATE_FILE, GroupName:groupName, Filename:appenderFilename, Size:int64(truncatedFileSize)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.truncateFileBySizeOnce(op.GroupName, op.Filename, int(op.Size))
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of TruncateFileBySize
+ */
+func (s *StorageClient) truncateFileBySizeOnce(groupName, appenderFilename string, truncatedFileSize int) (int, error) {
 	var (
-		header []byte
+		storageServer *StorageServer
 		bNewConnection bool
 		storageSocket net.Conn
-		hexLenBytes []byte
-		appenderFilenameBytes []byte
-		offset int
-		bodyLen int
 	)
 	var err error
 
 	if groupName == "" || len(groupName) == 0 || appenderFilename == "" || len(appenderFilename) == 0 {
-		s.errno = ERR_NO_EINVAL
-		return int(s.errno), errors.New("ERR_NO_EINVAL")
+		s.setErrno(ERR_NO_EINVAL)
+		return ERR_NO_EINVAL, newInvalidError("group name and appender filename are required")
 	}
 
-	if bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
+	if storageServer,bNewConnection,err = s.newUpdatableStorageConnection(groupName, appenderFilename); err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
 
-	if storageSocket,err = s.storageServer.GetSocket(); err != nil {
-		return -1, err
-	}
-
-	if appenderFilenameBytes,err = ConvertBytesToUTF8([]byte(appenderFilename), GCharset); err != nil {
+	if storageSocket,err = s.getSocket(storageServer); err != nil {
 		return -1, err
 	}
-	bodyLen = 2 * FDFS_PROTO_PKG_LEN_SIZE + len(appenderFilenameBytes)
 
-	if header,err = PackHeader(STORAGE_PROTO_CMD_TRUNCATE_FILE, int64(bodyLen), 0); err != nil {
+	var req = &proto.TruncateFileRequest{TruncatedFileSize:int64(truncatedFileSize)}
+	if req.AppenderFilename,err = s.config.encodeString(appenderFilename); err != nil {
 		return -1, err
 	}
-	var wholePkg = make([]byte, len(header) + bodyLen)
-	copy(wholePkg, header)
-	offset = len(header)
-
-	hexLenBytes = Long2Buff(int64(len(appenderFilename)))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	hexLenBytes = Long2Buff(int64(truncatedFileSize))
-	copy(wholePkg[offset:], hexLenBytes)
-	offset += len(hexLenBytes)
-
-	copy(wholePkg[offset:], appenderFilenameBytes)
-	offset += len(appenderFilenameBytes)
-
-	if _,err = storageSocket.Write(wholePkg); err != nil {
-		return -1, err
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return resultOf(err), err
 	}
 
-	pkgInfo,err := RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 0)
-	if err != nil {
-		return -1, err
-	}
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_TRUNCATE_FILE, new(proto.EmptyResponse)); err != nil {
+		return resultOf(err), err
 	}
 
 	return 0, nil
@@ -1056,10 +838,12 @@ func (s *StorageClient) TruncateFileBySize(groupName, appenderFilename string, t
  * @return file content/buff, return null if fail
  */
 func (s *StorageClient) DownloadBuffer(groupName, remoteFilename string) ([]byte, error) {
-	const fileOffset = 0
-	const downloadBytes = 0
+	var buff []byte
+	if err := s.download(groupName, remoteFilename, &buff); err != nil {
+		return nil, err
+	}
 
-	return s.DownloadOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes)
+	return buff, nil
 }
 
 /**
@@ -1072,39 +856,59 @@ func (s *StorageClient) DownloadBuffer(groupName, remoteFilename string) ([]byte
  * @return file content/buff, return null if fail
  */
 func (s *StorageClient) DownloadOffsetBuffer(groupName, remoteFilename string, fileOffset, downloadBytes int) ([]byte, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var buff []byte
+	if err := s.download(groupName, remoteFilename, &buff, WithRange(int64(fileOffset), int64(downloadBytes))); err != nil {
+		return nil, err
+	}
+
+	return buff, nil
+}
+
+/**
+ * download with retries, see DownloadOffsetBuffer
+ */
+func (s *StorageClient) downloadToBuffer(groupName, remoteFilename string, fileOffset, downloadBytes int) ([]byte, error) {
+	var result []byte
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE, GroupName:groupName, Filename:remoteFilename,
+		Offset:int64(fileOffset), Size:int64(downloadBytes)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.downloadToBufferOnce(op.GroupName, op.Filename, int(op.Offset), int(op.Size))
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of downloadToBuffer
+ */
+func (s *StorageClient) downloadToBufferOnce(groupName, remoteFilename string, fileOffset, downloadBytes int) ([]byte, error) {
+	storageServer,bNewConnection,err := s.newReadableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return nil, err
 	}
 
-	var pkgInfo *RecvPackageInfo
-
-	if err = s.sendDownloadPackage(groupName, remoteFilename, fileOffset, downloadBytes); err != nil {
+	if err = s.sendDownloadPackage(storageServer, groupName, remoteFilename, fileOffset, downloadBytes); err != nil {
 		return nil, err
 	}
-	if pkgInfo,err = RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, -1); err != nil {
+	var res proto.DownloadFileResponse
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_DOWNLOAD_FILE, &res); err != nil {
 		return nil, err
 	}
 
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		return nil, fmt.Errorf("errno:%d", s.errno)
-	}
-
-	return pkgInfo.Body, nil
+	return res.Content, nil
 }
 
 /**
@@ -1116,10 +920,7 @@ func (s *StorageClient) DownloadOffsetBuffer(groupName, remoteFilename string, f
  * @return 0 success, return none zero errno if fail
  */
 func (s *StorageClient) DownloadFile(groupName, remoteFilename, localFilename string) (int, error) {
-	const fileOffset = 0
-	const downloadBytes = 0
-
-	return s.DownloadFileByOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes, localFilename)
+	return resultCode(s.download(groupName, remoteFilename, localFilename))
 }
 
 /**
@@ -1133,20 +934,41 @@ func (s *StorageClient) DownloadFile(groupName, remoteFilename, localFilename st
  * @return 0 success, return none zero errno if fail
  */
 func (s *StorageClient) DownloadFileByOffsetBuffer(groupName, remoteFilename string, fileOffset, downloadBytes int, localFilename string) (int, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	return resultCode(s.download(groupName, remoteFilename, localFilename, WithRange(int64(fileOffset), int64(downloadBytes))))
+}
+
+/**
+ * download with retries, see DownloadFileByOffsetBuffer
+ */
+func (s *StorageClient) downloadToFile(groupName, remoteFilename string, fileOffset, downloadBytes int, localFilename string) (int, error) {
+	var result int
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE, GroupName:groupName, Filename:remoteFilename,
+		Offset:int64(fileOffset), Size:int64(downloadBytes)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.downloadToFileOnce(op.GroupName, op.Filename, int(op.Offset), int(op.Size), localFilename)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of downloadToFile
+ */
+func (s *StorageClient) downloadToFileOnce(groupName, remoteFilename string, fileOffset, downloadBytes int, localFilename string) (int, error) {
+	storageServer,bNewConnection,err := s.newReadableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return -1, err
 	}
@@ -1156,49 +978,37 @@ func (s *StorageClient) DownloadFileByOffsetBuffer(groupName, remoteFilename str
 		return -1, err
 	}
 	defer file.Close()
-	s.errno = 0
+	var success = false
 	defer func() {
-		if err != nil {
-			s.errno = ERR_NO_EIO
-		}
-		if s.errno != 0 {
+		if !success {
 			os.Remove(localFilename)
 		}
 	}()
-	if err = s.sendDownloadPackage(groupName, remoteFilename, fileOffset, downloadBytes); err != nil {
+	if err = s.sendDownloadPackage(storageServer, groupName, remoteFilename, fileOffset, downloadBytes); err != nil {
 		return -1, err
 	}
 
-	if header,err = RecvHeader(storageSocket, STORAGE_PROTO_CMD_RESP, -1); err != nil {
+	var start = time.Now()
+	var span = s.config.startSpan(contextOf(s.connCtx), PhaseResponseWait, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
+	header,err = RecvHeader(storageSocket, STORAGE_PROTO_CMD_RESP, -1)
+	span.End(0, err)
+	if err != nil {
+		s.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), 0, time.Since(start), 0, err)
 		return -1, err
 	}
-	s.errno = header.Errno
+	s.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), header.Errno, time.Since(start), int64(header.BodyLen), nil)
+	s.setErrno(header.Errno)
 	if header.Errno != 0 {
-		return int(header.Errno), fmt.Errorf("errno:%d", header.Errno)
+		return int(header.Errno), newError(header.Errno, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
 	}
 
-	var buff = make([]byte, 256 * 1024)
-	var remainBytes = header.BodyLen
-	var bytes int
-
-	for remainBytes > 0 {
-		var length = remainBytes
-		if length > len(buff) {
-			length = len(buff)
-		}
-		if bytes,err = storageSocket.Read(buff[:length]); err != nil {
-			return -1, err
-		}
-		if bytes < 0 {
-			return -1, fmt.Errorf("recv package size %d != %d", header.BodyLen - remainBytes, header.BodyLen)
-		}
-
-		if _,err = file.Write(buff[:bytes]); err != nil {
-			return -1, err
-		}
-
-		remainBytes -= bytes
+	if err = s.recvContent(storageServer, storageSocket, header.BodyLen, 256 * 1024, func(data []byte) error {
+		_,err := file.Write(data)
+		return err
+	}); err != nil {
+		return -1, err
 	}
+	success = true
 
 	return 0, nil
 }
@@ -1212,10 +1022,7 @@ func (s *StorageClient) DownloadFileByOffsetBuffer(groupName, remoteFilename str
  * @return 0 success, return none zero errno if fail
  */
 func (s *StorageClient) DownloadCallback(groupName, remoteFilename string, callback DownloadCallback) (int, error) {
-	const fileOffset = 0
-	const downloadBytes = 0
-
-	return s.DownloadCallbackByOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes, callback)
+	return resultCode(s.download(groupName, remoteFilename, callback))
 }
 
 /**
@@ -1229,63 +1036,123 @@ func (s *StorageClient) DownloadCallback(groupName, remoteFilename string, callb
  * @return 0 success, return none zero errno if fail
  */
 func (s *StorageClient) DownloadCallbackByOffsetBuffer(groupName, remoteFilename string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
+	return resultCode(s.download(groupName, remoteFilename, callback, WithRange(int64(fileOffset), int64(downloadBytes))))
+}
+
+/**
+ * download with retries, see DownloadCallbackByOffsetBuffer
+ */
+func (s *StorageClient) downloadToCallback(groupName, remoteFilename string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
 	var result int
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var counting = &countingDownloadCallback{callback:callback}
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE, GroupName:groupName, Filename:remoteFilename,
+		Offset:int64(fileOffset), Size:int64(downloadBytes)}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, func(c *StorageClient, err error) bool {
+			return !counting.received
+		}, func(c *StorageClient) (err error) {
+			result,err = c.downloadToCallbackOnce(op.GroupName, op.Filename, int(op.Offset), int(op.Size), counting)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of downloadToCallback
+ */
+func (s *StorageClient) downloadToCallbackOnce(groupName, remoteFilename string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
+	var result int
+	storageServer,bNewConnection,err := s.newReadableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return -1, err
 	}
 
 	var header *RecvHeaderInfo
-	if err = s.sendDownloadPackage(groupName, remoteFilename,fileOffset, downloadBytes); err != nil {
+	if err = s.sendDownloadPackage(storageServer, groupName, remoteFilename,fileOffset, downloadBytes); err != nil {
 		return -1, err
 	}
-	if header,err = RecvHeader(storageSocket, STORAGE_PROTO_CMD_RESP, -1); err != nil {
+	var start = time.Now()
+	var span = s.config.startSpan(contextOf(s.connCtx), PhaseResponseWait, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
+	header,err = RecvHeader(storageSocket, STORAGE_PROTO_CMD_RESP, -1)
+	span.End(0, err)
+	if err != nil {
+		s.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), 0, time.Since(start), 0, err)
 		return -1, err
 	}
-	s.errno = header.Errno
+	s.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), header.Errno, time.Since(start), int64(header.BodyLen), nil)
+	s.setErrno(header.Errno)
 	if header.Errno != 0 {
-		return int(header.Errno), fmt.Errorf("errno:%d", header.Errno)
+		return int(header.Errno), newError(header.Errno, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
 	}
 
-	var buff = make([]byte, 2 * 1024)
-	var remainBytes = header.BodyLen
-	var bytes int
-
-	for remainBytes > 0 {
-		var length = remainBytes
-		if length > len(buff) {
-			length = len(buff)
-		}
-		if bytes,err = storageSocket.Read(buff[:length]); err != nil {
-			return -1, err
-		}
-		if bytes < 0 {
-			return -1, fmt.Errorf("recv package size %d != %d", header.BodyLen - remainBytes, header.BodyLen)
-		}
-		if result,err = callback.Recv(header.BodyLen, buff, bytes); err != nil {
-			s.errno = byte(result)
+	var recvErr error
+	if err = s.recvContent(storageServer, storageSocket, header.BodyLen, 2 * 1024, func(data []byte) error {
+		result,recvErr = callback.Recv(header.BodyLen, data, len(data))
+		return recvErr
+	}); err != nil {
+		if recvErr != nil {
 			return result, err
 		}
-
-		remainBytes -= bytes
+		return -1, err
 	}
 
 	return 0, nil
 }
 
+/**
+ * receive the file content of the download, trace the body stream phase.
+ * the connection is discarded when recv fails because the remain bytes are not read.
+ *
+ * @param storageServer the storage server
+ * @param storageSocket the storage socket
+ * @param bodyLen       the length of the file content
+ * @param buffSize      the size of the receive buffer
+ * @param recv          consume the received bytes
+ */
+func (s *StorageClient) recvContent(storageServer *StorageServer, storageSocket net.Conn, bodyLen, buffSize int, recv func(data []byte) error) error {
+	var span = s.config.startSpan(contextOf(s.connCtx), PhaseBodyStream, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
+	var buff = make([]byte, buffSize)
+	var remainBytes = bodyLen
+	var err = func() error {
+		for remainBytes > 0 {
+			var length = remainBytes
+			if length > len(buff) {
+				length = len(buff)
+			}
+			bytes,err := storageSocket.Read(buff[:length])
+			if err != nil {
+				return err
+			}
+			if bytes < 0 {
+				return fmt.Errorf("recv package size %d != %d", bodyLen - remainBytes, bodyLen)
+			}
+			if err = recv(buff[:bytes]); err != nil {
+				markBroken(storageSocket)
+				s.setErrno(ERR_NO_EIO)
+				return err
+			}
+
+			remainBytes -= bytes
+		}
+		return nil
+	}()
+	span.End(int64(bodyLen - remainBytes), err)
+
+	return err
+}
+
 /**
  * get all metadata items from storage server
  *
@@ -1294,41 +1161,46 @@ func (s *StorageClient) DownloadCallbackByOffsetBuffer(groupName, remoteFilename
  * @return meta info array, return null if fail
  */
 func (s *StorageClient) GetMetadata(groupName, remoteFilename string) ([]NameValuePair, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var result []NameValuePair
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_GET_METADATA, GroupName:groupName, Filename:remoteFilename}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.getMetadataOnce(op.GroupName, op.Filename)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of GetMetadata
+ */
+func (s *StorageClient) getMetadataOnce(groupName, remoteFilename string) ([]NameValuePair, error) {
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return nil, err
 	}
 
-	var pkgInfo *RecvPackageInfo
-
-	if err = s.sendPackage(STORAGE_PROTO_CMD_GET_METADATA, groupName, remoteFilename); err != nil {
+	if err = s.sendPackage(storageServer, STORAGE_PROTO_CMD_GET_METADATA, groupName, remoteFilename); err != nil {
 		return nil, err
 	}
-	if pkgInfo,err = RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, -1); err != nil {
+	var res proto.GetMetadataResponse
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_GET_METADATA, &res); err != nil {
 		return nil, err
 	}
 
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return error or nil?
-		// java is return null.
-		return nil, nil
-	}
-
-	str,err := ConvertByteToString(pkgInfo.Body, GCharset)
+	str,err := ConvertByteToString([]byte(res.Metadata), s.config.getCharset())
 	if err != nil {
 		return nil, err
 	}
@@ -1349,91 +1221,67 @@ func (s *StorageClient) GetMetadata(groupName, remoteFilename string) ([]NameVal
  * @return 0 for success, !=0 fail (error code)
  */
 func (s *StorageClient) SetMetadata(groupName, remoteFilename string, metaList []NameValuePair, opFlag byte) (int, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var result int
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_SET_METADATA, GroupName:groupName, Filename:remoteFilename, Metadata:metaList, Flag:opFlag}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.setMetadataOnce(op.GroupName, op.Filename, op.Metadata, op.Flag)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of SetMetadata
+ */
+func (s *StorageClient) setMetadataOnce(groupName, remoteFilename string, metaList []NameValuePair, opFlag byte) (int, error) {
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return -1, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
-	if err != nil {
-		return -1, err
-	}
-
-	var (
-		header []byte
-		groupBytes []byte
-		filenameBytes []byte
-		metaBuff []byte
-		bs []byte
-		groupLen int
-		sizeBytes []byte
-		pkgInfo *RecvPackageInfo
-	)
 
-	if metaList == nil {
-		metaBuff = make([]byte, 0)
-	} else {
-		if metaBuff,err = ConvertBytesToUTF8([]byte(PackMetadata(metaList)), GCharset); err != nil {
-			return -1, err
-		}
-	}
+	return s.setMetadata(storageServer, groupName, remoteFilename, metaList, opFlag)
+}
 
-	if filenameBytes,err = ConvertBytesToUTF8([]byte(remoteFilename), GCharset); err != nil {
+/**
+ * set metadata items to the storage server
+ *
+ * @param storageServer   the storage server
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @param meta_list       meta item array
+ * @param op_flag         flag, STORAGE_SET_METADATA_FLAG_OVERWRITE or STORAGE_SET_METADATA_FLAG_MERGE
+ * @return 0 for success, !=0 fail (error code)
+ */
+func (s *StorageClient) setMetadata(storageServer *StorageServer, groupName, remoteFilename string, metaList []NameValuePair, opFlag byte) (int, error) {
+	storageSocket,err := s.getSocket(storageServer)
+	if err != nil {
 		return -1, err
 	}
-	sizeBytes = make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE)
-
-	bs = Long2Buff(int64(len(filenameBytes)))
-	copy(sizeBytes, bs)
-	bs = Long2Buff(int64(len(metaBuff)))
-	copy(sizeBytes[FDFS_PROTO_PKG_LEN_SIZE:], bs)
 
-	groupBytes = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
+	var req = &proto.SetMetadataRequest{OpFlag:opFlag}
+	if req.FileRef,err = s.config.fileRef(groupName, remoteFilename); err != nil {
 		return -1, err
 	}
-
-	if len(bs) <= len(groupBytes) {
-		groupLen = len(bs)
-	} else {
-		groupLen = len(groupBytes)
-	}
-
-	copy(groupBytes[:groupLen], bs)
-	if header,err = PackHeader(STORAGE_PROTO_CMD_SET_METADATA, int64(2 * FDFS_PROTO_PKG_LEN_SIZE + 1 + len(groupBytes) + len(filenameBytes) + len(metaBuff)), 0); err != nil {
-		return -1, err
-	}
-	var wholePkg = make([]byte, len(header) + len(sizeBytes) + 1 + len(groupBytes) + len(filenameBytes))
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], sizeBytes)
-	wholePkg[len(header) + len(sizeBytes)] = opFlag
-	copy(wholePkg[len(header) + len(sizeBytes) + 1:], groupBytes)
-	copy(wholePkg[len(header) + len(sizeBytes) + 1 + len(groupBytes):], filenameBytes)
-	if _,err = storageSocket.Write(wholePkg); err != nil {
-		return -1, err
-	}
-
-	if len(metaBuff) > 0 {
-		if _,err = storageSocket.Write(metaBuff); err != nil {
+	if metaList != nil {
+		if req.Metadata,err = s.config.encodeString(PackMetadata(metaList)); err != nil {
 			return -1, err
 		}
 	}
-
-	if pkgInfo,err = RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 0); err != nil {
-		return -1, err
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return resultOf(err), err
 	}
 
-	s.errno = pkgInfo.Errno
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_SET_METADATA, new(proto.EmptyResponse)); err != nil {
+		return resultOf(err), err
 	}
 
 	return 0, nil
@@ -1447,33 +1295,18 @@ func (s *StorageClient) SetMetadata(groupName, remoteFilename string, metaList [
  * @return FileInfo object for success, return null for fail
  */
 func (s *StorageClient) GetFileInfo(groupName, remoteFilename string) (*FileInfo, error) {
-	if len(remoteFilename) < FDFS_FILE_PATH_LEN + FDFS_FILENAME_BASE64_LENGTH + FDFS_FILE_EXT_NAME_MAX_LEN + 1 {
-		s.errno = ERR_NO_EINVAL
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	var buff,err = base64.DecodeAuto(remoteFilename[FDFS_FILE_PATH_LEN:FDFS_FILE_PATH_LEN + FDFS_FILENAME_BASE64_LENGTH])
+	fileId,err := NewFileID(groupName, remoteFilename)
 	if err != nil {
+		s.setErrno(ERR_NO_EINVAL)
 		return nil, err
 	}
 
-	var fileSize = Buff2long(buff, 4 * 2)
-	if ((len(remoteFilename) > TRUNK_LOGIC_FILENAME_LENGTH) || ((len(remoteFilename) > NORMAL_LOGIC_FILENAME_LENGTH) && ((fileSize & TRUNK_FILE_MARK_SIZE) == 0))) || ((fileSize & APPENDER_FILE_SIZE) != 0) {
-		//slave file or appender file
+	if fileId.IsSlave() || fileId.IsAppender() || fileId.GetSourceStorageId() != "" {
+		//slave file, appender file or the source ip address is not in the filename
 		return s.QueryFileInfo(groupName, remoteFilename)
 	}
 
-	var fileInfo = NewFileInfo(fileSize, 0, 0, GetIpAddress(buff, 0))
-	fileInfo.SetCreateTimestamp(int64(Buff2int32(buff, 4)))
-	if fileSize >> 63 != 0 {
-		fileSize &= 0xFFFFFFFF  //low 32 bits is file size
-		fileInfo.SetFileSize(fileSize)
-	}
-	fileInfo.SetCrc32(int(Buff2int32(buff, 4 * 4)))
-
-	return fileInfo, nil
+	return fileId.GetFileInfo(), nil
 }
 
 /**
@@ -1484,100 +1317,243 @@ func (s *StorageClient) GetFileInfo(groupName, remoteFilename string) (*FileInfo
  * @return FileInfo object for success, return null for fail
  */
 func (s *StorageClient) QueryFileInfo(groupName, remoteFilename string) (*FileInfo, error) {
-	var bNewConnection,err = s.newUpdatableStorageConnection(groupName, remoteFilename)
+	var result *FileInfo
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_QUERY_FILE_INFO, GroupName:groupName, Filename:remoteFilename}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, nil, func(c *StorageClient) (err error) {
+			result,err = c.queryFileInfoOnce(op.GroupName, op.Filename)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of QueryFileInfo
+ */
+func (s *StorageClient) queryFileInfoOnce(groupName, remoteFilename string) (*FileInfo, error) {
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, remoteFilename)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if bNewConnection {
-			if err = s.storageServer.Close(); err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				debug.PrintStack()
-			}
-			s.storageServer = nil
+			s.config.closeServer(storageServer)
 		}
 	}()
-	storageSocket,err := s.storageServer.GetSocket()
+	storageSocket,err := s.getSocket(storageServer)
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		header []byte
-		groupBytes []byte
-		filenameBytes []byte
-		bs []byte
-		groupLen int
-		pkgInfo *RecvPackageInfo
-	)
-
-	if filenameBytes,err = ConvertBytesToUTF8([]byte(remoteFilename), GCharset); err != nil {
+	if err = s.sendPackage(storageServer, STORAGE_PROTO_CMD_QUERY_FILE_INFO, groupName, remoteFilename); err != nil {
 		return nil, err
 	}
-	groupBytes = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
+	var res proto.QueryFileInfoResponse
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_QUERY_FILE_INFO, &res); err != nil {
 		return nil, err
 	}
 
-	if len(bs) <= len(groupBytes) {
-		groupLen = len(bs)
-	} else {
-		groupLen = len(groupBytes)
-	}
-	copy(groupBytes[:groupLen], bs)
+	return NewFileInfo(res.FileSize, res.CreateTimestamp, int(res.Crc32), res.SourceIpAddr), nil
+}
 
-	if header,err = PackHeader(STORAGE_PROTO_CMD_QUERY_FILE_INFO, int64(len(groupBytes) + len(filenameBytes)), 0); err != nil {
-		return nil, err
+/**
+ * check if the file exists on the storage server
+ *
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @return true if the file exists, false if the file does not exist
+ */
+func (s *StorageClient) FileExists(groupName, remoteFilename string) (bool, error) {
+	if _,err := s.QueryFileInfo(groupName, remoteFilename); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			s.setErrno(0)
+			return false, nil
+		}
+		return false, err
 	}
 
-	var wholePkg = make([]byte, len(header) + len(groupBytes) + len(filenameBytes))
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], groupBytes)
-	copy(wholePkg[len(header) + len(groupBytes):], filenameBytes)
+	return true, nil
+}
+
+/**
+ * regenerate the filename of the appender file, the appender file is renamed to a
+ * normal file which can not be appended any more and can be stored in trunk file
+ *
+ * @param group_name        the group name of the appender file
+ * @param appender_filename the appender filename
+ * @return the file id of the normal file if success, return null if fail
+ */
+func (s *StorageClient) RegenerateAppenderFilename(groupName, appenderFilename string) (*FileID, error) {
+	var result *FileID
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME, GroupName:groupName, Filename:appenderFilename}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, notApplied, func(c *StorageClient) (err error) {
+			result,err = c.regenerateAppenderFilenameOnce(op.GroupName, op.Filename)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of RegenerateAppenderFilename
+ */
+func (s *StorageClient) regenerateAppenderFilenameOnce(groupName, appenderFilename string) (*FileID, error) {
+	if groupName == "" || appenderFilename == "" {
+		s.setErrno(ERR_NO_EINVAL)
+		return nil, newInvalidError("group name and appender filename are required")
+	}
 
-	if _,err = storageSocket.Write(wholePkg); err != nil {
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, appenderFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if bNewConnection {
+			s.config.closeServer(storageServer)
+		}
+	}()
+	storageSocket,err := s.getSocket(storageServer)
+	if err != nil {
 		return nil, err
 	}
 
-	if pkgInfo,err = RecvPackage(storageSocket, STORAGE_PROTO_CMD_RESP, 3 * FDFS_PROTO_PKG_LEN_SIZE +	 FDFS_IPADDR_SIZE); err != nil {
+	var req = new(proto.RegenerateAppenderFilenameRequest)
+	if req.AppenderFilename,err = s.config.encodeString(appenderFilename); err != nil {
+		return nil, err
+	}
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return nil, err
+	}
+	var res proto.UploadFileResponse
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_REGENERATE_APPENDER_FILENAME, &res); err != nil {
+		return nil, err
+	}
+	fileId,err := NewFileID(res.GroupName, res.Filename)
+	if err != nil {
+		s.setErrno(ERR_NO_EINVAL)
 		return nil, err
 	}
 
-	s.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
+	return fileId, nil
+}
+
+/**
+ * create the link file to the source file on the storage server, the link file
+ * shares the content of the source file. the protocol has no delete link
+ * command, the link file is deleted by DeleteFile and the source file is kept
+ *
+ * @param group_name       the group name of the source file
+ * @param source_filename  the source filename
+ * @param source_signature the signature of the source file content, can be null
+ * @param master_filename  the master filename to generate the slave link file, can be empty
+ * @param prefix_name      the prefix name of the slave link file, required with the master filename
+ * @param file_ext_name    file ext name, do not include dot(.)
+ * @return the file id of the link file if success, return null if fail
+ */
+func (s *StorageClient) CreateLink(groupName, sourceFilename string, sourceSignature []byte, masterFilename, prefixName, fileExtName string) (*FileID, error) {
+	var result *FileID
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_CREATE_LINK, GroupName:groupName, Filename:sourceFilename,
+		MasterFilename:masterFilename, PrefixName:prefixName, FileExtName:fileExtName}
+	var err = s.intercept(op, func(op *Operation) error {
+		return s.withRetry(op.Cmd, notApplied, func(c *StorageClient) (err error) {
+			result,err = c.createLinkOnce(op.GroupName, op.Filename, sourceSignature, op.MasterFilename, op.PrefixName, op.FileExtName)
+			op.Result = result
+			return err
+		})
+	})
+
+	return result, err
+}
+
+/**
+ * one attempt of CreateLink
+ */
+func (s *StorageClient) createLinkOnce(groupName, sourceFilename string, sourceSignature []byte, masterFilename, prefixName, fileExtName string) (*FileID, error) {
+	if groupName == "" || sourceFilename == "" || (masterFilename != "" && prefixName == "") {
+		s.setErrno(ERR_NO_EINVAL)
+		return nil, newInvalidError("group name and source filename are required, prefix name is required with master filename")
 	}
 
-	var fileSize = Buff2long(pkgInfo.Body, 0)
-	var createTimestamp = Buff2long(pkgInfo.Body, FDFS_PROTO_PKG_LEN_SIZE)
-	var crc32 = int(Buff2long(pkgInfo.Body, 2 * FDFS_PROTO_PKG_LEN_SIZE))
-	var sourceIpAddr = strings.Trim(string(pkgInfo.Body[3 * FDFS_PROTO_PKG_LEN_SIZE:3 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_IPADDR_SIZE]), " \x00")
+	storageServer,bNewConnection,err := s.newUpdatableStorageConnection(groupName, sourceFilename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if bNewConnection {
+			s.config.closeServer(storageServer)
+		}
+	}()
+	storageSocket,err := s.getSocket(storageServer)
+	if err != nil {
+		return nil, err
+	}
 
-	return NewFileInfo(fileSize, createTimestamp, crc32, sourceIpAddr), nil
+	var req = &proto.CreateLinkRequest{GroupName:groupName, SourceSignature:sourceSignature}
+	if req.SourceFilename,err = s.config.encodeString(sourceFilename); err != nil {
+		return nil, err
+	}
+	if req.MasterFilename,err = s.config.encodeString(masterFilename); err != nil {
+		return nil, err
+	}
+	if req.PrefixName,err = s.config.encodeString(prefixName); err != nil {
+		return nil, err
+	}
+	if req.FileExtName,err = s.config.encodeString(fileExtName); err != nil {
+		return nil, err
+	}
+	if err = s.sendRequest(storageSocket, req); err != nil {
+		return nil, err
+	}
+	var res proto.UploadFileResponse
+	if err = s.recvResponse(storageServer, storageSocket, STORAGE_PROTO_CMD_CREATE_LINK, &res); err != nil {
+		return nil, err
+	}
+	fileId,err := NewFileID(res.GroupName, res.Filename)
+	if err != nil {
+		s.setErrno(ERR_NO_EINVAL)
+		s.deleteFile(storageServer, res.GroupName, res.Filename)
+		return nil, err
+	}
+
+	return fileId, nil
 }
 
 /**
  * check storage socket, if null create a new connection
  *
  * @param group_name the group name to upload file to, can be empty
- * @return true if create a new connection
+ * @return the storage server, true if create a new connection
  */
-func (s *StorageClient) newWritableStorageConnection(groupName string) (bool, error) {
+func (s *StorageClient) newWritableStorageConnection(groupName string) (*StorageServer, bool, error) {
 	if s.storageServer != nil {
-		return false, nil
+		return s.storageServer, false, nil
 	}
-	var err error
-	var tracker = NewTrackerClient()
-	if s.storageServer,err = tracker.GetStoreStorageByGroup(s.trackerServer, groupName); err != nil {
-		return false, err
+	var tracker = s.newTrackerClient()
+	if len(s.excludes) > 0 {
+		// fail over to another storage server
+		servers,storePath,err := tracker.queryStoreStorages(s.trackerServer, groupName)
+		if err != nil {
+			return nil, false, err
+		}
+		var server = s.selectServer(servers)
+		storageServer,err := newStorageServer(contextOf(s.connCtx), s.config, s.cmd, server.GetIpAddr(), server.GetPort(), storePath)
+		if err != nil {
+			return nil, false, err
+		}
+		return storageServer, true, nil
 	}
-	if s.storageServer == nil {
-		return false, fmt.Errorf("getStoreStorage fail, errno code: %d", tracker.GetErrorCode())
+	storageServer,err := tracker.GetStoreStorageByGroup(s.trackerServer, groupName)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return true, nil
+	return storageServer, true, nil
 }
 
 /**
@@ -1585,22 +1561,32 @@ func (s *StorageClient) newWritableStorageConnection(groupName string) (bool, er
  *
  * @param group_name      the group name of storage server
  * @param remote_filename filename on storage server
- * @return true if create a new connection
+ * @return the storage server, true if create a new connection
  */
-func (s *StorageClient) newReadableStorageConnection(groupName, remoteFilename string) (bool, error) {
+func (s *StorageClient) newReadableStorageConnection(groupName, remoteFilename string) (*StorageServer, bool, error) {
 	if s.storageServer != nil {
-		return false, nil
+		return s.storageServer, false, nil
 	}
-	var err error
-	var tracker = NewTrackerClient()
-	if s.storageServer,err = tracker.GetFetchStorage(s.trackerServer, groupName, remoteFilename); err != nil {
-		return false, err
+	var tracker = s.newTrackerClient()
+	if len(s.excludes) > 0 {
+		// fail over to another storage server
+		servers,err := tracker.GetFetchStorages(s.trackerServer, groupName, remoteFilename)
+		if err != nil {
+			return nil, false, err
+		}
+		var server = s.selectServer(servers)
+		storageServer,err := newStorageServer(contextOf(s.connCtx), s.config, s.cmd, server.GetIpAddr(), server.GetPort(), 0)
+		if err != nil {
+			return nil, false, err
+		}
+		return storageServer, true, nil
 	}
-	if s.storageServer == nil {
-		return false, fmt.Errorf("getStoreStorage fail, errno code: %d", tracker.GetErrorCode())
+	storageServer,err := tracker.GetFetchStorage(s.trackerServer, groupName, remoteFilename)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return true, nil
+	return storageServer, true, nil
 }
 
 /**
@@ -1608,129 +1594,250 @@ func (s *StorageClient) newReadableStorageConnection(groupName, remoteFilename s
  *
  * @param group_name      the group name of storage server
  * @param remote_filename filename on storage server
- * @return true if create a new connection
+ * @return the storage server, true if create a new connection
  */
-func (s *StorageClient) newUpdatableStorageConnection(groupName, remoteFilename string) (bool, error) {
+func (s *StorageClient) newUpdatableStorageConnection(groupName, remoteFilename string) (*StorageServer, bool, error) {
 	if s.storageServer != nil {
-		return false, nil
+		return s.storageServer, false, nil
 	}
-	var err error
-	var tracker = NewTrackerClient()
-	if s.storageServer,err = tracker.GetUpdateStorage(s.trackerServer, groupName, remoteFilename); err != nil {
-		return false, err
+	var tracker = s.newTrackerClient()
+	storageServer,err := tracker.GetUpdateStorage(s.trackerServer, groupName, remoteFilename)
+	if err != nil {
+		return nil, false, err
 	}
-	if s.storageServer == nil {
-		return false, fmt.Errorf("getStoreStorage fail, errno code: %d", tracker.GetErrorCode())
+
+	return storageServer, true, nil
+}
+
+/**
+ * select the first server not failed before, the first server if all failed
+ *
+ * @param servers the servers returned by tracker, at least one
+ */
+func (s *StorageClient) selectServer(servers []*ServerInfo) *ServerInfo {
+	for _,server := range servers {
+		if addr,err := net.ResolveTCPAddr("tcp", net.JoinHostPort(server.GetIpAddr(), strconv.Itoa(server.GetPort()))); err == nil && !containsAddr(s.excludes, addr) {
+			return server
+		}
 	}
 
-	return true, nil
+	return servers[0]
 }
 
 /**
- * send package to storage server
+ * send the file content by the callback, the connection is discarded
+ * when the callback fails because the request is incomplete
  *
- * @param cmd             which command to send
- * @param group_name      the group name of storage server
- * @param remote_filename filename on storage server
+ * @param storageSocket the storage socket
+ * @param callback      the write data callback object
+ * @param cmd           the command sent
  */
-func (s *StorageClient) sendPackage(cmd byte, groupName, remoteFilename string) error {
-	var (
-		header []byte
-		groupBytes []byte
-		filenameBytes []byte
-		bs []byte
-		groupLen int
-	)
-	var err error
+func (s *StorageClient) sendCallback(storageSocket net.Conn, callback UploadCallback, cmd byte) error {
+	var span = s.config.startSpan(contextOf(s.connCtx), PhaseBodyStream, cmd, storageSocket.RemoteAddr())
+	var out = &countingWriter{out:storageSocket}
+	errno,err := callback.Send(out)
+	if err == nil && errno != 0 {
+		err = &Error{Errno:byte(errno), Cmd:cmd, Message:"upload callback fail"}
+	}
+	span.End(out.bytes, err)
+	if err == nil {
+		return nil
+	}
 
-	groupBytes = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
-		return err
+	markBroken(storageSocket)
+	s.setErrno(ErrorCode(err))
+
+	return err
+}
+
+/**
+ * copy the client to run one operation with the context
+ *
+ * @param connCtx the context of the operation
+ */
+func (s *StorageClient) fork(connCtx *connContext) *StorageClient {
+	return &StorageClient{
+		trackerServer:s.trackerServer,
+		storageServer:s.storageServer,
+		connCtx:connCtx,
+		config:s.config,
+		cmd:s.cmd,
 	}
-	if filenameBytes,err = ConvertBytesToUTF8([]byte(remoteFilename), GCharset); err != nil {
-		return err
+}
+
+/**
+ * create tracker client with the settings and context of the storage client
+ */
+func (s *StorageClient) newTrackerClient() *TrackerClient {
+	return &TrackerClient{
+		trackerGroup:s.config.getTrackerGroup(),
+		connCtx:s.connCtx,
+		config:s.config,
+		cmd:s.cmd,
 	}
-	if len(bs) <= len(groupBytes) {
-		groupLen = len(bs)
-	} else {
-		groupLen = len(groupBytes)
+}
+
+/**
+ * get the storage socket, bind it to the context of the running operation
+ *
+ * @param storageServer the storage server
+ * @return the storage socket
+ */
+func (s *StorageClient) getSocket(storageServer *StorageServer) (net.Conn, error) {
+	conn,err := s.bindSocket(storageServer)
+	if err != nil {
+		return nil, err
 	}
-	copy(groupBytes[:groupLen], bs)
+	s.storageAddr = storageServer.GetAddress()
 
-	if header,err = PackHeader(cmd, int64(len(groupBytes) + len(filenameBytes)), 0); err != nil {
-		return err
+	return conn, nil
+}
+
+/**
+ * get the storage socket bound to the context of the running operation, the
+ * client may be shared by concurrent operations
+ *
+ * @param storageServer the storage server
+ * @return the storage socket
+ */
+func (s *StorageClient) bindSocket(storageServer *StorageServer) (net.Conn, error) {
+	conn,err := storageServer.GetSocket()
+	if err != nil {
+		return nil, err
 	}
-	var wholePkg = make([]byte, len(header) + len(groupBytes) + len(filenameBytes))
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], groupBytes)
-	copy(wholePkg[len(header) + len(groupBytes):], filenameBytes)
+	if s.connCtx != nil {
+		if err = s.connCtx.bind(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+/**
+ * send the request to the storage server
+ *
+ * @param storageSocket the storage socket
+ * @param req           the request
+ */
+func (s *StorageClient) sendRequest(storageSocket net.Conn, req proto.Request) error {
+	var err = s.config.writeRequest(contextOf(s.connCtx), storageSocket.RemoteAddr(), storageSocket, req)
+	if errors.Is(err, ErrInvalid) {
+		s.setErrno(ERR_NO_EINVAL)
+	} else if err != nil {
+		s.config.logRequestError(req.Cmd(), storageSocket.RemoteAddr(), err)
+	}
+
+	return err
+}
 
-	conn,err := s.storageServer.GetSocket()
+/**
+ * receive the response from the storage server, set the error code of the client
+ *
+ * @param storageServer the storage server
+ * @param storageSocket the storage socket
+ * @param cmd           the command sent
+ * @param res           the response to decode the body to
+ */
+func (s *StorageClient) recvResponse(storageServer *StorageServer, storageSocket net.Conn, cmd byte, res proto.Message) error {
+	var start = time.Now()
+	errno,bytes,err := s.config.readResponse(contextOf(s.connCtx), cmd, storageServer.GetAddress(), storageSocket, res)
+	s.config.logResponse(cmd, storageServer.GetAddress(), errno, time.Since(start), bytes, err)
 	if err != nil {
 		return err
 	}
-	if _,err = conn.Write(wholePkg); err != nil {
-		return err
+	s.setErrno(errno)
+	if errno != 0 {
+		return newError(errno, cmd, storageServer.GetAddress())
 	}
 
 	return nil
 }
 
+/**
+ * get the result code of the failed operation
+ *
+ * @param err the error
+ * @return the errno of *Error, -1 for the others
+ */
+func resultOf(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return int(e.Errno)
+	}
+
+	return -1
+}
+
+/**
+ * get the result code of the operation for the methods returning errno
+ *
+ * @param err the error
+ * @return 0 and nil for success, the result code and err for fail
+ */
+func resultCode(err error) (int, error) {
+	if err != nil {
+		return resultOf(err), err
+	}
+
+	return 0, nil
+}
+
 /**
  * send package to storage server
  *
+ * @param storageServer   the storage server
+ * @param cmd             which command to send
  * @param group_name      the group name of storage server
  * @param remote_filename filename on storage server
- * @param file_offset     the start offset of the file
- * @param download_bytes  download bytes
  */
-func (s *StorageClient) sendDownloadPackage(groupName, remoteFilename string, fileOffset, downloadBytes int) error {
-	var (
-		header []byte
-		bsOffset []byte
-		bsDownBytes []byte
-		groupBytes []byte
-		filenameBytes []byte
-		bs []byte
-		groupLen int
-	)
-	var err error
-
-	bsOffset = Long2Buff(int64(fileOffset))
-	bsDownBytes = Long2Buff(int64(downloadBytes))
-	groupBytes = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
-		return err
-	}
-	if filenameBytes,err = ConvertBytesToUTF8([]byte(remoteFilename), GCharset); err != nil {
+func (s *StorageClient) sendPackage(storageServer *StorageServer, cmd byte, groupName, remoteFilename string) error {
+	ref,err := s.config.fileRef(groupName, remoteFilename)
+	if err != nil {
 		return err
 	}
-	if len(bs) <= len(groupBytes) {
-		groupLen = len(bs)
-	} else {
-		groupLen = len(groupBytes)
+	var req proto.Request
+	switch cmd {
+	case STORAGE_PROTO_CMD_DELETE_FILE:
+		req = &proto.DeleteFileRequest{FileRef:ref}
+	case STORAGE_PROTO_CMD_GET_METADATA:
+		req = &proto.GetMetadataRequest{FileRef:ref}
+	case STORAGE_PROTO_CMD_QUERY_FILE_INFO:
+		req = &proto.QueryFileInfoRequest{FileRef:ref}
+	default:
+		return newInvalidError("cmd %d is not a file request", cmd)
 	}
-	copy(groupBytes[:groupLen], bs)
 
-	if header,err = PackHeader(STORAGE_PROTO_CMD_DOWNLOAD_FILE, int64(len(bsOffset) + len(bsDownBytes) + len(groupBytes) + len(filenameBytes)), 0); err != nil {
+	conn,err := s.bindSocket(storageServer)
+	if err != nil {
 		return err
 	}
-	var wholePkg = make([]byte, len(header) + len(bsOffset) + len(bsDownBytes) + len(groupBytes) + len(filenameBytes))
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], bsOffset)
-	copy(wholePkg[len(header) + len(bsOffset):], bsDownBytes)
-	copy(wholePkg[len(header) + len(bsOffset) + len(bsDownBytes):], groupBytes)
-	copy(wholePkg[len(header) + len(bsOffset) + len(bsDownBytes) + len(groupBytes):], filenameBytes)
 
-	conn,err := s.storageServer.GetSocket()
-	if err != nil {
+	return s.sendRequest(conn, req)
+}
+
+/**
+ * send package to storage server
+ *
+ * @param storageServer   the storage server
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @param file_offset     the start offset of the file
+ * @param download_bytes  download bytes
+ */
+func (s *StorageClient) sendDownloadPackage(storageServer *StorageServer, groupName, remoteFilename string, fileOffset, downloadBytes int) error {
+	var req = &proto.DownloadFileRequest{FileOffset:int64(fileOffset), DownloadBytes:int64(downloadBytes)}
+	var err error
+	if req.FileRef,err = s.config.fileRef(groupName, remoteFilename); err != nil {
 		return err
 	}
-	if _,err = conn.Write(wholePkg); err != nil {
+
+	conn,err := s.bindSocket(storageServer)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.sendRequest(conn, req)
 }
 
 type UploadBuff struct {
@@ -1759,5 +1866,9 @@ func NewUploadBuff(fileBuff []byte, offset, length int) *UploadBuff {
  * @return 0 success, return none zero(errno) if fail
  */
 func (u *UploadBuff) Send(out io.Writer) (int, error) {
-	return out.Write(u.fileBuff[u.offset:u.offset + u.length])
+	if _,err := out.Write(u.fileBuff[u.offset:u.offset + u.length]); err != nil {
+		return -1, err
+	}
+
+	return 0, nil
 }
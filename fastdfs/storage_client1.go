@@ -2,7 +2,6 @@ package fastdfs
 
 import (
 	"strings"
-	"fmt"
 )
 
 const SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR = "/"
@@ -29,6 +28,21 @@ func NewStorageClient1(trackerServer *TrackerServer, storageServer *StorageServe
 	}
 }
 
+/**
+ * split file id to group name and filename, set the errno if fail
+ *
+ * @param fileId  the file id
+ * @param results 2 elements array to store group name and filename
+ */
+func (s *StorageClient1) splitFileId(fileId string, results []string) error {
+	if errno := SplitFileId(fileId, results); errno != 0 {
+		s.setErrno(errno)
+		return newInvalidError("invalid file id %s", fileId)
+	}
+
+	return nil
+}
+
 func SplitFileId(fileId string, results []string) byte {
 	var pos = strings.Index(fileId, SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR)
 	if pos <= 0 || pos == len(fileId) - 1 {
@@ -51,9 +65,9 @@ func SplitFileId(fileId string, results []string) byte {
  * return null if fail
  */
 func (s *StorageClient1) UploadFile1(localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadFile(localFilename, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadFile(localFilename, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -70,9 +84,9 @@ func (s *StorageClient1) UploadFile1(localFilename, fileExtName string, metaList
  * return null if fail
  */
 func (s *StorageClient1) UploadFileByGroup1(groupName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.uploadFileByGroup(groupName, localFilename, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.uploadFileByGroup(groupName, localFilename, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -89,9 +103,9 @@ func (s *StorageClient1) UploadFileByGroup1(groupName, localFilename, fileExtNam
  * return null if fail
  */
 func (s *StorageClient1) UploadBuffer1(fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadBuffer(fileBuff, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadBuffer(fileBuff, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -108,9 +122,9 @@ func (s *StorageClient1) UploadBuffer1(fileBuff []byte, fileExtName string, meta
  * return null if fail
  */
 func (s *StorageClient1) UploadBufferByGroup1(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadBufferByGroup(groupName, fileBuff, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadBufferByGroup(groupName, fileBuff, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -128,9 +142,9 @@ func (s *StorageClient1) UploadBufferByGroup1(groupName string, fileBuff []byte,
  * return null if fail
  */
 func (s *StorageClient1) UploadCallback1(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadCallback(groupName, fileSize, callback, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadCallback(groupName, fileSize, callback, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -146,9 +160,9 @@ func (s *StorageClient1) UploadCallback1(groupName string, fileSize int, callbac
  * return null if fail
  */
 func (s *StorageClient1) UploadAppenderFile1(localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadAppenderFile(localFilename, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadAppenderFile(localFilename, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -165,9 +179,9 @@ func (s *StorageClient1) UploadAppenderFile1(localFilename, fileExtName string,
  * return null if fail
  */
 func (s *StorageClient1) UploadAppenderFileByGroup1(groupName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadAppenderFileByGroup(groupName, localFilename, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadAppenderFileByGroup(groupName, localFilename, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -183,9 +197,9 @@ func (s *StorageClient1) UploadAppenderFileByGroup1(groupName, localFilename, fi
  * return null if fail
  */
 func (s *StorageClient1) UploadAppenderBuffer1(fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadAppenderBuffer(fileBuff, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadAppenderBuffer(fileBuff, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -202,9 +216,9 @@ func (s *StorageClient1) UploadAppenderBuffer1(fileBuff []byte, fileExtName stri
  * return null if fail
  */
 func (s *StorageClient1) UploadAppenderBufferByGroup1(groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadAppenderBufferByGroup(groupName, fileBuff, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadAppenderBufferByGroup(groupName, fileBuff, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -222,9 +236,9 @@ func (s *StorageClient1) UploadAppenderBufferByGroup1(groupName string, fileBuff
  * return null if fail
  */
 func (s *StorageClient1) UploadAppenderCallback1(groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
-	var parts,err = s.UploadAppenderCallback(groupName, fileSize, callback, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	var fileId,err = s.UploadAppenderCallback(groupName, fileSize, callback, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -244,13 +258,12 @@ func (s *StorageClient1) UploadAppenderCallback1(groupName string, fileSize int,
 func (s *StorageClient1) UploadMasterFile1(masterFileId, prefixName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
 	var parts = make([]string, 2)
 	var err error
-	s.errno = SplitFileId(masterFileId, parts)
-	if s.errno != 0 {
-		return "", nil
+	if err := s.splitFileId(masterFileId, parts); err != nil {
+		return "", err
 	}
-	parts,err = s.UploadMasterFile(parts[0], parts[1], prefixName, localFilename, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	fileId,err := s.UploadMasterFile(parts[0], parts[1], prefixName, localFilename, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -270,13 +283,12 @@ func (s *StorageClient1) UploadMasterFile1(masterFileId, prefixName, localFilena
 func (s *StorageClient1) UploadMasterBuffer1(masterFileId, prefixName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
 	var parts = make([]string, 2)
 	var err error
-	s.errno = SplitFileId(masterFileId, parts)
-	if s.errno != 0 {
-		return "", nil
+	if err := s.splitFileId(masterFileId, parts); err != nil {
+		return "", err
 	}
-	parts,err = s.UploadMasterBuffer(parts[0], parts[1], prefixName, fileBuff, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	fileId,err := s.UploadMasterBuffer(parts[0], parts[1], prefixName, fileBuff, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -296,13 +308,12 @@ func (s *StorageClient1) UploadMasterBuffer1(masterFileId, prefixName string, fi
 func (s *StorageClient1) UploadMasterOffsetBuffer1(groupName, masterFileId, prefixName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (string, error) {
 	var parts = make([]string, 2)
 	var err error
-	s.errno = SplitFileId(masterFileId, parts)
-	if s.errno != 0 {
-		return "", nil
+	if err := s.splitFileId(masterFileId, parts); err != nil {
+		return "", err
 	}
-	parts,err = s.UploadMasterOffsetBuffer(parts[0], parts[1], prefixName, fileBuff, offset, length, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	fileId,err := s.UploadMasterOffsetBuffer(parts[0], parts[1], prefixName, fileBuff, offset, length, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -323,13 +334,12 @@ func (s *StorageClient1) UploadMasterOffsetBuffer1(groupName, masterFileId, pref
 func (s *StorageClient1) UploadMasterCallback1(groupName, masterFileId, prefixName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
 	var parts = make([]string, 2)
 	var err error
-	s.errno = SplitFileId(masterFileId, parts)
-	if s.errno != 0 {
-		return "", nil
+	if err := s.splitFileId(masterFileId, parts); err != nil {
+		return "", err
 	}
-	parts,err = s.UploadMasterCallback(parts[0], parts[1], prefixName, fileSize, callback, fileExtName, metaList)
-	if err == nil && parts != nil {
-		return parts[0] + SPLIT_GROUP_NAME_AND_FILENAME_SEPERATOR + parts[1], nil
+	fileId,err := s.UploadMasterCallback(parts[0], parts[1], prefixName, fileSize, callback, fileExtName, metaList)
+	if err == nil && fileId != nil {
+		return fileId.String(), nil
 	}
 
 	return "", err
@@ -344,9 +354,8 @@ func (s *StorageClient1) UploadMasterCallback1(groupName, masterFileId, prefixNa
  */
 func (s *StorageClient1) AppendFile1(appenderFileId, localFilename string) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 	return s.AppendFile(parts[0], parts[1], localFilename)
 }
@@ -360,9 +369,8 @@ func (s *StorageClient1) AppendFile1(appenderFileId, localFilename string) (int,
  */
 func (s *StorageClient1) AppendBuffer1(appenderFileId string, fileBuffer []byte) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.AppendBuffer(parts[0], parts[1], fileBuffer)
@@ -379,9 +387,8 @@ func (s *StorageClient1) AppendBuffer1(appenderFileId string, fileBuffer []byte)
  */
 func (s *StorageClient1) AppendOffsetBuffer1(appenderFileId string, fileBuffer []byte, offset, length int) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.AppendOffsetBuffer(parts[0], parts[1], fileBuffer, offset, length)
@@ -397,9 +404,8 @@ func (s *StorageClient1) AppendOffsetBuffer1(appenderFileId string, fileBuffer [
  */
 func (s *StorageClient1) AppendCallback1(appenderFileId string, fileSize int, callback UploadCallback) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.AppendCallback(parts[0], parts[1], fileSize, callback)
@@ -415,9 +421,8 @@ func (s *StorageClient1) AppendCallback1(appenderFileId string, fileSize int, ca
  */
 func (s *StorageClient1) ModifyFile1(appenderFileId string, fileOffset int, localFilename string) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.ModifyFile(parts[0], parts[1], fileOffset, localFilename)
@@ -433,9 +438,8 @@ func (s *StorageClient1) ModifyFile1(appenderFileId string, fileOffset int, loca
  */
 func (s *StorageClient1) ModifyBuffer1(appenderFileId string, fileOffset int, fileBuffer []byte) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.ModifyBuffer(parts[0], parts[1], fileOffset, fileBuffer)
@@ -453,9 +457,8 @@ func (s *StorageClient1) ModifyBuffer1(appenderFileId string, fileOffset int, fi
  */
 func (s *StorageClient1) ModifyOffsetBuffer1(appenderFileId string, fileOffset int, fileBuff []byte, bufferOffset, bufferLength int) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.ModifyOffsetBuffer(parts[0], parts[1], fileOffset, fileBuff, bufferOffset, bufferLength)
@@ -472,9 +475,8 @@ func (s *StorageClient1) ModifyOffsetBuffer1(appenderFileId string, fileOffset i
  */
 func (s *StorageClient1) ModifyCallback1(appenderFileId string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(appenderFileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.ModifyCallback(parts[0], parts[1], fileOffset, modifySize, callback)
@@ -488,9 +490,8 @@ func (s *StorageClient1) ModifyCallback1(appenderFileId string, fileOffset, modi
  */
 func (s *StorageClient1) DeleteFile1(fileId string) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.DeleteFile(parts[0], parts[1])
@@ -504,9 +505,8 @@ func (s *StorageClient1) DeleteFile1(fileId string) (int, error) {
  */
 func (s *StorageClient1) TruncateFile1(fileId string) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.TruncateFile(parts[0], parts[1])
@@ -521,9 +521,8 @@ func (s *StorageClient1) TruncateFile1(fileId string) (int, error) {
  */
 func (s *StorageClient1) TruncateFileBySize1(fileId string, truncatedFileSize int) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.TruncateFileBySize(parts[0], parts[1], truncatedFileSize)
@@ -552,14 +551,30 @@ func (s *StorageClient1) DownloadBuffer1(fileId string) ([]byte, error) {
  */
 func (s *StorageClient1) DownloadOffsetBuffer1(fileId string, fileOffset, downloadBytes int) ([]byte, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return nil, fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
 	}
 
 	return s.DownloadOffsetBuffer(parts[0], parts[1], fileOffset, downloadBytes)
 }
 
+/**
+ * open the file on storage server for reading
+ *
+ * @param file_id        the file id(including group name and filename)
+ * @param file_offset    the start offset of the file
+ * @param download_bytes download bytes, 0 for remain bytes from offset
+ * @return the reader of the range, should be closed
+ */
+func (s *StorageClient1) OpenReader1(fileId string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
+	}
+
+	return s.OpenReader(parts[0], parts[1], fileOffset, downloadBytes)
+}
+
 /**
  * download file from storage server
  *
@@ -585,9 +600,8 @@ func (s *StorageClient1) DownloadFile1(fileId, localFilename string) (int, error
  */
 func (s *StorageClient1) DownloadFileByOffsetBuffer1(fileId string, fileOffset, downloadBytes int, localFilename string) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.DownloadFileByOffsetBuffer(parts[0], parts[1], fileOffset, downloadBytes, localFilename)
@@ -618,9 +632,8 @@ func (s *StorageClient1) DownloadCallback1(fileId string, callback DownloadCallb
  */
 func (s *StorageClient1) DownloadCallbackByOffsetBuffer1(fileId string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.DownloadCallbackByOffsetBuffer(parts[0], parts[1], fileOffset, downloadBytes, callback)
@@ -634,9 +647,8 @@ func (s *StorageClient1) DownloadCallbackByOffsetBuffer1(fileId string, fileOffs
  */
 func (s *StorageClient1) GetMetadata1(fileId string) ([]NameValuePair, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return nil, fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
 	}
 
 	return s.GetMetadata(parts[0], parts[1])
@@ -656,9 +668,8 @@ func (s *StorageClient1) GetMetadata1(fileId string) ([]NameValuePair, error) {
  */
 func (s *StorageClient1) SetMetadata1(fileId string, metaList []NameValuePair, opFlag byte) (int, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return int(s.errno), fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return ERR_NO_EINVAL, err
 	}
 
 	return s.SetMetadata(parts[0], parts[1], metaList, opFlag)
@@ -672,9 +683,8 @@ func (s *StorageClient1) SetMetadata1(fileId string, metaList []NameValuePair, o
  */
 func (s *StorageClient1) QueryFileInfo1(fileId string) (*FileInfo, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return nil, fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
 	}
 
 	return s.QueryFileInfo(parts[0], parts[1])
@@ -688,10 +698,69 @@ func (s *StorageClient1) QueryFileInfo1(fileId string) (*FileInfo, error) {
  */
 func (s *StorageClient1) GetFileInfo1(fileId string) (*FileInfo, error) {
 	var parts = make([]string, 2)
-	s.errno = SplitFileId(fileId, parts)
-	if s.errno != 0 {
-		return nil, fmt.Errorf("errno:%d", s.errno)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
 	}
 
 	return s.GetFileInfo(parts[0], parts[1])
+}
+
+/**
+ * check if the file exists on the storage server
+ *
+ * @param file_id the file id(including group name and filename)
+ * @return true if the file exists, false if the file does not exist
+ */
+func (s *StorageClient1) FileExists1(fileId string) (bool, error) {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return false, err
+	}
+
+	return s.FileExists(parts[0], parts[1])
+}
+
+/**
+ * regenerate the filename of the appender file, the appender file is renamed to a normal file
+ *
+ * @param appender_file_id the appender file id
+ * @return the file id of the normal file if success, return null if fail
+ */
+func (s *StorageClient1) RegenerateAppenderFilename1(appenderFileId string) (string, error) {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return "", err
+	}
+
+	fileId,err := s.RegenerateAppenderFilename(parts[0], parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	return fileId.String(), nil
+}
+
+/**
+ * create the link file to the source file on the storage server,
+ * the link file is deleted by DeleteFile1
+ *
+ * @param source_file_id   the source file id
+ * @param source_signature the signature of the source file content, can be null
+ * @param master_filename  the master filename to generate the slave link file, can be empty
+ * @param prefix_name      the prefix name of the slave link file, required with the master filename
+ * @param file_ext_name    file ext name, do not include dot(.)
+ * @return the file id of the link file if success, return null if fail
+ */
+func (s *StorageClient1) CreateLink1(sourceFileId string, sourceSignature []byte, masterFilename, prefixName, fileExtName string) (string, error) {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(sourceFileId, parts); err != nil {
+		return "", err
+	}
+
+	fileId,err := s.CreateLink(parts[0], parts[1], sourceSignature, masterFilename, prefixName, fileExtName)
+	if err != nil {
+		return "", err
+	}
+
+	return fileId.String(), nil
 }
\ No newline at end of file
@@ -0,0 +1,531 @@
+package fastdfs
+
+import "context"
+
+/**
+ * run the operation with context, see StorageClient.withContext
+ *
+ * @param ctx the context
+ * @param fn  the operation
+ * @return the error of the operation
+ */
+func (s *StorageClient1) withContext(ctx context.Context, fn func(c *StorageClient1) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var c = &StorageClient1{
+		StorageClient:*s.fork(newConnContext(ctx)),
+	}
+	var err = c.connCtx.release(fn(c))
+	s.setErrno(c.GetErrorCode())
+
+	return err
+}
+
+/**
+ * context version of UploadFile1
+ */
+func (s *StorageClient1) UploadFile1Ctx(ctx context.Context, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadFile1(localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadFileByGroup1
+ */
+func (s *StorageClient1) UploadFileByGroup1Ctx(ctx context.Context, groupName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadFileByGroup1(groupName, localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBuffer1
+ */
+func (s *StorageClient1) UploadBuffer1Ctx(ctx context.Context, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadBuffer1(fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBufferByGroup1
+ */
+func (s *StorageClient1) UploadBufferByGroup1Ctx(ctx context.Context, groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadBufferByGroup1(groupName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadCallback1
+ */
+func (s *StorageClient1) UploadCallback1Ctx(ctx context.Context, groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadCallback1(groupName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderFile1
+ */
+func (s *StorageClient1) UploadAppenderFile1Ctx(ctx context.Context, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadAppenderFile1(localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderFileByGroup1
+ */
+func (s *StorageClient1) UploadAppenderFileByGroup1Ctx(ctx context.Context, groupName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadAppenderFileByGroup1(groupName, localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderBuffer1
+ */
+func (s *StorageClient1) UploadAppenderBuffer1Ctx(ctx context.Context, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadAppenderBuffer1(fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderBufferByGroup1
+ */
+func (s *StorageClient1) UploadAppenderBufferByGroup1Ctx(ctx context.Context, groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadAppenderBufferByGroup1(groupName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderCallback1
+ */
+func (s *StorageClient1) UploadAppenderCallback1Ctx(ctx context.Context, groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadAppenderCallback1(groupName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterFile1
+ */
+func (s *StorageClient1) UploadMasterFile1Ctx(ctx context.Context, masterFileId, prefixName, localFilename, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadMasterFile1(masterFileId, prefixName, localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterBuffer1
+ */
+func (s *StorageClient1) UploadMasterBuffer1Ctx(ctx context.Context, masterFileId, prefixName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadMasterBuffer1(masterFileId, prefixName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterOffsetBuffer1
+ */
+func (s *StorageClient1) UploadMasterOffsetBuffer1Ctx(ctx context.Context, groupName, masterFileId, prefixName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadMasterOffsetBuffer1(groupName, masterFileId, prefixName, fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterCallback1
+ */
+func (s *StorageClient1) UploadMasterCallback1Ctx(ctx context.Context, groupName, masterFileId, prefixName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.UploadMasterCallback1(groupName, masterFileId, prefixName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendFile1
+ */
+func (s *StorageClient1) AppendFile1Ctx(ctx context.Context, appenderFileId, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.AppendFile1(appenderFileId, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendBuffer1
+ */
+func (s *StorageClient1) AppendBuffer1Ctx(ctx context.Context, appenderFileId string, fileBuffer []byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.AppendBuffer1(appenderFileId, fileBuffer)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendOffsetBuffer1
+ */
+func (s *StorageClient1) AppendOffsetBuffer1Ctx(ctx context.Context, appenderFileId string, fileBuffer []byte, offset, length int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.AppendOffsetBuffer1(appenderFileId, fileBuffer, offset, length)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendCallback1
+ */
+func (s *StorageClient1) AppendCallback1Ctx(ctx context.Context, appenderFileId string, fileSize int, callback UploadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.AppendCallback1(appenderFileId, fileSize, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyFile1
+ */
+func (s *StorageClient1) ModifyFile1Ctx(ctx context.Context, appenderFileId string, fileOffset int, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.ModifyFile1(appenderFileId, fileOffset, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyBuffer1
+ */
+func (s *StorageClient1) ModifyBuffer1Ctx(ctx context.Context, appenderFileId string, fileOffset int, fileBuffer []byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.ModifyBuffer1(appenderFileId, fileOffset, fileBuffer)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyOffsetBuffer1
+ */
+func (s *StorageClient1) ModifyOffsetBuffer1Ctx(ctx context.Context, appenderFileId string, fileOffset int, fileBuff []byte, bufferOffset, bufferLength int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.ModifyOffsetBuffer1(appenderFileId, fileOffset, fileBuff, bufferOffset, bufferLength)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyCallback1
+ */
+func (s *StorageClient1) ModifyCallback1Ctx(ctx context.Context, appenderFileId string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.ModifyCallback1(appenderFileId, fileOffset, modifySize, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteFile1
+ */
+func (s *StorageClient1) DeleteFile1Ctx(ctx context.Context, fileId string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DeleteFile1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of TruncateFile1
+ */
+func (s *StorageClient1) TruncateFile1Ctx(ctx context.Context, fileId string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.TruncateFile1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of TruncateFileBySize1
+ */
+func (s *StorageClient1) TruncateFileBySize1Ctx(ctx context.Context, fileId string, truncatedFileSize int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.TruncateFileBySize1(fileId, truncatedFileSize)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadBuffer1
+ */
+func (s *StorageClient1) DownloadBuffer1Ctx(ctx context.Context, fileId string) ([]byte, error) {
+	var result []byte
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadBuffer1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadOffsetBuffer1
+ */
+func (s *StorageClient1) DownloadOffsetBuffer1Ctx(ctx context.Context, fileId string, fileOffset, downloadBytes int) ([]byte, error) {
+	var result []byte
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadOffsetBuffer1(fileId, fileOffset, downloadBytes)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadFile1
+ */
+func (s *StorageClient1) DownloadFile1Ctx(ctx context.Context, fileId, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadFile1(fileId, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadFileByOffsetBuffer1
+ */
+func (s *StorageClient1) DownloadFileByOffsetBuffer1Ctx(ctx context.Context, fileId string, fileOffset, downloadBytes int, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadFileByOffsetBuffer1(fileId, fileOffset, downloadBytes, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadCallback1
+ */
+func (s *StorageClient1) DownloadCallback1Ctx(ctx context.Context, fileId string, callback DownloadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadCallback1(fileId, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadCallbackByOffsetBuffer1
+ */
+func (s *StorageClient1) DownloadCallbackByOffsetBuffer1Ctx(ctx context.Context, fileId string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.DownloadCallbackByOffsetBuffer1(fileId, fileOffset, downloadBytes, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of OpenReader1, the context applies to every request
+ * of the reader until it is closed.
+ */
+func (s *StorageClient1) OpenReader1Ctx(ctx context.Context, fileId string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return nil, err
+	}
+
+	return s.OpenReaderCtx(ctx, parts[0], parts[1], fileOffset, downloadBytes)
+}
+
+/**
+ * context version of GetMetadata1
+ */
+func (s *StorageClient1) GetMetadata1Ctx(ctx context.Context, fileId string) ([]NameValuePair, error) {
+	var result []NameValuePair
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.GetMetadata1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of SetMetadata1
+ */
+func (s *StorageClient1) SetMetadata1Ctx(ctx context.Context, fileId string, metaList []NameValuePair, opFlag byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.SetMetadata1(fileId, metaList, opFlag)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of QueryFileInfo1
+ */
+func (s *StorageClient1) QueryFileInfo1Ctx(ctx context.Context, fileId string) (*FileInfo, error) {
+	var result *FileInfo
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.QueryFileInfo1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFileInfo1
+ */
+func (s *StorageClient1) GetFileInfo1Ctx(ctx context.Context, fileId string) (*FileInfo, error) {
+	var result *FileInfo
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.GetFileInfo1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of FileExists1
+ */
+func (s *StorageClient1) FileExists1Ctx(ctx context.Context, fileId string) (bool, error) {
+	var result bool
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.FileExists1(fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of RegenerateAppenderFilename1
+ */
+func (s *StorageClient1) RegenerateAppenderFilename1Ctx(ctx context.Context, appenderFileId string) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.RegenerateAppenderFilename1(appenderFileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of CreateLink1
+ */
+func (s *StorageClient1) CreateLink1Ctx(ctx context.Context, sourceFileId string, sourceSignature []byte, masterFilename, prefixName, fileExtName string) (string, error) {
+	var result string
+	var err = s.withContext(ctx, func(c *StorageClient1) (err error) {
+		result,err = c.CreateLink1(sourceFileId, sourceSignature, masterFilename, prefixName, fileExtName)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,561 @@
+package fastdfs
+
+import "context"
+
+/**
+ * run the operation with context, the deadline and cancel of ctx apply to
+ * dialing and socket io of every connection the operation uses.
+ * when ctx is done in the middle of a transfer the operation is aborted and
+ * ctx.Err() is returned, pooled connections are discarded then, a storage
+ * server passed by the caller should be closed.
+ * the operation runs on a copy of the client, so that concurrent
+ * operations do not share the context.
+ *
+ * @param ctx the context
+ * @param fn  the operation
+ * @return the error of the operation
+ */
+func (s *StorageClient) withContext(ctx context.Context, fn func(c *StorageClient) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var c = s.fork(newConnContext(ctx))
+	var err = c.connCtx.release(fn(c))
+	s.setErrno(c.GetErrorCode())
+
+	return err
+}
+
+/**
+ * context version of UploadFile
+ */
+func (s *StorageClient) UploadFileCtx(ctx context.Context, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadFile(localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBufferOffset
+ */
+func (s *StorageClient) UploadBufferOffsetCtx(ctx context.Context, fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadBufferOffset(fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBufferOffsetByGroup
+ */
+func (s *StorageClient) UploadBufferOffsetByGroupCtx(ctx context.Context, groupName string, fileBuff []byte, offset int, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadBufferOffsetByGroup(groupName, fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBuffer
+ */
+func (s *StorageClient) UploadBufferCtx(ctx context.Context, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadBuffer(fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadBufferByGroup
+ */
+func (s *StorageClient) UploadBufferByGroupCtx(ctx context.Context, groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadBufferByGroup(groupName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadCallback
+ */
+func (s *StorageClient) UploadCallbackCtx(ctx context.Context, groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadCallback(groupName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterFile
+ */
+func (s *StorageClient) UploadMasterFileCtx(ctx context.Context, groupName, masterFilename, prefixName, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadMasterFile(groupName, masterFilename, prefixName, localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterBuffer
+ */
+func (s *StorageClient) UploadMasterBufferCtx(ctx context.Context, groupName, masterFilename, prefixName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadMasterBuffer(groupName, masterFilename, prefixName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterOffsetBuffer
+ */
+func (s *StorageClient) UploadMasterOffsetBufferCtx(ctx context.Context, groupName, masterFilename, prefixName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadMasterOffsetBuffer(groupName, masterFilename, prefixName, fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadMasterCallback
+ */
+func (s *StorageClient) UploadMasterCallbackCtx(ctx context.Context, groupName, masterFilename, prefixName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadMasterCallback(groupName, masterFilename, prefixName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderFile
+ */
+func (s *StorageClient) UploadAppenderFileCtx(ctx context.Context, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderFile(localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderFileByGroup
+ */
+func (s *StorageClient) UploadAppenderFileByGroupCtx(ctx context.Context, groupName, localFilename, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderFileByGroup(groupName, localFilename, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderOffsetBuffer
+ */
+func (s *StorageClient) UploadAppenderOffsetBufferCtx(ctx context.Context, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderOffsetBuffer(fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderOffsetBufferByGroup
+ */
+func (s *StorageClient) UploadAppenderOffsetBufferByGroupCtx(ctx context.Context, groupName string, fileBuff []byte, offset, length int, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderOffsetBufferByGroup(groupName, fileBuff, offset, length, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderBuffer
+ */
+func (s *StorageClient) UploadAppenderBufferCtx(ctx context.Context, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderBuffer(fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderBufferByGroup
+ */
+func (s *StorageClient) UploadAppenderBufferByGroupCtx(ctx context.Context, groupName string, fileBuff []byte, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderBufferByGroup(groupName, fileBuff, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of UploadAppenderCallback
+ */
+func (s *StorageClient) UploadAppenderCallbackCtx(ctx context.Context, groupName string, fileSize int, callback UploadCallback, fileExtName string, metaList []NameValuePair) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.UploadAppenderCallback(groupName, fileSize, callback, fileExtName, metaList)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendFile
+ */
+func (s *StorageClient) AppendFileCtx(ctx context.Context, groupName, appenderFilename, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.AppendFile(groupName, appenderFilename, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendBuffer
+ */
+func (s *StorageClient) AppendBufferCtx(ctx context.Context, groupName, appenderFilename string, fileBuff []byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.AppendBuffer(groupName, appenderFilename, fileBuff)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendOffsetBuffer
+ */
+func (s *StorageClient) AppendOffsetBufferCtx(ctx context.Context, groupName, appenderFilename string, fileBuff []byte, offset, length int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.AppendOffsetBuffer(groupName, appenderFilename, fileBuff, offset, length)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of AppendCallback
+ */
+func (s *StorageClient) AppendCallbackCtx(ctx context.Context, groupName, appenderFilename string, fileSize int, callback UploadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.AppendCallback(groupName, appenderFilename, fileSize, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyFile
+ */
+func (s *StorageClient) ModifyFileCtx(ctx context.Context, groupName, appenderFilename string, fileOffset int, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.ModifyFile(groupName, appenderFilename, fileOffset, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyBuffer
+ */
+func (s *StorageClient) ModifyBufferCtx(ctx context.Context, groupName, appenderFilename string, fileOffset int, fileBuff []byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.ModifyBuffer(groupName, appenderFilename, fileOffset, fileBuff)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyOffsetBuffer
+ */
+func (s *StorageClient) ModifyOffsetBufferCtx(ctx context.Context, groupName, appenderFilename string, fileOffset int, fileBuff []byte, bufferOffset, bufferLength int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.ModifyOffsetBuffer(groupName, appenderFilename, fileOffset, fileBuff, bufferOffset, bufferLength)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ModifyCallback
+ */
+func (s *StorageClient) ModifyCallbackCtx(ctx context.Context, groupName, appenderFilename string, fileOffset, modifySize int, callback UploadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.ModifyCallback(groupName, appenderFilename, fileOffset, modifySize, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteFile
+ */
+func (s *StorageClient) DeleteFileCtx(ctx context.Context, groupName, remoteFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DeleteFile(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of TruncateFile
+ */
+func (s *StorageClient) TruncateFileCtx(ctx context.Context, groupName, appenderFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.TruncateFile(groupName, appenderFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of TruncateFileBySize
+ */
+func (s *StorageClient) TruncateFileBySizeCtx(ctx context.Context, groupName, appenderFilename string, truncatedFileSize int) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.TruncateFileBySize(groupName, appenderFilename, truncatedFileSize)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadBuffer
+ */
+func (s *StorageClient) DownloadBufferCtx(ctx context.Context, groupName, remoteFilename string) ([]byte, error) {
+	var result []byte
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadBuffer(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadOffsetBuffer
+ */
+func (s *StorageClient) DownloadOffsetBufferCtx(ctx context.Context, groupName, remoteFilename string, fileOffset, downloadBytes int) ([]byte, error) {
+	var result []byte
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadFile
+ */
+func (s *StorageClient) DownloadFileCtx(ctx context.Context, groupName, remoteFilename, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadFile(groupName, remoteFilename, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadFileByOffsetBuffer
+ */
+func (s *StorageClient) DownloadFileByOffsetBufferCtx(ctx context.Context, groupName, remoteFilename string, fileOffset, downloadBytes int, localFilename string) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadFileByOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes, localFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadCallback
+ */
+func (s *StorageClient) DownloadCallbackCtx(ctx context.Context, groupName, remoteFilename string, callback DownloadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadCallback(groupName, remoteFilename, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DownloadCallbackByOffsetBuffer
+ */
+func (s *StorageClient) DownloadCallbackByOffsetBufferCtx(ctx context.Context, groupName, remoteFilename string, fileOffset, downloadBytes int, callback DownloadCallback) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.DownloadCallbackByOffsetBuffer(groupName, remoteFilename, fileOffset, downloadBytes, callback)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetMetadata
+ */
+func (s *StorageClient) GetMetadataCtx(ctx context.Context, groupName, remoteFilename string) ([]NameValuePair, error) {
+	var result []NameValuePair
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.GetMetadata(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of SetMetadata
+ */
+func (s *StorageClient) SetMetadataCtx(ctx context.Context, groupName, remoteFilename string, metaList []NameValuePair, opFlag byte) (int, error) {
+	var result int
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.SetMetadata(groupName, remoteFilename, metaList, opFlag)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFileInfo
+ */
+func (s *StorageClient) GetFileInfoCtx(ctx context.Context, groupName, remoteFilename string) (*FileInfo, error) {
+	var result *FileInfo
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.GetFileInfo(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of QueryFileInfo
+ */
+func (s *StorageClient) QueryFileInfoCtx(ctx context.Context, groupName, remoteFilename string) (*FileInfo, error) {
+	var result *FileInfo
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.QueryFileInfo(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of FileExists
+ */
+func (s *StorageClient) FileExistsCtx(ctx context.Context, groupName, remoteFilename string) (bool, error) {
+	var result bool
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.FileExists(groupName, remoteFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of RegenerateAppenderFilename
+ */
+func (s *StorageClient) RegenerateAppenderFilenameCtx(ctx context.Context, groupName, appenderFilename string) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.RegenerateAppenderFilename(groupName, appenderFilename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of CreateLink
+ */
+func (s *StorageClient) CreateLinkCtx(ctx context.Context, groupName, sourceFilename string, sourceSignature []byte, masterFilename, prefixName, fileExtName string) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.CreateLink(groupName, sourceFilename, sourceSignature, masterFilename, prefixName, fileExtName)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,418 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+/**
+ * option of Upload, Append and Modify
+ */
+type UploadOption func(o *uploadOptions)
+
+type uploadOptions struct {
+	groupName       string
+	fileExtName     string
+	metaList        []NameValuePair
+	masterFilename  string
+	prefixName      string
+	slave           bool
+	appender        bool
+	fileSize        int64 //-1 for unknown
+}
+
+/**
+ * upload the file to the group
+ *
+ * @param group_name the group name to upload file to, empty for any group
+ */
+func WithGroup(groupName string) UploadOption {
+	return func(o *uploadOptions) {
+		o.groupName = groupName
+	}
+}
+
+/**
+ * set the ext name of the uploaded file, the ext name is extracted from the
+ * local filename when the source is a file path and the ext name is empty
+ *
+ * @param file_ext_name file ext name, do not include dot(.)
+ */
+func WithExtName(fileExtName string) UploadOption {
+	return func(o *uploadOptions) {
+		o.fileExtName = fileExtName
+	}
+}
+
+/**
+ * set the metadata of the uploaded file
+ *
+ * @param meta_list meta info array
+ */
+func WithMetadata(metaList ...NameValuePair) UploadOption {
+	return func(o *uploadOptions) {
+		o.metaList = metaList
+	}
+}
+
+/**
+ * upload the slave file of the master file, the filename of the slave file
+ * is generated by the master filename and the prefix name
+ *
+ * @param group_name      the group name of master file
+ * @param master_filename the master file name to generate the slave file
+ * @param prefix_name     the prefix name to generate the slave file
+ */
+func WithSlave(groupName, masterFilename, prefixName string) UploadOption {
+	return func(o *uploadOptions) {
+		o.groupName = groupName
+		o.masterFilename = masterFilename
+		o.prefixName = prefixName
+		o.slave = true
+	}
+}
+
+/**
+ * upload an appender file, which can be appended, modified and truncated
+ */
+func WithAppender() UploadOption {
+	return func(o *uploadOptions) {
+		o.appender = true
+	}
+}
+
+/**
+ * set the size of the content read from the source, required for UploadCallback
+ * and optional for io.Reader
+ *
+ * @param file_size the content size
+ */
+func WithSize(fileSize int64) UploadOption {
+	return func(o *uploadOptions) {
+		o.fileSize = fileSize
+	}
+}
+
+func newUploadOptions(opts []UploadOption) *uploadOptions {
+	var o = &uploadOptions{fileSize:-1}
+	for _,opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+/**
+ * get the callback sending the content of the source:<br>
+ * <ul><li> string: the local filename</li></ul>
+ * <ul><li> []byte: the content</li></ul>
+ * <ul><li> UploadCallback: the callback sending WithSize bytes</li></ul>
+ * <ul><li> io.Reader: the reader, the content is read from the current position
+ * to the end, and read into memory when the size is unknown</li></ul>
+ *
+ * @param source the source of the content
+ * @return the callback, the content size and the function to release the source
+ */
+func (o *uploadOptions) open(source interface{}) (UploadCallback, int64, func(), error) {
+	var release = func() {}
+	switch src := source.(type) {
+	case string:
+		file,err := os.Open(src)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		stat,err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, nil, err
+		}
+		if o.fileExtName == "" {
+			var nPos = strings.LastIndexByte(src, '.')
+			if nPos > 0 && len(src) - nPos <= FDFS_FILE_EXT_NAME_MAX_LEN + 1 {
+				o.fileExtName = src[nPos + 1:]
+			}
+		}
+		return NewUploadStream(file, int(stat.Size())), stat.Size(), func() {
+			file.Close()
+		}, nil
+	case []byte:
+		return NewUploadBuff(src, 0, len(src)), int64(len(src)), release, nil
+	case UploadCallback:
+		if o.fileSize < 0 {
+			return nil, 0, nil, newInvalidError("the size is required for upload callback")
+		}
+		return src, o.fileSize, release, nil
+	case io.Reader:
+		var fileSize = o.fileSize
+		if fileSize < 0 {
+			if seeker,ok := src.(io.Seeker); ok {
+				pos,err := seeker.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return nil, 0, nil, err
+				}
+				if fileSize,err = seeker.Seek(0, io.SeekEnd); err != nil {
+					return nil, 0, nil, err
+				}
+				if _,err = seeker.Seek(pos, io.SeekStart); err != nil {
+					return nil, 0, nil, err
+				}
+				fileSize -= pos
+			} else if buff,ok := src.(interface{ Len() int }); ok {
+				fileSize = int64(buff.Len())
+			} else {
+				content,err := io.ReadAll(src)
+				if err != nil {
+					return nil, 0, nil, err
+				}
+				return NewUploadBuff(content, 0, len(content)), int64(len(content)), release, nil
+			}
+		}
+		return NewUploadStream(src, int(fileSize)), fileSize, release, nil
+	}
+
+	return nil, 0, nil, newInvalidError("unsupported upload source %T", source)
+}
+
+/**
+ * upload file to storage server
+ *
+ * @param ctx    the context
+ * @param source the local filename, []byte, io.Reader or UploadCallback
+ * @param opts   the upload options
+ * @return the file id of the new created file if success, return null if fail
+ */
+func (s *StorageClient) Upload(ctx context.Context, source interface{}, opts ...UploadOption) (*FileID, error) {
+	var result *FileID
+	var err = s.withContext(ctx, func(c *StorageClient) (err error) {
+		result,err = c.upload(source, opts...)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *StorageClient) upload(source interface{}, opts ...UploadOption) (*FileID, error) {
+	var o = newUploadOptions(opts)
+	var cmd byte = STORAGE_PROTO_CMD_UPLOAD_FILE
+	if o.slave {
+		if o.groupName == "" || o.masterFilename == "" || o.prefixName == "" || o.appender {
+			s.setErrno(ERR_NO_EINVAL)
+			return nil, newInvalidError("group name, master filename and prefix name are required")
+		}
+		cmd = STORAGE_PROTO_CMD_UPLOAD_SLAVE_FILE
+	} else if o.appender {
+		cmd = STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE
+	}
+
+	callback,fileSize,release,err := o.open(source)
+	if err != nil {
+		if errors.Is(err, ErrInvalid) {
+			s.setErrno(ERR_NO_EINVAL)
+		}
+		return nil, err
+	}
+	defer release()
+
+	return s.doUploadFile(cmd, o.groupName, o.masterFilename, o.prefixName, o.fileExtName, int(fileSize), callback, o.metaList)
+}
+
+/**
+ * append file to storage server
+ *
+ * @param ctx               the context
+ * @param group_name        the group name of appender file
+ * @param appender_filename the appender filename
+ * @param source            the local filename, []byte, io.Reader or UploadCallback
+ * @param opts              the upload options, only WithSize applies
+ * @return nil for success
+ */
+func (s *StorageClient) Append(ctx context.Context, groupName, appenderFilename string, source interface{}, opts ...UploadOption) error {
+	return s.withContext(ctx, func(c *StorageClient) error {
+		return c.appendFile(groupName, appenderFilename, source, opts...)
+	})
+}
+
+func (s *StorageClient) appendFile(groupName, appenderFilename string, source interface{}, opts ...UploadOption) error {
+	callback,fileSize,release,err := newUploadOptions(opts).open(source)
+	if err != nil {
+		if errors.Is(err, ErrInvalid) {
+			s.setErrno(ERR_NO_EINVAL)
+		}
+		return err
+	}
+	defer release()
+
+	_,err = s.doAppendFile(groupName, appenderFilename, int(fileSize), callback)
+	return err
+}
+
+/**
+ * modify appender file to storage server
+ *
+ * @param ctx               the context
+ * @param group_name        the group name of appender file
+ * @param appender_filename the appender filename
+ * @param file_offset       the offset of appender file
+ * @param source            the local filename, []byte, io.Reader or UploadCallback
+ * @param opts              the upload options, only WithSize applies
+ * @return nil for success
+ */
+func (s *StorageClient) Modify(ctx context.Context, groupName, appenderFilename string, fileOffset int64, source interface{}, opts ...UploadOption) error {
+	return s.withContext(ctx, func(c *StorageClient) error {
+		return c.modifyFile(groupName, appenderFilename, fileOffset, source, opts...)
+	})
+}
+
+func (s *StorageClient) modifyFile(groupName, appenderFilename string, fileOffset int64, source interface{}, opts ...UploadOption) error {
+	callback,fileSize,release,err := newUploadOptions(opts).open(source)
+	if err != nil {
+		if errors.Is(err, ErrInvalid) {
+			s.setErrno(ERR_NO_EINVAL)
+		}
+		return err
+	}
+	defer release()
+
+	_,err = s.doModifyFile(groupName, appenderFilename, int(fileOffset), int(fileSize), callback)
+	return err
+}
+
+/**
+ * option of Download
+ */
+type DownloadOption func(o *downloadOptions)
+
+type downloadOptions struct {
+	fileOffset     int64
+	downloadBytes  int64
+}
+
+/**
+ * download the part of the file
+ *
+ * @param file_offset    the start offset of the file
+ * @param download_bytes download bytes, 0 for remain bytes from offset
+ */
+func WithRange(fileOffset, downloadBytes int64) DownloadOption {
+	return func(o *downloadOptions) {
+		o.fileOffset = fileOffset
+		o.downloadBytes = downloadBytes
+	}
+}
+
+/**
+ * download file from storage server
+ *
+ * @param ctx             the context
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @param dest            the local filename, *[]byte to store the content, io.Writer or DownloadCallback
+ * @param opts            the download options
+ * @return nil for success
+ */
+func (s *StorageClient) Download(ctx context.Context, groupName, remoteFilename string, dest interface{}, opts ...DownloadOption) error {
+	return s.withContext(ctx, func(c *StorageClient) error {
+		return c.download(groupName, remoteFilename, dest, opts...)
+	})
+}
+
+func (s *StorageClient) download(groupName, remoteFilename string, dest interface{}, opts ...DownloadOption) error {
+	var o downloadOptions
+	for _,opt := range opts {
+		opt(&o)
+	}
+	var fileOffset,downloadBytes = int(o.fileOffset), int(o.downloadBytes)
+
+	var err error
+	switch d := dest.(type) {
+	case string:
+		_,err = s.downloadToFile(groupName, remoteFilename, fileOffset, downloadBytes, d)
+	case *[]byte:
+		*d,err = s.downloadToBuffer(groupName, remoteFilename, fileOffset, downloadBytes)
+	case DownloadCallback:
+		_,err = s.downloadToCallback(groupName, remoteFilename, fileOffset, downloadBytes, d)
+	case io.Writer:
+		_,err = s.downloadToCallback(groupName, remoteFilename, fileOffset, downloadBytes, NewDownLoadStream(d))
+	default:
+		s.setErrno(ERR_NO_EINVAL)
+		err = newInvalidError("unsupported download destination %T", dest)
+	}
+
+	return err
+}
+
+/**
+ * upload file to storage server
+ *
+ * @param ctx    the context
+ * @param source the local filename, []byte, io.Reader or UploadCallback
+ * @param opts   the upload options
+ * @return file id(including group name and filename) if success, <br>
+ * return empty if fail
+ */
+func (s *StorageClient1) Upload1(ctx context.Context, source interface{}, opts ...UploadOption) (string, error) {
+	fileId,err := s.Upload(ctx, source, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return fileId.String(), nil
+}
+
+/**
+ * append file to storage server
+ *
+ * @param ctx              the context
+ * @param appender_file_id the appender file id
+ * @param source           the local filename, []byte, io.Reader or UploadCallback
+ * @param opts             the upload options, only WithSize applies
+ * @return nil for success
+ */
+func (s *StorageClient1) Append1(ctx context.Context, appenderFileId string, source interface{}, opts ...UploadOption) error {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return err
+	}
+
+	return s.Append(ctx, parts[0], parts[1], source, opts...)
+}
+
+/**
+ * modify appender file to storage server
+ *
+ * @param ctx              the context
+ * @param appender_file_id the appender file id
+ * @param file_offset      the offset of appender file
+ * @param source           the local filename, []byte, io.Reader or UploadCallback
+ * @param opts             the upload options, only WithSize applies
+ * @return nil for success
+ */
+func (s *StorageClient1) Modify1(ctx context.Context, appenderFileId string, fileOffset int64, source interface{}, opts ...UploadOption) error {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(appenderFileId, parts); err != nil {
+		return err
+	}
+
+	return s.Modify(ctx, parts[0], parts[1], fileOffset, source, opts...)
+}
+
+/**
+ * download file from storage server
+ *
+ * @param ctx     the context
+ * @param file_id the file id(including group name and filename)
+ * @param dest    the local filename, *[]byte to store the content, io.Writer or DownloadCallback
+ * @param opts    the download options
+ * @return nil for success
+ */
+func (s *StorageClient1) Download1(ctx context.Context, fileId string, dest interface{}, opts ...DownloadOption) error {
+	var parts = make([]string, 2)
+	if err := s.splitFileId(fileId, parts); err != nil {
+		return err
+	}
+
+	return s.Download(ctx, parts[0], parts[1], dest, opts...)
+}
@@ -0,0 +1,113 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reader without Seek and Len, the content is read into memory
+type onlyReader struct {
+	io.Reader
+}
+
+func TestStorageClientOptions(t *testing.T) {
+	listener,server := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+
+	var addr = listener.Addr().(*net.TCPAddr)
+	storageServer,err := NewStorageServer(addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	var storageClient = NewStorageClientByServer(nil, storageServer)
+	var ctx = context.Background()
+
+	var reader = strings.NewReader("skip:hello")
+	reader.Seek(5, io.SeekStart)
+	fileId,err := storageClient.Upload(ctx, reader, WithAppender(), WithExtName("txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileId.GetFilename() != appenderFilename(0, "txt") {
+		t.Fatalf("file id %s", fileId)
+	}
+	var groupName,filename = fileId.GetGroupName(), fileId.GetFilename()
+
+	var localFilename = filepath.Join(t.TempDir(), "local.dat")
+	if err = os.WriteFile(localFilename, []byte(" world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var sources = []interface{}{localFilename, []byte("!"), onlyReader{strings.NewReader("?")}, bytes.NewBufferString("*")}
+	for _,source := range sources {
+		if err = storageClient.Append(ctx, groupName, filename, source); err != nil {
+			t.Fatalf("append %T: %v", source, err)
+		}
+	}
+	if err = storageClient.Append(ctx, groupName, filename, NewUploadBuff([]byte("#"), 0, 1), WithSize(1)); err != nil {
+		t.Fatal(err)
+	}
+	if data,_ := server.get(filename); string(data) != "hello world!?*#" {
+		t.Fatalf("content %q", data)
+	}
+
+	var buff []byte
+	if err = storageClient.Download(ctx, groupName, filename, &buff, WithRange(6, 5)); err != nil || string(buff) != "world" {
+		t.Fatalf("download %q, err %v", buff, err)
+	}
+	var out bytes.Buffer
+	if err = storageClient.Download(ctx, groupName, filename, &out, WithRange(6, 0)); err != nil || out.String() != "world!?*#" {
+		t.Fatalf("download %q, err %v", out.String(), err)
+	}
+	if err = storageClient.Download(ctx, groupName, filename, localFilename); err != nil {
+		t.Fatal(err)
+	}
+	if data,_ := os.ReadFile(localFilename); string(data) != "hello world!?*#" {
+		t.Fatalf("downloaded file %q", data)
+	}
+
+	var storageClient1 = NewStorageClient1(nil, storageServer)
+	if err = storageClient1.Append1(ctx, fileId.String(), []byte("$")); err != nil {
+		t.Fatal(err)
+	}
+	if err = storageClient1.Download1(ctx, fileId.String(), &buff, WithRange(15, 0)); err != nil || string(buff) != "$" {
+		t.Fatalf("download %q, err %v", buff, err)
+	}
+
+	// invalid options and sources are rejected before sending
+	var invalid = []func() error{
+		func() error {
+			_,err := storageClient.Upload(ctx, []byte("x"), WithSlave("group1", "", "_s"))
+			return err
+		},
+		func() error {
+			_,err := storageClient.Upload(ctx, []byte("x"), WithSlave("group1", filename, "_s"), WithAppender())
+			return err
+		},
+		func() error {
+			_,err := storageClient.Upload(ctx, 100)
+			return err
+		},
+		func() error {
+			return storageClient.Append(ctx, groupName, filename, NewUploadBuff([]byte("x"), 0, 1))
+		},
+		func() error {
+			return storageClient.Download(ctx, groupName, filename, 100)
+		},
+		func() error {
+			return storageClient1.Download1(ctx, "invalid", &buff)
+		},
+	}
+	for i,fn := range invalid {
+		if err = fn(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%d: err %v, expect %v", i, err, ErrInvalid)
+		}
+	}
+}
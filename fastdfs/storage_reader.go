@@ -0,0 +1,378 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errReaderClosed = errors.New("fastdfs: read on closed reader")
+
+/**
+ * reader of a file range on storage server, the content is streamed by
+ * ranged download requests, Seek and ReadAt send a new request at the position.
+ * the reader owns its connections, it can be used while the storage client
+ * runs other operations, ReadAt is safe for concurrent use.
+ */
+type StorageReader struct {
+	client          *StorageClient
+	groupName       string
+	remoteFilename  string
+	addr            net.Addr       //the storage server address
+	storePathIndex  int
+	offset          int64          //start offset of the range in the file
+	size            int64          //bytes of the range
+	pos             int64          //read position in the range
+	storageServer   *StorageServer //server of the stream, nil for none
+	remainBytes     int64          //unread bytes of the stream
+	closed          bool
+	lock            sync.Mutex
+}
+
+/**
+ * open the file on storage server for reading
+ *
+ * @param group_name      the group name of storage server
+ * @param remote_filename filename on storage server
+ * @param file_offset     the start offset of the file
+ * @param download_bytes  download bytes, 0 for remain bytes from offset
+ * @return the reader of the range, should be closed
+ */
+func (s *StorageClient) OpenReader(groupName, remoteFilename string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	return s.openReader(s.fork(nil), groupName, remoteFilename, fileOffset, downloadBytes)
+}
+
+/**
+ * context version of OpenReader, the context applies to every request
+ * of the reader until it is closed.
+ */
+func (s *StorageClient) OpenReaderCtx(ctx context.Context, groupName, remoteFilename string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var c = s.fork(newConnContext(ctx))
+	reader,err := s.openReader(c, groupName, remoteFilename, fileOffset, downloadBytes)
+	if err != nil {
+		return nil, c.connCtx.release(err)
+	}
+
+	return reader, nil
+}
+
+func (s *StorageClient) openReader(c *StorageClient, groupName, remoteFilename string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	defer func() {
+		s.setErrno(c.GetErrorCode())
+	}()
+
+	if fileOffset < 0 || downloadBytes < 0 {
+		c.setErrno(ERR_NO_EINVAL)
+		return nil, newInvalidError("invalid range offset %d, bytes %d", fileOffset, downloadBytes)
+	}
+
+	var reader *StorageReader
+	var op = &Operation{Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE, GroupName:groupName, Filename:remoteFilename,
+		Offset:int64(fileOffset), Size:int64(downloadBytes)}
+	var err = c.intercept(op, func(op *Operation) error {
+		return c.withRetry(op.Cmd, nil, func(rc *StorageClient) (err error) {
+			reader,err = rc.openReaderOnce(op.GroupName, op.Filename, int(op.Offset), int(op.Size))
+			op.Result = reader
+			return err
+		})
+	})
+
+	return reader, err
+}
+
+/**
+ * one attempt of openReader, the reader keeps the copy of the client running it
+ */
+func (s *StorageClient) openReaderOnce(groupName, remoteFilename string, fileOffset, downloadBytes int) (*StorageReader, error) {
+	storageServer,bNewConnection,err := s.newReadableStorageConnection(groupName, remoteFilename)
+	if err != nil {
+		return nil, err
+	}
+	if !bNewConnection {
+		// the server of the client is shared, the reader uses its own connection.
+		if storageServer,err = s.dialStorage(storageServer.GetAddress(), storageServer.GetStorePathIndex()); err != nil {
+			return nil, err
+		}
+	}
+
+	s.storageAddr = storageServer.GetAddress()
+
+	var reader = &StorageReader{
+		client:s,
+		groupName:groupName,
+		remoteFilename:remoteFilename,
+		addr:storageServer.GetAddress(),
+		storePathIndex:storageServer.GetStorePathIndex(),
+		offset:int64(fileOffset),
+	}
+	if reader.remainBytes,err = reader.request(storageServer, reader.offset, int64(downloadBytes)); err != nil {
+		reader.closeServer(storageServer)
+		return nil, err
+	}
+	reader.storageServer = storageServer
+	reader.size = reader.remainBytes
+	reader.closeIfDone()
+
+	return reader, nil
+}
+
+/**
+ * connect to the storage server
+ *
+ * @param addr           the storage server address
+ * @param storePathIndex the store path index on the storage server
+ */
+func (s *StorageClient) dialStorage(addr net.Addr, storePathIndex int) (*StorageServer, error) {
+	host,port,err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	portNum,err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStorageServer(contextOf(s.connCtx), s.config, s.cmd, host, portNum, storePathIndex)
+}
+
+/**
+ * send the download request and receive the response header
+ *
+ * @param storageServer  the storage server
+ * @param fileOffset     the start offset of the file
+ * @param downloadBytes  download bytes, 0 for remain bytes from offset
+ * @return the body length of the response
+ */
+func (r *StorageReader) request(storageServer *StorageServer, fileOffset, downloadBytes int64) (int64, error) {
+	var c = r.client
+	storageSocket,err := c.bindSocket(storageServer)
+	if err != nil {
+		return 0, err
+	}
+	if err = c.sendDownloadPackage(storageServer, r.groupName, r.remoteFilename, int(fileOffset), int(downloadBytes)); err != nil {
+		return 0, err
+	}
+	var start = time.Now()
+	var span = c.config.startSpan(contextOf(c.connCtx), PhaseResponseWait, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
+	header,err := RecvHeader(storageSocket, STORAGE_PROTO_CMD_RESP, -1)
+	span.End(0, err)
+	if err != nil {
+		c.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), 0, time.Since(start), 0, err)
+		if err == io.EOF {
+			// the caller of Read takes io.EOF as the end of file.
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	c.config.logResponse(STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress(), header.Errno, time.Since(start), int64(header.BodyLen), nil)
+	c.setErrno(header.Errno)
+	if header.Errno != 0 {
+		return 0, newError(header.Errno, STORAGE_PROTO_CMD_DOWNLOAD_FILE, storageServer.GetAddress())
+	}
+	if downloadBytes > 0 && int64(header.BodyLen) != downloadBytes {
+		markBroken(storageSocket)
+		c.setErrno(ERR_NO_EIO)
+		return 0, &Error{Errno:ERR_NO_EIO, Cmd:STORAGE_PROTO_CMD_DOWNLOAD_FILE, Addr:storageServer.GetAddress().String(),
+			Message:fmt.Sprintf("recv body length %d != %d", header.BodyLen, downloadBytes)}
+	}
+
+	return int64(header.BodyLen), nil
+}
+
+/**
+ * @return the bytes of the range
+ */
+func (r *StorageReader) Size() int64 {
+	return r.size
+}
+
+/**
+ * read the range from the current position, io.Reader implementation
+ */
+func (r *StorageReader) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return 0, errReaderClosed
+	}
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.storageServer == nil {
+		storageServer,err := r.client.dialStorage(r.addr, r.storePathIndex)
+		if err != nil {
+			return 0, err
+		}
+		if r.remainBytes,err = r.request(storageServer, r.offset + r.pos, r.size - r.pos); err != nil {
+			r.closeServer(storageServer)
+			return 0, err
+		}
+		r.storageServer = storageServer
+	}
+
+	if int64(len(p)) > r.remainBytes {
+		p = p[:r.remainBytes]
+	}
+	storageSocket,err := r.storageServer.GetSocket()
+	if err != nil {
+		return 0, err
+	}
+	n,err := storageSocket.Read(p)
+	r.pos += int64(n)
+	r.remainBytes -= int64(n)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.client.setErrno(ERR_NO_EIO)
+		r.closeStream()
+		return n, err
+	}
+	r.closeIfDone()
+
+	return n, nil
+}
+
+/**
+ * set the position of the next Read, io.Seeker implementation,
+ * the stream is reopened at the new position by the next Read
+ */
+func (r *StorageReader) Seek(offset int64, whence int) (int64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return 0, errReaderClosed
+	}
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, newInvalidError("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return 0, newInvalidError("negative position %d", pos)
+	}
+	if pos != r.pos {
+		r.closeStream()
+		r.pos = pos
+	}
+
+	return pos, nil
+}
+
+/**
+ * read the range at the offset with a new request, io.ReaderAt implementation
+ */
+func (r *StorageReader) ReadAt(p []byte, off int64) (int, error) {
+	r.lock.Lock()
+	var closed = r.closed
+	r.lock.Unlock()
+	if closed {
+		return 0, errReaderClosed
+	}
+	if off < 0 {
+		return 0, newInvalidError("negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	var length = int64(len(p))
+	if length > r.size - off {
+		length = r.size - off
+	}
+	if length == 0 {
+		return 0, nil
+	}
+
+	storageServer,err := r.client.dialStorage(r.addr, r.storePathIndex)
+	if err != nil {
+		return 0, err
+	}
+	defer r.closeServer(storageServer)
+	if _,err = r.request(storageServer, r.offset + off, length); err != nil {
+		return 0, err
+	}
+	storageSocket,err := storageServer.GetSocket()
+	if err != nil {
+		return 0, err
+	}
+	n,err := io.ReadFull(storageSocket, p[:length])
+	if err != nil {
+		markBroken(storageSocket)
+		r.client.setErrno(ERR_NO_EIO)
+		return n, err
+	}
+	if length < int64(len(p)) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+/**
+ * close the reader, the connection with unread bytes is discarded
+ */
+func (r *StorageReader) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.closeStream()
+	if r.client.connCtx != nil {
+		return r.client.connCtx.release(nil)
+	}
+
+	return nil
+}
+
+// return the connection to the pool when the stream is read.
+func (r *StorageReader) closeIfDone() {
+	if r.remainBytes == 0 {
+		r.closeStream()
+	}
+}
+
+func (r *StorageReader) closeStream() {
+	if r.storageServer == nil {
+		return
+	}
+	if r.remainBytes > 0 {
+		if storageSocket,err := r.storageServer.GetSocket(); err == nil {
+			markBroken(storageSocket)
+		}
+	}
+	r.closeServer(r.storageServer)
+	r.storageServer = nil
+	r.remainBytes = 0
+}
+
+// unbind the connection of the finished request from the context of the
+// reader, then close it, the context keeps no connection between requests.
+func (r *StorageReader) closeServer(storageServer *StorageServer) {
+	if r.client.connCtx != nil && storageServer.conn != nil {
+		r.client.connCtx.unbind(storageServer.conn)
+	}
+	storageServer.Close()
+}
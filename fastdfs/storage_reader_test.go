@@ -0,0 +1,231 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// answer download requests with the ranges of content, file info with the crc32.
+func startDownloadServer(t *testing.T, address string, content []byte, crc32 uint32) net.Listener {
+	listener,err := net.Listen("tcp", address)
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				var header = make([]byte, FDFS_PROTO_PKG_LEN_SIZE + 2)
+				for {
+					if _,err := io.ReadFull(conn, header); err != nil {
+						return
+					}
+					var body = make([]byte, Buff2long(header, 0))
+					if _,err := io.ReadFull(conn, body); err != nil {
+						return
+					}
+					var res []byte
+					switch header[PROTO_HEADER_CMD_INDEX] {
+					case FDFS_PROTO_CMD_QUIT:
+						return
+					case STORAGE_PROTO_CMD_DOWNLOAD_FILE:
+						var offset = Buff2long(body, 0)
+						var length = Buff2long(body, FDFS_PROTO_PKG_LEN_SIZE)
+						if length == 0 {
+							length = int64(len(content)) - offset
+						}
+						if offset > int64(len(content)) || offset + length > int64(len(content)) {
+							res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_EINVAL)
+							break
+						}
+						res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, length, 0)
+						res = append(res, content[offset:offset + length]...)
+					case STORAGE_PROTO_CMD_QUERY_FILE_INFO:
+						res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 3 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_IPADDR_SIZE, 0)
+						res = append(res, Long2Buff(int64(len(content)))...)
+						res = append(res, Long2Buff(0)...)
+						res = append(res, Long2Buff(int64(crc32))...)
+						res = append(res, make([]byte, FDFS_IPADDR_SIZE)...)
+					default:
+						res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, 0)
+					}
+					// send in pieces to stream the body
+					for len(res) > 0 {
+						var n = 1000
+						if n > len(res) {
+							n = len(res)
+						}
+						if _,err := conn.Write(res[:n]); err != nil {
+							return
+						}
+						res = res[n:]
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener
+}
+
+func TestStorageReader(t *testing.T) {
+	var content = make([]byte, 100000)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	var listener = startDownloadServer(t, "127.0.0.1:", content, 0)
+	defer listener.Close()
+
+	var pool = NewConnectionPool(4, 2, time.Minute, time.Minute)
+	var client = NewClient(NewConfig(WithConnectionPool(pool)))
+	defer client.Close()
+	var addr = listener.Addr().(*net.TCPAddr)
+	storageServer,err := newStorageServer(context.Background(), client.config, 0, addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	var storageClient = client.NewStorageClientByServer(nil, storageServer)
+
+	reader,err := storageClient.OpenReader("group1", "M00/00/00/test", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data,err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("read all mismatch, len %d, err %v", len(data), err)
+	}
+	reader.Close()
+	if _,err = reader.Read(data); err != errReaderClosed {
+		t.Fatalf("read on closed reader, err %v", err)
+	}
+
+	// partial read then seek, the unread connection is discarded
+	reader,err = storageClient.OpenReader("group1", "M00/00/00/test", 1000, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Size() != 50000 {
+		t.Fatalf("size %d != 50000", reader.Size())
+	}
+	var buf = make([]byte, 10)
+	if _,err = io.ReadFull(reader, buf); err != nil || !bytes.Equal(buf, content[1000:1010]) {
+		t.Fatalf("read mismatch, err %v", err)
+	}
+	if _,err = reader.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if data,err = io.ReadAll(reader); err != nil || !bytes.Equal(data, content[50990:51000]) {
+		t.Fatalf("read after seek mismatch, err %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			var p = make([]byte, 5000)
+			if n,err := reader.ReadAt(p, off); n != len(p) || err != nil || !bytes.Equal(p, content[1000 + off:1000 + off + 5000]) {
+				t.Errorf("read at %d mismatch, n %d, err %v", off, n, err)
+			}
+		}(int64(i * 6000))
+	}
+	wg.Wait()
+	if n,err := reader.ReadAt(buf, 49995); n != 5 || err != io.EOF {
+		t.Fatalf("read at end, n %d, err %v", n, err)
+	}
+	reader.Close()
+
+	if _,err = storageClient.OpenReader("group1", "M00/00/00/test", 200000, 0); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("open out of range, err %v", err)
+	}
+
+	// serve ranges over http
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reader,err := storageClient.OpenReader("group1", "M00/00/00/test", 0, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer reader.Close()
+		http.ServeContent(w, req, "test", time.Time{}, reader)
+	}))
+	defer server.Close()
+	req,_ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Range", "bytes=300-399")
+	res,err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data,err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent || !bytes.Equal(data, content[300:400]) {
+		t.Fatalf("http range mismatch, status %d, err %v", res.StatusCode, err)
+	}
+
+	// the file id version with context
+	reader,err = client.NewStorageClient1(nil, storageServer).OpenReader1Ctx(context.Background(), "group1/M00/00/00/test", 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data,err = io.ReadAll(reader); err != nil || !bytes.Equal(data, content[100:110]) {
+		t.Fatalf("read by file id mismatch, err %v", err)
+	}
+	reader.Close()
+}
+
+func TestStorageReaderCtxConns(t *testing.T) {
+	var content = make([]byte, 10000)
+	var listener = startDownloadServer(t, "127.0.0.1:", content, 0)
+	defer listener.Close()
+
+	var client = NewClient(NewConfig(WithConnectionPool(NewConnectionPool(4, 2, time.Minute, time.Minute))))
+	defer client.Close()
+	var addr = listener.Addr().(*net.TCPAddr)
+	storageServer,err := newStorageServer(context.Background(), client.config, 0, addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+
+	ctx,cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	reader,err := client.NewStorageClientByServer(nil, storageServer).OpenReaderCtx(ctx, "group1", "M00/00/00/test", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	// the connection of each request is unbound when the request finishes
+	var p = make([]byte, 100)
+	for i := 0; i < 50; i++ {
+		if _,err = reader.ReadAt(p, int64(i * 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _,err = reader.Seek(5000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _,err = io.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	var connCtx = reader.client.connCtx
+	connCtx.lock.Lock()
+	var bound = len(connCtx.conns)
+	connCtx.lock.Unlock()
+	if bound != 0 {
+		t.Fatalf("%d connections bound after the requests, expect 0", bound)
+	}
+}
@@ -1,8 +1,9 @@
 package fastdfs
 
 import (
+	"context"
 	"net"
-	"fmt"
+	"strconv"
 )
 
 type StorageServer struct {
@@ -19,11 +20,27 @@ type StorageServer struct {
  * @param store_path the store path index on the storage server
  */
 func NewStorageServer(ipAddr string, port, storePath int) (*StorageServer, error) {
-	addr,err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", ipAddr, port))
+	return NewStorageServerContext(context.Background(), ipAddr, port, storePath)
+}
+
+/**
+ * Constructor, dialing is aborted when ctx is done
+ *
+ * @param ctx        the context
+ * @param ip_addr    the ip address of storage server
+ * @param port       the port of storage server
+ * @param store_path the store path index on the storage server
+ */
+func NewStorageServerContext(ctx context.Context, ipAddr string, port, storePath int) (*StorageServer, error) {
+	return newStorageServer(ctx, nil, 0, ipAddr, port, storePath)
+}
+
+func newStorageServer(ctx context.Context, config *Config, cmd byte, ipAddr string, port, storePath int) (*StorageServer, error) {
+	addr,err := net.ResolveTCPAddr("tcp", net.JoinHostPort(ipAddr, strconv.Itoa(port)))
 	if err != nil {
 		return nil, err
 	}
-	conn,err := GetSocket(ipAddr, port)
+	conn,err := config.connect(ctx, cmd, addr)
 	if err != nil {
 		return nil, err
 	}
@@ -42,31 +59,7 @@ func NewStorageServer(ipAddr string, port, storePath int) (*StorageServer, error
  * @param store_path the store path index on the storage server
  */
 func NewStorageServerByByte(ipAddr string, port int, storePath byte) (*StorageServer, error) {
-	//fmt.Println([]byte(ipAddr))
-	//ipAddr = ipAddr[:len(ipAddr) - 1]
-	//fmt.Println("address:", fmt.Sprintf("%s:%d", ipAddr, port))
-	//fmt.Println("length:", len(ipAddr))
-	addr,err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", ipAddr, port))
-	if err != nil {
-		return nil, err
-	}
-	conn,err := GetSocket(ipAddr, port)
-	if err != nil {
-		return nil, err
-	}
-
-	var storePathIndex = int(storePath)
-
-	// java code
-	//var storePathIndex = int(storePath)
-	//if int8(storePath) < 0 {
-	//	storePathIndex = 256 + int(int8(storePath))
-	//}
-
-	return &StorageServer{
-		TrackerServer:*NewTrackerServer(conn, addr),
-		storePathIndex:storePathIndex,
-	}, nil
+	return NewStorageServerContext(context.Background(), ipAddr, port, int(storePath))
 }
 
 /**
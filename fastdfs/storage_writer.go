@@ -0,0 +1,198 @@
+package fastdfs
+
+import (
+	"context"
+	"errors"
+)
+
+// the default bytes buffered before sending to storage server
+const DEFAULT_WRITER_CHUNK_SIZE = 1024 * 1024
+
+var errWriterClosed = errors.New("fastdfs: write on closed writer")
+
+/**
+ * writer to upload a file of unknown size, the content is buffered and sent
+ * by chunks, the first chunk creates an appender file and the others are appended.
+ * the writer is not safe for concurrent use.
+ */
+type StorageWriter struct {
+	client       *StorageClient
+	groupName    string
+	fileExtName  string
+	metaList     []NameValuePair
+	chunkSize    int
+	buff         []byte
+	fileId       *FileID  //the appender file, nil for not created
+	err          error    //the first error, the writer fails then
+	closed       bool
+}
+
+/**
+ * create writer to upload file to storage server
+ *
+ * @param group_name    the group name to upload file to, can be empty
+ * @param file_ext_name file ext name, do not include dot(.)
+ * @param meta_list     meta info array
+ * @return the writer, the file is uploaded when the writer is closed,
+ *         Finish closes the writer and returns the file id
+ */
+func (s *StorageClient) CreateWriter(groupName, fileExtName string, metaList []NameValuePair) *StorageWriter {
+	return s.createWriter(s.fork(nil), groupName, fileExtName, metaList)
+}
+
+/**
+ * context version of CreateWriter, the context applies to every request
+ * of the writer until it is closed.
+ */
+func (s *StorageClient) CreateWriterCtx(ctx context.Context, groupName, fileExtName string, metaList []NameValuePair) *StorageWriter {
+	return s.createWriter(s.fork(newConnContext(ctx)), groupName, fileExtName, metaList)
+}
+
+func (s *StorageClient) createWriter(c *StorageClient, groupName, fileExtName string, metaList []NameValuePair) *StorageWriter {
+	return &StorageWriter{
+		client:c,
+		groupName:groupName,
+		fileExtName:fileExtName,
+		metaList:metaList,
+		chunkSize:DEFAULT_WRITER_CHUNK_SIZE,
+	}
+}
+
+/**
+ * set the bytes buffered before sending, should be called before Write
+ *
+ * @param chunkSize the chunk size, <= 0 for the default
+ */
+func (w *StorageWriter) SetChunkSize(chunkSize int) {
+	if chunkSize <= 0 {
+		chunkSize = DEFAULT_WRITER_CHUNK_SIZE
+	}
+	w.chunkSize = chunkSize
+}
+
+/**
+ * buffer the content, the full chunks are sent, io.Writer implementation
+ */
+func (w *StorageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	var written = 0 //bytes of p sent or buffered
+	for len(p) > 0 {
+		var length = w.chunkSize - len(w.buff)
+		if length > len(p) {
+			length = len(p)
+		}
+		w.buff = append(w.buff, p[:length]...)
+		p = p[length:]
+		if len(w.buff) >= w.chunkSize {
+			if err := w.flush(); err != nil {
+				// the bytes of p in the failed chunk are not written
+				return written, err
+			}
+		}
+		written += length
+	}
+
+	return written, nil
+}
+
+/**
+ * send the buffered content and close the writer, io.Closer implementation.
+ * an empty file is created when nothing was written.
+ */
+func (w *StorageWriter) Close() error {
+	_,err := w.Finish()
+
+	return err
+}
+
+/**
+ * send the buffered content and close the writer, same as Close
+ *
+ * @return the file id of the uploaded file, return null if fail
+ */
+func (w *StorageWriter) Finish() (*FileID, error) {
+	if w.closed {
+		return w.GetResults(), w.err
+	}
+	w.closed = true
+	if w.err == nil && (len(w.buff) > 0 || w.fileId == nil) {
+		w.flush()
+	}
+	if w.client.connCtx != nil {
+		w.err = w.client.connCtx.release(w.err)
+	}
+
+	return w.GetResults(), w.err
+}
+
+/**
+ * close the writer and delete the uploaded content,
+ * should be called when the content is incomplete
+ */
+func (w *StorageWriter) Abort() error {
+	var err error
+	if w.fileId != nil {
+		_,err = w.client.DeleteFile(w.fileId.GetGroupName(), w.fileId.GetFilename())
+		w.fileId = nil
+	}
+	if !w.closed {
+		w.closed = true
+		w.buff = nil
+		if w.err == nil {
+			w.err = errWriterClosed
+		}
+		if w.client.connCtx != nil {
+			w.client.connCtx.release(nil)
+		}
+	}
+
+	return err
+}
+
+/**
+ * get the uploaded file, valid after Close or Finish returns nil
+ *
+ * @return the file id of the uploaded file, return null if fail
+ */
+func (w *StorageWriter) GetResults() *FileID {
+	if !w.closed || w.err != nil {
+		return nil
+	}
+
+	return w.fileId
+}
+
+/**
+ * get the uploaded file id, valid after Close or Finish returns nil
+ *
+ * @return file id(including group name and filename), return empty if fail
+ */
+func (w *StorageWriter) GetFileId() string {
+	var fileId = w.GetResults()
+	if fileId == nil {
+		return ""
+	}
+
+	return fileId.String()
+}
+
+// send the buffer, the appender file is deleted when the append fails.
+func (w *StorageWriter) flush() error {
+	var c = w.client
+	var callback = NewUploadBuff(w.buff, 0, len(w.buff))
+	if w.fileId == nil {
+		w.fileId,w.err = c.doUploadFile(STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, w.groupName, "", "", w.fileExtName, len(w.buff), callback, w.metaList)
+	} else if _,w.err = c.doAppendFile(w.fileId.GetGroupName(), w.fileId.GetFilename(), len(w.buff), callback); w.err != nil {
+		c.DeleteFile(w.fileId.GetGroupName(), w.fileId.GetFilename())
+		w.fileId = nil
+	}
+	w.buff = w.buff[:0]
+
+	return w.err
+}
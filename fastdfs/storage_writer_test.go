@@ -0,0 +1,254 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// store appender files in memory, append fails for the files larger than maxSize.
+type appenderServer struct {
+	files     map[string][]byte
+	maxSize   int
+	count     int
+	busy      int //answer the next requests with EBUSY
+	drop      int //close the connection without answer after the next requests
+	lock      sync.Mutex
+}
+
+func startAppenderServer(t *testing.T, maxSize int) (net.Listener, *appenderServer) {
+	listener,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		panic(err)
+	}
+	var server = &appenderServer{files:make(map[string][]byte), maxSize:maxSize}
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return listener, server
+}
+
+func (a *appenderServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var header = make([]byte, FDFS_PROTO_PKG_LEN_SIZE + 2)
+	for {
+		if _,err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		var body = make([]byte, Buff2long(header, 0))
+		if _,err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var res []byte
+		a.lock.Lock()
+		if a.busy > 0 && header[PROTO_HEADER_CMD_INDEX] != FDFS_PROTO_CMD_QUIT {
+			a.busy--
+			a.lock.Unlock()
+			res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_EBUSY)
+			if _,err := conn.Write(res); err != nil {
+				return
+			}
+			continue
+		}
+		switch header[PROTO_HEADER_CMD_INDEX] {
+		case FDFS_PROTO_CMD_QUIT:
+			a.lock.Unlock()
+			return
+		case STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE:
+			var filename = appenderFilename(a.count,
+				string(bytes.TrimRight(body[1 + FDFS_PROTO_PKG_LEN_SIZE:1 + FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_EXT_NAME_MAX_LEN], "\x00")))
+			a.count++
+			a.files[filename] = body[1 + FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_EXT_NAME_MAX_LEN:]
+			var groupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
+			copy(groupName, "group1")
+			res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, int64(len(groupName) + len(filename)), 0)
+			res = append(append(res, groupName...), filename...)
+		case STORAGE_PROTO_CMD_APPEND_FILE:
+			var filenameLen = Buff2long(body, 0)
+			var filename = string(body[2 * FDFS_PROTO_PKG_LEN_SIZE:2 * FDFS_PROTO_PKG_LEN_SIZE + filenameLen])
+			var content = body[2 * FDFS_PROTO_PKG_LEN_SIZE + filenameLen:]
+			if _,ok := a.files[filename]; !ok {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_ENOENT)
+			} else if len(a.files[filename]) + len(content) > a.maxSize {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_ENOSPC)
+			} else {
+				a.files[filename] = append(a.files[filename], content...)
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, 0)
+			}
+		case STORAGE_PROTO_CMD_TRUNCATE_FILE:
+			var filenameLen = Buff2long(body, 0)
+			var size = Buff2long(body, FDFS_PROTO_PKG_LEN_SIZE)
+			var filename = string(body[2 * FDFS_PROTO_PKG_LEN_SIZE:2 * FDFS_PROTO_PKG_LEN_SIZE + filenameLen])
+			if content,ok := a.files[filename]; !ok || size > int64(len(content)) {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_EINVAL)
+			} else {
+				a.files[filename] = content[:size]
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, 0)
+			}
+		case STORAGE_PROTO_CMD_QUERY_FILE_INFO:
+			if content,ok := a.files[string(body[FDFS_GROUP_NAME_MAX_LEN:])]; !ok {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_ENOENT)
+			} else {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 3 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_IPADDR_SIZE, 0)
+				res = append(res, Long2Buff(int64(len(content)))...)
+				res = append(res, make([]byte, 2 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_IPADDR_SIZE)...)
+			}
+		case STORAGE_PROTO_CMD_DOWNLOAD_FILE:
+			var offset = Buff2long(body, 0)
+			var length = Buff2long(body, FDFS_PROTO_PKG_LEN_SIZE)
+			var content,ok = a.files[string(body[2 * FDFS_PROTO_PKG_LEN_SIZE + FDFS_GROUP_NAME_MAX_LEN:])]
+			if length == 0 {
+				length = int64(len(content)) - offset
+			}
+			if !ok || offset + length > int64(len(content)) {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_EINVAL)
+			} else {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, length, 0)
+				res = append(res, content[offset:offset + length]...)
+			}
+		case STORAGE_PROTO_CMD_DELETE_FILE:
+			var filename = string(body[FDFS_GROUP_NAME_MAX_LEN:])
+			if _,ok := a.files[filename]; !ok {
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, ERR_NO_ENOENT)
+			} else {
+				delete(a.files, filename)
+				res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, 0)
+			}
+		default:
+			res,_ = PackHeader(STORAGE_PROTO_CMD_RESP, 0, 0)
+		}
+		if a.drop > 0 {
+			a.drop--
+			a.lock.Unlock()
+			return
+		}
+		a.lock.Unlock()
+		if _,err := conn.Write(res); err != nil {
+			return
+		}
+	}
+}
+
+// the filename of the count-th appender file, the count is encoded as the crc32
+func appenderFilename(count int, ext string) string {
+	return testFilename(0, APPENDER_FILE_SIZE, count, nil, strings.Repeat("0", FDFS_FILE_EXT_NAME_MAX_LEN - len(ext)), ext)
+}
+
+func (a *appenderServer) set(filename string, content []byte, maxSize int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.files[filename] = content
+	a.maxSize = maxSize
+}
+
+func (a *appenderServer) fail(busy, drop int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.busy = busy
+	a.drop = drop
+}
+
+func (a *appenderServer) get(filename string) ([]byte, bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	content,ok := a.files[filename]
+
+	return content, ok
+}
+
+func TestStorageWriter(t *testing.T) {
+	listener,server := startAppenderServer(t, 50000)
+	defer listener.Close()
+
+	var addr = listener.Addr().(*net.TCPAddr)
+	storageServer,err := NewStorageServer(addr.IP.String(), addr.Port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storageServer.Close()
+	var storageClient = NewStorageClientByServer(nil, storageServer)
+
+	var content = make([]byte, 25000)
+	for i := range content {
+		content[i] = byte(i * 3)
+	}
+	var writer = storageClient.CreateWriter("", "txt", nil)
+	writer.SetChunkSize(4096)
+	if n,err := io.Copy(writer, bytes.NewReader(content)); n != int64(len(content)) || err != nil {
+		t.Fatalf("copy n %d, err %v", n, err)
+	}
+	if writer.GetResults() != nil {
+		t.Fatalf("results before close")
+	}
+	fileId,err := writer.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileId.String() != "group1/" + appenderFilename(0, "txt") || writer.GetFileId() != fileId.String() {
+		t.Fatalf("file id %s, results %s", fileId, writer.GetFileId())
+	}
+	if data,_ := server.get(writer.GetResults().GetFilename()); !bytes.Equal(data, content) {
+		t.Fatalf("content mismatch, len %d", len(data))
+	}
+	if _,err = writer.Write(content); err != errWriterClosed {
+		t.Fatalf("write on closed writer, err %v", err)
+	}
+
+	// empty file
+	writer = storageClient.CreateWriterCtx(context.Background(), "", "", nil)
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data,ok := server.get(writer.GetResults().GetFilename()); !ok || len(data) != 0 {
+		t.Fatalf("empty file is not created")
+	}
+
+	// the failed appender file is deleted
+	writer = storageClient.CreateWriter("", "bin", nil)
+	writer.SetChunkSize(10000)
+	if _,err = writer.Write(make([]byte, 5000)); err != nil {
+		t.Fatal(err)
+	}
+	// the chunk from 50000 fails, 45000 bytes of the write are sent before
+	if n,err := writer.Write(make([]byte, 55000)); !errors.Is(err, ErrNoSpace) || n != 45000 {
+		t.Fatalf("write n %d, err %v, expect 45000, %v", n, err, ErrNoSpace)
+	}
+	if err = writer.Close(); !errors.Is(err, ErrNoSpace) || writer.GetFileId() != "" {
+		t.Fatalf("close err %v, expect %v", err, ErrNoSpace)
+	}
+	if _,ok := server.get(appenderFilename(2, "bin")); ok {
+		t.Fatalf("failed appender file is not deleted")
+	}
+
+	// abort
+	writer = storageClient.CreateWriter("", "bin", nil)
+	writer.SetChunkSize(100)
+	writer.Write(make([]byte, 150))
+	if err = writer.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if _,ok := server.get(appenderFilename(3, "bin")); ok {
+		t.Fatalf("aborted appender file is not deleted")
+	}
+
+	ctx,cancel := context.WithCancel(context.Background())
+	cancel()
+	writer = storageClient.CreateWriterCtx(ctx, "", "", nil)
+	var start = time.Now()
+	if err = writer.Close(); err != context.Canceled || time.Since(start) > time.Second {
+		t.Fatalf("close err %v, expect %v", err, context.Canceled)
+	}
+}
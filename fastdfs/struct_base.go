@@ -3,10 +3,13 @@ package fastdfs
 import (
 	"time"
 	"strings"
+	"github.com/go/fastdfs/proto"
 )
 
 type StructBaseInterface interface {
 	SetFields(bs []byte, offset int)
+	setCharset(charset string)
+	setProtoVersion(version proto.Version)
 	stringValue(bs []byte, offset int, fieldInfo *FieldInfo) string
 	int64Value(bs []byte, offset int, fieldInfo *FieldInfo) int64
 	longValue(bs []byte, offset int, fieldInfo *FieldInfo) int64
@@ -18,7 +21,8 @@ type StructBaseInterface interface {
 }
 
 type StructBase struct {
-	_ struct{}
+	charset string //empty for the global setting
+	protoVersion proto.Version //the protocol version of the record layout, 0 for PROTO_VERSION_5
 }
 
 /**
@@ -31,12 +35,25 @@ func (s *StructBase) SetFields(bs []byte, offset int) {
 
 }
 
+func (s *StructBase) setCharset(charset string) {
+	s.charset = charset
+}
+
+func (s *StructBase) setProtoVersion(version proto.Version) {
+	s.protoVersion = version
+}
+
 func (s *StructBase) stringValue(bs []byte, offset int, fieldInfo *FieldInfo) string {
-	if bytes,err := ConvertBytesToUTF8(bs, GCharset); err == nil {
+	var charset = s.charset
+	if charset == "" {
+		charset = GCharset
+	}
+	bs = bs[offset + fieldInfo.offset:offset + fieldInfo.offset + fieldInfo.size]
+	if bytes,err := ConvertBytesToUTF8(bs, charset); err == nil {
 		bs = bytes
 	}
 
-	return strings.TrimSpace(string(bs[offset + fieldInfo.offset:offset + fieldInfo.offset + fieldInfo.size]))
+	return strings.Trim(string(bs), " \x00")
 }
 
 func (s *StructBase) int64Value(bs []byte, offset int, fieldInfo *FieldInfo) int64 {
@@ -1,5 +1,9 @@
 package fastdfs
 
+import (
+	"github.com/go/fastdfs/proto"
+)
+
 const (
 	GROUP_FIELD_INDEX_GROUP_NAME = 0
 	GROUP_FIELD_INDEX_TOTAL_MB = 1
@@ -16,47 +20,61 @@ const (
 )
 
 var groupFieldsTotalSize int
-var groupFieldsArray = make([]*FieldInfo, 12)
+var groupFieldsArray []*FieldInfo
+var groupFieldsTotalSizeV6 int
+var groupFieldsArrayV6 []*FieldInfo
 
 func init() {
+	groupFieldsArray,groupFieldsTotalSize = newGroupFields(FDFS_GROUP_NAME_MAX_LEN + 1)
+	groupFieldsArrayV6,groupFieldsTotalSizeV6 = newGroupFields(GROUP_STAT_NAME_SIZE_V6)
+}
+
+/**
+ * layout the fields of the group stat record
+ *
+ * @param groupNameSize the size of the group name field
+ * @return the fields and the total size
+ */
+func newGroupFields(groupNameSize int) ([]*FieldInfo, int) {
+	var fields = make([]*FieldInfo, 12)
 	var offset = 0
-	groupFieldsArray[GROUP_FIELD_INDEX_GROUP_NAME] = NewFieldInfo("groupName", offset, FDFS_GROUP_NAME_MAX_LEN + 1)
-	offset += FDFS_GROUP_NAME_MAX_LEN + 1
+	fields[GROUP_FIELD_INDEX_GROUP_NAME] = NewFieldInfo("groupName", offset, groupNameSize)
+	offset += groupNameSize
 
-	groupFieldsArray[GROUP_FIELD_INDEX_TOTAL_MB] = NewFieldInfo("totalMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_TOTAL_MB] = NewFieldInfo("totalMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_FREE_MB] = NewFieldInfo("freeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_FREE_MB] = NewFieldInfo("freeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_TRUNK_FREE_MB] = NewFieldInfo("trunkFreeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_TRUNK_FREE_MB] = NewFieldInfo("trunkFreeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_COUNT] = NewFieldInfo("storageCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_STORAGE_COUNT] = NewFieldInfo("storageCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_PORT] = NewFieldInfo("storagePort", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_STORAGE_PORT] = NewFieldInfo("storagePort", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_HTTP_PORT] = NewFieldInfo("storageHttpPort", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_STORAGE_HTTP_PORT] = NewFieldInfo("storageHttpPort", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_ACTIVE_COUNT] = NewFieldInfo("activeCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_ACTIVE_COUNT] = NewFieldInfo("activeCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_CURRENT_WRITE_SERVER] = NewFieldInfo("currentWriteServer", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_CURRENT_WRITE_SERVER] = NewFieldInfo("currentWriteServer", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_STORE_PATH_COUNT] = NewFieldInfo("storePathCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_STORE_PATH_COUNT] = NewFieldInfo("storePathCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_SUBDIR_COUNT_PER_PATH] = NewFieldInfo("subdirCountPerPath", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_SUBDIR_COUNT_PER_PATH] = NewFieldInfo("subdirCountPerPath", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsArray[GROUP_FIELD_INDEX_CURRENT_TRUNK_FILE_ID] = NewFieldInfo("currentTrunkFileId", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[GROUP_FIELD_INDEX_CURRENT_TRUNK_FILE_ID] = NewFieldInfo("currentTrunkFileId", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	groupFieldsTotalSize = offset
+	return fields, offset
 }
 
 type StructGroupStat struct {
@@ -85,6 +103,28 @@ func GetGroupFieldsTotalSize() int {
 	return groupFieldsTotalSize
 }
 
+/**
+ * get fields total size of the protocol version
+ *
+ * @param version the protocol version
+ * @return fields total size
+ */
+func GetGroupFieldsTotalSizeByVersion(version proto.Version) int {
+	if version >= PROTO_VERSION_6 {
+		return groupFieldsTotalSizeV6
+	}
+
+	return groupFieldsTotalSize
+}
+
+func groupFieldsOf(version proto.Version) []*FieldInfo {
+	if version >= PROTO_VERSION_6 {
+		return groupFieldsArrayV6
+	}
+
+	return groupFieldsArray
+}
+
 /**
  * get group name
  *
@@ -200,16 +240,17 @@ func (s *StructGroupStat) GetCurrentTrunkFileId() int {
  * @param offset start offset
  */
 func (s *StructGroupStat) SetFields(bs []byte, offset int) {
-	s.groupName = s.StructBase.stringValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_GROUP_NAME])
-	s.totalMB = s.StructBase.longValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_TOTAL_MB])
-	s.freeMB = s.StructBase.longValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_FREE_MB])
-	s.trunkFreeMB = s.StructBase.longValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_TRUNK_FREE_MB])
-	s.storageCount = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_COUNT])
-	s.storagePort = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_PORT])
-	s.storageHttpPort = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_STORAGE_HTTP_PORT])
-	s.activeCount = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_ACTIVE_COUNT])
-	s.currentWriteServer = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_CURRENT_WRITE_SERVER])
-	s.storePathCount = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_STORE_PATH_COUNT])
-	s.subdirCountPerPath = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_SUBDIR_COUNT_PER_PATH])
-	s.currentTrunkFileId = s.StructBase.intValue(bs, offset, groupFieldsArray[GROUP_FIELD_INDEX_CURRENT_TRUNK_FILE_ID])
+	var fields = groupFieldsOf(s.protoVersion)
+	s.groupName = s.StructBase.stringValue(bs, offset, fields[GROUP_FIELD_INDEX_GROUP_NAME])
+	s.totalMB = s.StructBase.longValue(bs, offset, fields[GROUP_FIELD_INDEX_TOTAL_MB])
+	s.freeMB = s.StructBase.longValue(bs, offset, fields[GROUP_FIELD_INDEX_FREE_MB])
+	s.trunkFreeMB = s.StructBase.longValue(bs, offset, fields[GROUP_FIELD_INDEX_TRUNK_FREE_MB])
+	s.storageCount = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_STORAGE_COUNT])
+	s.storagePort = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_STORAGE_PORT])
+	s.storageHttpPort = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_STORAGE_HTTP_PORT])
+	s.activeCount = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_ACTIVE_COUNT])
+	s.currentWriteServer = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_CURRENT_WRITE_SERVER])
+	s.storePathCount = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_STORE_PATH_COUNT])
+	s.subdirCountPerPath = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_SUBDIR_COUNT_PER_PATH])
+	s.currentTrunkFileId = s.StructBase.intValue(bs, offset, fields[GROUP_FIELD_INDEX_CURRENT_TRUNK_FILE_ID])
 }
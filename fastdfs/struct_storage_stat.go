@@ -2,6 +2,7 @@ package fastdfs
 
 import (
 	"time"
+	"github.com/go/fastdfs/proto"
 )
 
 const (
@@ -72,198 +73,212 @@ const (
 )
 
 var storageFieldsTotalSize int
-var storageFieldsArray = make([]*FieldInfo, 62)
+var storageFieldsArray []*FieldInfo
+var storageFieldsTotalSizeV6 int
+var storageFieldsArrayV6 []*FieldInfo
 
 func init() {
+	storageFieldsArray,storageFieldsTotalSize = newStorageFields(FDFS_IPADDR_SIZE)
+	storageFieldsArrayV6,storageFieldsTotalSizeV6 = newStorageFields(IPV6_ADDRESS_SIZE)
+}
+
+/**
+ * layout the fields of the storage stat record
+ *
+ * @param ipAddrSize the size of the ip address fields
+ * @return the fields and the total size
+ */
+func newStorageFields(ipAddrSize int) ([]*FieldInfo, int) {
+	var fields = make([]*FieldInfo, 62)
 	var offset = 0
 
-	storageFieldsArray[FIELD_INDEX_STATUS] = NewFieldInfo("status", offset, 1)
+	fields[FIELD_INDEX_STATUS] = NewFieldInfo("status", offset, 1)
 	offset += 1
 
-	storageFieldsArray[FIELD_INDEX_ID] = NewFieldInfo("id", offset, FDFS_STORAGE_ID_MAX_SIZE)
+	fields[FIELD_INDEX_ID] = NewFieldInfo("id", offset, FDFS_STORAGE_ID_MAX_SIZE)
 	offset += FDFS_STORAGE_ID_MAX_SIZE
 
-	storageFieldsArray[FIELD_INDEX_IP_ADDR] = NewFieldInfo("ipAddr", offset, FDFS_IPADDR_SIZE)
-	offset += FDFS_IPADDR_SIZE
+	fields[FIELD_INDEX_IP_ADDR] = NewFieldInfo("ipAddr", offset, ipAddrSize)
+	offset += ipAddrSize
 
-	storageFieldsArray[FIELD_INDEX_DOMAIN_NAME] = NewFieldInfo("domainName", offset, FDFS_DOMAIN_NAME_MAX_SIZE)
+	fields[FIELD_INDEX_DOMAIN_NAME] = NewFieldInfo("domainName", offset, FDFS_DOMAIN_NAME_MAX_SIZE)
 	offset += FDFS_DOMAIN_NAME_MAX_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SRC_IP_ADDR] = NewFieldInfo("srcIpAddr", offset, FDFS_IPADDR_SIZE)
-	offset += FDFS_IPADDR_SIZE
+	fields[FIELD_INDEX_SRC_IP_ADDR] = NewFieldInfo("srcIpAddr", offset, ipAddrSize)
+	offset += ipAddrSize
 
-	storageFieldsArray[FIELD_INDEX_VERSION] = NewFieldInfo("version", offset, FDFS_VERSION_SIZE)
+	fields[FIELD_INDEX_VERSION] = NewFieldInfo("version", offset, FDFS_VERSION_SIZE)
 	offset += FDFS_VERSION_SIZE
 
-	storageFieldsArray[FIELD_INDEX_JOIN_TIME] = NewFieldInfo("joinTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_JOIN_TIME] = NewFieldInfo("joinTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_UP_TIME] = NewFieldInfo("upTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_UP_TIME] = NewFieldInfo("upTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_MB] = NewFieldInfo("totalMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_MB] = NewFieldInfo("totalMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_FREE_MB] = NewFieldInfo("freeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_FREE_MB] = NewFieldInfo("freeMB", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_UPLOAD_PRIORITY] = NewFieldInfo("uploadPriority", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_UPLOAD_PRIORITY] = NewFieldInfo("uploadPriority", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_STORE_PATH_COUNT] = NewFieldInfo("storePathCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_STORE_PATH_COUNT] = NewFieldInfo("storePathCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUBDIR_COUNT_PER_PATH] = NewFieldInfo("subdirCountPerPath", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUBDIR_COUNT_PER_PATH] = NewFieldInfo("subdirCountPerPath", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_CURRENT_WRITE_PATH] = NewFieldInfo("currentWritePath", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_CURRENT_WRITE_PATH] = NewFieldInfo("currentWritePath", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_STORAGE_PORT] = NewFieldInfo("storagePort", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_STORAGE_PORT] = NewFieldInfo("storagePort", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_STORAGE_HTTP_PORT] = NewFieldInfo("storageHttpPort", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_STORAGE_HTTP_PORT] = NewFieldInfo("storageHttpPort", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_CONNECTION_ALLOC_COUNT] = NewFieldInfo("connectionAllocCount", offset, 4)
+	fields[FIELD_INDEX_CONNECTION_ALLOC_COUNT] = NewFieldInfo("connectionAllocCount", offset, 4)
 	offset += 4
 
-	storageFieldsArray[FIELD_INDEX_CONNECTION_CURRENT_COUNT] = NewFieldInfo("connectionCurrentCount", offset, 4)
+	fields[FIELD_INDEX_CONNECTION_CURRENT_COUNT] = NewFieldInfo("connectionCurrentCount", offset, 4)
 	offset += 4
 
-	storageFieldsArray[FIELD_INDEX_CONNECTION_MAX_COUNT] = NewFieldInfo("connectionMaxCount", offset, 4)
+	fields[FIELD_INDEX_CONNECTION_MAX_COUNT] = NewFieldInfo("connectionMaxCount", offset, 4)
 	offset += 4
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_UPLOAD_COUNT] = NewFieldInfo("totalUploadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_UPLOAD_COUNT] = NewFieldInfo("totalUploadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_UPLOAD_COUNT] = NewFieldInfo("successUploadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_UPLOAD_COUNT] = NewFieldInfo("successUploadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_APPEND_COUNT] = NewFieldInfo("totalAppendCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_APPEND_COUNT] = NewFieldInfo("totalAppendCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_APPEND_COUNT] = NewFieldInfo("successAppendCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_APPEND_COUNT] = NewFieldInfo("successAppendCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_MODIFY_COUNT] = NewFieldInfo("totalModifyCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_MODIFY_COUNT] = NewFieldInfo("totalModifyCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_MODIFY_COUNT] = NewFieldInfo("successModifyCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_MODIFY_COUNT] = NewFieldInfo("successModifyCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_TRUNCATE_COUNT] = NewFieldInfo("totalTruncateCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_TRUNCATE_COUNT] = NewFieldInfo("totalTruncateCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_TRUNCATE_COUNT] = NewFieldInfo("successTruncateCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_TRUNCATE_COUNT] = NewFieldInfo("successTruncateCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_SET_META_COUNT] = NewFieldInfo("totalSetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_SET_META_COUNT] = NewFieldInfo("totalSetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_SET_META_COUNT] = NewFieldInfo("successSetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_SET_META_COUNT] = NewFieldInfo("successSetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_DELETE_COUNT] = NewFieldInfo("totalDeleteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_DELETE_COUNT] = NewFieldInfo("totalDeleteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_DELETE_COUNT] = NewFieldInfo("successDeleteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_DELETE_COUNT] = NewFieldInfo("successDeleteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_DOWNLOAD_COUNT] = NewFieldInfo("totalDownloadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_DOWNLOAD_COUNT] = NewFieldInfo("totalDownloadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_DOWNLOAD_COUNT] = NewFieldInfo("successDownloadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_DOWNLOAD_COUNT] = NewFieldInfo("successDownloadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_GET_META_COUNT] = NewFieldInfo("totalGetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_GET_META_COUNT] = NewFieldInfo("totalGetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_GET_META_COUNT] = NewFieldInfo("successGetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_GET_META_COUNT] = NewFieldInfo("successGetMetaCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_CREATE_LINK_COUNT] = NewFieldInfo("totalCreateLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_CREATE_LINK_COUNT] = NewFieldInfo("totalCreateLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_CREATE_LINK_COUNT] = NewFieldInfo("successCreateLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_CREATE_LINK_COUNT] = NewFieldInfo("successCreateLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_DELETE_LINK_COUNT] = NewFieldInfo("totalDeleteLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_DELETE_LINK_COUNT] = NewFieldInfo("totalDeleteLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_DELETE_LINK_COUNT] = NewFieldInfo("successDeleteLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_DELETE_LINK_COUNT] = NewFieldInfo("successDeleteLinkCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_UPLOAD_BYTES] = NewFieldInfo("totalUploadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_UPLOAD_BYTES] = NewFieldInfo("totalUploadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_UPLOAD_BYTES] = NewFieldInfo("successUploadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_UPLOAD_BYTES] = NewFieldInfo("successUploadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_APPEND_BYTES] = NewFieldInfo("totalAppendBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_APPEND_BYTES] = NewFieldInfo("totalAppendBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_APPEND_BYTES] = NewFieldInfo("successAppendBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_APPEND_BYTES] = NewFieldInfo("successAppendBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_MODIFY_BYTES] = NewFieldInfo("totalModifyBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_MODIFY_BYTES] = NewFieldInfo("totalModifyBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_MODIFY_BYTES] = NewFieldInfo("successModifyBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_MODIFY_BYTES] = NewFieldInfo("successModifyBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_DOWNLOAD_BYTES] = NewFieldInfo("totalDownloadloadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_DOWNLOAD_BYTES] = NewFieldInfo("totalDownloadloadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_DOWNLOAD_BYTES] = NewFieldInfo("successDownloadloadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_DOWNLOAD_BYTES] = NewFieldInfo("successDownloadloadBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_SYNC_IN_BYTES] = NewFieldInfo("totalSyncInBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_SYNC_IN_BYTES] = NewFieldInfo("totalSyncInBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_SYNC_IN_BYTES] = NewFieldInfo("successSyncInBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_SYNC_IN_BYTES] = NewFieldInfo("successSyncInBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_SYNC_OUT_BYTES] = NewFieldInfo("totalSyncOutBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_SYNC_OUT_BYTES] = NewFieldInfo("totalSyncOutBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_SYNC_OUT_BYTES] = NewFieldInfo("successSyncOutBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_SYNC_OUT_BYTES] = NewFieldInfo("successSyncOutBytes", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_FILE_OPEN_COUNT] = NewFieldInfo("totalFileOpenCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_FILE_OPEN_COUNT] = NewFieldInfo("totalFileOpenCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_OPEN_COUNT] = NewFieldInfo("successFileOpenCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_FILE_OPEN_COUNT] = NewFieldInfo("successFileOpenCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_FILE_READ_COUNT] = NewFieldInfo("totalFileReadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_FILE_READ_COUNT] = NewFieldInfo("totalFileReadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_READ_COUNT] = NewFieldInfo("successFileReadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_FILE_READ_COUNT] = NewFieldInfo("successFileReadCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_TOTAL_FILE_WRITE_COUNT] = NewFieldInfo("totalFileWriteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_TOTAL_FILE_WRITE_COUNT] = NewFieldInfo("totalFileWriteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_WRITE_COUNT] = NewFieldInfo("successFileWriteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_SUCCESS_FILE_WRITE_COUNT] = NewFieldInfo("successFileWriteCount", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_LAST_SOURCE_UPDATE] = NewFieldInfo("lastSourceUpdate", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_LAST_SOURCE_UPDATE] = NewFieldInfo("lastSourceUpdate", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_LAST_SYNC_UPDATE] = NewFieldInfo("lastSyncUpdate", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_LAST_SYNC_UPDATE] = NewFieldInfo("lastSyncUpdate", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_LAST_SYNCED_TIMESTAMP] = NewFieldInfo("lastSyncedTimestamp", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_LAST_SYNCED_TIMESTAMP] = NewFieldInfo("lastSyncedTimestamp", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_LAST_HEART_BEAT_TIME] = NewFieldInfo("lastHeartBeatTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
+	fields[FIELD_INDEX_LAST_HEART_BEAT_TIME] = NewFieldInfo("lastHeartBeatTime", offset, FDFS_PROTO_PKG_LEN_SIZE)
 	offset += FDFS_PROTO_PKG_LEN_SIZE
 
-	storageFieldsArray[FIELD_INDEX_IF_TRUNK_FILE] = NewFieldInfo("ifTrunkServer", offset, 1)
+	fields[FIELD_INDEX_IF_TRUNK_FILE] = NewFieldInfo("ifTrunkServer", offset, 1)
 	offset += 1
 
-	storageFieldsTotalSize = offset
+	return fields, offset
 }
 
 
@@ -343,6 +358,28 @@ func GetStorageFieldsTotalSize() int {
 	return storageFieldsTotalSize
 }
 
+/**
+ * get fields total size of the protocol version
+ *
+ * @param version the protocol version
+ * @return fields total size
+ */
+func GetStorageFieldsTotalSizeByVersion(version proto.Version) int {
+	if version >= PROTO_VERSION_6 {
+		return storageFieldsTotalSizeV6
+	}
+
+	return storageFieldsTotalSize
+}
+
+func storageFieldsOf(version proto.Version) []*FieldInfo {
+	if version >= PROTO_VERSION_6 {
+		return storageFieldsArrayV6
+	}
+
+	return storageFieldsArray
+}
+
 /**
  * get storage status
  *
@@ -870,7 +907,7 @@ func (s *StructStorageStat) GetLastHeartBeatTime() time.Time {
  *
  * @return true for the trunk server, otherwise false
  */
-func (s *StructStorageStat) isTrunkServer() bool {
+func (s *StructStorageStat) IsTrunkServer() bool {
 	return s.ifTrunkServer
 }
 
@@ -908,68 +945,69 @@ func (s *StructStorageStat) GetConnectionMaxCount() int {
  * @param offset start offset
  */
 func (s *StructStorageStat) SetFields(bs []byte, offset int) {
-	s.status = s.StructBase.byteValue(bs, offset, storageFieldsArray[FIELD_INDEX_STATUS]);
-	s.id = s.StructBase.stringValue(bs, offset, storageFieldsArray[FIELD_INDEX_ID]);
-	s.ipAddr = s.StructBase.stringValue(bs, offset, storageFieldsArray[FIELD_INDEX_IP_ADDR]);
-	s.srcIpAddr = s.StructBase.stringValue(bs, offset, storageFieldsArray[FIELD_INDEX_SRC_IP_ADDR]);
-	s.domainName = s.StructBase.stringValue(bs, offset, storageFieldsArray[FIELD_INDEX_DOMAIN_NAME]);
-	s.version = s.StructBase.stringValue(bs, offset, storageFieldsArray[FIELD_INDEX_VERSION]);
-	s.totalMB = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_MB]);
-	s.freeMB = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_FREE_MB]);
-	s.uploadPriority = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_UPLOAD_PRIORITY]);
-	s.joinTime = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_JOIN_TIME]);
-	s.upTime = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_UP_TIME]);
-	s.storePathCount = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_STORE_PATH_COUNT]);
-	s.subdirCountPerPath = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUBDIR_COUNT_PER_PATH]);
-	s.storagePort = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_STORAGE_PORT]);
-	s.storageHttpPort = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_STORAGE_HTTP_PORT]);
-	s.currentWritePath = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_CURRENT_WRITE_PATH]);
-
-	s.connectionAllocCount = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_CONNECTION_ALLOC_COUNT]);
-	s.connectionCurrentCount = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_CONNECTION_CURRENT_COUNT]);
-	s.connectionMaxCount = s.StructBase.intValue(bs, offset, storageFieldsArray[FIELD_INDEX_CONNECTION_MAX_COUNT]);
-
-	s.totalUploadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_UPLOAD_COUNT]);
-	s.successUploadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_UPLOAD_COUNT]);
-	s.totalAppendCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_APPEND_COUNT]);
-	s.successAppendCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_APPEND_COUNT]);
-	s.totalModifyCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_MODIFY_COUNT]);
-	s.successModifyCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_MODIFY_COUNT]);
-	s.totalTruncateCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_TRUNCATE_COUNT]);
-	s.successTruncateCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_TRUNCATE_COUNT]);
-	s.totalSetMetaCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_SET_META_COUNT]);
-	s.successSetMetaCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_SET_META_COUNT]);
-	s.totalDeleteCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_DELETE_COUNT]);
-	s.successDeleteCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_DELETE_COUNT]);
-	s.totalDownloadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_DOWNLOAD_COUNT]);
-	s.successDownloadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_DOWNLOAD_COUNT]);
-	s.totalGetMetaCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_GET_META_COUNT]);
-	s.successGetMetaCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_GET_META_COUNT]);
-	s.totalCreateLinkCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_CREATE_LINK_COUNT]);
-	s.successCreateLinkCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_CREATE_LINK_COUNT]);
-	s.totalDeleteLinkCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_DELETE_LINK_COUNT]);
-	s.successDeleteLinkCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_DELETE_LINK_COUNT]);
-	s.totalUploadBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_UPLOAD_BYTES]);
-	s.successUploadBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_UPLOAD_BYTES]);
-	s.totalAppendBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_APPEND_BYTES]);
-	s.successAppendBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_APPEND_BYTES]);
-	s.totalModifyBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_MODIFY_BYTES]);
-	s.successModifyBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_MODIFY_BYTES]);
-	s.totalDownloadloadBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_DOWNLOAD_BYTES]);
-	s.successDownloadloadBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_DOWNLOAD_BYTES]);
-	s.totalSyncInBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_SYNC_IN_BYTES]);
-	s.successSyncInBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_SYNC_IN_BYTES]);
-	s.totalSyncOutBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_SYNC_OUT_BYTES]);
-	s.successSyncOutBytes = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_SYNC_OUT_BYTES]);
-	s.totalFileOpenCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_FILE_OPEN_COUNT]);
-	s.successFileOpenCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_OPEN_COUNT]);
-	s.totalFileReadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_FILE_READ_COUNT]);
-	s.successFileReadCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_READ_COUNT]);
-	s.totalFileWriteCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_TOTAL_FILE_WRITE_COUNT]);
-	s.successFileWriteCount = s.StructBase.longValue(bs, offset, storageFieldsArray[FIELD_INDEX_SUCCESS_FILE_WRITE_COUNT]);
-	s.lastSourceUpdate = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_LAST_SOURCE_UPDATE]);
-	s.lastSyncUpdate = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_LAST_SYNC_UPDATE]);
-	s.lastSyncedTimestamp = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_LAST_SYNCED_TIMESTAMP]);
-	s.lastHeartBeatTime = s.StructBase.dateValue(bs, offset, storageFieldsArray[FIELD_INDEX_LAST_HEART_BEAT_TIME]);
-	s.ifTrunkServer = s.StructBase.boolValue(bs, offset, storageFieldsArray[FIELD_INDEX_IF_TRUNK_FILE]);
+	var fields = storageFieldsOf(s.protoVersion)
+	s.status = s.StructBase.byteValue(bs, offset, fields[FIELD_INDEX_STATUS]);
+	s.id = s.StructBase.stringValue(bs, offset, fields[FIELD_INDEX_ID]);
+	s.ipAddr = s.StructBase.stringValue(bs, offset, fields[FIELD_INDEX_IP_ADDR]);
+	s.srcIpAddr = s.StructBase.stringValue(bs, offset, fields[FIELD_INDEX_SRC_IP_ADDR]);
+	s.domainName = s.StructBase.stringValue(bs, offset, fields[FIELD_INDEX_DOMAIN_NAME]);
+	s.version = s.StructBase.stringValue(bs, offset, fields[FIELD_INDEX_VERSION]);
+	s.totalMB = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_MB]);
+	s.freeMB = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_FREE_MB]);
+	s.uploadPriority = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_UPLOAD_PRIORITY]);
+	s.joinTime = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_JOIN_TIME]);
+	s.upTime = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_UP_TIME]);
+	s.storePathCount = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_STORE_PATH_COUNT]);
+	s.subdirCountPerPath = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_SUBDIR_COUNT_PER_PATH]);
+	s.storagePort = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_STORAGE_PORT]);
+	s.storageHttpPort = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_STORAGE_HTTP_PORT]);
+	s.currentWritePath = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_CURRENT_WRITE_PATH]);
+
+	s.connectionAllocCount = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_CONNECTION_ALLOC_COUNT]);
+	s.connectionCurrentCount = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_CONNECTION_CURRENT_COUNT]);
+	s.connectionMaxCount = s.StructBase.intValue(bs, offset, fields[FIELD_INDEX_CONNECTION_MAX_COUNT]);
+
+	s.totalUploadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_UPLOAD_COUNT]);
+	s.successUploadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_UPLOAD_COUNT]);
+	s.totalAppendCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_APPEND_COUNT]);
+	s.successAppendCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_APPEND_COUNT]);
+	s.totalModifyCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_MODIFY_COUNT]);
+	s.successModifyCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_MODIFY_COUNT]);
+	s.totalTruncateCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_TRUNCATE_COUNT]);
+	s.successTruncateCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_TRUNCATE_COUNT]);
+	s.totalSetMetaCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_SET_META_COUNT]);
+	s.successSetMetaCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_SET_META_COUNT]);
+	s.totalDeleteCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_DELETE_COUNT]);
+	s.successDeleteCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_DELETE_COUNT]);
+	s.totalDownloadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_DOWNLOAD_COUNT]);
+	s.successDownloadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_DOWNLOAD_COUNT]);
+	s.totalGetMetaCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_GET_META_COUNT]);
+	s.successGetMetaCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_GET_META_COUNT]);
+	s.totalCreateLinkCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_CREATE_LINK_COUNT]);
+	s.successCreateLinkCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_CREATE_LINK_COUNT]);
+	s.totalDeleteLinkCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_DELETE_LINK_COUNT]);
+	s.successDeleteLinkCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_DELETE_LINK_COUNT]);
+	s.totalUploadBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_UPLOAD_BYTES]);
+	s.successUploadBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_UPLOAD_BYTES]);
+	s.totalAppendBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_APPEND_BYTES]);
+	s.successAppendBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_APPEND_BYTES]);
+	s.totalModifyBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_MODIFY_BYTES]);
+	s.successModifyBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_MODIFY_BYTES]);
+	s.totalDownloadloadBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_DOWNLOAD_BYTES]);
+	s.successDownloadloadBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_DOWNLOAD_BYTES]);
+	s.totalSyncInBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_SYNC_IN_BYTES]);
+	s.successSyncInBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_SYNC_IN_BYTES]);
+	s.totalSyncOutBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_SYNC_OUT_BYTES]);
+	s.successSyncOutBytes = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_SYNC_OUT_BYTES]);
+	s.totalFileOpenCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_FILE_OPEN_COUNT]);
+	s.successFileOpenCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_FILE_OPEN_COUNT]);
+	s.totalFileReadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_FILE_READ_COUNT]);
+	s.successFileReadCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_FILE_READ_COUNT]);
+	s.totalFileWriteCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_TOTAL_FILE_WRITE_COUNT]);
+	s.successFileWriteCount = s.StructBase.longValue(bs, offset, fields[FIELD_INDEX_SUCCESS_FILE_WRITE_COUNT]);
+	s.lastSourceUpdate = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_LAST_SOURCE_UPDATE]);
+	s.lastSyncUpdate = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_LAST_SYNC_UPDATE]);
+	s.lastSyncedTimestamp = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_LAST_SYNCED_TIMESTAMP]);
+	s.lastHeartBeatTime = s.StructBase.dateValue(bs, offset, fields[FIELD_INDEX_LAST_HEART_BEAT_TIME]);
+	s.ifTrunkServer = s.StructBase.boolValue(bs, offset, fields[FIELD_INDEX_IF_TRUNK_FILE]);
 }
\ No newline at end of file
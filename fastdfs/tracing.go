@@ -0,0 +1,143 @@
+package fastdfs
+
+import (
+	"context"
+	"io"
+	"net"
+)
+
+/**
+ * the phase of a protocol round-trip
+ */
+type Phase int
+
+const (
+	PhaseConnect       Phase = iota //dial the server or get the pooled connection for the command, 0 for none
+	PhaseRequestWrite               //send the request package, without the file content
+	PhaseBodyStream                 //send the file content of the upload, or receive the file content of the download
+	PhaseResponseWait               //wait for the response header
+	PhaseResponseRead               //receive and decode the response body
+)
+
+var phaseNames = []string{"connect", "request_write", "body_stream", "response_wait", "response_read"}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return "unknown"
+	}
+
+	return phaseNames[p]
+}
+
+/**
+ * the span of a phase, ended once
+ */
+type Span interface {
+	/**
+	 * end the phase
+	 *
+	 * @param bytes the bytes sent or received in the phase
+	 * @param err   the error of the phase, nil for success
+	 */
+	End(bytes int64, err error)
+}
+
+/**
+ * start the spans of the phases, a bridge to OpenTelemetry starts an otel
+ * span as the child of the span in ctx and tags it with the command and the
+ * server address.<br>
+ * the tracer is called by the operations concurrently.
+ */
+type Tracer interface {
+	/**
+	 * start the span of the phase
+	 *
+	 * @param ctx   the context of the operation
+	 * @param phase the phase
+	 * @param cmd   the command, STORAGE_PROTO_CMD_XXX or TRACKER_PROTO_CMD_XXX
+	 * @param addr  the server address
+	 * @return the span, never nil
+	 */
+	StartSpan(ctx context.Context, phase Phase, cmd byte, addr net.Addr) Span
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(bytes int64, err error) {}
+
+/**
+ * @param tracer the tracer of the protocol round-trips, nil for none
+ */
+func WithTracer(tracer Tracer) ConfigOption {
+	return func(c *Config) {
+		c.Tracer = tracer
+	}
+}
+
+/**
+ * start the span of the phase with the tracer of the settings
+ *
+ * @param ctx   the context of the operation
+ * @param phase the phase
+ * @param cmd   the command
+ * @param addr  the server address
+ * @return the span, never nil
+ */
+func (c *Config) startSpan(ctx context.Context, phase Phase, cmd byte, addr net.Addr) Span {
+	var tracer = GTracer
+	if c != nil {
+		tracer = c.Tracer
+	}
+	if tracer == nil {
+		return nopSpan{}
+	}
+
+	return tracer.StartSpan(ctx, phase, cmd, addr)
+}
+
+/**
+ * the tracers sharing the spans
+ */
+type multiTracer []Tracer
+
+type multiSpan []Span
+
+/**
+ * combine the tracers, such as the histogram and the OpenTelemetry bridge
+ *
+ * @param tracers the tracers
+ * @return the tracer starting a span of each tracer
+ */
+func NewMultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+func (m multiTracer) StartSpan(ctx context.Context, phase Phase, cmd byte, addr net.Addr) Span {
+	var spans = make(multiSpan, len(m))
+	for i,tracer := range m {
+		spans[i] = tracer.StartSpan(ctx, phase, cmd, addr)
+	}
+
+	return spans
+}
+
+func (m multiSpan) End(bytes int64, err error) {
+	for _,span := range m {
+		span.End(bytes, err)
+	}
+}
+
+/**
+ * the writer counting the bytes written
+ */
+type countingWriter struct {
+	out    io.Writer
+	bytes  int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n,err := w.out.Write(p)
+	w.bytes += int64(n)
+
+	return n, err
+}
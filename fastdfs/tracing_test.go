@@ -0,0 +1,149 @@
+package fastdfs
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"net"
+	"sync"
+)
+
+type spanRecord struct {
+	phase  Phase
+	cmd    byte
+	addr   string
+	value  interface{}
+	bytes  int64
+	err    error
+	ended  int
+}
+
+// record the spans of the client
+type recordTracer struct {
+	spans  []*spanRecord
+	lock   sync.Mutex
+}
+
+type tracerKey struct{}
+
+func (r *recordTracer) StartSpan(ctx context.Context, phase Phase, cmd byte, addr net.Addr) Span {
+	var span = &spanRecord{phase:phase, cmd:cmd, addr:addrString(addr), value:ctx.Value(tracerKey{})}
+	r.lock.Lock()
+	r.spans = append(r.spans, span)
+	r.lock.Unlock()
+
+	return &recordSpan{tracer:r, record:span}
+}
+
+type recordSpan struct {
+	tracer  *recordTracer
+	record  *spanRecord
+}
+
+func (s *recordSpan) End(bytes int64, err error) {
+	s.tracer.lock.Lock()
+	s.record.bytes,s.record.err = bytes, err
+	s.record.ended++
+	s.tracer.lock.Unlock()
+}
+
+func (r *recordTracer) find(phase Phase, cmd byte) *spanRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _,span := range r.spans {
+		if span.phase == phase && span.cmd == cmd {
+			return span
+		}
+	}
+
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	listener,_ := startAppenderServer(t, 1 << 20)
+	defer listener.Close()
+	var tracker = startQueryTracker(t, []string{"127.0.0.1"}, []string{"127.0.0.1"}, listener.Addr().(*net.TCPAddr).Port)
+	defer tracker.Close()
+
+	var recorder = &recordTracer{}
+	var histogram = NewLatencyHistogram()
+	var client = NewClient(NewConfig(WithTrackerServers(tracker.Addr()), WithConnectionPool(nil),
+		WithTracer(NewMultiTracer(recorder, histogram))))
+	var storageClient = client.NewStorageClient()
+	var ctx = context.WithValue(context.Background(), tracerKey{}, "traced")
+
+	fileId,err := storageClient.UploadAppenderBufferCtx(ctx, []byte("hello world"), "txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content bytes.Buffer
+	if _,err = storageClient.DownloadCallbackCtx(ctx, fileId.GetGroupName(), fileId.GetFilename(), NewDownLoadStream(&content)); err != nil {
+		t.Fatal(err)
+	}
+	if content.String() != "hello world" {
+		t.Fatalf("content %q", content.String())
+	}
+
+	var expected = []struct {
+		phase  Phase
+		cmd    byte
+		addr   net.Addr
+		bytes  int64
+	}{
+		{PhaseConnect, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, tracker.Addr(), 0},
+		{PhaseConnect, STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, listener.Addr(), 0},
+		{PhaseConnect, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE, tracker.Addr(), 0},
+		{PhaseConnect, STORAGE_PROTO_CMD_DOWNLOAD_FILE, listener.Addr(), 0},
+		{PhaseRequestWrite, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, tracker.Addr(), FDFS_PROTO_PKG_LEN_SIZE + 2},
+		{PhaseResponseWait, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, tracker.Addr(), 0},
+		{PhaseResponseRead, TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE, tracker.Addr(), TRACKER_QUERY_STORAGE_STORE_BODY_LEN},
+		{PhaseRequestWrite, STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, listener.Addr(), FDFS_PROTO_PKG_LEN_SIZE + 2 + 1 + FDFS_PROTO_PKG_LEN_SIZE + FDFS_FILE_EXT_NAME_MAX_LEN},
+		{PhaseBodyStream, STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, listener.Addr(), 11},
+		{PhaseResponseWait, STORAGE_PROTO_CMD_UPLOAD_APPENDER_FILE, listener.Addr(), 0},
+		{PhaseResponseWait, STORAGE_PROTO_CMD_DOWNLOAD_FILE, listener.Addr(), 0},
+		{PhaseBodyStream, STORAGE_PROTO_CMD_DOWNLOAD_FILE, listener.Addr(), 11},
+	}
+	for _,e := range expected {
+		var span = recorder.find(e.phase, e.cmd)
+		if span == nil || span.addr != e.addr.String() || span.bytes != e.bytes || span.err != nil || span.ended != 1 || span.value != "traced" {
+			t.Fatalf("span %s cmd %d: %+v", e.phase, e.cmd, span)
+		}
+	}
+
+	// the histogram shares the spans
+	var stats = histogram.Snapshot()
+	recorder.lock.Lock()
+	var spanCount = len(recorder.spans)
+	recorder.lock.Unlock()
+	var count int64
+	for i,stat := range stats {
+		count += stat.Count
+		if i > 0 && stats[i - 1].Phase > stat.Phase {
+			t.Fatalf("stats not ordered by phase")
+		}
+	}
+	if count != int64(spanCount) {
+		t.Fatalf("histogram count %d, expect %d", count, spanCount)
+	}
+
+	// the failed phase
+	closed,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	histogram.Reset()
+	client = NewClient(NewConfig(WithTrackerServers(closed.Addr()), WithConnectionPool(nil), WithTracer(histogram)))
+	if _,err = client.NewTrackerClient().GetStoreStorage(nil); err == nil {
+		t.Fatalf("connect to the closed port succeeded")
+	}
+	if stats = histogram.Snapshot(); len(stats) != 1 || stats[0].Phase != PhaseConnect || stats[0].Errors != 1 || stats[0].Addr != closed.Addr().String() {
+		t.Fatalf("stats of the failed connect %+v", stats)
+	}
+}
+
+func TestPhaseString(t *testing.T) {
+	if PhaseResponseRead.String() != "response_read" || Phase(100).String() != "unknown" {
+		t.Fatalf("phase names %s %s", PhaseResponseRead, Phase(100))
+	}
+}
@@ -1,16 +1,20 @@
 package fastdfs
 
 import (
+	"errors"
+	"sync/atomic"
 	"net"
-	"strings"
 	"fmt"
-	"os"
-	"runtime/debug"
+	"time"
+	"github.com/go/fastdfs/proto"
 )
 
 type TrackerClient struct {
 	trackerGroup *TrackerGroup
-	errno byte
+	errno int32 //error code of last call, accessed atomically
+	connCtx *connContext //context of the running operation, nil for none
+	config *Config //nil for the global settings
+	cmd byte //command of the storage operation connecting the storage servers, 0 for none
 }
 
 /**
@@ -39,7 +43,11 @@ func NewTrackerClientByGroup(trackerGroup *TrackerGroup) *TrackerClient {
  * @return the error code of last call
  */
 func (t *TrackerClient) GetErrorCode() byte {
-	return t.errno
+	return byte(atomic.LoadInt32(&t.errno))
+}
+
+func (t *TrackerClient) setErrno(errno byte) {
+	atomic.StoreInt32(&t.errno, int32(errno))
 }
 
 /**
@@ -48,7 +56,89 @@ func (t *TrackerClient) GetErrorCode() byte {
  * @return tracker server Socket object, return null if fail
  */
 func (t *TrackerClient) GetConnection() (*TrackerServer, error) {
-	return t.trackerGroup.GetConnection()
+	return t.getConnection(0)
+}
+
+/**
+ * get a connection to tracker server to send the command
+ *
+ * @param cmd the command
+ */
+func (t *TrackerClient) getConnection(cmd byte) (*TrackerServer, error) {
+	return t.trackerGroup.getConnection(contextOf(t.connCtx), t.config, cmd)
+}
+
+/**
+ * get the tracker socket, bind it to the context of the running operation
+ *
+ * @param trackerServer the tracker server
+ * @return the tracker socket
+ */
+func (t *TrackerClient) getSocket(trackerServer *TrackerServer) (net.Conn, error) {
+	conn,err := trackerServer.GetSocket()
+	if err != nil {
+		return nil, err
+	}
+	if t.connCtx != nil {
+		if err = t.connCtx.bind(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+/**
+ * send the request to the tracker server and receive the response through
+ * the interceptors, set the error code of the client
+ *
+ * @param trackerServer the tracker server
+ * @param trackerSocket the tracker socket
+ * @param req           the request
+ * @param res           the response to decode the body to
+ */
+func (t *TrackerClient) call(trackerServer *TrackerServer, trackerSocket net.Conn, req proto.Request, res proto.Message) error {
+	var invoked = false
+	var err = t.config.intercept(contextOf(t.connCtx), newTrackerOperation(req), func(op *Operation) error {
+		invoked = true
+		if err := t.exchange(trackerServer, trackerSocket, op.Request, res); err != nil {
+			return err
+		}
+		op.Result = res
+		return nil
+	})
+	if !invoked {
+		t.setErrno(ErrorCode(err))
+	}
+
+	return err
+}
+
+/**
+ * send the request and receive the response, see call
+ */
+func (t *TrackerClient) exchange(trackerServer *TrackerServer, trackerSocket net.Conn, req proto.Request, res proto.Message) error {
+	var ctx = contextOf(t.connCtx)
+	if err := t.config.writeRequest(ctx, trackerServer.GetAddress(), trackerSocket, req); err != nil {
+		if errors.Is(err, ErrInvalid) {
+			t.setErrno(ERR_NO_EINVAL)
+		} else {
+			t.config.logRequestError(req.Cmd(), trackerServer.GetAddress(), err)
+		}
+		return err
+	}
+	var start = time.Now()
+	errno,bytes,err := t.config.readResponse(ctx, req.Cmd(), trackerServer.GetAddress(), trackerSocket, res)
+	t.config.logResponse(req.Cmd(), trackerServer.GetAddress(), errno, time.Since(start), bytes, err)
+	if err != nil {
+		return err
+	}
+	t.setErrno(errno)
+	if errno != 0 {
+		return newError(errno, req.Cmd(), trackerServer.GetAddress())
+	}
+
+	return nil
 }
 
 /**
@@ -72,94 +162,64 @@ func (t *TrackerClient) GetStoreStorage(trackerServer *TrackerServer) (*StorageS
  */
 func (t *TrackerClient) GetStoreStorageByGroup(trackerServer *TrackerServer, groupName string) (*StorageServer, error) {
 	var (
-		header []byte
-		ipAddr string
-		port int
-		cmd byte
-		outLen int
 		bNewConnection bool
-		storePath byte
 		trackerSocket net.Conn
 	)
 	var err error
 
+	var req = new(proto.QueryStoreRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
+		return nil, err
+	}
 	if trackerServer == nil {
-		if trackerServer,err = t.GetConnection(); err != nil {
+		if trackerServer,err = t.getConnection(req.Cmd()); err != nil {
 			return nil, err
 		}
-		if trackerServer == nil {
-			// todo return nil or error?
-			// java is return null.
-			return nil, nil
-		}
 		bNewConnection = true
 	} else {
 		bNewConnection = false
 	}
 	defer func() {
 		if bNewConnection {
-			if err = trackerServer.Close(); err != nil {
-				fmt.Fprint(os.Stderr, err)
-				debug.PrintStack()
-			}
+			t.config.closeServer(trackerServer)
 		}
 	}()
 
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
 		return nil, err
 	}
 
-	if groupName == "" || len(groupName) == 0 {
-		cmd = TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ONE
-		outLen = 0
-	} else {
-		cmd = TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ONE
-		outLen = FDFS_GROUP_NAME_MAX_LEN
-	}
-	if header,err = PackHeader(cmd, int64(outLen), 0); err != nil {
-		return nil, err
-	}
-	if _,err = trackerSocket.Write(header); err != nil {
+	var res proto.QueryStoreResponse
+	if err = t.call(trackerServer, trackerSocket, req, &res); err != nil {
 		return nil, err
 	}
 
-	if groupName != "" && len(groupName) > 0 {
-		var bGroupName []byte
-		var bs []byte
-		var groupLen int
-
-		if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
-			return nil, err
-		}
-		bGroupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
+	ipAddr,port := t.config.translateAddr(res.IpAddr, res.Port)
 
-		if len(bs) <= FDFS_GROUP_NAME_MAX_LEN {
-			groupLen = len(bs)
-		} else {
-			groupLen = FDFS_GROUP_NAME_MAX_LEN
-		}
-		copy(bGroupName[:groupLen], bs)
-		if _,err = trackerSocket.Write(bGroupName); err != nil {
-			return nil, err
-		}
-	}
+	return newStorageServer(contextOf(t.connCtx), t.config, t.cmd, ipAddr, port, int(res.StorePathIndex))
+}
 
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, TRACKER_QUERY_STORAGE_STORE_BODY_LEN)
+/**
+ * query storage servers to upload file
+ *
+ * @param trackerServer the tracker server
+ * @param groupName     the group name to upload file to, can be empty
+ * @return storage servers, return null if fail
+ */
+func (t *TrackerClient) GetStoreStorages(trackerServer *TrackerServer, groupName string) ([]*StorageServer, error) {
+	servers,storePath,err := t.queryStoreStorages(trackerServer, groupName)
 	if err != nil {
 		return nil, err
 	}
-	t.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
 
-	ipAddr = strings.Trim(string(pkgInfo.Body[FDFS_GROUP_NAME_MAX_LEN:FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1]), " \x00")
-	port = int(Buff2long(pkgInfo.Body, FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1))
-	storePath = pkgInfo.Body[TRACKER_QUERY_STORAGE_STORE_BODY_LEN - 1]
+	var results = make([]*StorageServer, len(servers))
+	for i,server := range servers {
+		if results[i],err = newStorageServer(contextOf(t.connCtx), t.config, t.cmd, server.GetIpAddr(), server.GetPort(), storePath); err != nil {
+			return nil, err
+		}
+	}
 
-	return NewStorageServerByByte(ipAddr, port, storePath)
+	return results, nil
 }
 
 /**
@@ -167,28 +227,22 @@ func (t *TrackerClient) GetStoreStorageByGroup(trackerServer *TrackerServer, gro
  *
  * @param trackerServer the tracker server
  * @param groupName     the group name to upload file to, can be empty
- * @return storage servers, return null if fail
+ * @return storage servers and the store path index
  */
-func (t *TrackerClient) GetStoreStorages(trackerServer *TrackerServer, groupName string) ([]*StorageServer, error) {
+func (t *TrackerClient) queryStoreStorages(trackerServer *TrackerServer, groupName string) ([]*ServerInfo, int, error) {
 	var (
-		header []byte
-		ipAddr string
-		port int
-		cmd byte
-		outLen int
 		bNewConnection bool
 		trackerSocket net.Conn
 	)
 	var err error
 
+	var req = new(proto.QueryStoreAllRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
+		return nil, 0, err
+	}
 	if trackerServer == nil {
-		if trackerServer,err = t.GetConnection(); err != nil {
-			return nil, err
-		}
-		if trackerServer == nil {
-			// todo return nil or error?
-			// java is return null.
-			return nil, nil
+		if trackerServer,err = t.getConnection(req.Cmd()); err != nil {
+			return nil, 0, err
 		}
 		bNewConnection = true
 	} else {
@@ -196,104 +250,25 @@ func (t *TrackerClient) GetStoreStorages(trackerServer *TrackerServer, groupName
 	}
 	defer func() {
 		if bNewConnection {
-			if err = trackerServer.Close(); err != nil {
-				fmt.Fprint(os.Stderr, err)
-				debug.PrintStack()
-			}
+			t.config.closeServer(trackerServer)
 		}
 	}()
 
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
-		return nil, err
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
+		return nil, 0, err
 	}
 
-	if groupName == "" || len(groupName) == 0 {
-		cmd = TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITHOUT_GROUP_ALL
-		outLen = 0
-	} else {
-		cmd = TRACKER_PROTO_CMD_SERVICE_QUERY_STORE_WITH_GROUP_ALL
-		outLen = FDFS_GROUP_NAME_MAX_LEN
-	}
-	if header,err = PackHeader(cmd, int64(outLen), 0); err != nil {
-		return nil, err
+	var res proto.QueryStoreAllResponse
+	if err = t.call(trackerServer, trackerSocket, req, &res); err != nil {
+		return nil, 0, err
 	}
-	if _,err = trackerSocket.Write(header); err != nil {
-		return nil, err
-	}
-
-	if groupName != "" && len(groupName) > 0 {
-		var bGroupName []byte
-		var bs []byte
-		var groupLen int
 
-		if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
-			return nil, err
-		}
-		bGroupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-
-		if len(bs) <= FDFS_GROUP_NAME_MAX_LEN {
-			groupLen = len(bs)
-		} else {
-			groupLen = FDFS_GROUP_NAME_MAX_LEN
-		}
-		copy(bGroupName[:groupLen], bs)
-		if _,err = trackerSocket.Write(bGroupName); err != nil {
-			return nil, err
-		}
+	var results = make([]*ServerInfo, len(res.Servers))
+	for i,server := range res.Servers {
+		results[i] = NewServerInfo(t.config.translateAddr(server.IpAddr, server.Port))
 	}
 
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, -1)
-	if err != nil {
-		return nil, err
-	}
-	t.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	if len(pkgInfo.Body) < 0 {
-		t.errno = ERR_NO_EINVAL
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	var ipPortLen = len(pkgInfo.Body) - FDFS_GROUP_NAME_MAX_LEN + 1
-	const recordLength = FDFS_IPADDR_SIZE - 1 + FDFS_PROTO_PKG_LEN_SIZE
-
-	if ipPortLen % recordLength != 0 {
-		t.errno = ERR_NO_EINVAL
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	var serverCount = ipPortLen / recordLength
-	if serverCount > 16 {
-		t.errno = ERR_NO_EINVAL
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	var results = make([]*StorageServer, serverCount)
-	var storePath = pkgInfo.Body[len(pkgInfo.Body) - 1]
-	var offset = FDFS_GROUP_NAME_MAX_LEN
-
-	for i := 0; i < serverCount; i++ {
-		ipAddr = strings.TrimSpace(string(pkgInfo.Body[offset:offset + FDFS_IPADDR_SIZE - 1]))
-		offset += FDFS_IPADDR_SIZE - 1
-		port = int(Buff2long(pkgInfo.Body, offset))
-		offset += FDFS_PROTO_PKG_LEN_SIZE
-
-		if results[i],err = NewStorageServerByByte(ipAddr, port, storePath); err != nil {
-			return nil, err
-		}
-	}
-
-	return results, nil
+	return results, int(res.StorePathIndex), nil
 }
 
 /**
@@ -309,13 +284,8 @@ func (t *TrackerClient) GetFetchStorage(trackerServer *TrackerServer, groupName,
 	if err != nil {
 		return nil, err
 	}
-	if servers == nil {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
 
-	return NewStorageServer(servers[0].GetIpAddr(), servers[0].GetPort(), 0)
+	return newStorageServer(contextOf(t.connCtx), t.config, t.cmd, servers[0].GetIpAddr(), servers[0].GetPort(), 0)
 }
 
 /**
@@ -331,13 +301,8 @@ func (t *TrackerClient) GetUpdateStorage(trackerServer *TrackerServer, groupName
 	if err != nil {
 		return nil, err
 	}
-	if servers == nil {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
 
-	return NewStorageServer(servers[0].GetIpAddr(), servers[0].GetPort(), 0)
+	return newStorageServer(contextOf(t.connCtx), t.config, t.cmd, servers[0].GetIpAddr(), servers[0].GetPort(), 0)
 }
 
 /**
@@ -360,106 +325,75 @@ func (t *TrackerClient) GetFetchStorages(trackerServer *TrackerServer, groupName
  *                      ProtoCommon.TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE
  * @param groupName     the group name of storage server
  * @param filename      filename on storage server
- * @return storage server Socket object, return null if fail
+ * @return storage servers translated by the address translator, return null if fail
  */
 func (t *TrackerClient) GetStorages(trackerServer *TrackerServer, cmd byte, groupName, filename string) ([]*ServerInfo, error) {
+	servers,err := t.queryStorages(trackerServer, cmd, groupName, filename)
+	if err != nil {
+		return nil, err
+	}
+	for i,server := range servers {
+		servers[i] = NewServerInfo(t.config.translateAddr(server.GetIpAddr(), server.GetPort()))
+	}
+
+	return servers, nil
+}
+
+/**
+ * query storage servers to download or update file, the addresses are not translated
+ *
+ * @return the storage servers returned by the tracker
+ */
+func (t *TrackerClient) queryStorages(trackerServer *TrackerServer, cmd byte, groupName, filename string) ([]*ServerInfo, error) {
 	var (
-		header []byte
-		bFileName []byte
-		bGroupName []byte
-		bs []byte
-
-		length int
-		ipAddr string
-		port int
 		bNewConnection bool
 		trackerSocket net.Conn
+		req proto.Request
 	)
 	var err error
 
+	ref,err := t.config.fileRef(groupName, filename)
+	if err != nil {
+		return nil, err
+	}
+	switch cmd {
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE:
+		req = &proto.QueryFetchRequest{FileRef:ref}
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_UPDATE:
+		req = &proto.QueryUpdateRequest{FileRef:ref}
+	case TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ALL:
+		req = &proto.QueryFetchAllRequest{FileRef:ref}
+	default:
+		t.setErrno(ERR_NO_EINVAL)
+		return nil, newInvalidError("cmd %d is not a fetch query", cmd)
+	}
+
 	if trackerServer == nil {
-		if trackerServer,err = t.GetConnection(); err != nil {
+		if trackerServer,err = t.getConnection(cmd); err != nil {
 			return nil, err
 		}
-		if trackerServer == nil {
-			// todo return nil or error?
-			// java is return null.
-			return nil, nil
-		}
 		bNewConnection = true
 	} else {
 		bNewConnection = false
 	}
 	defer func() {
 		if bNewConnection {
-			if err = trackerServer.Close(); err != nil {
-				fmt.Fprint(os.Stderr, err)
-				debug.PrintStack()
-			}
+			t.config.closeServer(trackerServer)
 		}
 	}()
 
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
 		return nil, err
 	}
 
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
+	var res proto.QueryFetchResponse
+	if err = t.call(trackerServer, trackerSocket, req, &res); err != nil {
 		return nil, err
 	}
-	bGroupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if bFileName,err = ConvertBytesToUTF8([]byte(filename), GCharset); err != nil {
-		return nil, err
-	}
-
-	if len(bs) <= FDFS_GROUP_NAME_MAX_LEN {
-		length = len(bs)
-	} else {
-		length = FDFS_GROUP_NAME_MAX_LEN
-	}
-	copy(bGroupName[:length], bs)
 
-	if header,err = PackHeader(cmd, int64(FDFS_GROUP_NAME_MAX_LEN + len(bFileName)), 0); err != nil {
-		return nil, err
-	}
-
-	var wholePkg = make([]byte, len(header) + len(bGroupName) + len(bFileName))
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], bGroupName)
-	copy(wholePkg[len(header) + len(bGroupName):], bFileName)
-	if _,err = trackerSocket.Write(wholePkg); err != nil {
-		return nil, err
-	}
-
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, -1)
-	if err != nil {
-		return nil, err
-	}
-	t.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	if len(pkgInfo.Body) < TRACKER_QUERY_STORAGE_FETCH_BODY_LEN {
-		return nil, fmt.Errorf("invalid body length: %d", len(pkgInfo.Body))
-	}
-
-	if (len(pkgInfo.Body) - TRACKER_QUERY_STORAGE_FETCH_BODY_LEN) % (FDFS_IPADDR_SIZE - 1) != 0 {
-		return nil, fmt.Errorf("invalid body length: %d", len(pkgInfo.Body))
-	}
-
-	var serverCount = 1 + (len(pkgInfo.Body) - TRACKER_QUERY_STORAGE_FETCH_BODY_LEN) / (FDFS_IPADDR_SIZE - 1)
-	ipAddr = strings.TrimSpace(string(pkgInfo.Body[FDFS_GROUP_NAME_MAX_LEN:FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1]))
-	var offset = FDFS_GROUP_NAME_MAX_LEN + FDFS_IPADDR_SIZE - 1
-	port = int(Buff2long(pkgInfo.Body, offset))
-	offset += FDFS_PROTO_PKG_LEN_SIZE
-
-	var servers = make([]*ServerInfo, serverCount)
-	servers[0] = NewServerInfo(ipAddr, port)
-	for i := 1; i < serverCount; i++ {
-		servers[i] = NewServerInfo(strings.TrimSpace(string(pkgInfo.Body[offset: offset + FDFS_IPADDR_SIZE - 1])), port)
-		offset += FDFS_IPADDR_SIZE - 1
+	var servers = make([]*ServerInfo, len(res.IpAddrs))
+	for i,ipAddr := range res.IpAddrs {
+		servers[i] = NewServerInfo(ipAddr, res.Port)
 	}
 
 	return servers, nil
@@ -474,11 +408,9 @@ func (t *TrackerClient) GetStorages(trackerServer *TrackerServer, cmd byte, grou
  */
 func (t *TrackerClient) GetFetchStorage1(trackerServer *TrackerServer, fileId string) (*StorageServer, error) {
 	var parts = make([]string, 2)
-	t.errno = SplitFileId(fileId, parts)
-	if t.errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
+	if errno := SplitFileId(fileId, parts); errno != 0 {
+		t.setErrno(errno)
+		return nil, newInvalidError("invalid file id %s", fileId)
 	}
 
 	return t.GetFetchStorage(trackerServer, parts[0], parts[1])
@@ -493,11 +425,9 @@ func (t *TrackerClient) GetFetchStorage1(trackerServer *TrackerServer, fileId st
  */
 func (t *TrackerClient) GetFetchStorages1(trackerServer *TrackerServer, fileId string) ([]*ServerInfo, error) {
 	var parts = make([]string, 2)
-	t.errno = SplitFileId(fileId, parts)
-	if t.errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
+	if errno := SplitFileId(fileId, parts); errno != 0 {
+		t.setErrno(errno)
+		return nil, newInvalidError("invalid file id %s", fileId)
 	}
 
 	return t.GetFetchStorages(trackerServer, parts[0], parts[1])
@@ -511,68 +441,39 @@ func (t *TrackerClient) GetFetchStorages1(trackerServer *TrackerServer, fileId s
  */
 func (t *TrackerClient) ListGroups(trackerServer *TrackerServer) ([]StructGroupStat, error) {
 	var (
-		header []byte
-		//ipAddr string
-		//port int
-		//cmd byte
-		//outLen int
 		bNewConnection bool
-		//storePath byte
 		trackerSocket net.Conn
 	)
 	var err error
 
 	if trackerServer == nil {
-		if trackerServer,err = t.GetConnection(); err != nil {
+		if trackerServer,err = t.getConnection(TRACKER_PROTO_CMD_SERVER_LIST_GROUP); err != nil {
 			return nil, err
 		}
-		if trackerServer == nil {
-			// todo return nil or error?
-			// java is return null.
-			return nil, nil
-		}
 		bNewConnection = true
 	} else {
 		bNewConnection = false
 	}
 	defer func() {
 		if bNewConnection {
-			if err = trackerServer.Close(); err != nil {
-				fmt.Fprint(os.Stderr, err)
-				debug.PrintStack()
-			}
+			t.config.closeServer(trackerServer)
 		}
 	}()
 
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
 		return nil, err
 	}
 
-	if header,err = PackHeader(TRACKER_PROTO_CMD_SERVER_LIST_GROUP, 0, 0); err != nil {
+	var res proto.ListGroupsResponse
+	if err = t.call(trackerServer, trackerSocket, new(proto.ListGroupsRequest), &res); err != nil {
 		return nil, err
 	}
-	if _,err = trackerSocket.Write(header); err != nil {
-		return nil, err
-	}
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, -1)
-	if err != nil {
-		return nil, err
-	}
-	t.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
 
-	var decoder = NewProtoStructDecoder()
-	inters,err := decoder.Decode(pkgInfo.Body, StructGroupStat{}, GetGroupFieldsTotalSize())
-	if err != nil {
-		return nil, err
-	}
-	var stats = make([]StructGroupStat, len(inters))
-	for i,_ := range inters {
-		stats[i] = inters[i].(StructGroupStat)
+	var stats = make([]StructGroupStat, len(res.Groups))
+	for i,record := range res.Groups {
+		stats[i].setCharset(t.config.getCharset())
+		stats[i].setProtoVersion(t.config.getProtoVersion())
+		stats[i].SetFields(record, 0)
 	}
 	return stats, nil
 }
@@ -600,98 +501,46 @@ func (t *TrackerClient) ListStorages(trackerServer *TrackerServer, groupName str
  */
 func (t *TrackerClient) ListStoragesByIpAddress(trackerServer *TrackerServer, groupName, storageIpAddr string) ([]StructStorageStat, error) {
 	var (
-		header []byte
-		bGroupName []byte
-		bs []byte
-		length int
 		bNewConnection bool
 		trackerSocket net.Conn
 	)
 	var err error
 
 	if trackerServer == nil {
-		if trackerServer,err = t.GetConnection(); err != nil {
+		if trackerServer,err = t.getConnection(TRACKER_PROTO_CMD_SERVER_LIST_STORAGE); err != nil {
 			return nil, err
 		}
-		if trackerServer == nil {
-			// todo return nil or error?
-			// java is return null.
-			return nil, nil
-		}
 		bNewConnection = true
 	} else {
 		bNewConnection = false
 	}
 	defer func() {
 		if bNewConnection {
-			if err = trackerServer.Close(); err != nil {
-				fmt.Fprint(os.Stderr, err)
-				debug.PrintStack()
-			}
+			t.config.closeServer(trackerServer)
 		}
 	}()
 
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
 		return nil, err
 	}
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
-		return nil, err
-	}
-	bGroupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-
-	if len(bs) <= FDFS_GROUP_NAME_MAX_LEN {
-		length = len(bs)
-	} else {
-		length = FDFS_GROUP_NAME_MAX_LEN
-	}
-
-	copy(bGroupName[:length], bs)
 
-	var ipAddrLen int
-	var bIpAddr []byte
-	if storageIpAddr != "" && len(storageIpAddr) > 0 {
-		if bIpAddr,err = ConvertBytesToUTF8([]byte(storageIpAddr), GCharset); err != nil {
-			return nil, err
-		}
-		if len(bIpAddr) < FDFS_IPADDR_SIZE {
-			ipAddrLen = len(bIpAddr)
-		} else {
-			ipAddrLen = FDFS_IPADDR_SIZE - 1
-		}
-	} else {
-		bIpAddr = nil
-		ipAddrLen = 0
-	}
-
-	if header,err = PackHeader(TRACKER_PROTO_CMD_SERVER_LIST_STORAGE, int64(FDFS_GROUP_NAME_MAX_LEN + ipAddrLen), 0); err != nil {
+	var req = new(proto.ListStoragesRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
 		return nil, err
 	}
-	var wholePkg = make([]byte, len(header) + len(bGroupName) + ipAddrLen)
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], bGroupName)
-	if ipAddrLen > 0 {
-		copy(wholePkg[len(header) + len(bGroupName):], bIpAddr)
-	}
-	if _,err = trackerSocket.Write(wholePkg); err != nil {
+	if req.StorageId,err = t.config.encodeString(storageIpAddr); err != nil {
 		return nil, err
 	}
-
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, -1)
-	t.errno = pkgInfo.Errno
-	if pkgInfo.Errno != 0 {
-		// todo return nil or error?
-		// java is return null.
-		return nil, nil
-	}
-
-	var decoder = NewProtoStructDecoder()
-	inter,err := decoder.Decode(pkgInfo.Body, StructStorageStat{}, GetStorageFieldsTotalSize())
-	if err != nil {
+	var res proto.ListStoragesResponse
+	if err = t.call(trackerServer, trackerSocket, req, &res); err != nil {
 		return nil, err
 	}
-	var stats = make([]StructStorageStat, len(inter))
-	for i,_ := range inter {
-		stats[i] = inter[i].(StructStorageStat)
+
+	var stats = make([]StructStorageStat, len(res.Storages))
+	for i,record := range res.Storages {
+		stats[i].setCharset(t.config.getCharset())
+		stats[i].setProtoVersion(t.config.getProtoVersion())
+		stats[i].SetFields(record, 0)
 	}
 
 	return stats, nil
@@ -706,55 +555,23 @@ func (t *TrackerClient) ListStoragesByIpAddress(trackerServer *TrackerServer, gr
  * @return true for success, false for fail
  */
 func (t *TrackerClient) deleteStorage(trackerServer *TrackerServer, groupName, storageIpAddr string) (bool, error) {
-	var (
-		header []byte
-		bGroupName []byte
-		bs []byte
-		length int
-		trackerSocket net.Conn
-	)
-	var err error
-
-	if trackerSocket,err = trackerServer.GetSocket(); err != nil {
-		return false, err
-	}
-	if bs,err = ConvertBytesToUTF8([]byte(groupName), GCharset); err != nil {
+	trackerSocket,err := t.getSocket(trackerServer)
+	if err != nil {
 		return false, err
 	}
-	bGroupName = make([]byte, FDFS_GROUP_NAME_MAX_LEN)
-	if len(bs) <= FDFS_GROUP_NAME_MAX_LEN {
-		length = len(bs)
-	} else {
-		length = FDFS_GROUP_NAME_MAX_LEN
-	}
-	copy(bGroupName[:length], bs)
 
-	var ipAddrLen int
-	bIpAddr,err:= ConvertBytesToUTF8([]byte(storageIpAddr), GCharset)
-	if err != nil {
+	var req = new(proto.DeleteStorageRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
 		return false, err
 	}
-	if len(bIpAddr) < FDFS_IPADDR_SIZE {
-		ipAddrLen = FDFS_IPADDR_SIZE
-	} else {
-		ipAddrLen = FDFS_IPADDR_SIZE - 1
-	}
-
-	if header,err = PackHeader(TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE, int64(FDFS_GROUP_NAME_MAX_LEN + ipAddrLen), 0); err != nil {
+	if req.StorageId,err = t.config.encodeString(storageIpAddr); err != nil {
 		return false, err
 	}
-	var wholePkg = make([]byte, len(header) + len(bGroupName) + ipAddrLen)
-	copy(wholePkg, header)
-	copy(wholePkg[len(header):], bGroupName)
-	copy(wholePkg[len(header) + len(bGroupName):], bIpAddr)
-	if _,err = trackerSocket.Write(wholePkg); err != nil {
+	if err = t.call(trackerServer, trackerSocket, req, new(proto.EmptyResponse)); err != nil {
 		return false, err
 	}
 
-	pkgInfo,err := RecvPackage(trackerSocket, TRACKER_PROTO_CMD_RESP, 0)
-	t.errno = pkgInfo.Errno
-
-	return pkgInfo.Errno == 0, nil
+	return true, nil
 }
 
 /**
@@ -765,7 +582,7 @@ func (t *TrackerClient) deleteStorage(trackerServer *TrackerServer, groupName, s
  * @return true for success, false for fail
  */
 func (t *TrackerClient) DeleteStorage(groupName, storageIpAddr string) (bool, error) {
-	return t.DeleteStorageByTrackerGroup(GTrackerGroup, groupName, storageIpAddr)
+	return t.DeleteStorageByTrackerGroup(t.config.getTrackerGroup(), groupName, storageIpAddr)
 }
 
 /**
@@ -783,79 +600,352 @@ func (t *TrackerClient) DeleteStorageByTrackerGroup(trackerGroup *TrackerGroup,
 		trackerServer *TrackerServer
 	)
 	var err error
-	
+
 	notFoundCount = 0
 	for serverIndex = 0; serverIndex < len(trackerGroup.TrackerServers); serverIndex++ {
-		if trackerServer,err = trackerGroup.GetConnection(); err != nil {
-			t.errno = ECONNREFUSED
-			// todo return nil or error?
-			// java is return null.
-			return false, nil
+		if trackerServer,err = trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, TRACKER_PROTO_CMD_SERVER_LIST_STORAGE, serverIndex); err != nil {
+			t.setErrno(ECONNREFUSED)
+			return false, err
 		}
 
 		storageStats,err := t.ListStoragesByIpAddress(trackerServer, groupName, storageIpAddr)
+		trackerServer.Close()
 		if err != nil {
-			trackerServer.Close()
-			return false, err
-		}
-		if storageStats == nil {
-			if t.errno == ERR_NO_ENOENT {
+			if errors.Is(err, ErrNotFound) {
 				notFoundCount++
-			} else {
-				trackerServer.Close()
-				return false, nil
+				continue
 			}
-		} else if len(storageStats) == 0 {
+			return false, err
+		}
+		if len(storageStats) == 0 {
 			notFoundCount++
 		} else if storageStats[0].GetStatus() == FDFS_STORAGE_STATUS_ONLINE || storageStats[0].GetStatus() == FDFS_STORAGE_STATUS_ACTIVE {
-			t.errno = ERR_NO_EBUSY
-			trackerServer.Close()
-			return false, nil
+			t.setErrno(ERR_NO_EBUSY)
+			return false, &Error{Errno:ERR_NO_EBUSY, Cmd:TRACKER_PROTO_CMD_SERVER_LIST_STORAGE, Addr:trackerServer.GetAddress().String(),
+				Message:fmt.Sprintf("storage server %s is %s", storageIpAddr, GetStorageStatusCaption(storageStats[0].GetStatus()))}
 		}
-		trackerServer.Close()
 	}
 
 	if notFoundCount == len(trackerGroup.TrackerServers) {
-		t.errno = ERR_NO_ENOENT
-		return false, nil
+		t.setErrno(ERR_NO_ENOENT)
+		return false, &Error{Errno:ERR_NO_ENOENT, Message:fmt.Sprintf("storage server %s of group %s not found", storageIpAddr, groupName)}
 	}
 
 	notFoundCount = 0
 	for serverIndex = 0; serverIndex < len(trackerGroup.TrackerServers); serverIndex++ {
-		if trackerServer,err = trackerGroup.GetConnectionByIndex(serverIndex); err != nil {
-			fmt.Fprintln(os.Stderr, "connect to server ", trackerGroup.TrackerServers[serverIndex].String(), " fail")
-			t.errno = ECONNREFUSED
-			// todo return nil or error?
-			// java is return null.
-			return false, nil
+		if trackerServer,err = trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, TRACKER_PROTO_CMD_SERVER_DELETE_STORAGE, serverIndex); err != nil {
+			t.setErrno(ECONNREFUSED)
+			return false, err
 		}
 
-		if ok,err := t.deleteStorage(trackerServer, groupName, storageIpAddr); err != nil {
-			trackerServer.Close()
-			return false, err
-		} else if !ok {
-			if t.errno != 0 {
-				if t.errno == ERR_NO_ENOENT {
-					notFoundCount++
-				} else if t.errno != ERR_NO_EALREADY {
-					trackerServer.Close()
-					return false, nil
-				}
+		_,err = t.deleteStorage(trackerServer, groupName, storageIpAddr)
+		trackerServer.Close()
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				notFoundCount++
+			} else if !errors.Is(err, ErrAlready) {
+				return false, err
 			}
 		}
-		trackerServer.Close()
 	}
 
 	if notFoundCount == len(trackerGroup.TrackerServers) {
-		t.errno = ERR_NO_ENOENT
-		return false, nil
+		t.setErrno(ERR_NO_ENOENT)
+		return false, &Error{Errno:ERR_NO_ENOENT, Message:fmt.Sprintf("storage server %s of group %s not found", storageIpAddr, groupName)}
+	}
+	t.setErrno(0)
+
+	return true, nil
+}
+
+/**
+ * query the stat info of the group
+ *
+ * @param trackerServer the tracker server
+ * @param groupName     the group name
+ * @return group stat, return null if fail
+ */
+func (t *TrackerClient) ListOneGroup(trackerServer *TrackerServer, groupName string) (*StructGroupStat, error) {
+	var (
+		bNewConnection bool
+		trackerSocket net.Conn
+	)
+	var err error
+
+	if trackerServer == nil {
+		if trackerServer,err = t.getConnection(TRACKER_PROTO_CMD_SERVER_LIST_ONE_GROUP); err != nil {
+			return nil, err
+		}
+		bNewConnection = true
+	} else {
+		bNewConnection = false
+	}
+	defer func() {
+		if bNewConnection {
+			t.config.closeServer(trackerServer)
+		}
+	}()
+
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
+		return nil, err
+	}
+
+	var req = new(proto.ListOneGroupRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
+		return nil, err
+	}
+	var res proto.ListOneGroupResponse
+	if err = t.call(trackerServer, trackerSocket, req, &res); err != nil {
+		return nil, err
+	}
+
+	var stat = new(StructGroupStat)
+	stat.setCharset(t.config.getCharset())
+	stat.setProtoVersion(t.config.getProtoVersion())
+	stat.SetFields(res.Group, 0)
+
+	return stat, nil
+}
+
+/**
+ * query the status of the tracker server
+ *
+ * @param trackerServer the tracker server
+ * @return the tracker status, return null if fail
+ */
+func (t *TrackerClient) GetTrackerStatus(trackerServer *TrackerServer) (*TrackerStatus, error) {
+	var (
+		bNewConnection bool
+		trackerSocket net.Conn
+	)
+	var err error
+
+	if trackerServer == nil {
+		if trackerServer,err = t.getConnection(TRACKER_PROTO_CMD_TRACKER_GET_STATUS); err != nil {
+			return nil, err
+		}
+		bNewConnection = true
+	} else {
+		bNewConnection = false
+	}
+	defer func() {
+		if bNewConnection {
+			t.config.closeServer(trackerServer)
+		}
+	}()
+
+	if trackerSocket,err = t.getSocket(trackerServer); err != nil {
+		return nil, err
+	}
+
+	var res proto.TrackerStatusResponse
+	if err = t.call(trackerServer, trackerSocket, new(proto.TrackerStatusRequest), &res); err != nil {
+		return nil, err
+	}
+
+	return NewTrackerStatus(trackerServer.GetAddress().String(), res.IsLeader, res.RunningTime, res.RestartInterval), nil
+}
+
+/**
+ * query the status of all tracker servers of the global FastDFS cluster
+ *
+ * @return the tracker status array in the order of the tracker servers, return null if fail
+ */
+func (t *TrackerClient) ListTrackerStatus() ([]*TrackerStatus, error) {
+	return t.ListTrackerStatusByTrackerGroup(t.config.getTrackerGroup())
+}
+
+/**
+ * query the status of all tracker servers of the FastDFS cluster
+ *
+ * @param trackerGroup the tracker server group
+ * @return the tracker status array in the order of the tracker servers, return null if fail
+ */
+func (t *TrackerClient) ListTrackerStatusByTrackerGroup(trackerGroup *TrackerGroup) ([]*TrackerStatus, error) {
+	var stats = make([]*TrackerStatus, len(trackerGroup.TrackerServers))
+	for serverIndex := range trackerGroup.TrackerServers {
+		trackerServer,err := trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, TRACKER_PROTO_CMD_TRACKER_GET_STATUS, serverIndex)
+		if err != nil {
+			t.setErrno(ECONNREFUSED)
+			return nil, err
+		}
+
+		stats[serverIndex],err = t.GetTrackerStatus(trackerServer)
+		trackerServer.Close()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if t.errno == ERR_NO_ENOENT {
-		t.errno = 0
+	return stats, nil
+}
+
+/**
+ * find the leader of the tracker servers of the global FastDFS cluster
+ *
+ * @return the status of the leader tracker, return null if fail
+ */
+func (t *TrackerClient) GetLeaderTracker() (*TrackerStatus, error) {
+	return t.GetLeaderTrackerByTrackerGroup(t.config.getTrackerGroup())
+}
+
+/**
+ * find the leader of the tracker servers, the unreachable tracker servers are skipped
+ *
+ * @param trackerGroup the tracker server group
+ * @return the status of the leader tracker, return null if fail
+ */
+func (t *TrackerClient) GetLeaderTrackerByTrackerGroup(trackerGroup *TrackerGroup) (*TrackerStatus, error) {
+	var lastErr error
+	for serverIndex := range trackerGroup.TrackerServers {
+		trackerServer,err := trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, TRACKER_PROTO_CMD_TRACKER_GET_STATUS, serverIndex)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		stat,err := t.GetTrackerStatus(trackerServer)
+		trackerServer.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if stat.IsLeader() {
+			t.setErrno(0)
+			return stat, nil
+		}
 	}
 
-	return t.errno == 0, nil
+	t.setErrno(ERR_NO_ENOENT)
+	if lastErr != nil {
+		return nil, &Error{Errno:ERR_NO_ENOENT, Message:fmt.Sprintf("leader tracker not found, last error: %v", lastErr)}
+	}
+	return nil, &Error{Errno:ERR_NO_ENOENT, Message:"leader tracker not found"}
 }
 
+/**
+ * send the request to all tracker servers of the group, the tracker servers
+ * answer ENOENT for the missing target and EALREADY for the done request
+ *
+ * @param trackerGroup the tracker server group
+ * @param req          the request
+ * @return the count of the tracker servers answer ENOENT
+ */
+func (t *TrackerClient) callTrackers(trackerGroup *TrackerGroup, req proto.Request) (int, error) {
+	var notFoundCount = 0
+	for serverIndex := range trackerGroup.TrackerServers {
+		trackerServer,err := trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, req.Cmd(), serverIndex)
+		if err != nil {
+			t.setErrno(ECONNREFUSED)
+			return notFoundCount, err
+		}
+
+		trackerSocket,err := t.getSocket(trackerServer)
+		if err == nil {
+			err = t.call(trackerServer, trackerSocket, req, new(proto.EmptyResponse))
+		}
+		trackerServer.Close()
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				notFoundCount++
+			} else if !errors.Is(err, ErrAlready) {
+				return notFoundCount, err
+			}
+		}
+	}
+	t.setErrno(0)
 
+	return notFoundCount, nil
+}
+
+/**
+ * delete the group from the global FastDFS cluster
+ *
+ * @param groupName the group name
+ * @return true for success, false for fail
+ */
+func (t *TrackerClient) DeleteGroup(groupName string) (bool, error) {
+	return t.DeleteGroupByTrackerGroup(t.config.getTrackerGroup(), groupName)
+}
+
+/**
+ * delete the group from the FastDFS cluster, the storage servers of the group
+ * should be deleted first
+ *
+ * @param trackerGroup the tracker server group
+ * @param groupName    the group name
+ * @return true for success, false for fail
+ */
+func (t *TrackerClient) DeleteGroupByTrackerGroup(trackerGroup *TrackerGroup, groupName string) (bool, error) {
+	var err error
+	var req = new(proto.DeleteGroupRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
+		return false, err
+	}
+
+	notFoundCount,err := t.callTrackers(trackerGroup, req)
+	if err != nil {
+		return false, err
+	}
+	if notFoundCount == len(trackerGroup.TrackerServers) {
+		t.setErrno(ERR_NO_ENOENT)
+		return false, &Error{Errno:ERR_NO_ENOENT, Message:fmt.Sprintf("group %s not found", groupName)}
+	}
+
+	return true, nil
+}
+
+/**
+ * set the trunk server of the group of the global FastDFS cluster
+ *
+ * @param groupName the group name
+ * @param storageId the storage id or ip address of the new trunk server, empty for the tracker to select one
+ * @return the storage id of the new trunk server, return empty if fail
+ */
+func (t *TrackerClient) SetTrunkServer(groupName, storageId string) (string, error) {
+	return t.SetTrunkServerByTrackerGroup(t.config.getTrackerGroup(), groupName, storageId)
+}
+
+/**
+ * set the trunk server of the group, the request is sent to the tracker
+ * servers in turn until the leader tracker accepts it, the others answer EOPNOTSUPP
+ *
+ * @param trackerGroup the tracker server group
+ * @param groupName    the group name
+ * @param storageId    the storage id or ip address of the new trunk server, empty for the tracker to select one
+ * @return the storage id of the new trunk server, return empty if fail
+ */
+func (t *TrackerClient) SetTrunkServerByTrackerGroup(trackerGroup *TrackerGroup, groupName, storageId string) (string, error) {
+	var err error
+	var req = new(proto.SetTrunkServerRequest)
+	if req.GroupName,err = t.config.encodeString(groupName); err != nil {
+		return "", err
+	}
+	if req.StorageId,err = t.config.encodeString(storageId); err != nil {
+		return "", err
+	}
+
+	for serverIndex := range trackerGroup.TrackerServers {
+		trackerServer,err := trackerGroup.getConnectionByIndex(contextOf(t.connCtx), t.config, req.Cmd(), serverIndex)
+		if err != nil {
+			t.setErrno(ECONNREFUSED)
+			return "", err
+		}
+
+		var res proto.SetTrunkServerResponse
+		trackerSocket,err := t.getSocket(trackerServer)
+		if err == nil {
+			err = t.call(trackerServer, trackerSocket, req, &res)
+		}
+		trackerServer.Close()
+		if err == nil {
+			return res.StorageId, nil
+		}
+		if !errors.Is(err, ErrNotSupported) {
+			return "", err
+		}
+	}
+
+	t.setErrno(ERR_NO_EOPNOTSUPP)
+	return "", &Error{Errno:ERR_NO_EOPNOTSUPP, Message:"no tracker server accepts the trunk server setting, the leader tracker not found"}
+}
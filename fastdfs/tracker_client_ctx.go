@@ -0,0 +1,351 @@
+package fastdfs
+
+import "context"
+
+/**
+ * run the operation with context, see StorageClient.withContext
+ *
+ * @param ctx the context
+ * @param fn  the operation
+ * @return the error of the operation
+ */
+func (t *TrackerClient) withContext(ctx context.Context, fn func(c *TrackerClient) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var c = &TrackerClient{
+		trackerGroup:t.trackerGroup,
+		connCtx:newConnContext(ctx),
+		config:t.config,
+	}
+	var err = c.connCtx.release(fn(c))
+	t.setErrno(c.GetErrorCode())
+
+	return err
+}
+
+/**
+ * context version of GetConnection
+ */
+func (t *TrackerClient) GetConnectionCtx(ctx context.Context) (*TrackerServer, error) {
+	var result *TrackerServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetConnection()
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetStoreStorage
+ */
+func (t *TrackerClient) GetStoreStorageCtx(ctx context.Context, trackerServer *TrackerServer) (*StorageServer, error) {
+	var result *StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetStoreStorage(trackerServer)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetStoreStorageByGroup
+ */
+func (t *TrackerClient) GetStoreStorageByGroupCtx(ctx context.Context, trackerServer *TrackerServer, groupName string) (*StorageServer, error) {
+	var result *StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetStoreStorageByGroup(trackerServer, groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetStoreStorages
+ */
+func (t *TrackerClient) GetStoreStoragesCtx(ctx context.Context, trackerServer *TrackerServer, groupName string) ([]*StorageServer, error) {
+	var result []*StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetStoreStorages(trackerServer, groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFetchStorage
+ */
+func (t *TrackerClient) GetFetchStorageCtx(ctx context.Context, trackerServer *TrackerServer, groupName, filename string) (*StorageServer, error) {
+	var result *StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetFetchStorage(trackerServer, groupName, filename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetUpdateStorage
+ */
+func (t *TrackerClient) GetUpdateStorageCtx(ctx context.Context, trackerServer *TrackerServer, groupName, filename string) (*StorageServer, error) {
+	var result *StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetUpdateStorage(trackerServer, groupName, filename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFetchStorages
+ */
+func (t *TrackerClient) GetFetchStoragesCtx(ctx context.Context, trackerServer *TrackerServer, groupName, filename string) ([]*ServerInfo, error) {
+	var result []*ServerInfo
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetFetchStorages(trackerServer, groupName, filename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetStorages
+ */
+func (t *TrackerClient) GetStoragesCtx(ctx context.Context, trackerServer *TrackerServer, cmd byte, groupName, filename string) ([]*ServerInfo, error) {
+	var result []*ServerInfo
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetStorages(trackerServer, cmd, groupName, filename)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFetchStorage1
+ */
+func (t *TrackerClient) GetFetchStorage1Ctx(ctx context.Context, trackerServer *TrackerServer, fileId string) (*StorageServer, error) {
+	var result *StorageServer
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetFetchStorage1(trackerServer, fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetFetchStorages1
+ */
+func (t *TrackerClient) GetFetchStorages1Ctx(ctx context.Context, trackerServer *TrackerServer, fileId string) ([]*ServerInfo, error) {
+	var result []*ServerInfo
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetFetchStorages1(trackerServer, fileId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListGroups
+ */
+func (t *TrackerClient) ListGroupsCtx(ctx context.Context, trackerServer *TrackerServer) ([]StructGroupStat, error) {
+	var result []StructGroupStat
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListGroups(trackerServer)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListStorages
+ */
+func (t *TrackerClient) ListStoragesCtx(ctx context.Context, trackerServer *TrackerServer, groupName string) ([]StructStorageStat, error) {
+	var result []StructStorageStat
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListStorages(trackerServer, groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListStoragesByIpAddress
+ */
+func (t *TrackerClient) ListStoragesByIpAddressCtx(ctx context.Context, trackerServer *TrackerServer, groupName, storageIpAddr string) ([]StructStorageStat, error) {
+	var result []StructStorageStat
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListStoragesByIpAddress(trackerServer, groupName, storageIpAddr)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteStorage
+ */
+func (t *TrackerClient) DeleteStorageCtx(ctx context.Context, groupName, storageIpAddr string) (bool, error) {
+	var result bool
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.DeleteStorage(groupName, storageIpAddr)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteStorageByTrackerGroup
+ */
+func (t *TrackerClient) DeleteStorageByTrackerGroupCtx(ctx context.Context, trackerGroup *TrackerGroup, groupName, storageIpAddr string) (bool, error) {
+	var result bool
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.DeleteStorageByTrackerGroup(trackerGroup, groupName, storageIpAddr)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListOneGroup
+ */
+func (t *TrackerClient) ListOneGroupCtx(ctx context.Context, trackerServer *TrackerServer, groupName string) (*StructGroupStat, error) {
+	var result *StructGroupStat
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListOneGroup(trackerServer, groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetTrackerStatus
+ */
+func (t *TrackerClient) GetTrackerStatusCtx(ctx context.Context, trackerServer *TrackerServer) (*TrackerStatus, error) {
+	var result *TrackerStatus
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetTrackerStatus(trackerServer)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListTrackerStatus
+ */
+func (t *TrackerClient) ListTrackerStatusCtx(ctx context.Context) ([]*TrackerStatus, error) {
+	var result []*TrackerStatus
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListTrackerStatus()
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of ListTrackerStatusByTrackerGroup
+ */
+func (t *TrackerClient) ListTrackerStatusByTrackerGroupCtx(ctx context.Context, trackerGroup *TrackerGroup) ([]*TrackerStatus, error) {
+	var result []*TrackerStatus
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.ListTrackerStatusByTrackerGroup(trackerGroup)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetLeaderTracker
+ */
+func (t *TrackerClient) GetLeaderTrackerCtx(ctx context.Context) (*TrackerStatus, error) {
+	var result *TrackerStatus
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetLeaderTracker()
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of GetLeaderTrackerByTrackerGroup
+ */
+func (t *TrackerClient) GetLeaderTrackerByTrackerGroupCtx(ctx context.Context, trackerGroup *TrackerGroup) (*TrackerStatus, error) {
+	var result *TrackerStatus
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.GetLeaderTrackerByTrackerGroup(trackerGroup)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteGroup
+ */
+func (t *TrackerClient) DeleteGroupCtx(ctx context.Context, groupName string) (bool, error) {
+	var result bool
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.DeleteGroup(groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of DeleteGroupByTrackerGroup
+ */
+func (t *TrackerClient) DeleteGroupByTrackerGroupCtx(ctx context.Context, trackerGroup *TrackerGroup, groupName string) (bool, error) {
+	var result bool
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.DeleteGroupByTrackerGroup(trackerGroup, groupName)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of SetTrunkServer
+ */
+func (t *TrackerClient) SetTrunkServerCtx(ctx context.Context, groupName, storageId string) (string, error) {
+	var result string
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.SetTrunkServer(groupName, storageId)
+		return err
+	})
+
+	return result, err
+}
+
+/**
+ * context version of SetTrunkServerByTrackerGroup
+ */
+func (t *TrackerClient) SetTrunkServerByTrackerGroupCtx(ctx context.Context, trackerGroup *TrackerGroup, groupName, storageId string) (string, error) {
+	var result string
+	var err = t.withContext(ctx, func(c *TrackerClient) (err error) {
+		result,err = c.SetTrunkServerByTrackerGroup(trackerGroup, groupName, storageId)
+		return err
+	})
+
+	return result, err
+}
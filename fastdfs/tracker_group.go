@@ -1,13 +1,16 @@
 package fastdfs
 
 import (
+	"context"
 	"net"
 	"sync"
-	"time"
-	"fmt"
-	"os"
-	"runtime/debug"
 	"reflect"
+	"time"
+)
+
+const (
+	DefaultTrackerFailureThreshold = 3                //consecutive failures marking the tracker down
+	DefaultTrackerCooldown = 30 * time.Second         //the down tracker is skipped in the cooldown
 )
 
 /**
@@ -19,9 +22,26 @@ import (
 type TrackerGroup struct {
 	TrackerServerIndex     int
 	TrackerServers         []net.Addr
+	failureThreshold       int
+	cooldown               time.Duration
+	states                 []*TrackerState
+	stopProbe              chan struct{}
 	lock                   sync.Mutex
 }
 
+/**
+ * health state of a tracker server
+ */
+type TrackerState struct {
+	Addr           net.Addr
+	Up             bool
+	Failures       int           //consecutive failures
+	LastError      error         //nil if the last connection or probe succeeded
+	LastCheckTime  time.Time     //time of the last connection or probe
+	DownSince      time.Time     //zero if up
+	Latency        time.Duration //time of the last successful connection or probe
+}
+
 /**
  * Constructor
  *
@@ -31,26 +51,265 @@ func NewTrackerGroup(trackerServers []net.Addr) *TrackerGroup {
 	var trackerGroup = new(TrackerGroup)
 	trackerGroup.TrackerServers = trackerServers
 	trackerGroup.TrackerServerIndex = 0
+	trackerGroup.failureThreshold = DefaultTrackerFailureThreshold
+	trackerGroup.cooldown = DefaultTrackerCooldown
 
 	return trackerGroup
 }
 
+/**
+ * set the circuit breaking of the trackers
+ *
+ * @param failureThreshold consecutive failures marking the tracker down, <= 0 to never mark down
+ * @param cooldown         the down tracker is skipped in the cooldown, then it is tried again
+ */
+func (t *TrackerGroup) SetCircuitBreaker(failureThreshold int, cooldown time.Duration) {
+	t.lock.Lock()
+	t.failureThreshold = failureThreshold
+	t.cooldown = cooldown
+	t.lock.Unlock()
+}
+
+/**
+ * replace the tracker servers, safe with the running connections
+ *
+ * @param trackerServers the tracker servers
+ */
+func (t *TrackerGroup) SetTrackerServers(trackerServers []net.Addr) {
+	t.lock.Lock()
+	t.TrackerServers = trackerServers
+	t.lock.Unlock()
+}
+
+/**
+ * get the health states of the tracker servers
+ *
+ * @return the states in the order of the tracker servers
+ */
+func (t *TrackerGroup) GetStates() []TrackerState {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var states = make([]TrackerState, len(t.TrackerServers))
+	for i := range t.TrackerServers {
+		states[i] = *t.getState(i)
+	}
+
+	return states
+}
+
+/**
+ * get the health state of the tracker server
+ *
+ * @param serverIndex the tracker server index
+ * @return the state
+ */
+func (t *TrackerGroup) GetState(serverIndex int) TrackerState {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return *t.getState(serverIndex)
+}
+
+// must hold the lock, the tracker servers may be changed after created.
+func (t *TrackerGroup) getState(serverIndex int) *TrackerState {
+	if serverIndex >= len(t.TrackerServers) {
+		// the index of the replaced servers, not recorded
+		return &TrackerState{Up:true}
+	}
+	if len(t.states) != len(t.TrackerServers) {
+		var states = make([]*TrackerState, len(t.TrackerServers))
+		copy(states, t.states)
+		t.states = states
+	}
+	var state = t.states[serverIndex]
+	if state == nil || state.Addr != t.TrackerServers[serverIndex] {
+		state = &TrackerState{Addr:t.TrackerServers[serverIndex], Up:true}
+		t.states[serverIndex] = state
+	}
+
+	return state
+}
+
+/**
+ * record the result of connecting or probing the tracker server
+ *
+ * @param serverIndex the tracker server index
+ * @param latency     the time of the connection or probe
+ * @param err         the error, nil for success
+ */
+func (t *TrackerGroup) report(serverIndex int, latency time.Duration, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var state = t.getState(serverIndex)
+	state.LastCheckTime = time.Now()
+	state.LastError = err
+	if err == nil {
+		state.Up = true
+		state.Failures = 0
+		state.DownSince = time.Time{}
+		state.Latency = latency
+		return
+	}
+
+	state.Failures++
+	if state.Up && t.failureThreshold > 0 && state.Failures >= t.failureThreshold {
+		state.Up = false
+		state.DownSince = state.LastCheckTime
+	} else if !state.Up {
+		// the trial after the cooldown fails, wait another cooldown
+		state.DownSince = state.LastCheckTime
+	}
+}
+
+/**
+ * check if the tracker server is skipped, the down tracker is tried again after the cooldown
+ */
+func (t *TrackerGroup) isSkipped(serverIndex int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var state = t.getState(serverIndex)
+
+	return !state.Up && time.Since(state.DownSince) < t.cooldown
+}
+
+/**
+ * probe the tracker servers with ACTIVE_TEST in the background, the down
+ * trackers are marked up when they answer, the running probe is stopped
+ *
+ * @param config   the settings the group belongs to, nil for the global settings
+ * @param interval the probe interval
+ * @param timeout  the connect and network timeout of each probe, <= 0 for the timeouts of config
+ */
+func (t *TrackerGroup) StartProbe(config *Config, interval, timeout time.Duration) {
+	var stop = make(chan struct{})
+	t.lock.Lock()
+	if t.stopProbe != nil {
+		close(t.stopProbe)
+	}
+	t.stopProbe = stop
+	t.lock.Unlock()
+
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.Probe(config, timeout)
+			}
+		}
+	}()
+}
+
+/**
+ * stop the background probe
+ */
+func (t *TrackerGroup) StopProbe() {
+	t.lock.Lock()
+	if t.stopProbe != nil {
+		close(t.stopProbe)
+		t.stopProbe = nil
+	}
+	t.lock.Unlock()
+}
+
+/**
+ * probe each tracker server with ACTIVE_TEST and record the result
+ *
+ * @param config  the settings the group belongs to, nil for the global settings
+ * @param timeout the connect and network timeout of each probe, <= 0 for the timeouts of config
+ */
+func (t *TrackerGroup) Probe(config *Config, timeout time.Duration) {
+	t.lock.Lock()
+	var trackerServers = t.TrackerServers
+	t.lock.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range trackerServers {
+		wg.Add(1)
+		go func(serverIndex int) {
+			defer wg.Done()
+			var start = time.Now()
+			var err = probeTracker(config, trackerServers[serverIndex], timeout)
+			t.report(serverIndex, time.Since(start), err)
+		}(i)
+	}
+	wg.Wait()
+}
+
+/**
+ * send ACTIVE_TEST to the tracker server by a new connection
+ */
+func probeTracker(config *Config, addr net.Addr, timeout time.Duration) error {
+	var connectTimeout = config.getConnectTimeout()
+	var networkTimeout = config.getNetworkTimeout()
+	if timeout > 0 {
+		connectTimeout,networkTimeout = timeout, timeout
+	}
+
+	conn,err := net.DialTimeout("tcp", addr.String(), connectTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(networkTimeout)); err != nil {
+		return err
+	}
+	ok,err := ActiveTest(conn)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(ERR_NO_EIO, FDFS_PROTO_CMD_ACTIVE_TEST, addr)
+	}
+
+	return CloseSocket(conn)
+}
+
 /**
  * return connected tracker server
  *
  * @return connected tracker server, null for fail
  */
 func (t *TrackerGroup) GetConnectionByIndex(serverIndex int) (*TrackerServer, error) {
-	//fmt.Println("timeout:", time.Duration(GConnectTimeout) * time.Microsecond)
-	//conn,err := net.Dial("tcp", t.TrackerServers[serverIndex].String())
-	conn,err := net.DialTimeout("tcp", t.TrackerServers[serverIndex].String(), time.Duration(GConnectTimeout) * time.Microsecond)
+	return t.GetConnectionByIndexContext(context.Background(), serverIndex)
+}
+
+/**
+ * return connected tracker server, dialing is aborted when ctx is done
+ *
+ * @param ctx         the context
+ * @param serverIndex the tracker server index
+ * @return connected tracker server
+ */
+func (t *TrackerGroup) GetConnectionByIndexContext(ctx context.Context, serverIndex int) (*TrackerServer, error) {
+	return t.getConnectionByIndex(ctx, nil, 0, serverIndex)
+}
+
+func (t *TrackerGroup) getConnectionByIndex(ctx context.Context, config *Config, cmd byte, serverIndex int) (*TrackerServer, error) {
+	t.lock.Lock()
+	var trackerServers = t.TrackerServers
+	t.lock.Unlock()
+	if serverIndex < 0 || serverIndex >= len(trackerServers) {
+		return nil, newInvalidError("tracker server index %d out of range", serverIndex)
+	}
+
+	var start = time.Now()
+	conn,err := config.connect(ctx, cmd, trackerServers[serverIndex])
 	if err != nil {
+		if ctx.Err() == nil {
+			t.report(serverIndex, 0, err)
+		}
 		return nil, err
 	}
+	t.report(serverIndex, time.Since(start), nil)
 
-	// TODO set address reused.
-
-	return NewTrackerServer(conn, t.TrackerServers[serverIndex]), nil
+	return NewTrackerServer(conn, trackerServers[serverIndex]), nil
 }
 
 /**
@@ -59,55 +318,103 @@ func (t *TrackerGroup) GetConnectionByIndex(serverIndex int) (*TrackerServer, er
  * @return connected tracker server, null for fail
  */
 func (t *TrackerGroup) GetConnection() (*TrackerServer, error) {
+	return t.GetConnectionContext(context.Background())
+}
+
+/**
+ * return connected tracker server, try the others if fail until ctx is done,
+ * the down trackers are tried at last
+ *
+ * @param ctx the context
+ * @return connected tracker server
+ */
+func (t *TrackerGroup) GetConnectionContext(ctx context.Context) (*TrackerServer, error) {
+	return t.getConnection(ctx, nil, 0)
+}
+
+func (t *TrackerGroup) getConnection(ctx context.Context, config *Config, cmd byte) (*TrackerServer, error) {
 	var currentIndex int
+	var trackerServers []net.Addr
 	t.lock.Lock()
 	{
+		// the tracker servers may be changed after created
+		trackerServers = t.TrackerServers
 		t.TrackerServerIndex++
-		if t.TrackerServerIndex >= len(t.TrackerServers) {
+		if t.TrackerServerIndex >= len(trackerServers) {
 			t.TrackerServerIndex = 0
 		}
 
 		currentIndex = t.TrackerServerIndex
 	}
 	t.lock.Unlock()
-
-	if server,err := t.GetConnectionByIndex(currentIndex); err == nil {
-		return server, nil
-	} else {
-		fmt.Fprintln(os.Stderr, "connect to server " + t.TrackerServers[currentIndex].String() + " fail")
-		debug.PrintStack()
+	if len(trackerServers) == 0 {
+		return nil, newInvalidError("no tracker server")
 	}
 
+	var skipped []int
 	var trackerServer *TrackerServer
 	var err error
-	for i := 0; i < len(t.TrackerServers); i++ {
-		if i == currentIndex {
+	for i := 0; i < len(trackerServers); i++ {
+		var serverIndex = (currentIndex + i) % len(trackerServers)
+		if t.isSkipped(serverIndex) {
+			config.getLogger().Debug("fastdfs: skip down tracker", "addr", trackerServers[serverIndex].String())
+			skipped = append(skipped, serverIndex)
 			continue
 		}
-		trackerServer,err = t.GetConnectionByIndex(i)
-		if err == nil {
-			t.lock.Lock()
-			if t.TrackerServerIndex == currentIndex {
-				t.TrackerServerIndex = i
-			}
-			t.lock.Unlock()
-			return trackerServer, nil
-		} else {
-			fmt.Fprintln(os.Stderr, "connect to server " + t.TrackerServers[i].String() + " fail")
-			debug.PrintStack()
+		if trackerServer,err = t.tryConnection(ctx, config, cmd, currentIndex, serverIndex); err == nil || ctx.Err() != nil {
+			return trackerServer, err
+		}
+		config.getLogger().Warn("fastdfs: tracker failover", "addr", trackerServers[serverIndex].String(), "error", err)
+	}
+	for _,serverIndex := range skipped {
+		if trackerServer,err = t.tryConnection(ctx, config, cmd, currentIndex, serverIndex); err == nil || ctx.Err() != nil {
+			return trackerServer, err
 		}
+		config.getLogger().Warn("fastdfs: tracker failover", "addr", trackerServers[serverIndex].String(), "error", err)
 	}
 
 	return trackerServer, err
 }
 
+/**
+ * connect to the tracker server, the rotation continues from it if connected
+ */
+func (t *TrackerGroup) tryConnection(ctx context.Context, config *Config, cmd byte, currentIndex, serverIndex int) (*TrackerServer, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	trackerServer,err := t.getConnectionByIndex(ctx, config, cmd, serverIndex)
+	if err != nil {
+		return nil, err
+	}
+	if serverIndex != currentIndex {
+		t.lock.Lock()
+		if t.TrackerServerIndex == currentIndex {
+			t.TrackerServerIndex = serverIndex
+		}
+		t.lock.Unlock()
+	}
+
+	return trackerServer, nil
+}
+
 func (t *TrackerGroup) Clone() *TrackerGroup {
-	var trackerServers = make([]net.Addr, len(t.TrackerServers))
-	for i := 0; i < len(t.TrackerServers); i++ {
-		var val = reflect.New(reflect.TypeOf(t.TrackerServers[i]).Elem())
-		val.Elem().Set(reflect.ValueOf(t.TrackerServers[i]).Elem())
+	t.lock.Lock()
+	var servers = t.TrackerServers
+	t.lock.Unlock()
+
+	var trackerServers = make([]net.Addr, len(servers))
+	for i := 0; i < len(servers); i++ {
+		var val = reflect.New(reflect.TypeOf(servers[i]).Elem())
+		val.Elem().Set(reflect.ValueOf(servers[i]).Elem())
 		trackerServers[i] = val.Interface().(net.Addr)
 	}
 
-	return NewTrackerGroup(trackerServers)
+	var trackerGroup = NewTrackerGroup(trackerServers)
+	t.lock.Lock()
+	trackerGroup.failureThreshold = t.failureThreshold
+	trackerGroup.cooldown = t.cooldown
+	t.lock.Unlock()
+
+	return trackerGroup
 }
\ No newline at end of file
@@ -2,7 +2,9 @@ package fastdfs
 
 import (
 	"testing"
+	"context"
 	"net"
+	"time"
 )
 
 func TestNewTrackerGroup(t *testing.T) {
@@ -43,4 +45,123 @@ func TestNewTrackerGroup(t *testing.T) {
 		panic(err)
 	}
 	tracker.Close()
-}
\ No newline at end of file
+}
+
+func TestTrackerGroupHealth(t *testing.T) {
+	alive,_ := startActiveTestServer(t)
+	defer alive.Close()
+	dead,_ := startActiveTestServer(t)
+	var deadAddr = dead.Addr()
+	dead.Close()
+
+	var group = NewTrackerGroup([]net.Addr{deadAddr, alive.Addr()})
+	group.SetCircuitBreaker(2, time.Hour)
+	var config = NewConfig(WithTrackerGroup(group), WithConnectionPool(nil))
+	for i := 0; i < 6; i++ {
+		tracker,err := group.getConnection(context.Background(), config, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tracker.GetAddress() != alive.Addr() {
+			t.Fatalf("connected to %v, expect %v", tracker.GetAddress(), alive.Addr())
+		}
+		tracker.Close()
+	}
+	var states = group.GetStates()
+	if states[0].Up || states[0].Failures != 2 || states[0].LastError == nil || states[0].DownSince.IsZero() {
+		t.Fatalf("dead tracker state %+v", states[0])
+	}
+	if !states[1].Up || states[1].Failures != 0 || states[1].LastError != nil || states[1].LastCheckTime.IsZero() {
+		t.Fatalf("alive tracker state %+v", states[1])
+	}
+
+	// all trackers are down, the skipped ones are tried
+	alive.Close()
+	if _,err := group.getConnection(context.Background(), config, 0); err == nil {
+		t.Fatalf("connected without alive tracker")
+	}
+	if state := group.GetState(0); state.Failures != 3 {
+		t.Fatalf("skipped tracker is not tried, failures %d", state.Failures)
+	}
+
+	// the probe marks the tracker up again
+	restarted,_ := startActiveTestServerAt(t, deadAddr.String())
+	defer restarted.Close()
+	group.StartProbe(config, 10 * time.Millisecond, time.Second)
+	defer group.StopProbe()
+	var deadline = time.Now().Add(5 * time.Second)
+	for !group.GetState(0).Up {
+		if time.Now().After(deadline) {
+			t.Fatalf("tracker is not up after probe, state %+v", group.GetState(0))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state := group.GetState(1); state.Up {
+		t.Fatalf("closed tracker is up after probe")
+	}
+}
+
+func TestTrackerGroupProbeTimeout(t *testing.T) {
+	// the tracker accepts the connection and never answers
+	listener,err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	var group = NewTrackerGroup([]net.Addr{listener.Addr()})
+	var config = NewConfig(WithTrackerGroup(group), WithConnectionPool(nil), WithNetworkTimeout(100 * time.Millisecond))
+	var start = time.Now()
+	group.Probe(config, 0)
+	if elapsed := time.Since(start); elapsed > 5 * time.Second {
+		t.Fatalf("probe takes %v, expect the network timeout of the config", elapsed)
+	}
+	if state := group.GetState(0); state.LastError == nil || state.Failures != 1 {
+		t.Fatalf("state %+v after the probe timeout", state)
+	}
+}
+
+func TestTrackerGroupSetTrackerServers(t *testing.T) {
+	listener,err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn,err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// the servers are replaced while connecting
+	var group = NewTrackerGroup([]net.Addr{listener.Addr()})
+	var config = NewConfig(WithTrackerGroup(group), WithConnectionPool(nil))
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			group.SetTrackerServers([]net.Addr{listener.Addr(), listener.Addr()}[:i % 2 + 1])
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		tracker,err := group.getConnection(context.Background(), config, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.Close()
+	}
+	<-done
+}
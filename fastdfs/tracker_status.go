@@ -0,0 +1,74 @@
+package fastdfs
+
+import (
+	"fmt"
+	"time"
+)
+
+/**
+ * the status of a tracker server
+ */
+type TrackerStatus struct {
+	address           string
+	leader            bool
+	runningTime       time.Duration
+	restartInterval   time.Duration
+}
+
+/**
+ * Constructor
+ *
+ * @param address          the tracker server address
+ * @param leader           if the tracker server is the leader
+ * @param running_time     the running time in seconds
+ * @param restart_interval the restart interval of the leader in seconds
+ */
+func NewTrackerStatus(address string, leader bool, runningTime, restartInterval int64) *TrackerStatus {
+	return &TrackerStatus{
+		address:address,
+		leader:leader,
+		runningTime:time.Duration(runningTime) * time.Second,
+		restartInterval:time.Duration(restartInterval) * time.Second,
+	}
+}
+
+/**
+ * get the tracker server address
+ *
+ * @return the address, ip:port
+ */
+func (s *TrackerStatus) GetAddress() string {
+	return s.address
+}
+
+/**
+ * check if the tracker server is the leader of the tracker servers
+ *
+ * @return true for the leader
+ */
+func (s *TrackerStatus) IsLeader() bool {
+	return s.leader
+}
+
+/**
+ * get the running time of the tracker server
+ *
+ * @return the running time
+ */
+func (s *TrackerStatus) GetRunningTime() time.Duration {
+	return s.runningTime
+}
+
+/**
+ * get the interval between the last restart of the leader and the start of
+ * the tracker server, used by the trackers to select the leader
+ *
+ * @return the restart interval
+ */
+func (s *TrackerStatus) GetRestartInterval() time.Duration {
+	return s.restartInterval
+}
+
+func (s *TrackerStatus) String() string {
+	return fmt.Sprintf("address = %s, leader = %t, running_time = %s, restart_interval = %s", s.address, s.leader, s.runningTime, s.restartInterval)
+}
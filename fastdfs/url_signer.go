@@ -0,0 +1,290 @@
+package fastdfs
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultURLTTL = 900 * time.Second //the token_ttl of the storage server http.conf
+
+var (
+	ErrTokenInvalid = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+/**
+ * builder and verifier of the download URLs with the anti-steal token. the URL
+ * is formatted as:<br>
+ * http://&lt;host&gt;[:&lt;http port&gt;]/&lt;file id&gt;?token=&lt;token&gt;&amp;ts=&lt;timestamp&gt;<br>
+ * the token is signed by the first secret key, and verified by all of the keys,
+ * so the secret key can be rotated without breaking the URLs handed out:
+ * put the new key first and remove the old one after the ttl.
+ */
+type URLSigner struct {
+	secretKeys  []string //the first key signs, all keys verify
+	charset     string
+	ttl         time.Duration
+	scheme      string
+	httpPort    int
+	config      *Config //the address translator of the fetch URLs
+	now         func() time.Time
+}
+
+/**
+ * URL signer option
+ */
+type SignerOption func(s *URLSigner)
+
+/**
+ * set the secret keys, the first key signs the tokens
+ *
+ * @param secret_keys the active secret keys, none for no token
+ */
+func WithSecretKeys(secretKeys ...string) SignerOption {
+	return func(s *URLSigner) {
+		s.secretKeys = secretKeys
+	}
+}
+
+/**
+ * set the time to live of the URL, the token generated longer than ttl ago is rejected
+ *
+ * @param ttl the time to live, 0 for no expiration
+ */
+func WithURLTTL(ttl time.Duration) SignerOption {
+	return func(s *URLSigner) {
+		s.ttl = ttl
+	}
+}
+
+/**
+ * set the scheme of the URL
+ *
+ * @param scheme http or https
+ */
+func WithScheme(scheme string) SignerOption {
+	return func(s *URLSigner) {
+		s.scheme = scheme
+	}
+}
+
+/**
+ * set the http port of the URL, the tracker http port of the settings by default
+ *
+ * @param port the http port
+ */
+func WithHttpPort(port int) SignerOption {
+	return func(s *URLSigner) {
+		s.httpPort = port
+	}
+}
+
+/**
+ * Constructor
+ *
+ * @param config  the settings of the secret key, the charset, the tracker http port and the address
+ *                translator, nil for the global settings
+ * @param options the options to apply
+ */
+func NewURLSigner(config *Config, options ...SignerOption) *URLSigner {
+	var s = &URLSigner{
+		charset:config.getCharset(),
+		ttl:DefaultURLTTL,
+		scheme:"http",
+		config:config,
+		now:time.Now,
+	}
+	if config == nil {
+		if GAntiStealToken {
+			s.secretKeys = []string{GSecretKey}
+		}
+		s.httpPort = GTrackerHttpPort
+	} else {
+		if config.AntiStealToken {
+			s.secretKeys = []string{config.SecretKey}
+		}
+		s.httpPort = config.TrackerHttpPort
+	}
+	for _,option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+/**
+ * create URL signer with the settings of the client
+ *
+ * @param options the options to apply
+ */
+func (c *Client) NewURLSigner(options ...SignerOption) *URLSigner {
+	return NewURLSigner(c.config, options...)
+}
+
+/**
+ * get the time to live of the URL
+ *
+ * @return the time to live, 0 for no expiration
+ */
+func (s *URLSigner) GetTTL() time.Duration {
+	return s.ttl
+}
+
+/**
+ * sign the remote filename with the first secret key
+ *
+ * @param remote_filename the filename without the group name
+ * @param ts              unix timestamp, unit: second
+ * @return token string, empty for no secret key
+ */
+func (s *URLSigner) Sign(remoteFilename string, ts int) (string, error) {
+	if len(s.secretKeys) == 0 {
+		return "", nil
+	}
+
+	return GetTokenByCharset(remoteFilename, ts, s.secretKeys[0], s.charset)
+}
+
+/**
+ * build the download URL of the file, valid for the ttl from now
+ *
+ * @param host    the host name or ip address of the http server
+ * @param file_id the file id (including group name and filename)
+ * @return the URL
+ */
+func (s *URLSigner) BuildURL(host, fileId string) (string, error) {
+	return s.buildURL(host, s.httpPort, fileId)
+}
+
+/**
+ * build the download URL of the file on the http port
+ */
+func (s *URLSigner) buildURL(host string, httpPort int, fileId string) (string, error) {
+	var parts = make([]string, 2)
+	if errno := SplitFileId(fileId, parts); errno != 0 {
+		return "", newInvalidError("invalid file id %s", fileId)
+	}
+
+	var u = &url.URL{
+		Scheme:s.scheme,
+		Host:host,
+		Path:"/" + fileId,
+	}
+	if (s.scheme == "http" && httpPort != 80) || (s.scheme == "https" && httpPort != 443) {
+		u.Host = net.JoinHostPort(host, strconv.Itoa(httpPort))
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]" //ipv6
+	}
+	if len(s.secretKeys) > 0 {
+		var ts = int(s.now().Unix())
+		token,err := s.Sign(parts[1], ts)
+		if err != nil {
+			return "", err
+		}
+		u.RawQuery = url.Values{"token":{token}, "ts":{strconv.Itoa(ts)}}.Encode()
+	}
+
+	return u.String(), nil
+}
+
+/**
+ * build the download URL of the file on the storage server, the address of
+ * the storage server queried from the tracker is translated already
+ *
+ * @param storageServer the storage server
+ * @param file_id       the file id (including group name and filename)
+ * @return the URL
+ */
+func (s *URLSigner) BuildStorageURL(storageServer *StorageServer, fileId string) (string, error) {
+	host,_,err := net.SplitHostPort(storageServer.GetAddress().String())
+	if err != nil {
+		return "", err
+	}
+
+	return s.BuildURL(host, fileId)
+}
+
+/**
+ * build the download URL of the file on the storage server queried from the
+ * tracker, the ip address and the http port of the storage server are
+ * translated by the address translator of the settings
+ *
+ * @param ctx           the context
+ * @param trackerClient the tracker client
+ * @param file_id       the file id (including group name and filename)
+ * @return the URL
+ */
+func (s *URLSigner) BuildFetchURL(ctx context.Context, trackerClient *TrackerClient, fileId string) (string, error) {
+	var parts = make([]string, 2)
+	if errno := SplitFileId(fileId, parts); errno != 0 {
+		return "", newInvalidError("invalid file id %s", fileId)
+	}
+	var servers []*ServerInfo
+	var err = trackerClient.withContext(ctx, func(c *TrackerClient) (err error) {
+		servers,err = c.queryStorages(nil, TRACKER_PROTO_CMD_SERVICE_QUERY_FETCH_ONE, parts[0], parts[1])
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	host,httpPort := s.config.translateAddr(servers[0].GetIpAddr(), s.httpPort)
+	return s.buildURL(host, httpPort, fileId)
+}
+
+/**
+ * verify the token of the remote filename with each secret key
+ *
+ * @param remote_filename the filename without the group name
+ * @param token           the token to verify
+ * @param ts              unix timestamp of the token, unit: second
+ * @return nil for the valid token, ErrTokenExpired or ErrTokenInvalid for the invalid one
+ */
+func (s *URLSigner) Verify(remoteFilename, token string, ts int) error {
+	if s.ttl > 0 && s.now().Sub(time.Unix(int64(ts), 0)) > s.ttl {
+		return ErrTokenExpired
+	}
+	for _,secretKey := range s.secretKeys {
+		expected,err := GetTokenByCharset(remoteFilename, ts, secretKey, s.charset)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return ErrTokenInvalid
+}
+
+/**
+ * verify the download URL, the path ends with the file id and the token is
+ * passed by the query parameters token and ts
+ *
+ * @param u the URL to verify, such as the URL of the http request
+ * @return nil for the valid URL
+ */
+func (s *URLSigner) VerifyURL(u *url.URL) error {
+	var path = u.Path
+	var query = u.Query()
+	ts,err := strconv.Atoi(query.Get("ts"))
+	if err != nil {
+		return ErrTokenInvalid
+	}
+	for {
+		if fileId,err := ParseFileID(strings.TrimPrefix(path, "/")); err == nil {
+			return s.Verify(fileId.GetFilename(), query.Get("token"), ts)
+		}
+		var pos = strings.IndexByte(strings.TrimPrefix(path, "/"), '/') //strip the prefix of the path
+		if pos < 0 {
+			return ErrTokenInvalid
+		}
+		path = strings.TrimPrefix(path, "/")[pos:]
+	}
+}
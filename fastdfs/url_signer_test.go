@@ -0,0 +1,79 @@
+package fastdfs
+
+import (
+	"testing"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func TestURLSigner(t *testing.T) {
+	const fileId = "group1/M00/00/00/wKgBaF1xAAAAAAAAAAAAAAAAAAA.jpg"
+	var now = time.Unix(1600000000, 0)
+	var signer = NewURLSigner(NewConfig(WithAntiStealToken("old")), WithURLTTL(time.Minute))
+	signer.now = func() time.Time { return now }
+
+	u,err := signer.BuildURL("192.168.1.10", fileId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token,_ := GetToken(fileId[len("group1/"):], int(now.Unix()), "old")
+	if u != "http://192.168.1.10/" + fileId + "?token=" + token + "&ts=" + strconv.FormatInt(now.Unix(), 10) {
+		t.Fatalf("url %s", u)
+	}
+	parsed,err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signer.VerifyURL(parsed); err != nil {
+		t.Fatalf("verify err %v", err)
+	}
+
+	// rotate the secret key, the URLs signed by the old key are still valid
+	var rotated = NewURLSigner(nil, WithSecretKeys("new", "old"), WithURLTTL(time.Minute), WithHttpPort(8080))
+	rotated.now = signer.now
+	if err = rotated.VerifyURL(parsed); err != nil {
+		t.Fatalf("verify by rotated keys err %v", err)
+	}
+	u,_ = rotated.BuildURL("192.168.1.10", fileId)
+	if parsed,_ = url.Parse(u); parsed.Host != "192.168.1.10:8080" {
+		t.Fatalf("url %s", u)
+	}
+	if err = signer.VerifyURL(parsed); err != ErrTokenInvalid {
+		t.Fatalf("verify by old key err %v, expect %v", err, ErrTokenInvalid)
+	}
+	if err = rotated.VerifyURL(parsed); err != nil {
+		t.Fatalf("verify err %v", err)
+	}
+
+	// the path may be prefixed
+	if err = rotated.VerifyURL(&url.URL{Path:"/files/" + fileId, RawQuery:parsed.RawQuery}); err != nil {
+		t.Fatalf("verify prefixed path err %v", err)
+	}
+	for _,raw := range []string{"/" + fileId, "/" + fileId + "?token=x&ts=1600000000", "/group1/x?" + parsed.RawQuery, "/group2/" + fileId[len("group1/"):] + "?token=" + token + "&ts=x"} {
+		u,_ := url.Parse(raw)
+		if err = rotated.VerifyURL(u); err != ErrTokenInvalid {
+			t.Errorf("verify %s err %v, expect %v", raw, err, ErrTokenInvalid)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err = rotated.VerifyURL(parsed); err != ErrTokenExpired {
+		t.Fatalf("verify expired err %v, expect %v", err, ErrTokenExpired)
+	}
+	if err = NewURLSigner(nil, WithSecretKeys("new"), WithURLTTL(0)).Verify(fileId[len("group1/"):], parsed.Query().Get("token"), int(now.Unix()) - 120); err != nil {
+		t.Fatalf("verify without ttl err %v", err)
+	}
+
+	// no token without the secret key
+	var plain = NewURLSigner(NewConfig(WithTrackerHttpPort(8888)), WithScheme("https"))
+	if u,_ = plain.BuildURL("::1", fileId); u != "https://[::1]:8888/" + fileId {
+		t.Fatalf("url %s", u)
+	}
+	if u,_ = NewURLSigner(nil, WithScheme("https"), WithHttpPort(443)).BuildURL("::1", fileId); u != "https://[::1]/" + fileId {
+		t.Fatalf("url %s", u)
+	}
+	if _,err = plain.BuildURL("localhost", "group1"); err == nil {
+		t.Fatalf("build url with invalid file id")
+	}
+}